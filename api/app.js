@@ -41,7 +41,10 @@ module.exports = function(configFilePath) {
   options = {
       key: fs.readFileSync(settings.tls.keyFile),
       cert: fs.readFileSync(settings.tls.certFile),
-      ca: fs.readFileSync(settings.tls.caCertFile)
+      ca: fs.readFileSync(settings.tls.caCertFile),
+      // client certificates are optional and identify the author of policy revisions
+      requestCert: true,
+      rejectUnauthorized: false
   }
   var app = express();
   app.use(bodyParser.json());
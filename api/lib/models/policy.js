@@ -15,6 +15,12 @@ module.exports = function(sequelize, DataTypes) {
     updated_at: {
       type: DataTypes.DATE,
       field: 'updated_at'
+    },
+    revision: {
+      type: DataTypes.BIGINT,
+      field: 'revision',
+      allowNull: false,
+      defaultValue: 0
     }
   },{
     timestamps: false,
@@ -0,0 +1,43 @@
+'use strict';
+
+module.exports = function(sequelize, DataTypes) {
+  var PolicyHistory = sequelize.define('policy_history', {
+    revision: {
+      type: DataTypes.BIGINT,
+      field: 'revision',
+      primaryKey: true,
+      autoIncrement: true
+    },
+    app_id: {
+      type: DataTypes.STRING,
+      field: 'app_id'
+    },
+    policy_json: {
+      type: DataTypes.JSON,
+      field: 'policy_json'
+    },
+    author: {
+      type: DataTypes.STRING,
+      field: 'author',
+      allowNull: false
+    },
+    created_at: {
+      type: DataTypes.DATE,
+      field: 'created_at',
+      allowNull: false
+    },
+    diff: {
+      type: DataTypes.JSON,
+      field: 'diff'
+    },
+    rollback_of: {
+      type: DataTypes.BIGINT,
+      field: 'rollback_of'
+    }
+  },{
+    timestamps: false,
+    freezeTableName: true
+  });
+
+  return PolicyHistory;
+};
@@ -10,34 +10,38 @@ module.exports = function(settings,tlsOptions){
   var schedulerUtil = require('../utils/schedulerUtils')(settings, tlsOptions);
   var async = require('async');
 
+  var respondWithPolicy = function(req, res) {
+    return function(error, result) {
+      var responseDecorator = { };
+      var statusCode = HttpStatus.OK;
+      if(error) {
+        statusCode = error.statusCode;
+        responseDecorator = {
+          'success': false,
+          'error': error,
+          'result': null
+        };
+      }
+      else {
+        statusCode = result.statusCode;
+        if(result.statusCode === HttpStatus.CREATED) {
+          res.set('Location', '/v1/policies/' + req.params.app_id);
+        }
+        responseDecorator = {
+          'success': true,
+          'error': null,
+          'result': result.response    
+        }
+      }
+      res.status(statusCode).json(responseDecorator);
+    };
+  };
+
   router.put('/:app_id',validationMiddleWare,function(req, res) {
     logger.info('Policy creation request received',{ 'app id': req.params.app_id });
     async.waterfall([async.apply(schedulerUtil.createOrUpdateSchedule, req),
       async.apply(routeHelper.createOrUpdatePolicy, req)],
-      function(error, result) {
-        var responseDecorator = { };
-        var statusCode = HttpStatus.OK;
-        if(error) {
-          statusCode = error.statusCode;
-          responseDecorator = {
-            'success': false,
-            'error': error,
-            'result': null
-          };
-        }
-        else {
-          statusCode = result.statusCode;
-          if(result.statusCode === HttpStatus.CREATED) {
-            res.set('Location', '/v1/policies/' + req.params.app_id);
-          }
-          responseDecorator = {
-            'success': true,
-            'error': null,
-            'result': result.response    
-          }
-        }
-        res.status(statusCode).json(responseDecorator);
-      });
+      respondWithPolicy(req, res));
   });
 
   router.delete('/:app_id',function(req,res) {
@@ -80,6 +84,56 @@ module.exports = function(settings,tlsOptions){
     });
   });
 
+  router.get('/:app_id/revisions',function(req,res) {
+    logger.info('Request for policy revisions received',{ 'app id': req.params.app_id });
+    models.policy_history.findAll({
+      where: { app_id: req.params.app_id },
+      attributes: ['revision', 'author', 'created_at', 'diff', 'rollback_of'],
+      order: [['revision', 'DESC']]
+    }).then(function(revisions) {
+      res.status(HttpStatus.OK).json(revisions);
+    }).catch(function(error) {
+      logger.error ('Failed to retrieve policy revisions',
+          { 'app id': req.params.app_id,'error':error });
+      res.status(HttpStatus.INTERNAL_SERVER_ERROR).json(error);
+    });
+  });
+
+  router.get('/:app_id/revisions/:revision',function(req,res) {
+    logger.info('Request for policy revision received',
+      { 'app id': req.params.app_id, 'revision': req.params.revision });
+    routeHelper.getPolicyRevision(req, function(error, revision) {
+      if(error) {
+        res.status(error.statusCode).json(error.statusCode === HttpStatus.NOT_FOUND ? {} : error);
+        return;
+      }
+      res.status(HttpStatus.OK).json(revision);
+    });
+  });
+
+  router.post('/:app_id/revisions/:revision/rollback',function(req,res) {
+    logger.info('Policy rollback request received',
+      { 'app id': req.params.app_id, 'revision': req.params.revision });
+    async.waterfall([async.apply(routeHelper.getPolicyRevision, req),
+      function(revision, callback) {
+        /* The policy of the revision is applied like a new one, so that the
+        schedules are synchronized and the rollback is recorded as a revision. */
+        if(!revision.policy_json) {
+          callback({
+            message: 'Cannot roll back to a revision which deleted the policy',
+            statusCode: HttpStatus.BAD_REQUEST
+          });
+          return;
+        }
+        req.body = revision.policy_json;
+        req.rollbackOf = revision.revision;
+        callback(null);
+      },
+      async.apply(schedulerUtil.createOrUpdateSchedule, req),
+      async.apply(routeHelper.createOrUpdatePolicy, req)],
+      respondWithPolicy(req, res));
+  });
+
   return router;
 }
 
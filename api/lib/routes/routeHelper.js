@@ -3,43 +3,150 @@ module.exports = function(dbSettings){
   var logger = require('../log/logger');
   var models = require('../models')(dbSettings);
   var HttpStatus = require('http-status-codes');
+  var policyDiff = require('../utils/policyDiff');
   var helper = {};
+
+  var Sequelize = require('sequelize');
+  const DEFAULT_AUTHOR = 'unknown';
+
+  /* The API server has no end user identity, so the author of a revision is
+  the common name of the verified client certificate of the caller, e.g. the
+  service broker, or 'unknown' when the caller presents none. */
+  var getAuthor = function(req) {
+    var socket = req.socket;
+    if(socket && socket.authorized) {
+      var certificate = socket.getPeerCertificate();
+      if(certificate && certificate.subject && certificate.subject.CN) {
+        return certificate.subject.CN;
+      }
+    }
+    return DEFAULT_AUTHOR;
+  };
+
+  var savePolicy = function(req) {
+    var appId = req.params.app_id;
+    return models.sequelize.transaction(function(transaction) {
+      return models.policy_json.findById(appId, { transaction: transaction, lock: transaction.LOCK.UPDATE })
+        .then(function(existingPolicy) {
+          var history = {
+            app_id: appId,
+            policy_json: req.body,
+            author: getAuthor(req),
+            created_at: new Date(),
+            diff: policyDiff(existingPolicy ? existingPolicy.policy_json : null, req.body),
+            rollback_of: req.rollbackOf || null
+          };
+          return models.policy_history.create(history, { transaction: transaction })
+            .then(function(revision) {
+              if(!existingPolicy) {
+                logger.info('No policy exists, creating policy..',
+                  { 'app id': appId, 'revision': revision.revision });
+                return models.policy_json.create({
+                  app_id: appId,
+                  policy_json: req.body,
+                  revision: revision.revision
+                }, { transaction: transaction }).then(function(result) {
+                  return { 'statusCode':HttpStatus.CREATED,'response':result };
+                });
+              }
+              logger.info('Updating the existing policy',{ 'app id': appId, 'revision': revision.revision });
+              return models.policy_json.update({
+                app_id: appId,
+                policy_json: req.body,
+                revision: revision.revision
+              },{ where: { app_id: appId } ,returning:true, transaction: transaction }).then(function(result) {
+                return { 'statusCode':HttpStatus.OK,'response':result[1] };
+              });
+            });
+        });
+    });
+  };
+
   helper.createOrUpdatePolicy = function(req, callback) {
   /*  Create policy will only be called in the async waterfall when we do not 
-  get any error during the schedule creation/update. */
-    models.policy_json.findOrCreate({ where:{ app_id: req.params.app_id },
-      defaults: { app_id: req.params.app_id,
-      policy_json: req.body } })
-      .spread(function(result, created) {
-        if(created) {
-          logger.info('No policy exists, creating policy..',{ 'app id': req.params.app_id });  
-          callback(null, { 'statusCode':HttpStatus.CREATED,'response':result });
-        }
-        else {
-          logger.info('Updating the existing policy',{ 'app id': req.params.app_id });
-          models.policy_json.update({
-            app_id: req.params.app_id,
-            policy_json: req.body
-          },{ where: { app_id: req.params.app_id } ,returning:true }).then(function(result) {
-            callback(null, { 'statusCode':HttpStatus.OK,'response':result[1] });
-          }).catch(function(error) {
-            logger.error ('Failed to update policy',
-               { 'app id': req.params.app_id,'error':error });
-            error.statusCode = HttpStatus.INTERNAL_SERVER_ERROR;
-            callback(error);
-          });
+  get any error during the schedule creation/update. Every policy written is
+  recorded as a new revision in the policy history in the same transaction.
+  A missing policy row cannot be locked, so when a concurrent request creates
+  the policy first, the policy is saved again as an update. */
+    var appId = req.params.app_id;
+    savePolicy(req).catch(function(error) {
+      if(error instanceof Sequelize.UniqueConstraintError) {
+        logger.info('Policy was created concurrently, updating it', { 'app id': appId });
+        return savePolicy(req);
+      }
+      throw error;
+    }).then(function(result) {
+      callback(null, result);
+    }).catch(function(error) {
+      logger.error ('Failed to create or update policy', { 'app id': appId,'error':error });
+      error.statusCode = HttpStatus.INTERNAL_SERVER_ERROR;
+      callback(error);
+    });
+  };
+
+  helper.getPolicyRevision = function(req, callback) {
+    var appId = req.params.app_id;
+    models.policy_history.findOne({ where: { app_id: appId, revision: req.params.revision } })
+      .then(function(revision) {
+        if(!revision) {
+          var error = {
+            message: 'No such policy revision for application',
+            statusCode: HttpStatus.NOT_FOUND
+          };
+          logger.error('No such policy revision for application',
+            { 'app id': appId, 'revision': req.params.revision });
+          callback(error);
+          return;
         }
+        callback(null, revision);
       }).catch(function(error) {
-        logger.error ('Failed to create policy', { 'app id': req.params.app_id,'error':error });
+        logger.error ('Failed to retrieve policy revision',
+          { 'app id': appId, 'revision': req.params.revision, 'error':error });
         error.statusCode = HttpStatus.INTERNAL_SERVER_ERROR;
         callback(error);
       });
-  }
+  };
+
+  /* Removes the app from its app group like the policy db of the golang
+  components does, deleting the group when a single member would be left. */
+  var removeFromAppGroup = function(appId, transaction) {
+    var groupOfApp = '(SELECT group_id FROM app_group_member WHERE app_id = :appId)';
+    var options = { replacements: { appId: appId }, transaction: transaction };
+    return models.sequelize.query('DELETE FROM app_group_member WHERE group_id = ' + groupOfApp +
+      ' AND (SELECT COUNT(*) FROM app_group_member WHERE group_id = ' + groupOfApp + ') <= 2', options)
+      .then(function() {
+        return models.sequelize.query('DELETE FROM app_group_member WHERE app_id = :appId', options);
+      });
+  };
 
   helper.deletePolicy = function(req, callback) {
+  /* The deletion is recorded as a revision without a policy in the policy
+  history in the same transaction. */
     var appId = req.params.app_id;
-    models.policy_json.destroy({ where: { app_id: appId } }).then(function(result) {
-      if(result > 0) {
+    models.sequelize.transaction(function(transaction) {
+      return models.policy_json.findById(appId, { transaction: transaction, lock: transaction.LOCK.UPDATE })
+        .then(function(existingPolicy) {
+          if(!existingPolicy) {
+            return false;
+          }
+          return models.policy_history.create({
+            app_id: appId,
+            policy_json: null,
+            author: getAuthor(req),
+            created_at: new Date(),
+            diff: policyDiff(existingPolicy.policy_json, null),
+            rollback_of: null
+          }, { transaction: transaction }).then(function(revision) {
+            logger.info('Deleting the policy',{ 'app id': appId, 'revision': revision.revision });
+            return models.policy_json.destroy({ where: { app_id: appId }, transaction: transaction });
+          }).then(function(result) {
+            return removeFromAppGroup(appId, transaction).then(function() {
+              return result > 0;
+            });
+          });
+        });
+    }).then(function(deleted) {
+      if(deleted) {
         logger.info('Successfully deleted the policy for application',{ 'app id': appId });
         callback(null);
       }
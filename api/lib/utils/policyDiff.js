@@ -0,0 +1,44 @@
+'use strict';
+var _ = require('underscore');
+
+/* Computes the changes from one policy to another as a list of operations
+like { 'op': 'replace', 'path': '/scaling_rules/0/threshold', 'old_value': 30,
+'value': 40 }. Paths are JSON pointers, and a missing old policy is diffed as
+an empty one. */
+module.exports = function policyDiff(oldPolicy, newPolicy) {
+  var changes = [];
+  diffValues(oldPolicy || {}, newPolicy || {}, '', changes);
+  return changes;
+};
+
+function diffValues(oldValue, newValue, path, changes) {
+  if (_.isEqual(oldValue, newValue)) {
+    return;
+  }
+  if (isContainer(oldValue) && isContainer(newValue) &&
+      _.isArray(oldValue) === _.isArray(newValue)) {
+    var keys = _.union(_.keys(oldValue), _.keys(newValue));
+    _.each(keys, function(key) {
+      var keyPath = path + '/' + escapeKey(key);
+      if (!_.has(oldValue, key)) {
+        changes.push({ 'op': 'add', 'path': keyPath, 'value': newValue[key] });
+      }
+      else if (!_.has(newValue, key)) {
+        changes.push({ 'op': 'remove', 'path': keyPath, 'old_value': oldValue[key] });
+      }
+      else {
+        diffValues(oldValue[key], newValue[key], keyPath, changes);
+      }
+    });
+    return;
+  }
+  changes.push({ 'op': 'replace', 'path': path, 'old_value': oldValue, 'value': newValue });
+}
+
+function isContainer(value) {
+  return _.isArray(value) || _.isObject(value) && !_.isFunction(value);
+}
+
+function escapeKey(key) {
+  return String(key).replace(/~/g, '~0').replace(/\//g, '~1');
+}
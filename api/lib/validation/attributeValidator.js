@@ -215,6 +215,32 @@ var validateSpecificDateErrors = function(inputSpecificDates) {
   return errors;
 }
 
+var validateResizeBounds = function(policyJson,limit) {
+  // resize rules keep the limit of each instance within bounds, the max bound
+  // is required as the limit would otherwise grow unbounded
+  var errors = [];
+  var errorCount = 0;
+  var minProperty = 'instance_' + limit + '_min_mb';
+  var maxProperty = 'instance_' + limit + '_max_mb';
+  var resizeRules = policyJson.scaling_rules.filter(function(rule) {
+    return rule.action === 'resize' && rule[limit + '_adjustment'];
+  });
+  if(resizeRules.length === 0) {
+    return errors;
+  }
+  if(!policyJson[maxProperty]) {
+    errors[errorCount++] = createErrorResponse(maxProperty, maxProperty + ' is required by ' +
+        limit + '_adjustment', policyJson, maxProperty + ' is required when a resize rule has ' +
+        limit + '_adjustment in policy_json');
+  }
+  else if(policyJson[minProperty] && policyJson[minProperty] >= policyJson[maxProperty]) {
+    errors[errorCount++] = createErrorResponse(minProperty, minProperty + ' and ' + maxProperty +
+        ' values are not compatible', policyJson, minProperty + ' ' + policyJson[minProperty] +
+        ' is higher or equal to ' + maxProperty + ' ' + policyJson[maxProperty] + ' in policy_json');
+  }
+  return errors;
+}
+
 var validatePolicyJSONValues = function(policyJson) {
   var errorCount = 0;
   var errors = [];
@@ -237,6 +263,9 @@ var validatePolicyJSONValues = function(policyJson) {
     }
     errors = errors.concat(specificDateErrors,recurringScheduleErrors);
   }
+  if(policyJson.scaling_rules) {
+    errors = errors.concat(validateResizeBounds(policyJson,'memory'),validateResizeBounds(policyJson,'disk'));
+  }
 
   return errors;
 }
@@ -40,6 +40,11 @@ var getValidOperators = function() {
   return validOperators;
 };
 
+var getScalingActions = function() {
+  var scalingActions = ['scale','restart_instance','resize'];
+  return scalingActions;
+};
+
 var getAdjustmentPattern = function() {
   var adjustmentPattern = '^[-|+][1-9]+[0-9]*$';
   return adjustmentPattern;
@@ -69,6 +74,11 @@ var getPolicySchema = function() {
     'properties' :{
       'instance_min_count': { 'type':'integer','minimum':1 },
       'instance_max_count': { 'type':'integer','minimum':1 },
+      'process_type': { 'type':'string','minLength':1 },
+      'instance_memory_min_mb': { 'type':'integer','minimum':1 },
+      'instance_memory_max_mb': { 'type':'integer','minimum':1 },
+      'instance_disk_min_mb': { 'type':'integer','minimum':1 },
+      'instance_disk_max_mb': { 'type':'integer','minimum':1 },
       'scaling_rules': {
         'type':'array',
         'items': { '$ref': '/scaling_rules' }
@@ -85,6 +95,7 @@ var getPolicySchema = function() {
 var getScalingRuleSchema = function() {
   var validOperators = getValidOperators();
   var adjustmentPattern = getAdjustmentPattern();
+  var scalingActions = getScalingActions();
   var metricTypeEnum = getMetricTypes();
   var schema = {
     'type': 'object',
@@ -96,9 +107,19 @@ var getScalingRuleSchema = function() {
       'threshold':{ 'type':'number','minimum': 1,'maximum': 100 },
       'operator':{ 'type':'string','enum': validOperators },
       'cool_down_secs':{ 'type':'number','minimum': 60,'maximum': 3600 },
-      'adjustment':{ 'type':'string','pattern': adjustmentPattern }
+      'adjustment':{ 'type':'string','pattern': adjustmentPattern },
+      'action':{ 'type':'string','enum': scalingActions },
+      'memory_adjustment':{ 'type':'string','pattern': adjustmentPattern },
+      'disk_adjustment':{ 'type':'string','pattern': adjustmentPattern }
     },
-    'required' : ['metric_type','threshold','operator','adjustment']
+    'required' : ['metric_type','threshold','operator'],
+    'anyOf' : [ { 'properties' : { 'action':{ 'enum':['scale'] } }, 'required' : ['adjustment'],
+        'not' : { 'anyOf' : [ { 'required' : ['memory_adjustment'] }, { 'required' : ['disk_adjustment'] } ] } },
+      { 'properties' : { 'action':{ 'enum':['restart_instance'] } }, 'required' : ['action'],
+        'not' : { 'anyOf' : [ { 'required' : ['adjustment'] }, { 'required' : ['memory_adjustment'] }, { 'required' : ['disk_adjustment'] } ] } },
+      { 'properties' : { 'action':{ 'enum':['resize'] } }, 'required' : ['action'],
+        'anyOf' : [ { 'required' : ['memory_adjustment'] }, { 'required' : ['disk_adjustment'] } ],
+        'not' : { 'required' : ['adjustment'] } } ]
   };  
   return schema;
 };
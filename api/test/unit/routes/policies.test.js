@@ -9,7 +9,9 @@ var settings = require(path.join(__dirname, '../../../lib/config/setting.js'))((
   fs.readFileSync(path.join(__dirname, '../../../config/settings.json'), 'utf8'))));
 var API = require('../../../app.js');
 var app;
-var policy = require('../../../lib/models')(settings.db).policy_json;
+var models = require('../../../lib/models')(settings.db);
+var policy = models.policy_json;
+var policyHistory = models.policy_history;
 var logger = require('../../../lib/log/logger');
 var nock = require('nock');
 var schedulerURI = settings.schedulerUri ;
@@ -25,7 +27,11 @@ describe('Routing Policy Creation', function() {
     app.close(done);
   })
   beforeEach(function() {
-    return policy.truncate();
+    return policy.truncate().then(function() {
+      return policyHistory.truncate();
+    }).then(function() {
+      return models.sequelize.query('DELETE FROM app_group_member');
+    });
   });
 
   it('should create a policy for app id 12345', function(done) {
@@ -128,6 +134,90 @@ describe('Routing Policy Creation', function() {
       });
     });
 
+    it('should delete the app group left with a single member',function(done){
+      nock(schedulerURI)
+      .delete('/v2/schedules/12345')
+      .reply(200);
+
+      models.sequelize.query('INSERT INTO app_group_member(app_id, group_id, ratio, instance_min, instance_max) ' +
+        "VALUES ('12345', 'a-group-id', 1, 1, 5), ('12346', 'a-group-id', 1, 1, 5)").then(function() {
+        request(app)
+        .delete('/v1/policies/12345')
+        .end(function(error,result) {
+          expect(result.statusCode).to.equal(200);
+          models.sequelize.query("SELECT app_id FROM app_group_member WHERE group_id = 'a-group-id'",
+            { type: models.sequelize.QueryTypes.SELECT }).then(function(members) {
+            expect(members).to.be.empty;
+            done();
+          });
+        });
+      });
+    });
+
+    it('should record the deletion as a revision without a policy',function(done){
+      nock(schedulerURI)
+      .delete('/v2/schedules/12345')
+      .reply(200);
+
+      request(app)
+      .delete('/v1/policies/12345')
+      .end(function() {
+        request(app)
+        .get('/v1/policies/12345/revisions')
+        .end(function(error,result) {
+          expect(result.body).to.have.lengthOf(2);
+          expect(result.body[0].diff).to.not.be.empty;
+          request(app)
+          .get('/v1/policies/12345/revisions/' + result.body[0].revision)
+          .end(function(error,result) {
+            expect(result.statusCode).to.equal(200);
+            expect(result.body.policy_json).to.be.null;
+            done();
+          });
+        });
+      });
+    });
+
+    it('should fail to roll back to the revision which deleted the policy',function(done){
+      nock(schedulerURI)
+      .delete('/v2/schedules/12345')
+      .reply(200);
+
+      request(app)
+      .delete('/v1/policies/12345')
+      .end(function() {
+        request(app)
+        .get('/v1/policies/12345/revisions')
+        .end(function(error,result) {
+          request(app)
+          .post('/v1/policies/12345/revisions/' + result.body[0].revision + '/rollback')
+          .end(function(error,result) {
+            expect(result.statusCode).to.equal(400);
+            expect(result.body.success).to.equal(false);
+            done();
+          });
+        });
+      });
+    });
+
+    it('should not take the author of a revision from a request header', function(done) {
+      nock(schedulerURI)
+      .put('/v2/schedules/12345')
+      .reply(200);
+      request(app)
+      .put('/v1/policies/12345')
+      .set('X-Autoscaler-Author', 'someone-else')
+      .send(fakePolicy)
+      .end(function() {
+        request(app)
+        .get('/v1/policies/12345/revisions')
+        .end(function(error,result) {
+          expect(result.body[0].author).to.equal('unknown');
+          done();
+        });
+      });
+    });
+
     it('should fail to delete the policy with app id 12345 due to internal server error',function(done){
         nock(schedulerURI)
         .delete('/v2/schedules/12345')
@@ -144,6 +234,26 @@ describe('Routing Policy Creation', function() {
   });
 
   context('when policy does not exist' ,function() {
+    it('should create the policy once when it is created concurrently', function(done) {
+      nock(schedulerURI)
+      .put('/v2/schedules/12345')
+      .times(2)
+      .reply(200);
+      var statusCodes = [];
+      var onResponse = function(error,result) {
+        statusCodes.push(result.statusCode);
+        if(statusCodes.length === 2) {
+          expect(statusCodes.sort()).to.deep.equal([200, 201]);
+          policyHistory.count({ where: { app_id: '12345' } }).then(function(count) {
+            expect(count).to.equal(2);
+            done();
+          });
+        }
+      };
+      request(app).put('/v1/policies/12345').send(fakePolicy).end(onResponse);
+      request(app).put('/v1/policies/12345').send(fakePolicy).end(onResponse);
+    });
+
     it('should return 404 while deleting policy with app id 12345',function(done){
     
       request(app)
@@ -165,4 +275,98 @@ describe('Routing Policy Creation', function() {
     });
 
   });
+
+  context('when a policy was updated' ,function() {
+    var updatedPolicy;
+
+    beforeEach(function(done) {
+      updatedPolicy = JSON.parse(JSON.stringify(fakePolicy));
+      updatedPolicy.instance_max_count = fakePolicy.instance_max_count + 1;
+      nock(schedulerURI)
+      .put('/v2/schedules/12345')
+      .times(2)
+      .reply(200);
+      request(app)
+      .put('/v1/policies/12345')
+      .send(fakePolicy).end(function() {
+        request(app)
+        .put('/v1/policies/12345')
+        .send(updatedPolicy).end(done);
+      });
+    });
+
+    it('should list the revisions of the policy with the latest first', function(done) {
+      request(app)
+      .get('/v1/policies/12345/revisions')
+      .end(function(error,result) {
+        expect(result.statusCode).to.equal(200);
+        expect(result.body).to.have.lengthOf(2);
+        expect(result.body[0].author).to.equal('unknown');
+        expect(result.body[0].diff).to.deep.equal([{ 'op': 'replace', 'path': '/instance_max_count',
+          'old_value': fakePolicy.instance_max_count, 'value': updatedPolicy.instance_max_count }]);
+        expect(result.body[1].author).to.equal('unknown');
+        expect(result.body[0]).to.not.have.property('policy_json');
+        done();
+      });
+    });
+
+    it('should get the policy of a revision', function(done) {
+      request(app)
+      .get('/v1/policies/12345/revisions')
+      .end(function(error,result) {
+        request(app)
+        .get('/v1/policies/12345/revisions/' + result.body[1].revision)
+        .end(function(error,result) {
+          expect(result.statusCode).to.equal(200);
+          expect(result.body.policy_json).to.deep.equal(fakePolicy);
+          done();
+        });
+      });
+    });
+
+    it('should fail to get a revision which does not exist', function(done) {
+      request(app)
+      .get('/v1/policies/12345/revisions/0')
+      .end(function(error,result) {
+        expect(result.statusCode).to.equal(404);
+        expect(result.body).eql({});
+        done();
+      });
+    });
+
+    it('should roll back the policy to a revision', function(done) {
+      nock(schedulerURI)
+      .put('/v2/schedules/12345')
+      .reply(200);
+      request(app)
+      .get('/v1/policies/12345/revisions')
+      .end(function(error,result) {
+        var firstRevision = result.body[1].revision;
+        request(app)
+        .post('/v1/policies/12345/revisions/' + firstRevision + '/rollback')
+        .end(function(error,result) {
+          expect(result.statusCode).to.equal(200);
+          expect(result.body.success).to.equal(true);
+          expect(result.body.result[0].policy_json).eql(fakePolicy);
+          request(app)
+          .get('/v1/policies/12345/revisions')
+          .end(function(error,result) {
+            expect(result.body).to.have.lengthOf(3);
+            expect(result.body[0].rollback_of).to.equal(firstRevision);
+            done();
+          });
+        });
+      });
+    });
+
+    it('should fail to roll back to a revision which does not exist', function(done) {
+      request(app)
+      .post('/v1/policies/12345/revisions/0/rollback')
+      .end(function(error,result) {
+        expect(result.statusCode).to.equal(404);
+        expect(result.body.success).to.equal(false);
+        done();
+      });
+    });
+  });
 });
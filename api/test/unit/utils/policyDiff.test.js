@@ -0,0 +1,47 @@
+'use strict';
+
+var expect = require('chai').expect;
+var policyDiff = require('../../../lib/utils/policyDiff');
+
+describe('Policy diff', function() {
+  var oldPolicy;
+
+  beforeEach(function() {
+    oldPolicy = {
+      'instance_min_count': 1,
+      'instance_max_count': 4,
+      'scaling_rules': [{ 'metric_type': 'memoryused', 'threshold': 30 }]
+    };
+  });
+
+  it('should return no changes for equal policies', function() {
+    expect(policyDiff(oldPolicy, JSON.parse(JSON.stringify(oldPolicy)))).to.deep.equal([]);
+  });
+
+  it('should diff a new policy against an empty one', function() {
+    expect(policyDiff(null, { 'instance_min_count': 1 })).to.deep.equal([
+      { 'op': 'add', 'path': '/instance_min_count', 'value': 1 }
+    ]);
+  });
+
+  it('should return the changed, added and removed attributes', function() {
+    var newPolicy = {
+      'instance_min_count': 1,
+      'scaling_rules': [{ 'metric_type': 'memoryused', 'threshold': 40 },
+        { 'metric_type': 'throughput', 'threshold': 100 }],
+      'schedules': { 'timezone': 'Asia/Shanghai' }
+    };
+    expect(policyDiff(oldPolicy, newPolicy)).to.deep.equal([
+      { 'op': 'remove', 'path': '/instance_max_count', 'old_value': 4 },
+      { 'op': 'replace', 'path': '/scaling_rules/0/threshold', 'old_value': 30, 'value': 40 },
+      { 'op': 'add', 'path': '/scaling_rules/1', 'value': { 'metric_type': 'throughput', 'threshold': 100 } },
+      { 'op': 'add', 'path': '/schedules', 'value': { 'timezone': 'Asia/Shanghai' } }
+    ]);
+  });
+
+  it('should replace values whose type changed', function() {
+    expect(policyDiff({ 'schedules': [] }, { 'schedules': {} })).to.deep.equal([
+      { 'op': 'replace', 'path': '/schedules', 'old_value': [], 'value': {} }
+    ]);
+  });
+});
@@ -28,6 +28,35 @@ describe('Validating Policy JSON properties',function(){
         expect(result[0]).to.have.property('property').and.equal('instance_min_count');
       });
     });
+    it('Should validate the policy with resize rules within bounds successfully',function(){
+      fakePolicy.instance_memory_min_mb = 256;
+      fakePolicy.instance_memory_max_mb = 1024;
+      fakePolicy.scaling_rules.push({ 'metric_type':'MemoryUsage','threshold':90,'operator':'>',
+          'action':'resize','memory_adjustment':'+256' });
+      attributeValidator.validatePolicy(fakePolicy,function(result){
+        expect(result).to.be.empty;
+      });
+    });
+    it('Should fail to validate the policy as a resize rule has no max bound',function(){
+      fakePolicy.scaling_rules.push({ 'metric_type':'MemoryUsage','threshold':90,'operator':'>',
+          'action':'resize','disk_adjustment':'+256' });
+      attributeValidator.validatePolicy(fakePolicy,function(result){
+        expect(result[0].property).to.equal('instance_disk_max_mb');
+        expect(result[0].message).to.equal('instance_disk_max_mb is required by disk_adjustment');
+        expect(result[0].stack).to.equal('instance_disk_max_mb is required when a resize rule has disk_adjustment in policy_json');
+      });
+    });
+    it('Should fail to validate the policy as instance_memory_min_mb is greater than instance_memory_max_mb',function(){
+      fakePolicy.instance_memory_min_mb = 2048;
+      fakePolicy.instance_memory_max_mb = 1024;
+      fakePolicy.scaling_rules.push({ 'metric_type':'MemoryUsage','threshold':90,'operator':'>',
+          'action':'resize','memory_adjustment':'+256' });
+      attributeValidator.validatePolicy(fakePolicy,function(result){
+        expect(result[0].property).to.equal('instance_memory_min_mb');
+        expect(result[0].message).to.equal('instance_memory_min_mb and instance_memory_max_mb values are not compatible');
+        expect(result[0].stack).to.equal('instance_memory_min_mb 2048 is higher or equal to instance_memory_max_mb 1024 in policy_json');
+      });
+    });
     it('Should fail to validate the policy as end_date is before start_date',function(){
       fakePolicy.schedules.specific_date[0].start_date_time = '2016-06-19T10:30';
       fakePolicy.schedules.specific_date[0].end_date_time = '2014-06-19T13:30';
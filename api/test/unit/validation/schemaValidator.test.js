@@ -1,6 +1,7 @@
 'use strict';
 
 var expect = require("chai").expect;
+var fs = require('fs');
 var logger = require('../../../lib/log/logger');
 var schemaValidator = require('../../../lib/validation/schemaValidator');
 var rewire = require('rewire');
@@ -59,6 +60,7 @@ describe('Validating Policy JSON schema construction',function(){
     var schema = schemaValidatorPrivate.__get__('getScalingRuleSchema')();
     var validOperator = schemaValidatorPrivate.__get__('getValidOperators')();
     var adjustmentPattern = schemaValidatorPrivate.__get__('getAdjustmentPattern')();
+    var scalingActions = schemaValidatorPrivate.__get__('getScalingActions')();
     var metricTypeEnum = schemaValidatorPrivate.__get__('getMetricTypes')();
     expect(schema.id).to.equal('/scaling_rules');
     expect(schema.properties.metric_type).to.deep.equal({ 'type':'string','enum':metricTypeEnum});
@@ -68,7 +70,17 @@ describe('Validating Policy JSON schema construction',function(){
     expect(schema.properties.operator).to.deep.equal({ 'type':'string','enum':validOperator });
     expect(schema.properties.cool_down_secs).to.deep.equal({ 'type':'number','minimum': 60,'maximum': 3600 });
     expect(schema.properties.adjustment).to.deep.equal({ 'type':'string','pattern':adjustmentPattern });
-    expect(schema.required).to.deep.equal(['metric_type','threshold','operator','adjustment']);
+    expect(schema.properties.action).to.deep.equal({ 'type':'string','enum':scalingActions });
+    expect(schema.properties.memory_adjustment).to.deep.equal({ 'type':'string','pattern':adjustmentPattern });
+    expect(schema.properties.disk_adjustment).to.deep.equal({ 'type':'string','pattern':adjustmentPattern });
+    expect(schema.required).to.deep.equal(['metric_type','threshold','operator']);
+    expect(schema.anyOf).to.deep.equal([{'properties':{'action':{'enum':['scale']}},'required':['adjustment'],
+        'not':{'anyOf':[{'required':['memory_adjustment']},{'required':['disk_adjustment']}]}},
+      {'properties':{'action':{'enum':['restart_instance']}},'required':['action'],
+        'not':{'anyOf':[{'required':['adjustment']},{'required':['memory_adjustment']},{'required':['disk_adjustment']}]}},
+      {'properties':{'action':{'enum':['resize']}},'required':['action'],
+        'anyOf':[{'required':['memory_adjustment']},{'required':['disk_adjustment']}],
+        'not':{'required':['adjustment']}}]);
   });
   
   it('should validate the getPolicySchema successfully',function(){
@@ -76,6 +88,11 @@ describe('Validating Policy JSON schema construction',function(){
     expect(schema.id).to.equal('/policySchema');
     expect(schema.properties.instance_min_count).to.deep.equal( { 'type':'integer','minimum':1});
     expect(schema.properties.instance_min_count).to.deep.equal( { 'type':'integer','minimum':1 });
+    expect(schema.properties.process_type).to.deep.equal({ 'type':'string','minLength':1 });
+    expect(schema.properties.instance_memory_min_mb).to.deep.equal({ 'type':'integer','minimum':1 });
+    expect(schema.properties.instance_memory_max_mb).to.deep.equal({ 'type':'integer','minimum':1 });
+    expect(schema.properties.instance_disk_min_mb).to.deep.equal({ 'type':'integer','minimum':1 });
+    expect(schema.properties.instance_disk_max_mb).to.deep.equal({ 'type':'integer','minimum':1 });
     expect(schema.properties.scaling_rules.type).to.equal('array');
     expect(schema.properties.scaling_rules.items).to.deep.equal({ '$ref': '/scaling_rules' });
     expect(schema.properties.schedules).to.deep.equal({ '$ref':'/schedules' });
@@ -89,6 +106,11 @@ describe('Validating Policy JSON schema construction',function(){
     expect(validOperators).to.have.members(['<','>','<=','>=']);
   });
   
+  it('should validate the getScalingActions successfully',function(){
+    var scalingActions = schemaValidatorPrivate.__get__('getScalingActions')();
+    expect(scalingActions).to.have.members(['scale','restart_instance','resize']);
+  });
+
     it('should validate the getAdjustmentPattern successfully',function(){
     var adjustmentPattern = schemaValidatorPrivate.__get__('getAdjustmentPattern')();
     expect(adjustmentPattern).to.not.be.null;
@@ -107,4 +129,66 @@ describe('Validating Policy JSON schema construction',function(){
       expect(daysInMonthInISO).to.have.members([1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,
                                               18,19,20,21,22,23,24,25,26,27,28,29,30,31]);
     });  
-});
\ No newline at end of file
+});
+
+describe('Validating the actions of scaling rules',function(){
+  var fakePolicy;
+  var rule;
+
+  beforeEach(function(){
+    fakePolicy = JSON.parse(fs.readFileSync(__dirname+'/../fakePolicy.json', 'utf8'));
+    rule = fakePolicy.scaling_rules[0];
+  });
+
+  it('Should validate a scale rule with adjustment successfully',function(){
+    rule.action = 'scale';
+    schemaValidator.validatePolicy(fakePolicy,function(errors){
+      expect(errors).to.be.empty;
+    });
+  });
+  it('Should fail to validate a scale rule with memory_adjustment',function(){
+    rule.memory_adjustment = '+256';
+    schemaValidator.validatePolicy(fakePolicy,function(errors){
+      expect(errors).to.not.be.empty;
+      expect(errors[0].property).to.equal('instance.scaling_rules[0]');
+    });
+  });
+  it('Should validate a restart_instance rule without adjustment successfully',function(){
+    delete rule.adjustment;
+    rule.action = 'restart_instance';
+    schemaValidator.validatePolicy(fakePolicy,function(errors){
+      expect(errors).to.be.empty;
+    });
+  });
+  it('Should fail to validate a restart_instance rule with adjustment',function(){
+    rule.action = 'restart_instance';
+    schemaValidator.validatePolicy(fakePolicy,function(errors){
+      expect(errors).to.not.be.empty;
+      expect(errors[0].property).to.equal('instance.scaling_rules[0]');
+    });
+  });
+  it('Should validate a resize rule with memory_adjustment successfully',function(){
+    delete rule.adjustment;
+    rule.action = 'resize';
+    rule.memory_adjustment = '+256';
+    schemaValidator.validatePolicy(fakePolicy,function(errors){
+      expect(errors).to.be.empty;
+    });
+  });
+  it('Should fail to validate a resize rule without memory_adjustment or disk_adjustment',function(){
+    delete rule.adjustment;
+    rule.action = 'resize';
+    schemaValidator.validatePolicy(fakePolicy,function(errors){
+      expect(errors).to.not.be.empty;
+      expect(errors[0].property).to.equal('instance.scaling_rules[0]');
+    });
+  });
+  it('Should fail to validate a resize rule with adjustment',function(){
+    rule.action = 'resize';
+    rule.disk_adjustment = '+256';
+    schemaValidator.validatePolicy(fakePolicy,function(errors){
+      expect(errors).to.not.be.empty;
+      expect(errors[0].property).to.equal('instance.scaling_rules[0]');
+    });
+  });
+});
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"strconv"
 
 	"code.cloudfoundry.org/lager"
 
@@ -15,9 +16,77 @@ import (
 const (
 	TokenTypeBearer = "bearer"
 	PathApp         = "/v2/apps"
+	PathAppV3       = "/v3/apps"
+	PathProcessV3   = "/v3/processes"
 )
 
-func (c *cfClient) GetAppInstances(appId string) (int, error) {
+type AppNotFoundError struct {
+	AppId string
+}
+
+func (e *AppNotFoundError) Error() string {
+	return fmt.Sprintf("app %s is not found", e.AppId)
+}
+
+// ProcessNotFoundError is returned when the cloud controller v3 api does not
+// find the process of an app. It does not tell whether the app itself exists.
+type ProcessNotFoundError struct {
+	AppId       string
+	ProcessType string
+}
+
+func (e *ProcessNotFoundError) Error() string {
+	return fmt.Sprintf("process %s of app %s is not found", e.ProcessType, e.AppId)
+}
+
+// AppExists checks whether the app is known to the cloud controller.
+func (c *cfClient) AppExists(appId string) (bool, error) {
+	url := c.conf.Api + path.Join(PathApp, appId)
+	if c.conf.ApiVersion == ApiVersionV3 {
+		url = c.conf.Api + path.Join(PathAppV3, appId)
+	}
+	c.logger.Debug("app-exists", lager.Data{"url": url})
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		c.logger.Error("app-exists-new-request", err)
+		return false, err
+	}
+
+	resp, err := c.doAuthorizedRequest("app-exists", req)
+	if err != nil {
+		c.logger.Error("app-exists-do-request", err)
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		err = fmt.Errorf("failed getting application: %s [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error("app-exists-response", err)
+		return false, err
+	}
+}
+
+func (c *cfClient) GetAppInstances(appId string, processType string) (int, error) {
+	if c.conf.ApiVersion == ApiVersionV3 {
+		process, err := c.getProcess(appId, processType)
+		if err != nil {
+			return -1, err
+		}
+		return process.Instances, nil
+	}
+
+	err := checkV2ProcessType(processType)
+	if err != nil {
+		c.logger.Error("get-app-instances", err, lager.Data{"appid": appId})
+		return -1, err
+	}
+
 	url := c.conf.Api + path.Join(PathApp, appId)
 	c.logger.Debug("get-app-instances", lager.Data{"url": url})
 
@@ -26,10 +95,9 @@ func (c *cfClient) GetAppInstances(appId string) (int, error) {
 		c.logger.Error("get-app-instances-new-request", err)
 		return -1, err
 	}
-	req.Header.Set("Authorization", TokenTypeBearer+" "+c.GetTokensWithRefresh().AccessToken)
 
 	var resp *http.Response
-	resp, err = c.httpClient.Do(req)
+	resp, err = c.doAuthorizedRequest("get-app-instances", req)
 
 	if err != nil {
 		c.logger.Error("get-app-instances-do-request", err)
@@ -37,6 +105,11 @@ func (c *cfClient) GetAppInstances(appId string) (int, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		err = &AppNotFoundError{AppId: appId}
+		c.logger.Error("get-app-instances-response", err)
+		return -1, err
+	}
 	if resp.StatusCode != http.StatusOK {
 		err = fmt.Errorf("failed getting application summary: %s [%d] %s", url, resp.StatusCode, resp.Status)
 		c.logger.Error("get-app-instances-response", err)
@@ -52,7 +125,21 @@ func (c *cfClient) GetAppInstances(appId string) (int, error) {
 	return appInfo.Entity.Instances, nil
 }
 
-func (c *cfClient) SetAppInstances(appId string, num int) error {
+func (c *cfClient) SetAppInstances(appId string, processType string, num int) error {
+	if c.conf.ApiVersion == ApiVersionV3 {
+		process, err := c.getProcess(appId, processType)
+		if err != nil {
+			return err
+		}
+		return c.scaleProcess(process.Guid, num)
+	}
+
+	err := checkV2ProcessType(processType)
+	if err != nil {
+		c.logger.Error("set-app-instances", err, lager.Data{"appid": appId})
+		return err
+	}
+
 	url := c.conf.Api + path.Join(PathApp, appId)
 	c.logger.Debug("set-app-instances", lager.Data{"url": url})
 
@@ -71,17 +158,21 @@ func (c *cfClient) SetAppInstances(appId string, num int) error {
 		c.logger.Error("set-app-instances-new-request", err)
 		return err
 	}
-	req.Header.Set("Authorization", TokenTypeBearer+" "+c.GetTokensWithRefresh().AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	var resp *http.Response
-	resp, err = c.httpClient.Do(req)
+	resp, err = c.doAuthorizedRequest("set-app-instances", req)
 	if err != nil {
 		c.logger.Error("set-app-instances-do-request", err)
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		err = &AppNotFoundError{AppId: appId}
+		c.logger.Error("set-app-instances-response", err)
+		return err
+	}
 	if resp.StatusCode != http.StatusCreated {
 		err = fmt.Errorf("failed setting application instances: %s [%d] %s", url, resp.StatusCode, resp.Status)
 		c.logger.Error("set-app-instances-response", err)
@@ -90,3 +181,289 @@ func (c *cfClient) SetAppInstances(appId string, num int) error {
 
 	return nil
 }
+
+// GetAppLimits returns the memory and disk limits of each instance of the app
+// process.
+func (c *cfClient) GetAppLimits(appId string, processType string) (models.InstanceLimits, error) {
+	if c.conf.ApiVersion == ApiVersionV3 {
+		process, err := c.getProcess(appId, processType)
+		if err != nil {
+			return models.InstanceLimits{}, err
+		}
+		return models.InstanceLimits{MemoryMb: process.MemoryInMb, DiskMb: process.DiskInMb}, nil
+	}
+
+	err := checkV2ProcessType(processType)
+	if err != nil {
+		c.logger.Error("get-app-limits", err, lager.Data{"appid": appId})
+		return models.InstanceLimits{}, err
+	}
+
+	url := c.conf.Api + path.Join(PathApp, appId)
+	c.logger.Debug("get-app-limits", lager.Data{"url": url})
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		c.logger.Error("get-app-limits-new-request", err)
+		return models.InstanceLimits{}, err
+	}
+
+	var resp *http.Response
+	resp, err = c.doAuthorizedRequest("get-app-limits", req)
+	if err != nil {
+		c.logger.Error("get-app-limits-do-request", err)
+		return models.InstanceLimits{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		err = &AppNotFoundError{AppId: appId}
+		c.logger.Error("get-app-limits-response", err)
+		return models.InstanceLimits{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed getting application summary: %s [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error("get-app-limits-response", err)
+		return models.InstanceLimits{}, err
+	}
+
+	appInfo := &models.AppInfo{}
+	err = json.NewDecoder(resp.Body).Decode(appInfo)
+	if err != nil {
+		c.logger.Error("get-app-limits-decode", err)
+		return models.InstanceLimits{}, err
+	}
+	return models.InstanceLimits{MemoryMb: appInfo.Entity.Memory, DiskMb: appInfo.Entity.DiskQuota}, nil
+}
+
+// SetAppLimits changes the memory and disk limits of each instance of the app
+// process, a limit of 0 is left unchanged. The cloud controller restarts the
+// instances to apply the new limits.
+func (c *cfClient) SetAppLimits(appId string, processType string, limits models.InstanceLimits) error {
+	if c.conf.ApiVersion == ApiVersionV3 {
+		process, err := c.getProcess(appId, processType)
+		if err != nil {
+			return err
+		}
+		return c.resizeProcess(process.Guid, limits)
+	}
+
+	err := checkV2ProcessType(processType)
+	if err != nil {
+		c.logger.Error("set-app-limits", err, lager.Data{"appid": appId})
+		return err
+	}
+
+	url := c.conf.Api + path.Join(PathApp, appId)
+	c.logger.Debug("set-app-limits", lager.Data{"url": url})
+
+	body, err := json.Marshal(struct {
+		Memory    int `json:"memory,omitempty"`
+		DiskQuota int `json:"disk_quota,omitempty"`
+	}{limits.MemoryMb, limits.DiskMb})
+	if err != nil {
+		c.logger.Error("set-app-limits-marshal", err, lager.Data{"appid": appId, "limits": limits})
+		return err
+	}
+
+	var req *http.Request
+	req, err = http.NewRequest("PUT", url, bytes.NewReader(body))
+	if err != nil {
+		c.logger.Error("set-app-limits-new-request", err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp *http.Response
+	resp, err = c.doAuthorizedRequest("set-app-limits", req)
+	if err != nil {
+		c.logger.Error("set-app-limits-do-request", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		err = &AppNotFoundError{AppId: appId}
+		c.logger.Error("set-app-limits-response", err)
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		err = fmt.Errorf("failed setting application limits: %s [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error("set-app-limits-response", err)
+		return err
+	}
+
+	return nil
+}
+
+// RestartAppInstance stops the instance of the app process at index, the cloud
+// controller then starts a new instance in its place.
+func (c *cfClient) RestartAppInstance(appId string, processType string, index int) error {
+	url := c.conf.Api + path.Join(PathApp, appId, "instances", strconv.Itoa(index))
+	if c.conf.ApiVersion == ApiVersionV3 {
+		if processType == "" {
+			processType = models.DefaultProcessType
+		}
+		url = c.conf.Api + path.Join(PathAppV3, appId, "processes", processType, "instances", strconv.Itoa(index))
+	} else {
+		err := checkV2ProcessType(processType)
+		if err != nil {
+			c.logger.Error("restart-app-instance", err, lager.Data{"appid": appId})
+			return err
+		}
+	}
+	c.logger.Debug("restart-app-instance", lager.Data{"url": url})
+
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		c.logger.Error("restart-app-instance-new-request", err)
+		return err
+	}
+
+	var resp *http.Response
+	resp, err = c.doAuthorizedRequest("restart-app-instance", req)
+	if err != nil {
+		c.logger.Error("restart-app-instance-do-request", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		if c.conf.ApiVersion == ApiVersionV3 {
+			err = &ProcessNotFoundError{AppId: appId, ProcessType: processType}
+		} else {
+			err = &AppNotFoundError{AppId: appId}
+		}
+		c.logger.Error("restart-app-instance-response", err)
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
+		err = fmt.Errorf("failed restarting application instance: %s [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error("restart-app-instance-response", err)
+		return err
+	}
+
+	return nil
+}
+
+// v2 apps only have the web process.
+func checkV2ProcessType(processType string) error {
+	if processType != "" && processType != models.DefaultProcessType {
+		return fmt.Errorf("process type %s is not supported by cf api %s", processType, ApiVersionV2)
+	}
+	return nil
+}
+
+func (c *cfClient) getProcess(appId string, processType string) (*models.Process, error) {
+	if processType == "" {
+		processType = models.DefaultProcessType
+	}
+	url := c.conf.Api + path.Join(PathAppV3, appId, "processes", processType)
+	c.logger.Debug("get-process", lager.Data{"url": url})
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		c.logger.Error("get-process-new-request", err)
+		return nil, err
+	}
+
+	var resp *http.Response
+	resp, err = c.doAuthorizedRequest("get-process", req)
+	if err != nil {
+		c.logger.Error("get-process-do-request", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		err = &ProcessNotFoundError{AppId: appId, ProcessType: processType}
+		c.logger.Error("get-process-response", err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed getting application process: %s [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error("get-process-response", err)
+		return nil, err
+	}
+
+	process := &models.Process{}
+	err = json.NewDecoder(resp.Body).Decode(process)
+	if err != nil {
+		c.logger.Error("get-process-decode", err)
+		return nil, err
+	}
+	return process, nil
+}
+
+func (c *cfClient) scaleProcess(processGuid string, num int) error {
+	url := c.conf.Api + path.Join(PathProcessV3, processGuid, "actions", "scale")
+	c.logger.Debug("scale-process", lager.Data{"url": url})
+
+	body, err := json.Marshal(models.AppEntity{Instances: num})
+	if err != nil {
+		c.logger.Error("scale-process-marshal", err, lager.Data{"processGuid": processGuid})
+		return err
+	}
+
+	var req *http.Request
+	req, err = http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		c.logger.Error("scale-process-new-request", err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp *http.Response
+	resp, err = c.doAuthorizedRequest("scale-process", req)
+	if err != nil {
+		c.logger.Error("scale-process-do-request", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed scaling application process: %s [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error("scale-process-response", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *cfClient) resizeProcess(processGuid string, limits models.InstanceLimits) error {
+	url := c.conf.Api + path.Join(PathProcessV3, processGuid, "actions", "scale")
+	c.logger.Debug("resize-process", lager.Data{"url": url})
+
+	body, err := json.Marshal(struct {
+		MemoryInMb int `json:"memory_in_mb,omitempty"`
+		DiskInMb   int `json:"disk_in_mb,omitempty"`
+	}{limits.MemoryMb, limits.DiskMb})
+	if err != nil {
+		c.logger.Error("resize-process-marshal", err, lager.Data{"processGuid": processGuid})
+		return err
+	}
+
+	var req *http.Request
+	req, err = http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		c.logger.Error("resize-process-new-request", err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp *http.Response
+	resp, err = c.doAuthorizedRequest("resize-process", req)
+	if err != nil {
+		c.logger.Error("resize-process-do-request", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed resizing application process: %s [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error("resize-process-response", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package cf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"code.cloudfoundry.org/lager"
+
+	"autoscaler/models"
+)
+
+const PathBuildsV3 = "/v3/builds"
+
+type appV3 struct {
+	Guid  string `json:"guid"`
+	State string `json:"state"`
+}
+
+type buildsV3 struct {
+	Pagination struct {
+		TotalResults int `json:"total_results"`
+	} `json:"pagination"`
+}
+
+type processStatsV3 struct {
+	Resources []models.InstanceStats `json:"resources"`
+}
+
+// GetAppStatus returns the state of the app, whether it is staging, and how many
+// instances of the given process type are crashed.
+func (c *cfClient) GetAppStatus(appId string, processType string) (models.AppStatus, error) {
+	if c.conf.ApiVersion == ApiVersionV3 {
+		return c.getAppStatusV3(appId, processType)
+	}
+
+	status := models.AppStatus{}
+	err := checkV2ProcessType(processType)
+	if err != nil {
+		c.logger.Error("get-app-status", err, lager.Data{"appid": appId})
+		return status, err
+	}
+
+	appInfo := &models.AppInfo{}
+	err = c.getJSON("get-app-status", appId, c.conf.Api+path.Join(PathApp, appId), appInfo)
+	if err != nil {
+		return status, err
+	}
+	status.State = appInfo.Entity.State
+	status.Staging = appInfo.Entity.PackageState == models.PackageStatePending
+	if status.State != models.AppStateStarted || appInfo.Entity.PackageState != models.PackageStateStaged {
+		return status, nil
+	}
+
+	instances := map[string]models.InstanceStats{}
+	err = c.getJSON("get-app-instance-stats", appId, c.conf.Api+path.Join(PathApp, appId, "instances"), &instances)
+	if err != nil {
+		return status, err
+	}
+	for _, instance := range instances {
+		countInstance(&status, instance)
+	}
+	return status, nil
+}
+
+func (c *cfClient) getAppStatusV3(appId string, processType string) (models.AppStatus, error) {
+	if processType == "" {
+		processType = models.DefaultProcessType
+	}
+	status := models.AppStatus{}
+
+	app := &appV3{}
+	err := c.getJSON("get-app-status", appId, c.conf.Api+path.Join(PathAppV3, appId), app)
+	if err != nil {
+		return status, err
+	}
+	status.State = app.State
+
+	query := url.Values{"app_guids": {appId}, "states": {"STAGING"}}
+	builds := &buildsV3{}
+	err = c.getJSON("get-app-builds", appId, c.conf.Api+PathBuildsV3+"?"+query.Encode(), builds)
+	if err != nil {
+		return status, err
+	}
+	status.Staging = builds.Pagination.TotalResults > 0
+	if status.State != models.AppStateStarted || status.Staging {
+		return status, nil
+	}
+
+	stats := &processStatsV3{}
+	err = c.getJSON("get-process-stats", appId, c.conf.Api+path.Join(PathAppV3, appId, "processes", processType, "stats"), stats)
+	if err != nil {
+		return status, err
+	}
+	for _, instance := range stats.Resources {
+		countInstance(&status, instance)
+	}
+	return status, nil
+}
+
+func countInstance(status *models.AppStatus, instance models.InstanceStats) {
+	status.Instances++
+	if instance.State == models.InstanceStateCrashed {
+		status.CrashedInstances++
+	}
+}
+
+// getJSON decodes the response of a GET request to a cloud controller resource of
+// the app into v. A 404 is reported as AppNotFoundError.
+func (c *cfClient) getJSON(operation string, appId string, url string, v interface{}) error {
+	c.logger.Debug(operation, lager.Data{"url": url})
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		c.logger.Error(operation+"-new-request", err)
+		return err
+	}
+
+	resp, err := c.doAuthorizedRequest(operation, req)
+	if err != nil {
+		c.logger.Error(operation+"-do-request", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		err = &AppNotFoundError{AppId: appId}
+		c.logger.Error(operation+"-response", err)
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed getting %s: [%d] %s", url, resp.StatusCode, resp.Status)
+		c.logger.Error(operation+"-response", err)
+		return err
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		c.logger.Error(operation+"-decode", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package cf_test
+
+import (
+	. "autoscaler/cf"
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	"net/http"
+)
+
+var _ = Describe("GetAppStatus", func() {
+
+	var (
+		conf            *CfConfig
+		cfc             CfClient
+		fakeCC          *ghttp.Server
+		fakeLoginServer *ghttp.Server
+		status          models.AppStatus
+		err             error
+	)
+
+	BeforeEach(func() {
+		fakeCC = ghttp.NewServer()
+		fakeLoginServer = ghttp.NewServer()
+		fakeCC.RouteToHandler("GET", PathCfInfo, ghttp.RespondWithJSONEncoded(http.StatusOK, Endpoints{
+			AuthEndpoint:    fakeLoginServer.URL(),
+			TokenEndpoint:   "test-token-endpoint",
+			DopplerEndpoint: "test-doppler-endpoint",
+		}))
+		fakeLoginServer.RouteToHandler("POST", PathCfAuth, ghttp.RespondWithJSONEncoded(http.StatusOK, Tokens{
+			AccessToken:  "test-access-token",
+			RefreshToken: "test-refresh-token",
+			ExpiresIn:    12000,
+		}))
+		conf = &CfConfig{}
+		conf.Api = fakeCC.URL()
+		cfc, err = NewCfClient(conf, lager.NewLogger("cf"), clock.NewClock())
+		Expect(err).NotTo(HaveOccurred())
+		cfc.Login()
+	})
+
+	AfterEach(func() {
+		fakeCC.Close()
+		fakeLoginServer.Close()
+	})
+
+	JustBeforeEach(func() {
+		status, err = cfc.GetAppStatus("test-app-id", "web")
+	})
+
+	Context("when the app is started and staged", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", PathApp+"/test-app-id"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, models.AppInfo{Entity: models.AppEntity{
+						Instances:    3,
+						State:        models.AppStateStarted,
+						PackageState: models.PackageStateStaged,
+					}}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", PathApp+"/test-app-id/instances"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, map[string]models.InstanceStats{
+						"0": {State: models.InstanceStateRunning},
+						"1": {State: models.InstanceStateCrashed},
+						"2": {State: "STARTING"},
+					}),
+				),
+			)
+		})
+
+		It("returns the state and the health of the instances", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(models.AppStatus{
+				State:            models.AppStateStarted,
+				Instances:        3,
+				CrashedInstances: 1,
+			}))
+		})
+	})
+
+	Context("when the app is staging", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, models.AppInfo{Entity: models.AppEntity{
+					State:        models.AppStateStarted,
+					PackageState: models.PackageStatePending,
+				}}),
+			)
+		})
+
+		It("returns the app as staging without getting the instances", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(models.AppStatus{State: models.AppStateStarted, Staging: true}))
+			Expect(fakeCC.ReceivedRequests()).To(HaveLen(2))
+		})
+	})
+
+	Context("when the app is stopped", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, models.AppInfo{Entity: models.AppEntity{
+					State:        models.AppStateStopped,
+					PackageState: models.PackageStateStaged,
+				}}),
+			)
+		})
+
+		It("returns the app as stopped without getting the instances", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(models.AppStatus{State: models.AppStateStopped}))
+			Expect(fakeCC.ReceivedRequests()).To(HaveLen(2))
+		})
+	})
+
+	Context("when the app does not exist", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+		})
+
+		It("returns an app not found error", func() {
+			Expect(err).To(Equal(&AppNotFoundError{AppId: "test-app-id"}))
+		})
+	})
+
+	Context("when getting the instances fails", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, models.AppInfo{Entity: models.AppEntity{
+					State:        models.AppStateStarted,
+					PackageState: models.PackageStateStaged,
+				}}),
+				ghttp.RespondWith(http.StatusBadRequest, ""),
+			)
+		})
+
+		It("should error", func() {
+			Expect(err).To(MatchError(MatchRegexp("failed getting .*/v2/apps/test-app-id/instances: \\[400\\]")))
+		})
+	})
+
+	Context("when using cf api v3", func() {
+		BeforeEach(func() {
+			conf.ApiVersion = ApiVersionV3
+			fakeCC.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", PathAppV3+"/test-app-id"),
+					ghttp.RespondWith(http.StatusOK, `{"guid":"test-app-id","state":"STARTED"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", PathBuildsV3, "app_guids=test-app-id&states=STAGING"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination":{"total_results":0}}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", PathAppV3+"/test-app-id/processes/web/stats"),
+					ghttp.RespondWith(http.StatusOK, `{"resources":[{"index":0,"state":"CRASHED"},{"index":1,"state":"CRASHED"}]}`),
+				),
+			)
+		})
+
+		It("returns the state and the health of the process instances", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(models.AppStatus{
+				State:            models.AppStateStarted,
+				Instances:        2,
+				CrashedInstances: 2,
+			}))
+		})
+	})
+})
@@ -0,0 +1,100 @@
+package cf
+
+import (
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+
+	"fmt"
+	"sync"
+	"time"
+)
+
+type CircuitOpenError struct {
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for cloud foundry api calls until %s", e.RetryAt.Format(time.RFC3339))
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker opens after threshold consecutive failed calls and then
+// rejects calls until openTimeout has passed. The first call afterwards is let
+// through as a trial: it closes the circuit when it succeeds and opens it again
+// when it fails. A threshold of 0 disables the breaker.
+type circuitBreaker struct {
+	logger      lager.Logger
+	threshold   int
+	openTimeout time.Duration
+	clk         clock.Clock
+	lock        sync.Mutex
+	state       circuitState
+	failures    int
+	openedAt    time.Time
+}
+
+func newCircuitBreaker(logger lager.Logger, conf CircuitBreakerConfig, clk clock.Clock) *circuitBreaker {
+	return &circuitBreaker{
+		logger:      logger.Session("circuit-breaker"),
+		threshold:   conf.FailureThreshold,
+		openTimeout: conf.OpenTimeout,
+		clk:         clk,
+	}
+}
+
+func (b *circuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.clk.Since(b.openedAt) < b.openTimeout {
+			return &CircuitOpenError{RetryAt: b.openedAt.Add(b.openTimeout)}
+		}
+		b.state = circuitHalfOpen
+		b.logger.Info("half-open")
+		return nil
+	case circuitHalfOpen:
+		return &CircuitOpenError{RetryAt: b.clk.Now()}
+	default:
+		return nil
+	}
+}
+
+func (b *circuitBreaker) record(failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !failed {
+		b.failures = 0
+		if b.state != circuitClosed {
+			b.state = circuitClosed
+			circuitBreakerOpen.Set(0)
+			b.logger.Info("closed")
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.failures >= b.threshold) {
+		b.state = circuitOpen
+		b.openedAt = b.clk.Now()
+		circuitBreakerOpen.Set(1)
+		b.logger.Info("opened", lager.Data{"failures": b.failures})
+	}
+}
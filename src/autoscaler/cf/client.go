@@ -1,15 +1,18 @@
 package cf
 
 import (
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 
+	"autoscaler/models"
+
 	"code.cloudfoundry.org/cfhttp"
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
@@ -37,14 +40,32 @@ type Endpoints struct {
 	DopplerEndpoint string `json:"doppler_logging_endpoint"`
 }
 
+// AuthError is returned when UAA rejects the credentials or the refresh token of
+// the client, or when cloud foundry still rejects a request after re-authenticating.
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return "authentication failed: " + e.Err.Error()
+}
+
 type CfClient interface {
 	Login() error
 	RefreshAuthToken() (string, error)
+	Reauthenticate(rejectedAccessToken string) (Tokens, error)
 	GetTokens() Tokens
-	GetTokensWithRefresh() Tokens
+	GetTokensWithRefresh() (Tokens, error)
+	IsTokenValid() bool
 	GetEndpoints() Endpoints
-	GetAppInstances(string) (int, error)
-	SetAppInstances(string, int) error
+	AppExists(appId string) (bool, error)
+	GetAppStatus(appId string, processType string) (models.AppStatus, error)
+	GetAppQuotas(appId string, processType string) (models.AppQuotas, error)
+	GetAppInstances(appId string, processType string) (int, error)
+	SetAppInstances(appId string, processType string, num int) error
+	GetAppLimits(appId string, processType string) (models.InstanceLimits, error)
+	SetAppLimits(appId string, processType string, limits models.InstanceLimits) error
+	RestartAppInstance(appId string, processType string, index int) error
 }
 
 type cfClient struct {
@@ -59,10 +80,14 @@ type cfClient struct {
 	authHeader string
 	httpClient *http.Client
 	lock       *sync.Mutex
+	// tokensLock guards tokens, grantTime, endpoints and authUrl, which are
+	// written while lock is held and read without it.
+	tokensLock *sync.RWMutex
 	grantTime  time.Time
+	breaker    *circuitBreaker
 }
 
-func NewCfClient(conf *CfConfig, logger lager.Logger, clk clock.Clock) CfClient {
+func NewCfClient(conf *CfConfig, logger lager.Logger, clk clock.Clock) (CfClient, error) {
 	c := &cfClient{}
 	c.logger = logger
 	c.conf = conf
@@ -85,18 +110,31 @@ func NewCfClient(conf *CfConfig, logger lager.Logger, clk clock.Clock) CfClient
 		c.authHeader = "Basic " + base64.StdEncoding.EncodeToString([]byte(conf.ClientId+":"+conf.Secret))
 	}
 
+	tlsConfig, err := conf.TLSConfig()
+	if err != nil {
+		logger.Error("new-cf-client-tls-config", err, lager.Data{"caCertFile": conf.CACertFile})
+		return nil, err
+	}
 	c.httpClient = cfhttp.NewClient()
-	c.httpClient.Transport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	c.httpClient.Transport.(*http.Transport).TLSClientConfig = tlsConfig
 
 	c.lock = &sync.Mutex{}
+	c.tokensLock = &sync.RWMutex{}
+	c.breaker = newCircuitBreaker(logger, conf.CircuitBreaker, clk)
 
-	return c
+	return c, nil
 }
 
 func (c *cfClient) retrieveEndpoints() error {
 	c.logger.Info("retrieve-endpoints", lager.Data{"infoUrl": c.infoUrl})
 
-	resp, err := c.httpClient.Get(c.infoUrl)
+	req, err := http.NewRequest("GET", c.infoUrl, nil)
+	if err != nil {
+		c.logger.Error("retrieve-endpoints-new-request", err)
+		return err
+	}
+
+	resp, err := c.doRequest("retrieve-endpoints", req)
 	if err != nil {
 		c.logger.Error("retrieve-endpoints-get", err)
 		return err
@@ -109,13 +147,17 @@ func (c *cfClient) retrieveEndpoints() error {
 		return err
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&c.endpoints)
+	endpoints := Endpoints{}
+	err = json.NewDecoder(resp.Body).Decode(&endpoints)
 	if err != nil {
 		c.logger.Error("retrieve-endpoints-decode", err)
 		return err
 	}
 
-	c.authUrl = c.endpoints.AuthEndpoint + PathCfAuth
+	c.tokensLock.Lock()
+	c.endpoints = endpoints
+	c.authUrl = endpoints.AuthEndpoint + PathCfAuth
+	c.tokensLock.Unlock()
 	return nil
 }
 
@@ -131,7 +173,7 @@ func (c *cfClient) requestTokenGrant(formData *url.Values) error {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
 
 	var resp *http.Response
-	resp, err = c.httpClient.Do(req)
+	resp, err = c.doRequest("request-token-grant", req)
 	if err != nil {
 		c.logger.Error("request-token-grant-do", err)
 		return err
@@ -140,20 +182,38 @@ func (c *cfClient) requestTokenGrant(formData *url.Values) error {
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		err = fmt.Errorf("request token grant failed: %s [%d] %s", c.authUrl, resp.StatusCode, resp.Status)
+		if isAuthFailure(resp.StatusCode) {
+			err = &AuthError{Err: err}
+		}
 		c.logger.Error("request-token-grant-response", err)
 		return err
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&c.tokens)
+	tokens := Tokens{}
+	err = json.NewDecoder(resp.Body).Decode(&tokens)
 	if err != nil {
 		c.logger.Error("request-token-grant-decode", err)
 		return err
 	}
-	c.grantTime = time.Now()
+
+	c.tokensLock.Lock()
+	c.tokens = tokens
+	c.grantTime = c.clk.Now()
+	c.tokensLock.Unlock()
 	return nil
 }
 
+func isAuthFailure(statusCode int) bool {
+	return statusCode == http.StatusBadRequest || statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
+
 func (c *cfClient) Login() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.login()
+}
+
+func (c *cfClient) login() error {
 	c.logger.Info("login", lager.Data{"infoUrl": c.infoUrl})
 
 	err := c.retrieveEndpoints()
@@ -167,7 +227,8 @@ func (c *cfClient) Login() error {
 func (c *cfClient) refresh() error {
 	c.logger.Info("refresh", lager.Data{"authUrl": c.authUrl})
 
-	if c.tokens.RefreshToken == "" {
+	refreshToken := c.GetTokens().RefreshToken
+	if refreshToken == "" {
 		err := fmt.Errorf("empty refresh_token")
 		c.logger.Error("refresh", err)
 		return err
@@ -175,7 +236,7 @@ func (c *cfClient) refresh() error {
 
 	refreshForm := url.Values{
 		"grant_type":    {GrantTypeRefreshToken},
-		"refresh_token": {c.tokens.RefreshToken},
+		"refresh_token": {refreshToken},
 		"scope":         {""},
 	}
 
@@ -183,35 +244,117 @@ func (c *cfClient) refresh() error {
 }
 
 func (c *cfClient) RefreshAuthToken() (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.refreshAuthToken()
+}
+
+func (c *cfClient) refreshAuthToken() (string, error) {
 	c.logger.Info("refresh-auth-token", lager.Data{"authUrl": c.authUrl})
 
 	err := c.refresh()
 	if err != nil {
 		c.logger.Info("refresh-auth-token-login-again")
-		if err = c.Login(); err != nil {
+		if err = c.login(); err != nil {
 			return "", err
 		}
 	}
-	return c.tokens.AccessToken, nil
+	return c.GetTokens().AccessToken, nil
+}
+
+// Reauthenticate refreshes the tokens after cloud foundry rejected the given
+// access token. When another caller has already replaced that token, the current
+// tokens are returned without refreshing them again.
+func (c *cfClient) Reauthenticate(rejectedAccessToken string) (Tokens, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.GetTokens().AccessToken != rejectedAccessToken {
+		return c.GetTokens(), nil
+	}
+
+	c.logger.Info("reauthenticate")
+	_, err := c.refreshAuthToken()
+	return c.GetTokens(), err
 }
 
 func (c *cfClient) GetTokens() Tokens {
+	c.tokensLock.RLock()
+	defer c.tokensLock.RUnlock()
 	return c.tokens
 }
 
-func (c *cfClient) GetTokensWithRefresh() Tokens {
+// GetTokensWithRefresh refreshes the tokens when the access token is about to
+// expire. When refreshing fails, the error is returned along with the existing
+// tokens, which may still be valid.
+func (c *cfClient) GetTokensWithRefresh() (Tokens, error) {
 	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	if c.isTokenToBeExpired() {
-		c.RefreshAuthToken()
+		_, err := c.refreshAuthToken()
+		if err != nil {
+			c.logger.Error("get-tokens-with-refresh", err)
+			return c.GetTokens(), err
+		}
 	}
-	c.lock.Unlock()
-	return c.tokens
+	return c.GetTokens(), nil
+}
+
+func (c *cfClient) IsTokenValid() bool {
+	c.tokensLock.RLock()
+	defer c.tokensLock.RUnlock()
+	if c.tokens.AccessToken == "" {
+		return false
+	}
+	return c.clk.Now().Sub(c.grantTime) < time.Duration(c.tokens.ExpiresIn)*time.Second
 }
 
 func (c *cfClient) isTokenToBeExpired() bool {
+	c.tokensLock.RLock()
+	defer c.tokensLock.RUnlock()
 	return c.clk.Now().Sub(c.grantTime) > (time.Duration(c.tokens.ExpiresIn)*time.Second - TimeToRefreshBeforeTokenExpire)
 }
 
 func (c *cfClient) GetEndpoints() Endpoints {
+	c.tokensLock.RLock()
+	defer c.tokensLock.RUnlock()
 	return c.endpoints
 }
+
+// doAuthorizedRequest sends req with the access token of the client. When cloud
+// foundry rejects the token, the client re-authenticates and retries once.
+func (c *cfClient) doAuthorizedRequest(operation string, req *http.Request) (*http.Response, error) {
+	tokens, err := c.GetTokensWithRefresh()
+	if err != nil && tokens.AccessToken == "" {
+		return nil, err
+	}
+	req.Header.Set("Authorization", TokenTypeBearer+" "+tokens.AccessToken)
+
+	resp, err := c.doRequest(operation, req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	c.logger.Info("unauthorized-reauthenticate", lager.Data{"operation": operation, "url": req.URL.String()})
+	tokens, err = c.Reauthenticate(tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetBody != nil {
+		req.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	req.Header.Set("Authorization", TokenTypeBearer+" "+tokens.AccessToken)
+
+	resp, err = c.doRequest(operation, req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+	return nil, &AuthError{Err: fmt.Errorf("request still unauthorized after re-authenticating: %s [%d] %s", req.URL.String(), resp.StatusCode, resp.Status)}
+}
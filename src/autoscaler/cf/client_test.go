@@ -1,9 +1,12 @@
 package cf_test
 
 import (
+	"encoding/pem"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
@@ -45,10 +48,25 @@ var _ = Describe("Client", func() {
 		}
 	})
 
+	Describe("NewCfClient", func() {
+		Context("when the ca cert file can not be read", func() {
+			BeforeEach(func() {
+				conf.CACertFile = "not-exist-ca-file"
+			})
+
+			It("should error", func() {
+				cfc, err = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+				Expect(err).To(HaveOccurred())
+				Expect(cfc).To(BeNil())
+			})
+		})
+	})
+
 	Describe("Login", func() {
 
 		JustBeforeEach(func() {
-			cfc = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+			cfc, err = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+			Expect(err).NotTo(HaveOccurred())
 			err = cfc.Login()
 		})
 
@@ -71,6 +89,21 @@ var _ = Describe("Client", func() {
 				Expect(cfc.GetEndpoints().TokenEndpoint).To(Equal("test-token-endpoint"))
 				Expect(cfc.GetEndpoints().DopplerEndpoint).To(Equal("test-doppler-endpoint"))
 			})
+
+			It("can be read while logging in again", func() {
+				fakeCC.RouteToHandler("GET", PathCfInfo, ghttp.RespondWithJSONEncoded(http.StatusOK, Endpoints{
+					AuthEndpoint: "test-oauth-endpoint",
+				}))
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					cfc.Login()
+					close(done)
+				}()
+				Expect(cfc.GetEndpoints().AuthEndpoint).To(Equal("test-oauth-endpoint"))
+				Eventually(done).Should(BeClosed())
+			})
 		})
 
 		Context("when retrieving endpoints fails", func() {
@@ -103,6 +136,61 @@ var _ = Describe("Client", func() {
 			})
 		})
 
+		Context("when the Cloud Controller uses tls", func() {
+			BeforeEach(func() {
+				fakeCC.Close()
+				fakeCC = ghttp.NewTLSServer()
+				fakeCC.RouteToHandler("GET", PathCfInfo, ghttp.RespondWithJSONEncoded(http.StatusOK, Endpoints{
+					AuthEndpoint:    fakeLoginServer.URL(),
+					TokenEndpoint:   "test-token-endpoint",
+					DopplerEndpoint: "test-doppler-endpoint",
+				}))
+				fakeLoginServer.RouteToHandler("POST", PathCfAuth, ghttp.RespondWithJSONEncoded(http.StatusOK, Tokens{
+					AccessToken: "test-access-token",
+					ExpiresIn:   12000,
+				}))
+				conf.Api = fakeCC.URL()
+			})
+
+			Context("when the server certificate is not trusted", func() {
+				It("should error", func() {
+					Expect(err).To(BeAssignableToTypeOf(&url.Error{}))
+					Expect(err.Error()).To(ContainSubstring("certificate"))
+				})
+			})
+
+			Context("when skipping ssl validation", func() {
+				BeforeEach(func() {
+					conf.SkipSSLValidation = true
+				})
+
+				It("logs in", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(cfc.GetTokens().AccessToken).To(Equal("test-access-token"))
+				})
+			})
+
+			Context("when the server certificate is signed by the configured ca", func() {
+				BeforeEach(func() {
+					caFile, err := ioutil.TempFile("", "cf-ca")
+					Expect(err).NotTo(HaveOccurred())
+					defer caFile.Close()
+					err = pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: fakeCC.HTTPTestServer.Certificate().Raw})
+					Expect(err).NotTo(HaveOccurred())
+					conf.CACertFile = caFile.Name()
+				})
+
+				AfterEach(func() {
+					os.Remove(conf.CACertFile)
+				})
+
+				It("logs in", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(cfc.GetTokens().AccessToken).To(Equal("test-access-token"))
+				})
+			})
+		})
+
 		Context("when the auth url is valid", func() {
 			BeforeEach(func() {
 				fakeCC.AppendHandlers(
@@ -210,8 +298,25 @@ var _ = Describe("Client", func() {
 					)
 				})
 
-				It("should error", func() {
+				It("should return an authentication error", func() {
+					Expect(err).To(MatchError(MatchRegexp("request token grant failed: .*")))
+					Expect(err).To(BeAssignableToTypeOf(&AuthError{}))
+				})
+			})
+
+			Context("when loginserver is unavailable", func() {
+				BeforeEach(func() {
+					fakeLoginServer.AppendHandlers(
+						ghttp.CombineHandlers(
+							ghttp.VerifyRequest("POST", PathCfAuth),
+							ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+						),
+					)
+				})
+
+				It("should error without an authentication error", func() {
 					Expect(err).To(MatchError(MatchRegexp("request token grant failed: .*")))
+					Expect(err).NotTo(BeAssignableToTypeOf(&AuthError{}))
 				})
 			})
 
@@ -220,7 +325,8 @@ var _ = Describe("Client", func() {
 
 	Describe("RefreshAuthToken", func() {
 		BeforeEach(func() {
-			cfc = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+			cfc, err = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+			Expect(err).NotTo(HaveOccurred())
 		})
 
 		JustBeforeEach(func() {
@@ -406,11 +512,12 @@ var _ = Describe("Client", func() {
 
 	Describe("GetTokensWithRefresh", func() {
 		JustBeforeEach(func() {
-			tokens = cfc.GetTokensWithRefresh()
+			tokens, err = cfc.GetTokensWithRefresh()
 		})
 
 		BeforeEach(func() {
-			cfc = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+			cfc, err = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+			Expect(err).NotTo(HaveOccurred())
 			fakeCC.AppendHandlers(
 				ghttp.CombineHandlers(
 					ghttp.VerifyRequest("GET", PathCfInfo),
@@ -439,6 +546,7 @@ var _ = Describe("Client", func() {
 				fclock.Increment(12000*time.Second - TimeToRefreshBeforeTokenExpire)
 			})
 			It("does not refresh tokens", func() {
+				Expect(err).NotTo(HaveOccurred())
 				Expect(tokens.AccessToken).To(Equal("test-access-token"))
 				Expect(tokens.RefreshToken).To(Equal("test-refresh-token"))
 				Expect(tokens.ExpiresIn).To(Equal(int64(12000)))
@@ -467,6 +575,7 @@ var _ = Describe("Client", func() {
 				})
 
 				It("refreshes tokens", func() {
+					Expect(err).NotTo(HaveOccurred())
 					Expect(tokens.AccessToken).To(Equal("test-access-token-refreshed"))
 					Expect(tokens.RefreshToken).To(Equal("test-refresh-token-refreshed"))
 					Expect(tokens.ExpiresIn).To(Equal(int64(24000)))
@@ -481,7 +590,8 @@ var _ = Describe("Client", func() {
 					fclock.Increment(12001*time.Second - TimeToRefreshBeforeTokenExpire)
 				})
 
-				It("returns existing tokens", func() {
+				It("returns the error with existing tokens", func() {
+					Expect(err).To(HaveOccurred())
 					Expect(tokens.AccessToken).To(Equal("test-access-token"))
 					Expect(tokens.RefreshToken).To(Equal("test-refresh-token"))
 					Expect(tokens.ExpiresIn).To(Equal(int64(12000)))
@@ -492,4 +602,62 @@ var _ = Describe("Client", func() {
 		})
 
 	})
+
+	Describe("IsTokenValid", func() {
+		BeforeEach(func() {
+			cfc, err = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when not logged in", func() {
+			It("returns false", func() {
+				Expect(cfc.IsTokenValid()).To(BeFalse())
+			})
+		})
+
+		Context("when logged in", func() {
+			BeforeEach(func() {
+				fakeCC.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", PathCfInfo),
+						ghttp.RespondWithJSONEncoded(http.StatusOK, Endpoints{
+							AuthEndpoint: fakeLoginServer.URL(),
+						}),
+					),
+				)
+				fakeLoginServer.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("POST", PathCfAuth),
+						ghttp.RespondWithJSONEncoded(http.StatusOK, Tokens{
+							AccessToken:  "test-access-token",
+							RefreshToken: "test-refresh-token",
+							ExpiresIn:    12000,
+						}),
+					),
+				)
+				err = cfc.Login()
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			Context("when the token has not expired", func() {
+				BeforeEach(func() {
+					fclock.Increment(11999 * time.Second)
+				})
+
+				It("returns true", func() {
+					Expect(cfc.IsTokenValid()).To(BeTrue())
+				})
+			})
+
+			Context("when the token has expired", func() {
+				BeforeEach(func() {
+					fclock.Increment(12000 * time.Second)
+				})
+
+				It("returns false", func() {
+					Expect(cfc.IsTokenValid()).To(BeFalse())
+				})
+			})
+		})
+	})
 })
@@ -1,18 +1,54 @@
 package cf
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"strings"
+	"time"
 )
 
+const (
+	ApiVersionV2 = "v2"
+	ApiVersionV3 = "v3"
+)
+
+type RetryConfig struct {
+	MaxRetries     int           `yaml:"max_retries"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries:     3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	OpenTimeout      time.Duration `yaml:"open_timeout"`
+}
+
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+}
+
 type CfConfig struct {
-	Api       string `yaml:"api"`
-	GrantType string `yaml:"grant_type"`
-	Username  string `yaml:"username"`
-	Password  string `yaml:"password"`
-	ClientId  string `yaml:"client_id"`
-	Secret    string `yaml:"secret"`
+	Api               string               `yaml:"api"`
+	ApiVersion        string               `yaml:"api_version"`
+	GrantType         string               `yaml:"grant_type"`
+	Username          string               `yaml:"username"`
+	Password          string               `yaml:"password"`
+	ClientId          string               `yaml:"client_id"`
+	Secret            string               `yaml:"secret"`
+	SkipSSLValidation bool                 `yaml:"skip_ssl_validation"`
+	CACertFile        string               `yaml:"ca_cert_file"`
+	Retry             RetryConfig          `yaml:"retry"`
+	CircuitBreaker    CircuitBreakerConfig `yaml:"circuit_breaker"`
 }
 
 func (conf *CfConfig) Validate() error {
@@ -39,6 +75,13 @@ func (conf *CfConfig) Validate() error {
 	}
 	conf.Api = apiUrl.String()
 
+	if conf.ApiVersion == "" {
+		conf.ApiVersion = ApiVersionV2
+	}
+	if conf.ApiVersion != ApiVersionV2 && conf.ApiVersion != ApiVersionV3 {
+		return fmt.Errorf("Configuration error: unsupported cf api version [%s]", conf.ApiVersion)
+	}
+
 	if conf.GrantType != GrantTypePassword && conf.GrantType != GrantTypeClientCredentials {
 		return fmt.Errorf("Configuration error: unsupported grant type [%s]", conf.GrantType)
 	}
@@ -54,5 +97,53 @@ func (conf *CfConfig) Validate() error {
 			return fmt.Errorf("Configuration error: client id is empty")
 		}
 	}
+
+	_, err = conf.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("Configuration error: cf ca cert file is invalid: %s", err.Error())
+	}
+
+	if conf.Retry.MaxRetries < 0 {
+		return fmt.Errorf("Configuration error: cf retry max retries is less than 0")
+	}
+	if conf.Retry.MaxRetries > 0 {
+		if conf.Retry.InitialBackoff <= 0 {
+			return fmt.Errorf("Configuration error: cf retry initial backoff is less than or equal to 0")
+		}
+		if conf.Retry.MaxBackoff < conf.Retry.InitialBackoff {
+			return fmt.Errorf("Configuration error: cf retry max backoff is less than initial backoff")
+		}
+	}
+
+	if conf.CircuitBreaker.FailureThreshold < 0 {
+		return fmt.Errorf("Configuration error: cf circuit breaker failure threshold is less than 0")
+	}
+	if conf.CircuitBreaker.FailureThreshold > 0 && conf.CircuitBreaker.OpenTimeout <= 0 {
+		return fmt.Errorf("Configuration error: cf circuit breaker open timeout is less than or equal to 0")
+	}
 	return nil
 }
+
+// TLSConfig returns the TLS configuration for connecting to the Cloud Foundry
+// endpoints. The CA in CACertFile is trusted in addition to the system roots.
+func (conf *CfConfig) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: conf.SkipSSLValidation}
+	if conf.CACertFile == "" {
+		return tlsConfig, nil
+	}
+
+	caCert, err := ioutil.ReadFile(conf.CACertFile)
+	if err != nil {
+		return nil, err
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificate found in %s", conf.CACertFile)
+	}
+	tlsConfig.RootCAs = rootCAs
+	return tlsConfig, nil
+}
@@ -3,6 +3,9 @@ package cf_test
 import (
 	. "autoscaler/cf"
 
+	"crypto/tls"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -27,6 +30,34 @@ var _ = Describe("Config", func() {
 			err = conf.Validate()
 		})
 
+		Context("when api version is not set", func() {
+			It("should default to v2", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(conf.ApiVersion).To(Equal(ApiVersionV2))
+			})
+		})
+
+		Context("when api version is v3", func() {
+			BeforeEach(func() {
+				conf.ApiVersion = ApiVersionV3
+			})
+
+			It("should not error", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(conf.ApiVersion).To(Equal(ApiVersionV3))
+			})
+		})
+
+		Context("when api version is not supported", func() {
+			BeforeEach(func() {
+				conf.ApiVersion = "v1"
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError("Configuration error: unsupported cf api version [v1]"))
+			})
+		})
+
 		Context("when api is not set", func() {
 			BeforeEach(func() {
 				conf.Api = ""
@@ -140,5 +171,126 @@ var _ = Describe("Config", func() {
 			})
 		})
 
+		Context("when ca cert file is set", func() {
+			BeforeEach(func() {
+				conf.CACertFile = "../../../test-certs/autoscaler-ca.crt"
+			})
+
+			It("is valid", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when ca cert file does not exist", func() {
+			BeforeEach(func() {
+				conf.CACertFile = "not-exist-ca.crt"
+			})
+
+			It("returns error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: cf ca cert file is invalid: .*not-exist-ca.crt")))
+			})
+		})
+
+		Context("when ca cert file does not contain a certificate", func() {
+			BeforeEach(func() {
+				conf.CACertFile = "../../../test-certs/autoscaler-ca.key"
+			})
+
+			It("returns error", func() {
+				Expect(err).To(MatchError("Configuration error: cf ca cert file is invalid: no certificate found in ../../../test-certs/autoscaler-ca.key"))
+			})
+		})
+
+		Context("when retry max retries is negative", func() {
+			BeforeEach(func() {
+				conf.Retry.MaxRetries = -1
+			})
+
+			It("returns error", func() {
+				Expect(err).To(MatchError("Configuration error: cf retry max retries is less than 0"))
+			})
+		})
+
+		Context("when retrying without an initial backoff", func() {
+			BeforeEach(func() {
+				conf.Retry = RetryConfig{MaxRetries: 3, MaxBackoff: 10 * time.Second}
+			})
+
+			It("returns error", func() {
+				Expect(err).To(MatchError("Configuration error: cf retry initial backoff is less than or equal to 0"))
+			})
+		})
+
+		Context("when retry max backoff is less than initial backoff", func() {
+			BeforeEach(func() {
+				conf.Retry = RetryConfig{MaxRetries: 3, InitialBackoff: 10 * time.Second, MaxBackoff: time.Second}
+			})
+
+			It("returns error", func() {
+				Expect(err).To(MatchError("Configuration error: cf retry max backoff is less than initial backoff"))
+			})
+		})
+
+		Context("when circuit breaker failure threshold is negative", func() {
+			BeforeEach(func() {
+				conf.CircuitBreaker.FailureThreshold = -1
+			})
+
+			It("returns error", func() {
+				Expect(err).To(MatchError("Configuration error: cf circuit breaker failure threshold is less than 0"))
+			})
+		})
+
+		Context("when circuit breaker is enabled without an open timeout", func() {
+			BeforeEach(func() {
+				conf.CircuitBreaker = CircuitBreakerConfig{FailureThreshold: 5}
+			})
+
+			It("returns error", func() {
+				Expect(err).To(MatchError("Configuration error: cf circuit breaker open timeout is less than or equal to 0"))
+			})
+		})
+
+	})
+
+	Describe("TLSConfig", func() {
+		var tlsConfig *tls.Config
+
+		BeforeEach(func() {
+			conf = &CfConfig{}
+		})
+
+		JustBeforeEach(func() {
+			tlsConfig, err = conf.TLSConfig()
+		})
+
+		It("verifies the server certificate by default", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tlsConfig.InsecureSkipVerify).To(BeFalse())
+			Expect(tlsConfig.RootCAs).To(BeNil())
+		})
+
+		Context("when skip ssl validation is set", func() {
+			BeforeEach(func() {
+				conf.SkipSSLValidation = true
+			})
+
+			It("skips verifying the server certificate", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tlsConfig.InsecureSkipVerify).To(BeTrue())
+			})
+		})
+
+		Context("when ca cert file is set", func() {
+			BeforeEach(func() {
+				conf.CACertFile = "../../../test-certs/autoscaler-ca.crt"
+			})
+
+			It("trusts the ca", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tlsConfig.InsecureSkipVerify).To(BeFalse())
+				Expect(tlsConfig.RootCAs).NotTo(BeNil())
+			})
+		})
 	})
 })
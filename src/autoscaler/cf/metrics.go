@@ -0,0 +1,39 @@
+package cf
+
+import (
+	"net/http"
+
+	"autoscaler/metrics"
+)
+
+var apiRequestDuration = metrics.NewHistogram(
+	"autoscaler_cf_api_request_duration_seconds",
+	"Latency of requests to the cloud foundry API and UAA, by operation.",
+	nil,
+	"operation",
+)
+
+var apiRequestRetries = metrics.NewCounter(
+	"autoscaler_cf_api_request_retries_total",
+	"Number of retried requests to the cloud foundry API and UAA, by operation.",
+	"operation",
+)
+
+var circuitBreakerOpen = metrics.NewGauge(
+	"autoscaler_cf_circuit_breaker_open",
+	"Whether the circuit breaker short-circuits calls to the cloud foundry API and UAA (1) or not (0).",
+)
+
+func init() {
+	metrics.MustRegister(apiRequestDuration)
+	metrics.MustRegister(apiRequestRetries)
+	metrics.MustRegister(circuitBreakerOpen)
+}
+
+func (c *cfClient) observeRequest(operation string, req *http.Request) (*http.Response, error) {
+	start := c.clk.Now()
+	defer func() {
+		apiRequestDuration.Observe(c.clk.Since(start).Seconds(), operation)
+	}()
+	return c.httpClient.Do(req)
+}
@@ -0,0 +1,222 @@
+package cf
+
+import (
+	"path"
+
+	"autoscaler/models"
+)
+
+const (
+	PathSpace                = "/v2/spaces"
+	PathOrg                  = "/v2/organizations"
+	PathQuotaDefinition      = "/v2/quota_definitions"
+	PathSpaceQuotaDefinition = "/v2/space_quota_definitions"
+	PathSpaceV3              = "/v3/spaces"
+	PathOrgV3                = "/v3/organizations"
+	PathOrgQuotaV3           = "/v3/organization_quotas"
+	PathSpaceQuotaV3         = "/v3/space_quotas"
+)
+
+type spaceInfo struct {
+	Entity struct {
+		OrgGuid        string `json:"organization_guid"`
+		SpaceQuotaGuid string `json:"space_quota_definition_guid"`
+	} `json:"entity"`
+}
+
+type orgInfo struct {
+	Entity struct {
+		QuotaGuid string `json:"quota_definition_guid"`
+	} `json:"entity"`
+}
+
+type quotaDefinition struct {
+	Entity struct {
+		MemoryLimit int `json:"memory_limit"`
+	} `json:"entity"`
+}
+
+type orgMemoryUsage struct {
+	MemoryUsageInMb int `json:"memory_usage_in_mb"`
+}
+
+type spaceSummary struct {
+	Apps []models.AppEntity `json:"apps"`
+}
+
+type relationship struct {
+	Data *struct {
+		Guid string `json:"guid"`
+	} `json:"data"`
+}
+
+func (r relationship) guid() string {
+	if r.Data == nil {
+		return ""
+	}
+	return r.Data.Guid
+}
+
+type resourceV3 struct {
+	Relationships struct {
+		Space        relationship `json:"space"`
+		Organization relationship `json:"organization"`
+		Quota        relationship `json:"quota"`
+	} `json:"relationships"`
+}
+
+type quotaV3 struct {
+	Apps struct {
+		TotalMemoryInMb *int `json:"total_memory_in_mb"`
+	} `json:"apps"`
+}
+
+func (q *quotaV3) memoryLimit() int {
+	if q.Apps.TotalMemoryInMb == nil {
+		return models.UnlimitedMemory
+	}
+	return *q.Apps.TotalMemoryInMb
+}
+
+type usageSummaryV3 struct {
+	UsageSummary struct {
+		MemoryInMb int `json:"memory_in_mb"`
+	} `json:"usage_summary"`
+}
+
+// GetAppQuotas returns the memory of one instance of the app process, and the
+// memory limit and usage of the org and the space quotas of the app.
+func (c *cfClient) GetAppQuotas(appId string, processType string) (models.AppQuotas, error) {
+	if c.conf.ApiVersion == ApiVersionV3 {
+		return c.getAppQuotasV3(appId, processType)
+	}
+
+	quotas := models.AppQuotas{}
+	err := checkV2ProcessType(processType)
+	if err != nil {
+		c.logger.Error("get-app-quotas", err)
+		return quotas, err
+	}
+
+	app := &models.AppInfo{}
+	err = c.getJSON("get-app-memory", appId, c.conf.Api+path.Join(PathApp, appId), app)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.InstanceMemory = app.Entity.Memory
+
+	space := &spaceInfo{}
+	err = c.getJSON("get-space", appId, c.conf.Api+path.Join(PathSpace, app.Entity.SpaceGuid), space)
+	if err != nil {
+		return quotas, err
+	}
+
+	org := &orgInfo{}
+	err = c.getJSON("get-org", appId, c.conf.Api+path.Join(PathOrg, space.Entity.OrgGuid), org)
+	if err != nil {
+		return quotas, err
+	}
+
+	orgQuota := &quotaDefinition{}
+	err = c.getJSON("get-org-quota", appId, c.conf.Api+path.Join(PathQuotaDefinition, org.Entity.QuotaGuid), orgQuota)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.Org.Limit = orgQuota.Entity.MemoryLimit
+
+	orgUsage := &orgMemoryUsage{}
+	err = c.getJSON("get-org-memory-usage", appId, c.conf.Api+path.Join(PathOrg, space.Entity.OrgGuid, "memory_usage"), orgUsage)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.Org.Used = orgUsage.MemoryUsageInMb
+
+	quotas.Space.Limit = models.UnlimitedMemory
+	if space.Entity.SpaceQuotaGuid == "" {
+		return quotas, nil
+	}
+
+	spaceQuota := &quotaDefinition{}
+	err = c.getJSON("get-space-quota", appId, c.conf.Api+path.Join(PathSpaceQuotaDefinition, space.Entity.SpaceQuotaGuid), spaceQuota)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.Space.Limit = spaceQuota.Entity.MemoryLimit
+
+	summary := &spaceSummary{}
+	err = c.getJSON("get-space-summary", appId, c.conf.Api+path.Join(PathSpace, app.Entity.SpaceGuid, "summary"), summary)
+	if err != nil {
+		return quotas, err
+	}
+	for _, a := range summary.Apps {
+		if a.State == models.AppStateStarted {
+			quotas.Space.Used += a.Memory * a.Instances
+		}
+	}
+	return quotas, nil
+}
+
+func (c *cfClient) getAppQuotasV3(appId string, processType string) (models.AppQuotas, error) {
+	quotas := models.AppQuotas{}
+
+	process, err := c.getProcess(appId, processType)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.InstanceMemory = process.MemoryInMb
+
+	app := &resourceV3{}
+	err = c.getJSON("get-app", appId, c.conf.Api+path.Join(PathAppV3, appId), app)
+	if err != nil {
+		return quotas, err
+	}
+	spaceGuid := app.Relationships.Space.guid()
+
+	space := &resourceV3{}
+	err = c.getJSON("get-space", appId, c.conf.Api+path.Join(PathSpaceV3, spaceGuid), space)
+	if err != nil {
+		return quotas, err
+	}
+	orgGuid := space.Relationships.Organization.guid()
+
+	org := &resourceV3{}
+	err = c.getJSON("get-org", appId, c.conf.Api+path.Join(PathOrgV3, orgGuid), org)
+	if err != nil {
+		return quotas, err
+	}
+
+	orgQuota := &quotaV3{}
+	err = c.getJSON("get-org-quota", appId, c.conf.Api+path.Join(PathOrgQuotaV3, org.Relationships.Quota.guid()), orgQuota)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.Org.Limit = orgQuota.memoryLimit()
+
+	orgUsage := &usageSummaryV3{}
+	err = c.getJSON("get-org-usage", appId, c.conf.Api+path.Join(PathOrgV3, orgGuid, "usage_summary"), orgUsage)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.Org.Used = orgUsage.UsageSummary.MemoryInMb
+
+	quotas.Space.Limit = models.UnlimitedMemory
+	spaceQuotaGuid := space.Relationships.Quota.guid()
+	if spaceQuotaGuid == "" {
+		return quotas, nil
+	}
+
+	spaceQuota := &quotaV3{}
+	err = c.getJSON("get-space-quota", appId, c.conf.Api+path.Join(PathSpaceQuotaV3, spaceQuotaGuid), spaceQuota)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.Space.Limit = spaceQuota.memoryLimit()
+
+	spaceUsage := &usageSummaryV3{}
+	err = c.getJSON("get-space-usage", appId, c.conf.Api+path.Join(PathSpaceV3, spaceGuid, "usage_summary"), spaceUsage)
+	if err != nil {
+		return quotas, err
+	}
+	quotas.Space.Used = spaceUsage.UsageSummary.MemoryInMb
+	return quotas, nil
+}
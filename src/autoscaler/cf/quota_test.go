@@ -0,0 +1,142 @@
+package cf_test
+
+import (
+	. "autoscaler/cf"
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	"net/http"
+)
+
+var _ = Describe("GetAppQuotas", func() {
+
+	var (
+		conf            *CfConfig
+		cfc             CfClient
+		fakeCC          *ghttp.Server
+		fakeLoginServer *ghttp.Server
+		quotas          models.AppQuotas
+		err             error
+	)
+
+	BeforeEach(func() {
+		fakeCC = ghttp.NewServer()
+		fakeLoginServer = ghttp.NewServer()
+		fakeCC.RouteToHandler("GET", PathCfInfo, ghttp.RespondWithJSONEncoded(http.StatusOK, Endpoints{
+			AuthEndpoint:    fakeLoginServer.URL(),
+			TokenEndpoint:   "test-token-endpoint",
+			DopplerEndpoint: "test-doppler-endpoint",
+		}))
+		fakeLoginServer.RouteToHandler("POST", PathCfAuth, ghttp.RespondWithJSONEncoded(http.StatusOK, Tokens{
+			AccessToken:  "test-access-token",
+			RefreshToken: "test-refresh-token",
+			ExpiresIn:    12000,
+		}))
+		conf = &CfConfig{}
+		conf.Api = fakeCC.URL()
+		cfc, err = NewCfClient(conf, lager.NewLogger("cf"), clock.NewClock())
+		Expect(err).NotTo(HaveOccurred())
+		cfc.Login()
+	})
+
+	AfterEach(func() {
+		fakeCC.Close()
+		fakeLoginServer.Close()
+	})
+
+	JustBeforeEach(func() {
+		quotas, err = cfc.GetAppQuotas("test-app-id", "web")
+	})
+
+	Context("when using cf api v2", func() {
+		routeV2 := func(spaceQuotaGuid string) {
+			fakeCC.RouteToHandler("GET", PathApp+"/test-app-id", ghttp.RespondWithJSONEncoded(http.StatusOK,
+				models.AppInfo{Entity: models.AppEntity{Memory: 256, SpaceGuid: "test-space-guid"}}))
+			fakeCC.RouteToHandler("GET", PathSpace+"/test-space-guid", ghttp.RespondWith(http.StatusOK,
+				`{"entity":{"organization_guid":"test-org-guid","space_quota_definition_guid":"`+spaceQuotaGuid+`"}}`))
+			fakeCC.RouteToHandler("GET", PathOrg+"/test-org-guid", ghttp.RespondWith(http.StatusOK,
+				`{"entity":{"quota_definition_guid":"test-org-quota-guid"}}`))
+			fakeCC.RouteToHandler("GET", PathQuotaDefinition+"/test-org-quota-guid", ghttp.RespondWith(http.StatusOK,
+				`{"entity":{"memory_limit":10240}}`))
+			fakeCC.RouteToHandler("GET", PathOrg+"/test-org-guid/memory_usage", ghttp.RespondWith(http.StatusOK,
+				`{"memory_usage_in_mb":8192}`))
+			fakeCC.RouteToHandler("GET", PathSpaceQuotaDefinition+"/test-space-quota-guid", ghttp.RespondWith(http.StatusOK,
+				`{"entity":{"memory_limit":2048}}`))
+			fakeCC.RouteToHandler("GET", PathSpace+"/test-space-guid/summary", ghttp.RespondWith(http.StatusOK,
+				`{"apps":[{"memory":256,"instances":4,"state":"STARTED"},{"memory":1024,"instances":1,"state":"STOPPED"}]}`))
+		}
+
+		Context("when the space has a quota", func() {
+			BeforeEach(func() {
+				routeV2("test-space-quota-guid")
+			})
+
+			It("returns the org and the space quotas with the memory used by started apps", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quotas).To(Equal(models.AppQuotas{
+					InstanceMemory: 256,
+					Org:            models.MemoryQuota{Limit: 10240, Used: 8192},
+					Space:          models.MemoryQuota{Limit: 2048, Used: 1024},
+				}))
+			})
+		})
+
+		Context("when the space has no quota", func() {
+			BeforeEach(func() {
+				routeV2("")
+			})
+
+			It("returns an unlimited space quota", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quotas.Org).To(Equal(models.MemoryQuota{Limit: 10240, Used: 8192}))
+				Expect(quotas.Space).To(Equal(models.MemoryQuota{Limit: models.UnlimitedMemory}))
+			})
+		})
+	})
+
+	Context("when using cf api v3", func() {
+		BeforeEach(func() {
+			conf.ApiVersion = ApiVersionV3
+			fakeCC.RouteToHandler("GET", PathAppV3+"/test-app-id/processes/web", ghttp.RespondWithJSONEncoded(http.StatusOK,
+				models.Process{Guid: "test-process-guid", Type: "web", Instances: 2, MemoryInMb: 512}))
+			fakeCC.RouteToHandler("GET", PathAppV3+"/test-app-id", ghttp.RespondWith(http.StatusOK,
+				`{"relationships":{"space":{"data":{"guid":"test-space-guid"}}}}`))
+			fakeCC.RouteToHandler("GET", PathSpaceV3+"/test-space-guid", ghttp.RespondWith(http.StatusOK,
+				`{"relationships":{"organization":{"data":{"guid":"test-org-guid"}},"quota":{"data":{"guid":"test-space-quota-guid"}}}}`))
+			fakeCC.RouteToHandler("GET", PathOrgV3+"/test-org-guid", ghttp.RespondWith(http.StatusOK,
+				`{"relationships":{"quota":{"data":{"guid":"test-org-quota-guid"}}}}`))
+			fakeCC.RouteToHandler("GET", PathOrgQuotaV3+"/test-org-quota-guid", ghttp.RespondWith(http.StatusOK,
+				`{"apps":{"total_memory_in_mb":null}}`))
+			fakeCC.RouteToHandler("GET", PathOrgV3+"/test-org-guid/usage_summary", ghttp.RespondWith(http.StatusOK,
+				`{"usage_summary":{"memory_in_mb":4096}}`))
+			fakeCC.RouteToHandler("GET", PathSpaceQuotaV3+"/test-space-quota-guid", ghttp.RespondWith(http.StatusOK,
+				`{"apps":{"total_memory_in_mb":3072}}`))
+			fakeCC.RouteToHandler("GET", PathSpaceV3+"/test-space-guid/usage_summary", ghttp.RespondWith(http.StatusOK,
+				`{"usage_summary":{"memory_in_mb":2048}}`))
+		})
+
+		It("returns the org and the space quotas", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quotas).To(Equal(models.AppQuotas{
+				InstanceMemory: 512,
+				Org:            models.MemoryQuota{Limit: models.UnlimitedMemory, Used: 4096},
+				Space:          models.MemoryQuota{Limit: 3072, Used: 2048},
+			}))
+		})
+	})
+
+	Context("when the app does not exist", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+		})
+
+		It("returns an app not found error", func() {
+			Expect(err).To(Equal(&AppNotFoundError{AppId: "test-app-id"}))
+		})
+	})
+})
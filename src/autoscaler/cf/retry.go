@@ -0,0 +1,105 @@
+package cf
+
+import (
+	"code.cloudfoundry.org/lager"
+
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// doRequest sends req through the circuit breaker. Idempotent requests are
+// retried with exponential backoff on connection errors and 5xx responses, and
+// any request is retried on 429 after the delay asked for in Retry-After.
+func (c *cfClient) doRequest(operation string, req *http.Request) (*http.Response, error) {
+	err := c.breaker.allow()
+	if err != nil {
+		c.logger.Error("circuit-open", err, lager.Data{"operation": operation})
+		return nil, err
+	}
+
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		resp, err = c.observeRequest(operation, req)
+		if attempt >= c.conf.Retry.MaxRetries || !shouldRetry(req, resp, err) {
+			break
+		}
+		wait, ok := c.retryWait(attempt, resp)
+		if !ok || (req.Body != nil && req.GetBody == nil) {
+			break
+		}
+
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				resp = nil
+				break
+			}
+		}
+
+		c.logger.Info("retry-request", lager.Data{"operation": operation, "attempt": attempt + 1, "wait": wait.String()})
+		apiRequestRetries.Inc(operation)
+		c.clk.Sleep(wait)
+	}
+
+	c.breaker.record(err != nil || isServerFailure(resp.StatusCode))
+	return resp, err
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodPut {
+		return false
+	}
+	return err != nil || isServerFailure(resp.StatusCode)
+}
+
+func isServerFailure(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests
+}
+
+// retryWait returns the exponential backoff for the attempt, or the Retry-After
+// delay of the response when it is longer. It returns false when Retry-After
+// asks for a longer delay than the maximum backoff.
+func (c *cfClient) retryWait(attempt int, resp *http.Response) (time.Duration, bool) {
+	wait := c.conf.Retry.InitialBackoff << uint(attempt)
+	if wait <= 0 || wait > c.conf.Retry.MaxBackoff {
+		wait = c.conf.Retry.MaxBackoff
+	}
+
+	if resp == nil {
+		return wait, true
+	}
+	retryAfter, ok := c.parseRetryAfter(resp.Header.Get("Retry-After"))
+	if !ok {
+		return wait, true
+	}
+	if retryAfter > c.conf.Retry.MaxBackoff {
+		return 0, false
+	}
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+	return wait, true
+}
+
+func (c *cfClient) parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return at.Sub(c.clk.Now()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,241 @@
+package cf_test
+
+import (
+	. "autoscaler/cf"
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	"net/http"
+	"time"
+)
+
+var _ = Describe("Retry and circuit breaker", func() {
+	var (
+		conf            *CfConfig
+		cfc             CfClient
+		fakeCC          *ghttp.Server
+		fakeLoginServer *ghttp.Server
+		fclock          *fakeclock.FakeClock
+		appPath         string
+	)
+
+	type result struct {
+		instances int
+		err       error
+	}
+
+	getAppInstances := func() chan result {
+		results := make(chan result, 1)
+		go func() {
+			instances, err := cfc.GetAppInstances("test-app-id", "web")
+			results <- result{instances, err}
+		}()
+		return results
+	}
+
+	BeforeEach(func() {
+		fakeCC = ghttp.NewServer()
+		fakeLoginServer = ghttp.NewServer()
+		fakeCC.RouteToHandler("GET", PathCfInfo, ghttp.RespondWithJSONEncoded(http.StatusOK, Endpoints{
+			AuthEndpoint:    fakeLoginServer.URL(),
+			TokenEndpoint:   "test-token-endpoint",
+			DopplerEndpoint: "test-doppler-endpoint",
+		}))
+		fakeLoginServer.RouteToHandler("POST", PathCfAuth, ghttp.RespondWithJSONEncoded(http.StatusOK, Tokens{
+			AccessToken:  "test-access-token",
+			RefreshToken: "test-refresh-token",
+			ExpiresIn:    12000,
+		}))
+		conf = &CfConfig{}
+		conf.Api = fakeCC.URL()
+		conf.Retry = RetryConfig{
+			MaxRetries:     2,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Second,
+		}
+		fclock = fakeclock.NewFakeClock(time.Now())
+		appPath = PathApp + "/test-app-id"
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		cfc, err = NewCfClient(conf, lager.NewLogger("cf"), fclock)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfc.Login()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		fakeCC.Close()
+		fakeLoginServer.Close()
+	})
+
+	Context("when the cloud controller fails temporarily", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(
+				ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", appPath),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, models.AppInfo{Entity: models.AppEntity{Instances: 3}}),
+				),
+			)
+		})
+
+		It("retries idempotent requests with exponential backoff", func() {
+			results := getAppInstances()
+
+			fclock.WaitForWatcherAndIncrement(time.Second)
+			Consistently(results).ShouldNot(Receive())
+			fclock.WaitForWatcherAndIncrement(2 * time.Second)
+
+			Eventually(results).Should(Receive(Equal(result{3, nil})))
+			Expect(fakeCC.ReceivedRequests()).To(HaveLen(4))
+		})
+	})
+
+	Context("when the cloud controller keeps failing", func() {
+		BeforeEach(func() {
+			fakeCC.AppendHandlers(
+				ghttp.RespondWith(http.StatusInternalServerError, ""),
+				ghttp.RespondWith(http.StatusInternalServerError, ""),
+				ghttp.RespondWith(http.StatusInternalServerError, ""),
+			)
+		})
+
+		It("gives up after the maximum number of retries", func() {
+			results := getAppInstances()
+
+			fclock.WaitForWatcherAndIncrement(time.Second)
+			fclock.WaitForWatcherAndIncrement(2 * time.Second)
+
+			var r result
+			Eventually(results).Should(Receive(&r))
+			Expect(r.err).To(MatchError(MatchRegexp("failed getting application summary: .* \\[500\\]")))
+			Expect(fakeCC.ReceivedRequests()).To(HaveLen(4))
+		})
+	})
+
+	Context("when a non-idempotent request fails", func() {
+		BeforeEach(func() {
+			conf.ApiVersion = ApiVersionV3
+			fakeCC.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, models.Process{Guid: "test-process-guid", Type: "web", Instances: 2}),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", PathProcessV3+"/test-process-guid/actions/scale"),
+					ghttp.RespondWith(http.StatusInternalServerError, ""),
+				),
+			)
+		})
+
+		It("does not retry it", func() {
+			err := cfc.SetAppInstances("test-app-id", "web", 3)
+			Expect(err).To(MatchError(MatchRegexp("failed scaling application process: .* \\[500\\]")))
+			Expect(fakeCC.ReceivedRequests()).To(HaveLen(3))
+		})
+	})
+
+	Context("when the cloud controller rate limits requests", func() {
+		var retryAfter string
+
+		JustBeforeEach(func() {
+			header := http.Header{"Retry-After": []string{retryAfter}}
+			fakeCC.AppendHandlers(
+				ghttp.RespondWith(http.StatusTooManyRequests, "", header),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, models.AppInfo{Entity: models.AppEntity{Instances: 3}}),
+			)
+		})
+
+		Context("when Retry-After is within the maximum backoff", func() {
+			BeforeEach(func() {
+				retryAfter = "3"
+			})
+
+			It("retries after the requested delay", func() {
+				results := getAppInstances()
+
+				fclock.WaitForWatcherAndIncrement(time.Second)
+				Consistently(results).ShouldNot(Receive())
+				fclock.Increment(2 * time.Second)
+
+				Eventually(results).Should(Receive(Equal(result{3, nil})))
+			})
+		})
+
+		Context("when Retry-After exceeds the maximum backoff", func() {
+			BeforeEach(func() {
+				retryAfter = "60"
+			})
+
+			It("does not retry", func() {
+				var r result
+				Eventually(getAppInstances()).Should(Receive(&r))
+				Expect(r.err).To(MatchError(MatchRegexp("failed getting application summary: .* \\[429\\]")))
+				Expect(fakeCC.ReceivedRequests()).To(HaveLen(2))
+			})
+		})
+	})
+
+	Context("when the circuit breaker is enabled", func() {
+		BeforeEach(func() {
+			conf.Retry = RetryConfig{}
+			conf.CircuitBreaker = CircuitBreakerConfig{
+				FailureThreshold: 2,
+				OpenTimeout:      30 * time.Second,
+			}
+			fakeCC.AppendHandlers(
+				ghttp.RespondWith(http.StatusInternalServerError, ""),
+				ghttp.RespondWith(http.StatusInternalServerError, ""),
+			)
+		})
+
+		JustBeforeEach(func() {
+			_, err := cfc.GetAppInstances("test-app-id", "web")
+			Expect(err).To(HaveOccurred())
+			_, err = cfc.GetAppInstances("test-app-id", "web")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("opens after consecutive failures and rejects calls without sending them", func() {
+			_, err := cfc.GetAppInstances("test-app-id", "web")
+			Expect(err).To(BeAssignableToTypeOf(&CircuitOpenError{}))
+			Expect(fakeCC.ReceivedRequests()).To(HaveLen(3))
+		})
+
+		Context("when the open timeout has passed", func() {
+			JustBeforeEach(func() {
+				fclock.Increment(30 * time.Second)
+			})
+
+			It("closes when the trial call succeeds", func() {
+				fakeCC.AppendHandlers(
+					ghttp.RespondWithJSONEncoded(http.StatusOK, models.AppInfo{Entity: models.AppEntity{Instances: 3}}),
+					ghttp.RespondWith(http.StatusInternalServerError, ""),
+				)
+
+				instances, err := cfc.GetAppInstances("test-app-id", "web")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(instances).To(Equal(3))
+
+				_, err = cfc.GetAppInstances("test-app-id", "web")
+				Expect(err).To(MatchError(MatchRegexp("failed getting application summary")))
+				Expect(fakeCC.ReceivedRequests()).To(HaveLen(5))
+			})
+
+			It("opens again when the trial call fails", func() {
+				fakeCC.AppendHandlers(ghttp.RespondWith(http.StatusServiceUnavailable, ""))
+
+				_, err := cfc.GetAppInstances("test-app-id", "web")
+				Expect(err).To(MatchError(MatchRegexp("failed getting application summary")))
+
+				_, err = cfc.GetAppInstances("test-app-id", "web")
+				Expect(err).To(BeAssignableToTypeOf(&CircuitOpenError{}))
+				Expect(fakeCC.ReceivedRequests()).To(HaveLen(4))
+			})
+		})
+	})
+})
@@ -2,21 +2,53 @@ package db
 
 import (
 	"autoscaler/models"
+
+	"fmt"
+	"time"
 )
 
 const PostgresDriverName = "postgres"
 
+// PolicyChangeChannel is the notification channel on which the policy tables
+// publish the id of the app whose policy, orphan mark or observation changed.
+const PolicyChangeChannel = "policy_change"
+
 type InstanceMetricsDB interface {
 	RetrieveInstanceMetrics(appid string, name string, start int64, end int64) ([]*models.AppInstanceMetric, error)
 	SaveMetric(metric *models.AppInstanceMetric) error
 	PruneInstanceMetrics(before int64) error
+	Ping() error
 	Close() error
 }
 
+// AppGroupConflictError is returned when saving an app group whose member is
+// already a member of another group.
+type AppGroupConflictError struct {
+	AppId string
+}
+
+func (e *AppGroupConflictError) Error() string {
+	return fmt.Sprintf("app %s is a member of another app group", e.AppId)
+}
+
 type PolicyDB interface {
 	GetAppIds() (map[string]bool, error)
 	GetAppPolicy(appId string) (*models.ScalingPolicy, error)
 	RetrievePolicies() ([]*models.PolicyJson, error)
+	RetrievePoliciesSince(version int64) ([]*models.PolicyJson, error)
+	MarkAppOrphaned(appId string, orphanedAt int64) error
+	UnmarkAppOrphaned(appId string) error
+	RetrieveOrphanedApps(before int64) ([]string, error)
+	DeletePolicy(appId string) error
+	ObserveApp(appId string, expireAt int64) error
+	UnobserveApp(appId string) error
+	RetrieveObservedApps(now int64) ([]string, error)
+	GetAppGroup(groupId string) (*models.AppGroup, error)
+	GetAppGroupOfApp(appId string) (*models.AppGroup, error)
+	SaveAppGroup(group *models.AppGroup) error
+	DeleteAppGroup(groupId string) error
+	SubscribePolicyChanges() (<-chan string, error)
+	Ping() error
 	Close() error
 }
 
@@ -24,23 +56,44 @@ type AppMetricDB interface {
 	SaveAppMetric(appMetric *models.AppMetric) error
 	RetrieveAppMetrics(appId string, metricType string, start int64, end int64) ([]*models.AppMetric, error)
 	PruneAppMetrics(before int64) error
+	Ping() error
 	Close() error
 }
 
 type ScalingEngineDB interface {
 	SaveScalingHistory(history *models.AppScalingHistory) error
 	RetrieveScalingHistories(appId string, start int64, end int64) ([]*models.AppScalingHistory, error)
+	RetrieveGroupScalingHistories(groupId string, start int64, end int64) ([]*models.AppScalingHistory, error)
 	PruneScalingHistories(before int64) error
 	UpdateScalingCooldownExpireTime(appId string, expireAt int64) error
-	CanScaleApp(appId string) (bool, error)
+	RemoveScalingCooldown(appId string) error
+	ScaleWithCooldown(appId string, now int64, scale func() (int64, error)) (bool, error)
+	RestartWithCooldown(appId string, instanceIndex int, now int64, restart func() (int64, error)) (bool, error)
 	GetActiveSchedule(appId string) (*models.ActiveSchedule, error)
 	GetActiveSchedules() (map[string]string, error)
 	SetActiveSchedule(appId string, schedule *models.ActiveSchedule) error
 	RemoveActiveSchedule(appId string) error
+	Ping() error
 	Close() error
 }
 
 type SchedulerDB interface {
 	GetActiveSchedules() (map[string]*models.ActiveSchedule, error)
+	Ping() error
+	Close() error
+}
+
+type ShardDB interface {
+	RegisterShardMember(group string, memberId string, ttl time.Duration) error
+	RetrieveShardMembers(group string) ([]string, error)
+	RemoveShardMember(group string, memberId string) error
+	Ping() error
+	Close() error
+}
+
+type LeaseDB interface {
+	AcquireLease(name string, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(name string, owner string) error
+	Ping() error
 	Close() error
 }
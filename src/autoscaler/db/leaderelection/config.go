@@ -0,0 +1,36 @@
+package leaderelection
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultLeaseTTL      time.Duration = 30 * time.Second
+	DefaultRetryInterval time.Duration = 10 * time.Second
+)
+
+type LeaderElectionConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	OwnerId       string        `yaml:"owner_id"`
+	LeaseTTL      time.Duration `yaml:"lease_ttl"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+var DefaultLeaderElectionConfig = LeaderElectionConfig{
+	LeaseTTL:      DefaultLeaseTTL,
+	RetryInterval: DefaultRetryInterval,
+}
+
+func (conf *LeaderElectionConfig) Validate() error {
+	if !conf.Enabled {
+		return nil
+	}
+	if conf.RetryInterval <= 0 {
+		return fmt.Errorf("Configuration error: leader election retry interval is less than or equal to 0")
+	}
+	if conf.LeaseTTL <= conf.RetryInterval {
+		return fmt.Errorf("Configuration error: leader election lease ttl is not greater than retry interval")
+	}
+	return nil
+}
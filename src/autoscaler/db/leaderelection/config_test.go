@@ -0,0 +1,57 @@
+package leaderelection_test
+
+import (
+	. "autoscaler/db/leaderelection"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LeaderElectionConfig", func() {
+	var (
+		conf LeaderElectionConfig
+		err  error
+	)
+
+	BeforeEach(func() {
+		conf = DefaultLeaderElectionConfig
+		conf.Enabled = true
+	})
+
+	JustBeforeEach(func() {
+		err = conf.Validate()
+	})
+
+	Context("when using the default config", func() {
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when retry interval is 0", func() {
+		BeforeEach(func() {
+			conf.RetryInterval = 0
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("Configuration error: leader election retry interval is less than or equal to 0"))
+		})
+	})
+
+	Context("when lease ttl is not greater than retry interval", func() {
+		BeforeEach(func() {
+			conf.LeaseTTL = conf.RetryInterval
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("Configuration error: leader election lease ttl is not greater than retry interval"))
+		})
+
+		Context("when leader election is disabled", func() {
+			BeforeEach(func() {
+				conf.Enabled = false
+			})
+			It("should not error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+})
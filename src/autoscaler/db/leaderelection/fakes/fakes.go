@@ -0,0 +1,3 @@
+package fakes
+
+//go:generate counterfeiter -o ./fake_lease_db.go ../.. LeaseDB
@@ -0,0 +1,13 @@
+package leaderelection_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLeaderelection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Leaderelection Suite")
+}
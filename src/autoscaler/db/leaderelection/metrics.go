@@ -0,0 +1,17 @@
+package leaderelection
+
+import (
+	"autoscaler/metrics"
+)
+
+var (
+	isLeader = metrics.NewGauge(
+		"autoscaler_leader_election_is_leader",
+		"Whether this instance holds the lease and runs the elected loop (1) or stands by (0), by lease.",
+		"lease",
+	)
+)
+
+func init() {
+	metrics.MustRegister(isLeader)
+}
@@ -0,0 +1,126 @@
+package leaderelection
+
+import (
+	"autoscaler/db"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+
+	"os"
+	"sync"
+	"time"
+)
+
+// Runner runs the wrapped runner only while it holds the named lease. All the
+// instances sharing the lease try to acquire it every retry interval; the
+// holder renews it at the same interval. The holder stops the wrapped runner
+// when another instance has taken the lease, or when renewals keep failing and
+// its lease would expire before the next attempt. When the holder dies its
+// lease expires after the ttl and a standby instance takes over.
+type Runner struct {
+	logger        lager.Logger
+	leaseDB       db.LeaseDB
+	name          string
+	owner         string
+	ttl           time.Duration
+	retryInterval time.Duration
+	lclock        clock.Clock
+	runner        ifrit.Runner
+	expireAt      time.Time
+	leader        bool
+	lock          sync.Mutex
+}
+
+func NewRunner(logger lager.Logger, leaseDB db.LeaseDB, name string, owner string, ttl time.Duration, retryInterval time.Duration, lclock clock.Clock, runner ifrit.Runner) *Runner {
+	return &Runner{
+		logger:        logger,
+		leaseDB:       leaseDB,
+		name:          name,
+		owner:         owner,
+		ttl:           ttl,
+		retryInterval: retryInterval,
+		lclock:        lclock,
+		runner:        runner,
+	}
+}
+
+func (r *Runner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	r.setLeader(false)
+	close(ready)
+	r.logger.Info("started", lager.Data{"lease": r.name, "owner": r.owner})
+
+	ticker := r.lclock.NewTicker(r.retryInterval)
+	defer ticker.Stop()
+
+	var process ifrit.Process
+	var exited <-chan error
+	for {
+		acquired := r.acquireLease(process != nil)
+		if acquired && process == nil {
+			r.logger.Info("became-leader", lager.Data{"lease": r.name})
+			process = ifrit.Background(r.runner)
+			exited = process.Wait()
+			r.setLeader(true)
+		} else if !acquired && process != nil {
+			r.logger.Info("lost-leadership", lager.Data{"lease": r.name})
+			r.setLeader(false)
+			process.Signal(os.Interrupt)
+			<-exited
+			process, exited = nil, nil
+		}
+
+		select {
+		case sig := <-signals:
+			if process != nil {
+				process.Signal(sig)
+				<-exited
+				r.setLeader(false)
+				r.leaseDB.ReleaseLease(r.name, r.owner)
+			}
+			r.logger.Info("stopped")
+			return nil
+		case err := <-exited:
+			r.logger.Info("elected-runner-exited", lager.Data{"lease": r.name})
+			r.setLeader(false)
+			r.leaseDB.ReleaseLease(r.name, r.owner)
+			return err
+		case <-ticker.C():
+		}
+	}
+}
+
+// acquireLease acquires or renews the lease. When the database cannot be
+// reached, the leader keeps the lease it already holds as long as it outlives
+// the next attempt; expireAt is measured from before the last successful
+// renewal, so it never passes the expiry the database recorded.
+func (r *Runner) acquireLease(leading bool) bool {
+	start := r.lclock.Now()
+	acquired, err := r.leaseDB.AcquireLease(r.name, r.owner, r.ttl)
+	if err != nil {
+		r.logger.Error("failed-to-acquire-lease", err, lager.Data{"lease": r.name})
+		return leading && r.lclock.Now().Add(r.retryInterval).Before(r.expireAt)
+	}
+	if acquired {
+		r.expireAt = start.Add(r.ttl)
+	}
+	return acquired
+}
+
+func (r *Runner) setLeader(leader bool) {
+	r.lock.Lock()
+	r.leader = leader
+	r.lock.Unlock()
+
+	value := 0.0
+	if leader {
+		value = 1
+	}
+	isLeader.Set(value, r.name)
+}
+
+func (r *Runner) IsLeader() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.leader
+}
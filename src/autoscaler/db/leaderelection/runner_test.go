@@ -0,0 +1,227 @@
+package leaderelection_test
+
+import (
+	. "autoscaler/db/leaderelection"
+	"autoscaler/db/leaderelection/fakes"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	testLeaseTTL      = 30 * time.Second
+	testRetryInterval = 10 * time.Second
+)
+
+var _ = Describe("Runner", func() {
+	var (
+		leaseDB  *fakes.FakeLeaseDB
+		fclock   *fakeclock.FakeClock
+		buffer   *gbytes.Buffer
+		runner   *Runner
+		proc     ifrit.Process
+		started  int32
+		stopped  int32
+		exitChan chan error
+	)
+
+	// The lease is switched while the code under test acquires it on another
+	// goroutine, so the stub reads it under a lock.
+	var (
+		leaseLock     sync.Mutex
+		leaseAcquired bool
+		leaseErr      error
+	)
+	setLease := func(acquired bool, err error) {
+		leaseLock.Lock()
+		defer leaseLock.Unlock()
+		leaseAcquired, leaseErr = acquired, err
+	}
+
+	BeforeEach(func() {
+		leaseDB = &fakes.FakeLeaseDB{}
+		setLease(false, nil)
+		leaseDB.AcquireLeaseStub = func(name string, owner string, ttl time.Duration) (bool, error) {
+			leaseLock.Lock()
+			defer leaseLock.Unlock()
+			return leaseAcquired, leaseErr
+		}
+		fclock = fakeclock.NewFakeClock(time.Now())
+		logger := lagertest.NewTestLogger("leader-election-test")
+		buffer = logger.Buffer()
+		atomic.StoreInt32(&started, 0)
+		atomic.StoreInt32(&stopped, 0)
+		exitChan = make(chan error)
+
+		elected := ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+			atomic.AddInt32(&started, 1)
+			close(ready)
+			select {
+			case <-signals:
+				atomic.AddInt32(&stopped, 1)
+				return nil
+			case err := <-exitChan:
+				return err
+			}
+		})
+		runner = NewRunner(logger, leaseDB, "a-lease", "owner-1", testLeaseTTL, testRetryInterval, fclock, elected)
+	})
+
+	JustBeforeEach(func() {
+		proc = ifrit.Invoke(runner)
+		Eventually(buffer).Should(gbytes.Say("started"))
+	})
+
+	AfterEach(func() {
+		ginkgomon.Kill(proc)
+	})
+
+	startedCount := func() int32 { return atomic.LoadInt32(&started) }
+	stoppedCount := func() int32 { return atomic.LoadInt32(&stopped) }
+
+	Context("when the lease is acquired", func() {
+		BeforeEach(func() {
+			setLease(true, nil)
+		})
+
+		It("runs the elected runner", func() {
+			Eventually(startedCount).Should(Equal(int32(1)))
+			Eventually(runner.IsLeader).Should(BeTrue())
+
+			name, owner, ttl := leaseDB.AcquireLeaseArgsForCall(0)
+			Expect(name).To(Equal("a-lease"))
+			Expect(owner).To(Equal("owner-1"))
+			Expect(ttl).To(Equal(testLeaseTTL))
+		})
+
+		It("renews the lease every retry interval", func() {
+			Eventually(startedCount).Should(Equal(int32(1)))
+			fclock.WaitForWatcherAndIncrement(testRetryInterval)
+			Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(2))
+			fclock.Increment(testRetryInterval)
+			Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(3))
+			Consistently(startedCount).Should(Equal(int32(1)))
+		})
+
+		Context("when another instance has taken the lease", func() {
+			JustBeforeEach(func() {
+				Eventually(startedCount).Should(Equal(int32(1)))
+				setLease(false, nil)
+				fclock.WaitForWatcherAndIncrement(testRetryInterval)
+			})
+
+			It("stops the elected runner and stands by", func() {
+				Eventually(stoppedCount).Should(Equal(int32(1)))
+				Eventually(buffer).Should(gbytes.Say("lost-leadership"))
+				Expect(runner.IsLeader()).To(BeFalse())
+			})
+
+			Context("when the lease is acquired again", func() {
+				It("runs the elected runner again", func() {
+					Eventually(stoppedCount).Should(Equal(int32(1)))
+					setLease(true, nil)
+					fclock.Increment(testRetryInterval)
+					Eventually(startedCount).Should(Equal(int32(2)))
+				})
+			})
+		})
+
+		Context("when renewing the lease fails", func() {
+			JustBeforeEach(func() {
+				Eventually(startedCount).Should(Equal(int32(1)))
+				setLease(false, errors.New("an error"))
+				fclock.WaitForWatcherAndIncrement(testRetryInterval)
+				Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(2))
+			})
+
+			It("keeps the elected runner while the lease outlives the next attempt", func() {
+				Consistently(stoppedCount).Should(BeZero())
+				Expect(runner.IsLeader()).To(BeTrue())
+			})
+
+			Context("when the lease would expire before the next attempt", func() {
+				It("stops the elected runner", func() {
+					fclock.Increment(testRetryInterval)
+					Eventually(stoppedCount).Should(Equal(int32(1)))
+					Eventually(buffer).Should(gbytes.Say("lost-leadership"))
+					Expect(runner.IsLeader()).To(BeFalse())
+				})
+			})
+
+			Context("when renewing the lease succeeds again", func() {
+				It("keeps the elected runner", func() {
+					setLease(true, nil)
+					fclock.Increment(testRetryInterval)
+					Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(3))
+					fclock.Increment(testRetryInterval)
+					Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(4))
+					Consistently(stoppedCount).Should(BeZero())
+				})
+			})
+		})
+
+		Context("when an interrupt is sent", func() {
+			It("stops the elected runner and releases the lease", func() {
+				Eventually(startedCount).Should(Equal(int32(1)))
+				ginkgomon.Interrupt(proc)
+				Expect(stoppedCount()).To(Equal(int32(1)))
+				Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(1))
+				name, owner := leaseDB.ReleaseLeaseArgsForCall(0)
+				Expect(name).To(Equal("a-lease"))
+				Expect(owner).To(Equal("owner-1"))
+			})
+		})
+
+		Context("when the elected runner exits", func() {
+			It("releases the lease and exits with the error", func() {
+				Eventually(startedCount).Should(Equal(int32(1)))
+				exitChan <- errors.New("runner error")
+				Eventually(proc.Wait()).Should(Receive(MatchError("runner error")))
+				Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Context("when another instance holds the lease", func() {
+		BeforeEach(func() {
+			setLease(false, nil)
+		})
+
+		It("does not run the elected runner", func() {
+			Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(1))
+			Consistently(startedCount).Should(BeZero())
+			Expect(runner.IsLeader()).To(BeFalse())
+		})
+
+		Context("when the lease is released or expires", func() {
+			JustBeforeEach(func() {
+				Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(1))
+				setLease(true, nil)
+				fclock.WaitForWatcherAndIncrement(testRetryInterval)
+			})
+
+			It("takes over", func() {
+				Eventually(startedCount).Should(Equal(int32(1)))
+				Eventually(runner.IsLeader).Should(BeTrue())
+			})
+		})
+
+		Context("when an interrupt is sent", func() {
+			It("stops without releasing the lease", func() {
+				ginkgomon.Interrupt(proc)
+				Expect(leaseDB.ReleaseLeaseCallCount()).To(BeZero())
+			})
+		})
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"code.cloudfoundry.org/lager"
 	"database/sql"
 	_ "github.com/lib/pq"
+	"time"
 )
 
 type AppMetricSQLDB struct {
@@ -46,7 +47,16 @@ func (adb *AppMetricSQLDB) Close() error {
 	}
 	return nil
 }
+
+func (adb *AppMetricSQLDB) Ping() error {
+	err := adb.sqldb.Ping()
+	if err != nil {
+		adb.logger.Error("ping-appmetric-db", err, lager.Data{"url": adb.url})
+	}
+	return err
+}
 func (adb *AppMetricSQLDB) SaveAppMetric(appMetric *models.AppMetric) error {
+	defer observeQuery("appmetrics", "save-app-metric", time.Now())
 	query := "INSERT INTO app_metric(app_id, metric_type, unit, timestamp, value) values($1, $2, $3, $4, $5)"
 	_, err := adb.sqldb.Exec(query, appMetric.AppId, appMetric.MetricType, appMetric.Unit, appMetric.Timestamp, appMetric.Value)
 
@@ -57,6 +67,7 @@ func (adb *AppMetricSQLDB) SaveAppMetric(appMetric *models.AppMetric) error {
 	return err
 }
 func (adb *AppMetricSQLDB) RetrieveAppMetrics(appIdP string, metricTypeP string, startP int64, endP int64) ([]*models.AppMetric, error) {
+	defer observeQuery("appmetrics", "retrieve-app-metrics", time.Now())
 	query := "SELECT app_id,metric_type,value,unit,timestamp FROM app_metric WHERE app_id=$1 AND metric_type=$2 AND timestamp>=$3 AND timestamp<=$4 ORDER BY timestamp ASC"
 	appMetricList := []*models.AppMetric{}
 	rows, err := adb.sqldb.Query(query, appIdP, metricTypeP, startP, endP)
@@ -88,11 +99,15 @@ func (adb *AppMetricSQLDB) RetrieveAppMetrics(appIdP string, metricTypeP string,
 }
 
 func (adb *AppMetricSQLDB) PruneAppMetrics(before int64) error {
+	defer observeQuery("appmetrics", "prune-app-metrics", time.Now())
 	query := "DELETE FROM app_metric WHERE timestamp <= $1"
-	_, err := adb.sqldb.Exec(query, before)
+	result, err := adb.sqldb.Exec(query, before)
 	if err != nil {
 		adb.logger.Error("prune-metrics-from-app_metric-table", err, lager.Data{"query": query, "before": before})
+		return err
 	}
 
-	return err
+	countPrunedRows("app_metric", result)
+
+	return nil
 }
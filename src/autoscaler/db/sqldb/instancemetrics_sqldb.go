@@ -47,7 +47,16 @@ func (idb *InstanceMetricsSQLDB) Close() error {
 	return nil
 }
 
+func (idb *InstanceMetricsSQLDB) Ping() error {
+	err := idb.sqldb.Ping()
+	if err != nil {
+		idb.logger.Error("ping-instancemetrics-db", err, lager.Data{"url": idb.url})
+	}
+	return err
+}
+
 func (idb *InstanceMetricsSQLDB) SaveMetric(metric *models.AppInstanceMetric) error {
+	defer observeQuery("instancemetrics", "save-metric", time.Now())
 	query := "INSERT INTO appinstancemetrics(appid, instanceindex, collectedat, name, unit, value, timestamp) values($1, $2, $3, $4, $5, $6, $7)"
 	_, err := idb.sqldb.Exec(query, metric.AppId, metric.InstanceIndex, metric.CollectedAt, metric.Name, metric.Unit, metric.Value, metric.Timestamp)
 
@@ -58,6 +67,7 @@ func (idb *InstanceMetricsSQLDB) SaveMetric(metric *models.AppInstanceMetric) er
 }
 
 func (idb *InstanceMetricsSQLDB) RetrieveInstanceMetrics(appid string, name string, start int64, end int64) ([]*models.AppInstanceMetric, error) {
+	defer observeQuery("instancemetrics", "retrieve-instance-metrics", time.Now())
 	query := "SELECT instanceindex, collectedat, unit, value, timestamp FROM appinstancemetrics WHERE " +
 		" appid = $1 " +
 		" AND name = $2 " +
@@ -108,11 +118,15 @@ func (idb *InstanceMetricsSQLDB) RetrieveInstanceMetrics(appid string, name stri
 }
 
 func (idb *InstanceMetricsSQLDB) PruneInstanceMetrics(before int64) error {
+	defer observeQuery("instancemetrics", "prune-instance-metrics", time.Now())
 	query := "DELETE FROM appinstancemetrics WHERE timestamp <= $1"
-	_, err := idb.sqldb.Exec(query, before)
+	result, err := idb.sqldb.Exec(query, before)
 	if err != nil {
 		idb.logger.Error("failed-prune-instancemetric-from-appinstancemetrics-table", err, lager.Data{"query": query, "before": before})
+		return err
 	}
 
-	return err
+	countPrunedRows("appinstancemetrics", result)
+
+	return nil
 }
@@ -0,0 +1,87 @@
+package sqldb
+
+import (
+	"code.cloudfoundry.org/lager"
+	_ "github.com/lib/pq"
+
+	"autoscaler/db"
+
+	"database/sql"
+	"time"
+)
+
+type LeaseSQLDB struct {
+	url    string
+	logger lager.Logger
+	sqldb  *sql.DB
+}
+
+func NewLeaseSQLDB(url string, logger lager.Logger) (*LeaseSQLDB, error) {
+	sqldb, err := sql.Open(db.PostgresDriverName, url)
+	if err != nil {
+		logger.Error("open-lease-db", err, lager.Data{"url": url})
+		return nil, err
+	}
+
+	err = sqldb.Ping()
+	if err != nil {
+		sqldb.Close()
+		logger.Error("ping-lease-db", err, lager.Data{"url": url})
+		return nil, err
+	}
+
+	return &LeaseSQLDB{
+		url:    url,
+		logger: logger,
+		sqldb:  sqldb,
+	}, nil
+}
+
+func (ldb *LeaseSQLDB) Close() error {
+	err := ldb.sqldb.Close()
+	if err != nil {
+		ldb.logger.Error("close-lease-db", err, lager.Data{"url": ldb.url})
+		return err
+	}
+	return nil
+}
+
+func (ldb *LeaseSQLDB) Ping() error {
+	err := ldb.sqldb.Ping()
+	if err != nil {
+		ldb.logger.Error("ping-lease-db", err, lager.Data{"url": ldb.url})
+	}
+	return err
+}
+
+// AcquireLease takes the lease for ttl when it is free or expired, or extends
+// it when owner already holds it. It returns false when another owner holds
+// an unexpired lease.
+func (ldb *LeaseSQLDB) AcquireLease(name string, owner string, ttl time.Duration) (bool, error) {
+	defer observeQuery("lease", "acquire-lease", time.Now())
+	query := "INSERT INTO leases(name, owner, expireat) VALUES($1, $2, " + dbNow + " + $3) " +
+		" ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expireat = EXCLUDED.expireat " +
+		" WHERE leases.owner = EXCLUDED.owner OR leases.expireat <= " + dbNow
+	result, err := ldb.sqldb.Exec(query, name, owner, ttl.Nanoseconds())
+	if err != nil {
+		ldb.logger.Error("acquire-lease", err, lager.Data{"query": query, "name": name, "owner": owner})
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		ldb.logger.Error("acquire-lease-rows-affected", err, lager.Data{"name": name, "owner": owner})
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (ldb *LeaseSQLDB) ReleaseLease(name string, owner string) error {
+	defer observeQuery("lease", "release-lease", time.Now())
+	query := "DELETE FROM leases WHERE name = $1 AND owner = $2"
+	_, err := ldb.sqldb.Exec(query, name, owner)
+	if err != nil {
+		ldb.logger.Error("release-lease", err, lager.Data{"query": query, "name": name, "owner": owner})
+	}
+	return err
+}
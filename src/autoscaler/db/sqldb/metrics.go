@@ -0,0 +1,37 @@
+package sqldb
+
+import (
+	"database/sql"
+	"time"
+
+	"autoscaler/metrics"
+)
+
+var (
+	queryDuration = metrics.NewHistogram(
+		"autoscaler_db_query_duration_seconds",
+		"Latency of database queries, by database and query.",
+		nil,
+		"db", "query",
+	)
+	rowsPruned = metrics.NewCounter(
+		"autoscaler_db_rows_pruned_total",
+		"Number of rows deleted by pruning, by table.",
+		"table",
+	)
+)
+
+func init() {
+	metrics.MustRegister(queryDuration, rowsPruned)
+}
+
+func observeQuery(dbName string, query string, start time.Time) {
+	queryDuration.ObserveSince(start, dbName, query)
+}
+
+func countPrunedRows(table string, result sql.Result) {
+	rows, err := result.RowsAffected()
+	if err == nil {
+		rowsPruned.Add(float64(rows), table)
+	}
+}
@@ -4,16 +4,28 @@ import (
 	"code.cloudfoundry.org/lager"
 	"database/sql"
 	"encoding/json"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"autoscaler/db"
 	"autoscaler/models"
+
+	"sync"
+	"time"
+)
+
+const (
+	policyListenerMinReconnectInterval = 10 * time.Second
+	policyListenerMaxReconnectInterval = time.Minute
+	policyListenerPingInterval         = 90 * time.Second
+	policyChangesBufferSize            = 100
 )
 
 type PolicySQLDB struct {
-	url    string
-	logger lager.Logger
-	sqldb  *sql.DB
+	url       string
+	logger    lager.Logger
+	sqldb     *sql.DB
+	listeners []*pq.Listener
+	lock      sync.Mutex
 }
 
 func NewPolicySQLDB(url string, logger lager.Logger) (*PolicySQLDB, error) {
@@ -38,6 +50,13 @@ func NewPolicySQLDB(url string, logger lager.Logger) (*PolicySQLDB, error) {
 }
 
 func (pdb *PolicySQLDB) Close() error {
+	pdb.lock.Lock()
+	for _, listener := range pdb.listeners {
+		listener.Close()
+	}
+	pdb.listeners = nil
+	pdb.lock.Unlock()
+
 	err := pdb.sqldb.Close()
 	if err != nil {
 		pdb.logger.Error("Close-policy-db", err, lager.Data{"url": pdb.url})
@@ -46,9 +65,18 @@ func (pdb *PolicySQLDB) Close() error {
 	return nil
 }
 
+func (pdb *PolicySQLDB) Ping() error {
+	err := pdb.sqldb.Ping()
+	if err != nil {
+		pdb.logger.Error("ping-policy-db", err, lager.Data{"url": pdb.url})
+	}
+	return err
+}
+
 func (pdb *PolicySQLDB) GetAppIds() (map[string]bool, error) {
+	defer observeQuery("policy", "get-app-ids", time.Now())
 	appIds := make(map[string]bool)
-	query := "SELECT app_id FROM policy_json"
+	query := "SELECT app_id FROM policy_json WHERE app_id NOT IN (SELECT app_id FROM orphaned_apps)"
 
 	rows, err := pdb.sqldb.Query(query)
 	if err != nil {
@@ -69,7 +97,8 @@ func (pdb *PolicySQLDB) GetAppIds() (map[string]bool, error) {
 }
 
 func (pdb *PolicySQLDB) RetrievePolicies() ([]*models.PolicyJson, error) {
-	query := "SELECT app_id,policy_json FROM policy_json WHERE 1=1 "
+	defer observeQuery("policy", "retrieve-policies", time.Now())
+	query := "SELECT app_id,policy_json FROM policy_json WHERE app_id NOT IN (SELECT app_id FROM orphaned_apps)"
 	policyList := []*models.PolicyJson{}
 	rows, err := pdb.sqldb.Query(query)
 	if err != nil {
@@ -97,10 +126,44 @@ func (pdb *PolicySQLDB) RetrievePolicies() ([]*models.PolicyJson, error) {
 	return policyList, nil
 }
 
+// RetrievePoliciesSince returns the policies changed after the given version,
+// ordered by version. Policies that were deleted or whose app is orphaned are
+// returned marked as deleted. Version 0 retrieves all policies.
+func (pdb *PolicySQLDB) RetrievePoliciesSince(version int64) ([]*models.PolicyJson, error) {
+	defer observeQuery("policy", "retrieve-policies-since", time.Now())
+	query := "SELECT p.app_id, p.policy_json, p.version, o.app_id IS NOT NULL FROM policy_json p " +
+		"LEFT JOIN orphaned_apps o ON o.app_id = p.app_id WHERE p.version > $1 " +
+		"UNION ALL SELECT app_id, NULL, version, true FROM deleted_policies WHERE version > $1 " +
+		"ORDER BY 3"
+	rows, err := pdb.sqldb.Query(query, version)
+	if err != nil {
+		pdb.logger.Error("retrieve-policies-since", err, lager.Data{"query": query, "version": version})
+		return nil, err
+	}
+	defer rows.Close()
+
+	policyList := []*models.PolicyJson{}
+	for rows.Next() {
+		policyJson := models.PolicyJson{}
+		var policyStr sql.NullString
+		if err = rows.Scan(&policyJson.AppId, &policyStr, &policyJson.Version, &policyJson.Deleted); err != nil {
+			pdb.logger.Error("retrieve-policies-since-scan", err)
+			return nil, err
+		}
+		if !policyJson.Deleted {
+			policyJson.PolicyStr = policyStr.String
+		}
+		policyList = append(policyList, &policyJson)
+	}
+	return policyList, rows.Err()
+}
+
 func (pdb *PolicySQLDB) GetAppPolicy(appId string) (*models.ScalingPolicy, error) {
+	defer observeQuery("policy", "get-app-policy", time.Now())
 	var policyJson []byte
-	query := "SELECT policy_json FROM policy_json WHERE app_id = $1"
-	err := pdb.sqldb.QueryRow(query, appId).Scan(&policyJson)
+	var revision int64
+	query := "SELECT policy_json, revision FROM policy_json WHERE app_id = $1"
+	err := pdb.sqldb.QueryRow(query, appId).Scan(&policyJson, &revision)
 	if err != nil {
 		pdb.logger.Error("get-app-policy-from-policy-table", err, lager.Data{"query": query, "appid": appId})
 		return nil, err
@@ -112,5 +175,280 @@ func (pdb *PolicySQLDB) GetAppPolicy(appId string) (*models.ScalingPolicy, error
 		pdb.logger.Error("get-app-policy-unmarshal", err, lager.Data{"policyJson": string(policyJson)})
 		return nil, err
 	}
+	scalingPolicy.Revision = revision
 	return scalingPolicy, nil
 }
+
+// MarkAppOrphaned records that the app no longer exists in cloud foundry. An app
+// that is already marked keeps the time it was first found orphaned.
+func (pdb *PolicySQLDB) MarkAppOrphaned(appId string, orphanedAt int64) error {
+	defer observeQuery("policy", "mark-app-orphaned", time.Now())
+	query := "INSERT INTO orphaned_apps(app_id, orphaned_at) VALUES($1, $2) ON CONFLICT (app_id) DO NOTHING"
+	_, err := pdb.sqldb.Exec(query, appId, orphanedAt)
+	if err != nil {
+		pdb.logger.Error("mark-app-orphaned", err, lager.Data{"query": query, "appid": appId, "orphanedAt": orphanedAt})
+	}
+	return err
+}
+
+func (pdb *PolicySQLDB) UnmarkAppOrphaned(appId string) error {
+	defer observeQuery("policy", "unmark-app-orphaned", time.Now())
+	query := "DELETE FROM orphaned_apps WHERE app_id = $1"
+	_, err := pdb.sqldb.Exec(query, appId)
+	if err != nil {
+		pdb.logger.Error("unmark-app-orphaned", err, lager.Data{"query": query, "appid": appId})
+	}
+	return err
+}
+
+// RetrieveOrphanedApps returns the ids of the apps found orphaned at or before the given time.
+func (pdb *PolicySQLDB) RetrieveOrphanedApps(before int64) ([]string, error) {
+	defer observeQuery("policy", "retrieve-orphaned-apps", time.Now())
+	query := "SELECT app_id FROM orphaned_apps WHERE orphaned_at <= $1 ORDER BY orphaned_at"
+	rows, err := pdb.sqldb.Query(query, before)
+	if err != nil {
+		pdb.logger.Error("retrieve-orphaned-apps", err, lager.Data{"query": query, "before": before})
+		return nil, err
+	}
+	defer rows.Close()
+
+	appIds := []string{}
+	var appId string
+	for rows.Next() {
+		if err = rows.Scan(&appId); err != nil {
+			pdb.logger.Error("retrieve-orphaned-apps-scan", err)
+			return nil, err
+		}
+		appIds = append(appIds, appId)
+	}
+	return appIds, rows.Err()
+}
+
+// DeletePolicy deletes the policy of the app together with its orphan mark, its
+// observation and its app group membership. The group of the app is deleted
+// when fewer than two members would be left in it.
+func (pdb *PolicySQLDB) DeletePolicy(appId string) error {
+	defer observeQuery("policy", "delete-policy", time.Now())
+	tx, err := pdb.sqldb.Begin()
+	if err != nil {
+		pdb.logger.Error("delete-policy-begin", err, lager.Data{"appid": appId})
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM policy_json WHERE app_id = $1",
+		"DELETE FROM orphaned_apps WHERE app_id = $1",
+		"DELETE FROM observed_apps WHERE app_id = $1",
+		"DELETE FROM app_group_member WHERE group_id = (SELECT group_id FROM app_group_member WHERE app_id = $1) " +
+			"AND (SELECT COUNT(*) FROM app_group_member WHERE group_id = (SELECT group_id FROM app_group_member WHERE app_id = $1)) <= 2",
+		"DELETE FROM app_group_member WHERE app_id = $1",
+	} {
+		_, err = tx.Exec(query, appId)
+		if err != nil {
+			pdb.logger.Error("delete-policy", err, lager.Data{"query": query, "appid": appId})
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		pdb.logger.Error("delete-policy-commit", err, lager.Data{"appid": appId})
+	}
+	return err
+}
+
+// ObserveApp registers the app to have its metrics collected until expireAt
+// without a scaling policy. Registering an app again replaces its expiration.
+func (pdb *PolicySQLDB) ObserveApp(appId string, expireAt int64) error {
+	defer observeQuery("policy", "observe-app", time.Now())
+	query := "INSERT INTO observed_apps(app_id, expire_at) VALUES($1, $2) " +
+		"ON CONFLICT (app_id) DO UPDATE SET expire_at = EXCLUDED.expire_at"
+	_, err := pdb.sqldb.Exec(query, appId, expireAt)
+	if err != nil {
+		pdb.logger.Error("observe-app", err, lager.Data{"query": query, "appid": appId, "expireAt": expireAt})
+	}
+	return err
+}
+
+func (pdb *PolicySQLDB) UnobserveApp(appId string) error {
+	defer observeQuery("policy", "unobserve-app", time.Now())
+	query := "DELETE FROM observed_apps WHERE app_id = $1"
+	_, err := pdb.sqldb.Exec(query, appId)
+	if err != nil {
+		pdb.logger.Error("unobserve-app", err, lager.Data{"query": query, "appid": appId})
+	}
+	return err
+}
+
+// RetrieveObservedApps returns the ids of the apps whose observation has not
+// expired at the given time. Orphaned apps are left out.
+func (pdb *PolicySQLDB) RetrieveObservedApps(now int64) ([]string, error) {
+	defer observeQuery("policy", "retrieve-observed-apps", time.Now())
+	query := "SELECT app_id FROM observed_apps WHERE expire_at > $1 " +
+		"AND app_id NOT IN (SELECT app_id FROM orphaned_apps) ORDER BY app_id"
+	rows, err := pdb.sqldb.Query(query, now)
+	if err != nil {
+		pdb.logger.Error("retrieve-observed-apps", err, lager.Data{"query": query, "now": now})
+		return nil, err
+	}
+	defer rows.Close()
+
+	appIds := []string{}
+	var appId string
+	for rows.Next() {
+		if err = rows.Scan(&appId); err != nil {
+			pdb.logger.Error("retrieve-observed-apps-scan", err)
+			return nil, err
+		}
+		appIds = append(appIds, appId)
+	}
+	return appIds, rows.Err()
+}
+
+// GetAppGroup returns the group with the given id, or nil when it has no
+// members.
+func (pdb *PolicySQLDB) GetAppGroup(groupId string) (*models.AppGroup, error) {
+	defer observeQuery("policy", "get-app-group", time.Now())
+	query := "SELECT app_id, group_id, ratio, instance_min, instance_max, process_type FROM app_group_member " +
+		"WHERE group_id = $1 ORDER BY app_id"
+	return pdb.retrieveAppGroup(query, groupId)
+}
+
+// GetAppGroupOfApp returns the group the app is a member of, or nil when the
+// app is not in a group.
+func (pdb *PolicySQLDB) GetAppGroupOfApp(appId string) (*models.AppGroup, error) {
+	defer observeQuery("policy", "get-app-group-of-app", time.Now())
+	query := "SELECT app_id, group_id, ratio, instance_min, instance_max, process_type FROM app_group_member " +
+		"WHERE group_id = (SELECT group_id FROM app_group_member WHERE app_id = $1) ORDER BY app_id"
+	return pdb.retrieveAppGroup(query, appId)
+}
+
+func (pdb *PolicySQLDB) retrieveAppGroup(query string, arg string) (*models.AppGroup, error) {
+	rows, err := pdb.sqldb.Query(query, arg)
+	if err != nil {
+		pdb.logger.Error("retrieve-app-group", err, lager.Data{"query": query, "arg": arg})
+		return nil, err
+	}
+	defer rows.Close()
+
+	var group *models.AppGroup
+	for rows.Next() {
+		var groupId string
+		member := &models.AppGroupMember{}
+		if err = rows.Scan(&member.AppId, &groupId, &member.Ratio, &member.InstanceMin, &member.InstanceMax, &member.ProcessType); err != nil {
+			pdb.logger.Error("retrieve-app-group-scan", err)
+			return nil, err
+		}
+		if group == nil {
+			group = &models.AppGroup{GroupId: groupId}
+		}
+		group.Members = append(group.Members, member)
+	}
+	return group, rows.Err()
+}
+
+// SaveAppGroup replaces the members of the group. It returns an
+// AppGroupConflictError when one of the apps is already a member of another
+// group.
+func (pdb *PolicySQLDB) SaveAppGroup(group *models.AppGroup) error {
+	defer observeQuery("policy", "save-app-group", time.Now())
+	tx, err := pdb.sqldb.Begin()
+	if err != nil {
+		pdb.logger.Error("save-app-group-begin", err, lager.Data{"groupid": group.GroupId})
+		return err
+	}
+
+	query := "DELETE FROM app_group_member WHERE group_id = $1"
+	_, err = tx.Exec(query, group.GroupId)
+	if err != nil {
+		pdb.logger.Error("save-app-group-delete", err, lager.Data{"query": query, "groupid": group.GroupId})
+		tx.Rollback()
+		return err
+	}
+
+	query = "INSERT INTO app_group_member(app_id, group_id, ratio, instance_min, instance_max, process_type) " +
+		"VALUES($1, $2, $3, $4, $5, $6)"
+	for _, m := range group.Members {
+		_, err = tx.Exec(query, m.AppId, group.GroupId, m.Ratio, m.InstanceMin, m.InstanceMax, m.ProcessType)
+		if err != nil {
+			pdb.logger.Error("save-app-group-insert", err, lager.Data{"query": query, "groupid": group.GroupId, "member": m})
+			tx.Rollback()
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+				return &db.AppGroupConflictError{AppId: m.AppId}
+			}
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		pdb.logger.Error("save-app-group-commit", err, lager.Data{"groupid": group.GroupId})
+	}
+	return err
+}
+
+func (pdb *PolicySQLDB) DeleteAppGroup(groupId string) error {
+	defer observeQuery("policy", "delete-app-group", time.Now())
+	query := "DELETE FROM app_group_member WHERE group_id = $1"
+	_, err := pdb.sqldb.Exec(query, groupId)
+	if err != nil {
+		pdb.logger.Error("delete-app-group", err, lager.Data{"query": query, "groupid": groupId})
+	}
+	return err
+}
+
+// SubscribePolicyChanges listens to the notifications sent on
+// db.PolicyChangeChannel. The returned channel receives the id of every app
+// whose policy, orphan mark or observation changed, and an empty id after the
+// connection was re-established as notifications might have been missed
+// meanwhile. Changes are dropped while the channel is full, so it is only meant
+// to wake up a reload of the policies. The channel is closed when the database
+// is closed.
+func (pdb *PolicySQLDB) SubscribePolicyChanges() (<-chan string, error) {
+	logger := pdb.logger.Session("policy-listener")
+	listener := pq.NewListener(pdb.url, policyListenerMinReconnectInterval, policyListenerMaxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logger.Error("connection-event", err, lager.Data{"event": event})
+			}
+		})
+
+	err := listener.Listen(db.PolicyChangeChannel)
+	if err != nil {
+		listener.Close()
+		pdb.logger.Error("subscribe-policy-changes", err, lager.Data{"channel": db.PolicyChangeChannel})
+		return nil, err
+	}
+
+	pdb.lock.Lock()
+	pdb.listeners = append(pdb.listeners, listener)
+	pdb.lock.Unlock()
+
+	changes := make(chan string, policyChangesBufferSize)
+	go forwardPolicyChanges(logger, listener, changes)
+	return changes, nil
+}
+
+func forwardPolicyChanges(logger lager.Logger, listener *pq.Listener, changes chan<- string) {
+	defer close(changes)
+	for {
+		select {
+		case notification, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			appId := ""
+			if notification != nil {
+				appId = notification.Extra
+			}
+			select {
+			case changes <- appId:
+			default:
+				logger.Info("policy-change-dropped", lager.Data{"appid": appId})
+			}
+		case <-time.After(policyListenerPingInterval):
+			go listener.Ping()
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package sqldb_test
 
 import (
+	"autoscaler/db"
 	. "autoscaler/db/sqldb"
 	"autoscaler/models"
 
@@ -61,6 +62,27 @@ var _ = Describe("PolicySQLDB", func() {
 		})
 	})
 
+	Describe("Ping", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the database is reachable", func() {
+			It("should not error", func() {
+				Expect(pdb.Ping()).To(Succeed())
+				Expect(pdb.Close()).To(Succeed())
+			})
+		})
+
+		Context("when the connection is closed", func() {
+			It("should error", func() {
+				Expect(pdb.Close()).To(Succeed())
+				Expect(pdb.Ping()).To(HaveOccurred())
+			})
+		})
+	})
+
 	Describe("GetAppIds", func() {
 		BeforeEach(func() {
 			pdb, err = NewPolicySQLDB(url, logger)
@@ -99,6 +121,17 @@ var _ = Describe("PolicySQLDB", func() {
 				Expect(appIds).To(HaveKey("second-app-id"))
 				Expect(appIds).To(HaveKey("third-app-id"))
 			})
+
+			Context("when an app is orphaned", func() {
+				BeforeEach(func() {
+					insertOrphanedApp("second-app-id", 111111)
+				})
+
+				It("does not return the orphaned app", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(appIds).To(Equal(map[string]bool{"first-app-id": true, "third-app-id": true}))
+				})
+			})
 		})
 	})
 
@@ -164,6 +197,20 @@ var _ = Describe("PolicySQLDB", func() {
 
 		})
 
+		Context("when the policy has a revision", func() {
+			BeforeEach(func() {
+				appId = "another-app-id"
+				_, err = dbHelper.Exec("UPDATE policy_json SET revision = 5 WHERE app_id = $1", appId)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns the revision with the policy", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(scalingPolicy.Revision).To(BeEquivalentTo(5))
+				Expect(scalingPolicy.InstanceMin).To(Equal(2))
+			})
+		})
+
 		Context("when policy table does not have the app", func() {
 			BeforeEach(func() {
 				appId = "non-existent-app"
@@ -219,5 +266,554 @@ var _ = Describe("PolicySQLDB", func() {
 				))
 			})
 		})
+
+		Context("when an app is orphaned", func() {
+			BeforeEach(func() {
+				insertOrphanedApp("second-app-id", 111111)
+			})
+
+			It("does not return the policy of the orphaned app", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				policyJson, err = json.Marshal(models.ScalingPolicy{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(policies).To(ConsistOf(
+					&models.PolicyJson{
+						AppId:     "first-app-id",
+						PolicyStr: string(policyJson),
+					},
+					&models.PolicyJson{
+						AppId:     "third-app-id",
+						PolicyStr: string(policyJson),
+					},
+				))
+			})
+		})
+	})
+
+	Describe("RetrievePoliciesSince", func() {
+		var version int64
+
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			insertPolicy("first-app-id", &models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6})
+			insertPolicy("second-app-id", &models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6})
+
+			policies, err = pdb.RetrievePoliciesSince(0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(HaveLen(2))
+			version = policies[1].Version
+			Expect(version).To(BeNumerically(">", policies[0].Version))
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when nothing changed", func() {
+			It("returns no policies", func() {
+				policies, err = pdb.RetrievePoliciesSince(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(policies).To(BeEmpty())
+			})
+		})
+
+		Context("when policies are added and deleted", func() {
+			BeforeEach(func() {
+				Expect(pdb.DeletePolicy("first-app-id")).To(Succeed())
+				insertPolicy("third-app-id", &models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6})
+			})
+
+			It("returns the changed policies ordered by version", func() {
+				policies, err = pdb.RetrievePoliciesSince(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(policies).To(HaveLen(2))
+
+				Expect(policies[0].AppId).To(Equal("first-app-id"))
+				Expect(policies[0].Deleted).To(BeTrue())
+				Expect(policies[0].PolicyStr).To(BeEmpty())
+
+				Expect(policies[1].AppId).To(Equal("third-app-id"))
+				Expect(policies[1].Deleted).To(BeFalse())
+				Expect(policies[1].PolicyStr).NotTo(BeEmpty())
+				Expect(policies[1].Version).To(BeNumerically(">", policies[0].Version))
+			})
+		})
+
+		Context("when an app is orphaned", func() {
+			BeforeEach(func() {
+				insertOrphanedApp("second-app-id", 111111)
+			})
+
+			It("returns the policy of the app marked as deleted", func() {
+				policies, err = pdb.RetrievePoliciesSince(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(policies).To(HaveLen(1))
+				Expect(policies[0].AppId).To(Equal("second-app-id"))
+				Expect(policies[0].Deleted).To(BeTrue())
+				Expect(policies[0].Version).To(BeNumerically(">", version))
+			})
+		})
+	})
+
+	Describe("MarkAppOrphaned", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("marks the app orphaned", func() {
+			Expect(pdb.MarkAppOrphaned("an-app-id", 111111)).To(Succeed())
+			orphanedAt, found := getOrphanedAt("an-app-id")
+			Expect(found).To(BeTrue())
+			Expect(orphanedAt).To(BeEquivalentTo(111111))
+		})
+
+		Context("when the app is already marked", func() {
+			BeforeEach(func() {
+				insertOrphanedApp("an-app-id", 111111)
+			})
+
+			It("keeps the time it was first marked", func() {
+				Expect(pdb.MarkAppOrphaned("an-app-id", 222222)).To(Succeed())
+				orphanedAt, _ := getOrphanedAt("an-app-id")
+				Expect(orphanedAt).To(BeEquivalentTo(111111))
+			})
+		})
+	})
+
+	Describe("UnmarkAppOrphaned", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			insertOrphanedApp("an-app-id", 111111)
+			insertOrphanedApp("another-app-id", 111111)
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("removes the orphan mark of the app only", func() {
+			Expect(pdb.UnmarkAppOrphaned("an-app-id")).To(Succeed())
+			_, found := getOrphanedAt("an-app-id")
+			Expect(found).To(BeFalse())
+			_, found = getOrphanedAt("another-app-id")
+			Expect(found).To(BeTrue())
+		})
+	})
+
+	Describe("RetrieveOrphanedApps", func() {
+		var orphanedApps []string
+
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			insertOrphanedApp("first-app-id", 333333)
+			insertOrphanedApp("second-app-id", 111111)
+			insertOrphanedApp("third-app-id", 444444)
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			orphanedApps, err = pdb.RetrieveOrphanedApps(333333)
+		})
+
+		It("returns the apps orphaned before the given time", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphanedApps).To(Equal([]string{"second-app-id", "first-app-id"}))
+		})
+	})
+
+	Describe("DeletePolicy", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			insertPolicy("an-app-id", &models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6})
+			insertPolicy("another-app-id", &models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6})
+			insertOrphanedApp("an-app-id", 111111)
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("deletes the policy, the orphan mark and the observation of the app", func() {
+			Expect(pdb.ObserveApp("an-app-id", 111111)).To(Succeed())
+			Expect(pdb.DeletePolicy("an-app-id")).To(Succeed())
+			Expect(hasPolicy("an-app-id")).To(BeFalse())
+			_, found := getOrphanedAt("an-app-id")
+			Expect(found).To(BeFalse())
+			_, found = getObservedAppExpireAt("an-app-id")
+			Expect(found).To(BeFalse())
+			Expect(hasPolicy("another-app-id")).To(BeTrue())
+		})
+
+		Context("when the app is a member of an app group", func() {
+			var members []*models.AppGroupMember
+
+			JustBeforeEach(func() {
+				Expect(pdb.SaveAppGroup(&models.AppGroup{GroupId: "a-group-id", Members: members})).To(Succeed())
+				Expect(pdb.DeletePolicy("an-app-id")).To(Succeed())
+			})
+
+			Context("when other members are left in the group", func() {
+				BeforeEach(func() {
+					members = []*models.AppGroupMember{
+						{AppId: "an-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+						{AppId: "another-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+						{AppId: "third-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+					}
+				})
+
+				It("removes the app from the group", func() {
+					group, err := pdb.GetAppGroup("a-group-id")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(group.Members).To(HaveLen(2))
+					Expect(group.Members[0].AppId).To(Equal("another-app-id"))
+					Expect(group.Members[1].AppId).To(Equal("third-app-id"))
+				})
+			})
+
+			Context("when a single member would be left in the group", func() {
+				BeforeEach(func() {
+					members = []*models.AppGroupMember{
+						{AppId: "an-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+						{AppId: "another-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+					}
+				})
+
+				It("deletes the group", func() {
+					group, err := pdb.GetAppGroup("a-group-id")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(group).To(BeNil())
+				})
+			})
+		})
+	})
+
+	Describe("ObserveApp", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("registers the app until the expiration", func() {
+			Expect(pdb.ObserveApp("an-app-id", 111111)).To(Succeed())
+			expireAt, found := getObservedAppExpireAt("an-app-id")
+			Expect(found).To(BeTrue())
+			Expect(expireAt).To(BeEquivalentTo(111111))
+		})
+
+		Context("when the app is already observed", func() {
+			BeforeEach(func() {
+				Expect(pdb.ObserveApp("an-app-id", 111111)).To(Succeed())
+			})
+
+			It("replaces the expiration", func() {
+				Expect(pdb.ObserveApp("an-app-id", 222222)).To(Succeed())
+				expireAt, _ := getObservedAppExpireAt("an-app-id")
+				Expect(expireAt).To(BeEquivalentTo(222222))
+			})
+		})
+	})
+
+	Describe("UnobserveApp", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			Expect(pdb.ObserveApp("an-app-id", 111111)).To(Succeed())
+			Expect(pdb.ObserveApp("another-app-id", 111111)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("removes the observation of the app only", func() {
+			Expect(pdb.UnobserveApp("an-app-id")).To(Succeed())
+			_, found := getObservedAppExpireAt("an-app-id")
+			Expect(found).To(BeFalse())
+			_, found = getObservedAppExpireAt("another-app-id")
+			Expect(found).To(BeTrue())
+		})
+	})
+
+	Describe("RetrieveObservedApps", func() {
+		var observedApps []string
+
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			Expect(pdb.ObserveApp("first-app-id", 333333)).To(Succeed())
+			Expect(pdb.ObserveApp("second-app-id", 111111)).To(Succeed())
+			Expect(pdb.ObserveApp("third-app-id", 444444)).To(Succeed())
+			Expect(pdb.ObserveApp("fourth-app-id", 444444)).To(Succeed())
+			insertOrphanedApp("fourth-app-id", 111111)
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			observedApps, err = pdb.RetrieveObservedApps(333333)
+		})
+
+		It("returns the apps whose observation has not expired and that are not orphaned", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(observedApps).To(Equal([]string{"third-app-id"}))
+		})
+	})
+
+	Describe("GetAppGroup", func() {
+		var group *models.AppGroup
+
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			Expect(pdb.SaveAppGroup(&models.AppGroup{
+				GroupId: "a-group-id",
+				Members: []*models.AppGroupMember{
+					{AppId: "second-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5, ProcessType: "worker"},
+					{AppId: "first-app-id", Ratio: 2, InstanceMin: 2, InstanceMax: 10},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the members of the group", func() {
+			group, err = pdb.GetAppGroup("a-group-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(group).To(Equal(&models.AppGroup{
+				GroupId: "a-group-id",
+				Members: []*models.AppGroupMember{
+					{AppId: "first-app-id", Ratio: 2, InstanceMin: 2, InstanceMax: 10},
+					{AppId: "second-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5, ProcessType: "worker"},
+				},
+			}))
+		})
+
+		Context("when the group does not exist", func() {
+			It("returns nil", func() {
+				group, err = pdb.GetAppGroup("another-group-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(group).To(BeNil())
+			})
+		})
+	})
+
+	Describe("GetAppGroupOfApp", func() {
+		var group *models.AppGroup
+
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			Expect(pdb.SaveAppGroup(&models.AppGroup{
+				GroupId: "a-group-id",
+				Members: []*models.AppGroupMember{
+					{AppId: "first-app-id", Ratio: 2, InstanceMin: 2, InstanceMax: 10},
+					{AppId: "second-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+				},
+			})).To(Succeed())
+			Expect(pdb.SaveAppGroup(&models.AppGroup{
+				GroupId: "another-group-id",
+				Members: []*models.AppGroupMember{
+					{AppId: "third-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the group of the app with all its members", func() {
+			group, err = pdb.GetAppGroupOfApp("second-app-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(group.GroupId).To(Equal("a-group-id"))
+			Expect(group.Members).To(HaveLen(2))
+			Expect(group.Member("first-app-id")).NotTo(BeNil())
+		})
+
+		Context("when the app is not in a group", func() {
+			It("returns nil", func() {
+				group, err = pdb.GetAppGroupOfApp("fourth-app-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(group).To(BeNil())
+			})
+		})
+	})
+
+	Describe("SaveAppGroup", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			Expect(pdb.SaveAppGroup(&models.AppGroup{
+				GroupId: "a-group-id",
+				Members: []*models.AppGroupMember{
+					{AppId: "first-app-id", Ratio: 2, InstanceMin: 2, InstanceMax: 10},
+					{AppId: "second-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("replaces the members of the group", func() {
+			Expect(pdb.SaveAppGroup(&models.AppGroup{
+				GroupId: "a-group-id",
+				Members: []*models.AppGroupMember{
+					{AppId: "second-app-id", Ratio: 3, InstanceMin: 3, InstanceMax: 9},
+				},
+			})).To(Succeed())
+
+			group, err := pdb.GetAppGroup("a-group-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(group.Members).To(Equal([]*models.AppGroupMember{
+				{AppId: "second-app-id", Ratio: 3, InstanceMin: 3, InstanceMax: 9},
+			}))
+		})
+
+		Context("when an app is a member of another group", func() {
+			It("fails and keeps the groups unchanged", func() {
+				Expect(pdb.SaveAppGroup(&models.AppGroup{
+					GroupId: "another-group-id",
+					Members: []*models.AppGroupMember{
+						{AppId: "third-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+						{AppId: "first-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+					},
+				})).To(Equal(&db.AppGroupConflictError{AppId: "first-app-id"}))
+
+				group, err := pdb.GetAppGroup("another-group-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(group).To(BeNil())
+				group, err = pdb.GetAppGroupOfApp("first-app-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(group.GroupId).To(Equal("a-group-id"))
+			})
+		})
+	})
+
+	Describe("DeleteAppGroup", func() {
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			Expect(pdb.SaveAppGroup(&models.AppGroup{
+				GroupId: "a-group-id",
+				Members: []*models.AppGroupMember{{AppId: "first-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5}},
+			})).To(Succeed())
+			Expect(pdb.SaveAppGroup(&models.AppGroup{
+				GroupId: "another-group-id",
+				Members: []*models.AppGroupMember{{AppId: "second-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 5}},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err = pdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("removes the members of the group only", func() {
+			Expect(pdb.DeleteAppGroup("a-group-id")).To(Succeed())
+			group, err := pdb.GetAppGroupOfApp("first-app-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(group).To(BeNil())
+			group, err = pdb.GetAppGroupOfApp("second-app-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(group).NotTo(BeNil())
+		})
+	})
+
+	Describe("SubscribePolicyChanges", func() {
+		var changes <-chan string
+
+		BeforeEach(func() {
+			pdb, err = NewPolicySQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			cleanPolicyTable()
+			changes, err = pdb.SubscribePolicyChanges()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			if pdb != nil {
+				err = pdb.Close()
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("receives the ids of the apps whose policy changed", func() {
+			insertPolicy("an-app-id", &models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6})
+			Eventually(changes).Should(Receive(Equal("an-app-id")))
+
+			Expect(pdb.DeletePolicy("an-app-id")).To(Succeed())
+			Eventually(changes).Should(Receive(Equal("an-app-id")))
+		})
+
+		It("receives the ids of the apps whose orphan mark changed", func() {
+			Expect(pdb.MarkAppOrphaned("an-app-id", 111111)).To(Succeed())
+			Eventually(changes).Should(Receive(Equal("an-app-id")))
+		})
+
+		It("closes the changes when the database is closed", func() {
+			Expect(pdb.Close()).To(Succeed())
+			pdb = nil
+			Eventually(changes).Should(BeClosed())
+		})
 	})
 })
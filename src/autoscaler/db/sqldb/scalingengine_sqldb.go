@@ -47,12 +47,23 @@ func (sdb *ScalingEngineSQLDB) Close() error {
 	return nil
 }
 
+func (sdb *ScalingEngineSQLDB) Ping() error {
+	err := sdb.sqldb.Ping()
+	if err != nil {
+		sdb.logger.Error("ping-scalingengine-db", err, lager.Data{"url": sdb.url})
+	}
+	return err
+}
+
 func (sdb *ScalingEngineSQLDB) SaveScalingHistory(history *models.AppScalingHistory) error {
+	defer observeQuery("scalingengine", "save-scaling-history", time.Now())
 	query := "INSERT INTO scalinghistory" +
-		"(appid, timestamp, scalingtype, status, oldinstances, newinstances, reason, message, error) " +
-		" VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+		"(appid, timestamp, scalingtype, status, oldinstances, newinstances, reason, message, error, policyrevision, " +
+		"oldmemory, newmemory, olddisk, newdisk, groupid) " +
+		" VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)"
 	_, err := sdb.sqldb.Exec(query, history.AppId, history.Timestamp, history.ScalingType, history.Status,
-		history.OldInstances, history.NewInstances, history.Reason, history.Message, history.Error)
+		history.OldInstances, history.NewInstances, history.Reason, history.Message, history.Error, history.PolicyRevision,
+		history.OldMemoryMb, history.NewMemoryMb, history.OldDiskMb, history.NewDiskMb, history.GroupId)
 
 	if err != nil {
 		sdb.logger.Error("save-scaling-history", err, lager.Data{"query": query, "history": history})
@@ -61,45 +72,72 @@ func (sdb *ScalingEngineSQLDB) SaveScalingHistory(history *models.AppScalingHist
 }
 
 func (sdb *ScalingEngineSQLDB) RetrieveScalingHistories(appId string, start int64, end int64) ([]*models.AppScalingHistory, error) {
-	query := "SELECT timestamp, scalingtype, status, oldinstances, newinstances, reason, message, error FROM scalinghistory WHERE" +
+	defer observeQuery("scalingengine", "retrieve-scaling-histories", time.Now())
+	query := "SELECT appid, timestamp, scalingtype, status, oldinstances, newinstances, reason, message, error, policyrevision, " +
+		"oldmemory, newmemory, olddisk, newdisk, groupid FROM scalinghistory WHERE" +
 		" appid = $1 " +
 		" AND timestamp >= $2" +
 		" AND timestamp <= $3 ORDER BY timestamp"
+	return sdb.retrieveScalingHistories(query, appId, start, end)
+}
+
+// RetrieveGroupScalingHistories returns the histories recorded for the members
+// of the app group when they were scaled together, ordered by timestamp and
+// app id. A scaling of the group is the set of member histories sharing its
+// timestamp, as each of them records its trigger, ratio outcome and status.
+func (sdb *ScalingEngineSQLDB) RetrieveGroupScalingHistories(groupId string, start int64, end int64) ([]*models.AppScalingHistory, error) {
+	defer observeQuery("scalingengine", "retrieve-group-scaling-histories", time.Now())
+	query := "SELECT appid, timestamp, scalingtype, status, oldinstances, newinstances, reason, message, error, policyrevision, " +
+		"oldmemory, newmemory, olddisk, newdisk, groupid FROM scalinghistory WHERE" +
+		" groupid = $1 " +
+		" AND timestamp >= $2" +
+		" AND timestamp <= $3 ORDER BY timestamp, appid"
+	return sdb.retrieveScalingHistories(query, groupId, start, end)
+}
 
+func (sdb *ScalingEngineSQLDB) retrieveScalingHistories(query string, arg string, start int64, end int64) ([]*models.AppScalingHistory, error) {
 	if end < 0 {
 		end = time.Now().UnixNano()
 	}
 
 	histories := []*models.AppScalingHistory{}
-	rows, err := sdb.sqldb.Query(query, appId, start, end)
+	rows, err := sdb.sqldb.Query(query, arg, start, end)
 	if err != nil {
 		sdb.logger.Error("retrieve-scaling-histories", err,
-			lager.Data{"query": query, "appid": appId, "start": start, "end": end})
+			lager.Data{"query": query, "arg": arg, "start": start, "end": end})
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var timestamp int64
+	var timestamp, policyRevision int64
 	var scalingType, status, oldInstances, newInstances int
-	var reason, message, errorMsg string
+	var oldMemory, newMemory, oldDisk, newDisk int
+	var appId, reason, message, errorMsg, groupId string
 
 	for rows.Next() {
-		if err = rows.Scan(&timestamp, &scalingType, &status, &oldInstances, &newInstances, &reason, &message, &errorMsg); err != nil {
+		if err = rows.Scan(&appId, &timestamp, &scalingType, &status, &oldInstances, &newInstances, &reason, &message, &errorMsg, &policyRevision,
+			&oldMemory, &newMemory, &oldDisk, &newDisk, &groupId); err != nil {
 			sdb.logger.Error("retrieve-scaling-history-scan", err)
 			return nil, err
 		}
 
 		history := models.AppScalingHistory{
-			AppId:        appId,
-			Timestamp:    timestamp,
-			ScalingType:  models.ScalingType(scalingType),
-			Status:       models.ScalingStatus(status),
-			OldInstances: oldInstances,
-			NewInstances: newInstances,
-			Reason:       reason,
-			Message:      message,
-			Error:        errorMsg,
+			AppId:          appId,
+			Timestamp:      timestamp,
+			ScalingType:    models.ScalingType(scalingType),
+			Status:         models.ScalingStatus(status),
+			OldInstances:   oldInstances,
+			NewInstances:   newInstances,
+			Reason:         reason,
+			Message:        message,
+			Error:          errorMsg,
+			PolicyRevision: policyRevision,
+			OldMemoryMb:    oldMemory,
+			NewMemoryMb:    newMemory,
+			OldDiskMb:      oldDisk,
+			NewDiskMb:      newDisk,
+			GroupId:        groupId,
 		}
 		histories = append(histories, &history)
 	}
@@ -107,55 +145,143 @@ func (sdb *ScalingEngineSQLDB) RetrieveScalingHistories(appId string, start int6
 }
 
 func (sdb *ScalingEngineSQLDB) PruneScalingHistories(before int64) error {
+	defer observeQuery("scalingengine", "prune-scaling-histories", time.Now())
 	query := "DELETE FROM scalinghistory WHERE timestamp <= $1"
-	_, err := sdb.sqldb.Exec(query, before)
+	result, err := sdb.sqldb.Exec(query, before)
 	if err != nil {
 		sdb.logger.Error("failed-prune-scaling-histories-from-scalinghistory-table", err, lager.Data{"query": query, "before": before})
+		return err
 	}
-	return err
+
+	countPrunedRows("scalinghistory", result)
+
+	return nil
 }
 
-func (sdb *ScalingEngineSQLDB) CanScaleApp(appId string) (bool, error) {
-	query := "SELECT expireat FROM scalingcooldown where appid = $1"
-	rows, err := sdb.sqldb.Query(query, appId)
+// cooldownClaimTTL is how long a claimed cooldown keeps other callers out
+// while the claimer calls out to scale or restart, in case it dies before it
+// records the outcome.
+const cooldownClaimTTL = 5 * time.Minute
+
+// ScaleWithCooldown claims the cooldown of the app with a short conditional
+// update, so that concurrent callers see the app in cooldown, and then calls
+// scale outside of any transaction. It returns false without calling scale
+// when the app is in cooldown at now. The cooldown expiration returned by
+// scale is recorded when it is greater than 0; otherwise, or when scale
+// returns an error, the previous cooldown is restored.
+func (sdb *ScalingEngineSQLDB) ScaleWithCooldown(appId string, now int64, scale func() (int64, error)) (bool, error) {
+	defer observeQuery("scalingengine", "scale-with-cooldown", time.Now())
+	query := "INSERT INTO scalingcooldown(appid, expireat) VALUES($1, 0) ON CONFLICT (appid) DO NOTHING"
+	_, err := sdb.sqldb.Exec(query, appId)
 	if err != nil {
-		sdb.logger.Error("can-scale-app-query-record", err, lager.Data{"query": query, "appid": appId})
+		sdb.logger.Error("scale-with-cooldown-insert", err, lager.Data{"query": query, "appid": appId})
 		return false, err
 	}
-	defer rows.Close()
 
-	if rows.Next() {
-		var expireAt int64
-		if err = rows.Scan(&expireAt); err != nil {
-			sdb.logger.Error("can-scale-app-scan", err, lager.Data{"query": query, "appid": appId})
-			return false, err
-		}
-		if expireAt < time.Now().UnixNano() {
-			return true, nil
-		} else {
-			return false, nil
+	claim := now + cooldownClaimTTL.Nanoseconds()
+	query = "UPDATE scalingcooldown c SET expireat = $3 " +
+		" FROM (SELECT appid, expireat FROM scalingcooldown WHERE appid = $1 FOR UPDATE) p " +
+		" WHERE c.appid = p.appid AND p.expireat < $2 RETURNING p.expireat"
+	var previous int64
+	err = sdb.sqldb.QueryRow(query, appId, now, claim).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		sdb.logger.Error("scale-with-cooldown-claim", err, lager.Data{"query": query, "appid": appId})
+		return false, err
+	}
+
+	expireAt, scaleErr := scale()
+	if scaleErr != nil || expireAt <= 0 {
+		expireAt = previous
+	}
+
+	query = "UPDATE scalingcooldown SET expireat = $3 WHERE appid = $1 AND expireat = $2"
+	_, err = sdb.sqldb.Exec(query, appId, claim, expireAt)
+	if err != nil {
+		sdb.logger.Error("scale-with-cooldown-update", err, lager.Data{"query": query, "appid": appId, "expireAt": expireAt})
+		if scaleErr == nil {
+			return true, err
 		}
 	}
+	return true, scaleErr
+}
+
+// RestartWithCooldown runs restart like ScaleWithCooldown, with the cooldown of
+// the app instance at instanceIndex. Restart cooldowns are independent from the
+// scaling cooldown of the app.
+func (sdb *ScalingEngineSQLDB) RestartWithCooldown(appId string, instanceIndex int, now int64, restart func() (int64, error)) (bool, error) {
+	defer observeQuery("scalingengine", "restart-with-cooldown", time.Now())
+	query := "INSERT INTO restartcooldown(appid, instanceindex, expireat) VALUES($1, $2, 0) ON CONFLICT (appid, instanceindex) DO NOTHING"
+	_, err := sdb.sqldb.Exec(query, appId, instanceIndex)
+	if err != nil {
+		sdb.logger.Error("restart-with-cooldown-insert", err, lager.Data{"query": query, "appid": appId, "instanceIndex": instanceIndex})
+		return false, err
+	}
+
+	claim := now + cooldownClaimTTL.Nanoseconds()
+	query = "UPDATE restartcooldown c SET expireat = $4 " +
+		" FROM (SELECT appid, instanceindex, expireat FROM restartcooldown WHERE appid = $1 AND instanceindex = $2 FOR UPDATE) p " +
+		" WHERE c.appid = p.appid AND c.instanceindex = p.instanceindex AND p.expireat < $3 RETURNING p.expireat"
+	var previous int64
+	err = sdb.sqldb.QueryRow(query, appId, instanceIndex, now, claim).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		sdb.logger.Error("restart-with-cooldown-claim", err, lager.Data{"query": query, "appid": appId, "instanceIndex": instanceIndex})
+		return false, err
+	}
+
+	expireAt, restartErr := restart()
+	if restartErr != nil || expireAt <= 0 {
+		expireAt = previous
+	}
 
-	return true, nil
+	query = "UPDATE restartcooldown SET expireat = $4 WHERE appid = $1 AND instanceindex = $2 AND expireat = $3"
+	_, err = sdb.sqldb.Exec(query, appId, instanceIndex, claim, expireAt)
+	if err != nil {
+		sdb.logger.Error("restart-with-cooldown-update", err, lager.Data{"query": query, "appid": appId, "instanceIndex": instanceIndex, "expireAt": expireAt})
+		if restartErr == nil {
+			return true, err
+		}
+	}
+	return true, restartErr
 }
 
 func (sdb *ScalingEngineSQLDB) UpdateScalingCooldownExpireTime(appId string, expireAt int64) error {
-	_, err := sdb.sqldb.Exec("DELETE FROM scalingcooldown where appid = $1", appId)
+	defer observeQuery("scalingengine", "update-scaling-cooldown-expire-time", time.Now())
+	query := "INSERT INTO scalingcooldown(appid, expireat) VALUES($1, $2) " +
+		" ON CONFLICT (appid) DO UPDATE SET expireat = EXCLUDED.expireat"
+	_, err := sdb.sqldb.Exec(query, appId, expireAt)
 	if err != nil {
-		sdb.logger.Error("update-scaling-cooldown-time-delete", err, lager.Data{"appid": appId})
-		return err
+		sdb.logger.Error("update-scaling-cooldown-time", err, lager.Data{"query": query, "appid": appId, "expireAt": expireAt})
 	}
+	return err
+}
 
-	_, err = sdb.sqldb.Exec("INSERT INTO scalingcooldown(appid, expireat) values($1, $2)", appId, expireAt)
+// RemoveScalingCooldown removes the scaling cooldown and the restart cooldowns
+// of the instances of the app.
+func (sdb *ScalingEngineSQLDB) RemoveScalingCooldown(appId string) error {
+	defer observeQuery("scalingengine", "remove-scaling-cooldown", time.Now())
+	query := "DELETE FROM scalingcooldown WHERE appid = $1"
+	_, err := sdb.sqldb.Exec(query, appId)
 	if err != nil {
-		sdb.logger.Error("update-scaling-cooldown-time-insert", err, lager.Data{"appid": appId, "expireAt": expireAt})
+		sdb.logger.Error("remove-scaling-cooldown", err, lager.Data{"query": query, "appid": appId})
 		return err
 	}
-	return nil
+
+	query = "DELETE FROM restartcooldown WHERE appid = $1"
+	_, err = sdb.sqldb.Exec(query, appId)
+	if err != nil {
+		sdb.logger.Error("remove-restart-cooldown", err, lager.Data{"query": query, "appid": appId})
+	}
+	return err
 }
 
 func (sdb *ScalingEngineSQLDB) GetActiveSchedule(appId string) (*models.ActiveSchedule, error) {
+	defer observeQuery("scalingengine", "get-active-schedule", time.Now())
 	query := "SELECT scheduleid, instancemincount, instancemaxcount, initialmininstancecount" +
 		" FROM activeschedule WHERE appid = $1"
 
@@ -180,6 +306,7 @@ func (sdb *ScalingEngineSQLDB) GetActiveSchedule(appId string) (*models.ActiveSc
 }
 
 func (sdb *ScalingEngineSQLDB) GetActiveSchedules() (map[string]string, error) {
+	defer observeQuery("scalingengine", "get-active-schedules", time.Now())
 	query := "SELECT scheduleid, appid FROM activeschedule"
 	rows, err := sdb.sqldb.Query(query)
 	if err != nil {
@@ -201,6 +328,7 @@ func (sdb *ScalingEngineSQLDB) GetActiveSchedules() (map[string]string, error) {
 }
 
 func (sdb *ScalingEngineSQLDB) RemoveActiveSchedule(appId string) error {
+	defer observeQuery("scalingengine", "remove-active-schedule", time.Now())
 	query := "DELETE FROM activeschedule WHERE appid = $1"
 	_, err := sdb.sqldb.Exec(query, appId)
 	if err != nil {
@@ -210,18 +338,15 @@ func (sdb *ScalingEngineSQLDB) RemoveActiveSchedule(appId string) error {
 }
 
 func (sdb *ScalingEngineSQLDB) SetActiveSchedule(appId string, schedule *models.ActiveSchedule) error {
-	err := sdb.RemoveActiveSchedule(appId)
-	if err != nil {
-		sdb.logger.Error("failed-set-active-scheudle-remove", err, lager.Data{"appid": appId})
-		return err
-	}
-
+	defer observeQuery("scalingengine", "set-active-schedule", time.Now())
 	query := "INSERT INTO activeschedule(appid, scheduleid, instancemincount, instancemaxcount, initialmininstancecount) " +
-		" VALUES ($1, $2, $3, $4, $5)"
-	_, err = sdb.sqldb.Exec(query, appId, schedule.ScheduleId, schedule.InstanceMin, schedule.InstanceMax, schedule.InstanceMinInitial)
-
+		" VALUES ($1, $2, $3, $4, $5) " +
+		" ON CONFLICT (appid) DO UPDATE SET scheduleid = EXCLUDED.scheduleid, createdat = EXCLUDED.createdat, " +
+		" instancemincount = EXCLUDED.instancemincount, instancemaxcount = EXCLUDED.instancemaxcount, " +
+		" initialmininstancecount = EXCLUDED.initialmininstancecount"
+	_, err := sdb.sqldb.Exec(query, appId, schedule.ScheduleId, schedule.InstanceMin, schedule.InstanceMax, schedule.InstanceMinInitial)
 	if err != nil {
-		sdb.logger.Error("failed-set-active-scheudle-insert", err, lager.Data{"appid": appId, "schedule": schedule})
+		sdb.logger.Error("failed-set-active-scheudle", err, lager.Data{"query": query, "appid": appId, "schedule": schedule})
 	}
 	return err
 }
@@ -9,7 +9,11 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"errors"
+	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -156,11 +160,12 @@ var _ = Describe("ScalingEngineSqldb", func() {
 
 		JustBeforeEach(func() {
 			history = &models.AppScalingHistory{
-				AppId:        "an-app-id",
-				OldInstances: 2,
-				NewInstances: 4,
-				Reason:       "a reason",
-				Message:      "a message",
+				AppId:          "an-app-id",
+				OldInstances:   2,
+				NewInstances:   4,
+				Reason:         "a reason",
+				Message:        "a message",
+				PolicyRevision: 3,
 			}
 
 			history.Timestamp = 666666
@@ -262,46 +267,50 @@ var _ = Describe("ScalingEngineSqldb", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(histories).To(Equal([]*models.AppScalingHistory{
 					&models.AppScalingHistory{
-						AppId:        "an-app-id",
-						Timestamp:    222222,
-						ScalingType:  models.ScalingTypeDynamic,
-						Status:       models.ScalingStatusFailed,
-						OldInstances: 2,
-						NewInstances: 4,
-						Reason:       "a reason",
-						Message:      "a message",
-						Error:        "an error",
+						AppId:          "an-app-id",
+						Timestamp:      222222,
+						ScalingType:    models.ScalingTypeDynamic,
+						Status:         models.ScalingStatusFailed,
+						OldInstances:   2,
+						NewInstances:   4,
+						Reason:         "a reason",
+						Message:        "a message",
+						PolicyRevision: 3,
+						Error:          "an error",
 					},
 					&models.AppScalingHistory{
-						AppId:        "an-app-id",
-						Timestamp:    333333,
-						ScalingType:  models.ScalingTypeSchedule,
-						Status:       models.ScalingStatusIgnored,
-						OldInstances: 2,
-						NewInstances: 4,
-						Reason:       "a reason",
-						Message:      "a message",
+						AppId:          "an-app-id",
+						Timestamp:      333333,
+						ScalingType:    models.ScalingTypeSchedule,
+						Status:         models.ScalingStatusIgnored,
+						OldInstances:   2,
+						NewInstances:   4,
+						Reason:         "a reason",
+						Message:        "a message",
+						PolicyRevision: 3,
 					},
 					&models.AppScalingHistory{
-						AppId:        "an-app-id",
-						Timestamp:    555555,
-						ScalingType:  models.ScalingTypeSchedule,
-						Status:       models.ScalingStatusFailed,
-						OldInstances: 2,
-						NewInstances: 4,
-						Reason:       "a reason",
-						Message:      "a message",
-						Error:        "an error",
+						AppId:          "an-app-id",
+						Timestamp:      555555,
+						ScalingType:    models.ScalingTypeSchedule,
+						Status:         models.ScalingStatusFailed,
+						OldInstances:   2,
+						NewInstances:   4,
+						Reason:         "a reason",
+						Message:        "a message",
+						PolicyRevision: 3,
+						Error:          "an error",
 					},
 					&models.AppScalingHistory{
-						AppId:        "an-app-id",
-						Timestamp:    666666,
-						ScalingType:  models.ScalingTypeDynamic,
-						Status:       models.ScalingStatusSucceeded,
-						OldInstances: 2,
-						NewInstances: 4,
-						Reason:       "a reason",
-						Message:      "a message",
+						AppId:          "an-app-id",
+						Timestamp:      666666,
+						ScalingType:    models.ScalingTypeDynamic,
+						Status:         models.ScalingStatusSucceeded,
+						OldInstances:   2,
+						NewInstances:   4,
+						Reason:         "a reason",
+						Message:        "a message",
+						PolicyRevision: 3,
 					}}))
 			})
 		})
@@ -316,29 +325,138 @@ var _ = Describe("ScalingEngineSqldb", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(histories).To(Equal([]*models.AppScalingHistory{
 					&models.AppScalingHistory{
-						AppId:        "an-app-id",
-						Timestamp:    333333,
-						ScalingType:  models.ScalingTypeSchedule,
-						Status:       models.ScalingStatusIgnored,
-						OldInstances: 2,
-						NewInstances: 4,
-						Reason:       "a reason",
-						Message:      "a message",
+						AppId:          "an-app-id",
+						Timestamp:      333333,
+						ScalingType:    models.ScalingTypeSchedule,
+						Status:         models.ScalingStatusIgnored,
+						OldInstances:   2,
+						NewInstances:   4,
+						Reason:         "a reason",
+						Message:        "a message",
+						PolicyRevision: 3,
 					},
+					&models.AppScalingHistory{
+						AppId:          "an-app-id",
+						Timestamp:      555555,
+						ScalingType:    models.ScalingTypeSchedule,
+						Status:         models.ScalingStatusFailed,
+						OldInstances:   2,
+						NewInstances:   4,
+						Reason:         "a reason",
+						Message:        "a message",
+						PolicyRevision: 3,
+						Error:          "an error",
+					}}))
+
+			})
+
+		})
+
+		Context("when the history is a vertical scaling", func() {
+			JustBeforeEach(func() {
+				err = sdb.SaveScalingHistory(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    777777,
+					ScalingType:  models.ScalingTypeVertical,
+					Status:       models.ScalingStatusSucceeded,
+					OldInstances: 2,
+					NewInstances: 2,
+					Reason:       "a reason",
+					OldMemoryMb:  512,
+					NewMemoryMb:  1024,
+					OldDiskMb:    1024,
+					NewDiskMb:    2048,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				histories, err = sdb.RetrieveScalingHistories(appId, 777777, 777777)
+			})
+
+			It("returns the old and new limits", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(histories).To(Equal([]*models.AppScalingHistory{
 					&models.AppScalingHistory{
 						AppId:        "an-app-id",
-						Timestamp:    555555,
-						ScalingType:  models.ScalingTypeSchedule,
-						Status:       models.ScalingStatusFailed,
+						Timestamp:    777777,
+						ScalingType:  models.ScalingTypeVertical,
+						Status:       models.ScalingStatusSucceeded,
 						OldInstances: 2,
-						NewInstances: 4,
+						NewInstances: 2,
 						Reason:       "a reason",
-						Message:      "a message",
-						Error:        "an error",
+						OldMemoryMb:  512,
+						NewMemoryMb:  1024,
+						OldDiskMb:    1024,
+						NewDiskMb:    2048,
 					}}))
+			})
+		})
 
+		Context("when the history is a scaling of an app group", func() {
+			JustBeforeEach(func() {
+				err = sdb.SaveScalingHistory(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    777777,
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusSucceeded,
+					OldInstances: 2,
+					NewInstances: 4,
+					Reason:       "a reason",
+					GroupId:      "a-group-id",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				histories, err = sdb.RetrieveScalingHistories(appId, 777777, 777777)
+			})
+
+			It("returns the group id", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(histories).To(HaveLen(1))
+				Expect(histories[0].GroupId).To(Equal("a-group-id"))
 			})
+		})
+	})
+
+	Describe("RetrieveGroupScalingHistories", func() {
+		BeforeEach(func() {
+			sdb, err = NewScalingEngineSQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+			cleanScalingHistoryTable()
+
+			for _, h := range []*models.AppScalingHistory{
+				{AppId: "second-app-id", Timestamp: 222222, GroupId: "a-group-id", OldInstances: 1, NewInstances: 2},
+				{AppId: "first-app-id", Timestamp: 222222, GroupId: "a-group-id", OldInstances: 2, NewInstances: 4},
+				{AppId: "first-app-id", Timestamp: 111111, GroupId: "a-group-id", OldInstances: 1, NewInstances: 2},
+				{AppId: "first-app-id", Timestamp: 333333, OldInstances: 4, NewInstances: 5},
+				{AppId: "third-app-id", Timestamp: 222222, GroupId: "another-group-id", OldInstances: 1, NewInstances: 2},
+			} {
+				Expect(sdb.SaveScalingHistory(h)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			err = sdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the histories of the members scaled with the group ordered by timestamp and app id", func() {
+			histories, err = sdb.RetrieveGroupScalingHistories("a-group-id", 0, -1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(histories).To(HaveLen(3))
+			Expect([]string{histories[0].AppId, histories[1].AppId, histories[2].AppId}).To(Equal([]string{"first-app-id", "first-app-id", "second-app-id"}))
+			Expect([]int64{histories[0].Timestamp, histories[1].Timestamp, histories[2].Timestamp}).To(Equal([]int64{111111, 222222, 222222}))
+			Expect(histories[1].NewInstances).To(Equal(4))
+		})
 
+		It("returns the histories within the time range", func() {
+			histories, err = sdb.RetrieveGroupScalingHistories("a-group-id", 222222, 222222)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(histories).To(HaveLen(2))
+		})
+
+		It("returns no histories for a group never scaled", func() {
+			histories, err = sdb.RetrieveGroupScalingHistories("no-history-group-id", 0, -1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(histories).To(BeEmpty())
 		})
 	})
 
@@ -456,13 +574,39 @@ var _ = Describe("ScalingEngineSqldb", func() {
 				Expect(hasScalingCooldownRecord("an-app-id", 222222)).To(BeTrue())
 			})
 		})
+
+		Context("when there are concurrent writers", func() {
+			It("keeps exactly one record of the app", func() {
+				wg := sync.WaitGroup{}
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func(expireAt int64) {
+						defer GinkgoRecover()
+						defer wg.Done()
+						Expect(sdb.UpdateScalingCooldownExpireTime("another-app-id", expireAt)).To(Succeed())
+					}(int64(i))
+				}
+				wg.Wait()
+
+				Expect(getNumberOfScalingCooldownRecords("another-app-id")).To(Equal(1))
+			})
+		})
 	})
 
-	Describe("CanScaleApp", func() {
+	Describe("ScaleWithCooldown", func() {
+		var (
+			scaleCalled bool
+			scaleErr    error
+			expireAt    int64
+		)
+
 		BeforeEach(func() {
 			sdb, err = NewScalingEngineSQLDB(url, logger)
 			Expect(err).NotTo(HaveOccurred())
 			cleanScalingCooldownTable()
+			scaleCalled = false
+			scaleErr = nil
+			expireAt = 333333
 		})
 
 		AfterEach(func() {
@@ -471,35 +615,230 @@ var _ = Describe("ScalingEngineSqldb", func() {
 		})
 
 		JustBeforeEach(func() {
-			canScale, err = sdb.CanScaleApp("an-app-id")
+			canScale, err = sdb.ScaleWithCooldown("an-app-id", 222222, func() (int64, error) {
+				scaleCalled = true
+				return expireAt, scaleErr
+			})
 		})
 
 		Context("when there is no cooldown record before", func() {
-			It("returns true", func() {
+			It("scales and stores the new cooldown", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(canScale).To(BeTrue())
+				Expect(scaleCalled).To(BeTrue())
+				Expect(hasScalingCooldownRecord("an-app-id", 333333)).To(BeTrue())
 			})
 		})
 
 		Context("when the app is still in cooldown period", func() {
 			BeforeEach(func() {
-				err = sdb.UpdateScalingCooldownExpireTime("an-app-id", time.Now().Add(10*time.Second).UnixNano())
+				err = sdb.UpdateScalingCooldownExpireTime("an-app-id", 222223)
 				Expect(err).NotTo(HaveOccurred())
 			})
-			It("returns false", func() {
+
+			It("does not scale", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(canScale).To(BeFalse())
+				Expect(scaleCalled).To(BeFalse())
+				Expect(hasScalingCooldownRecord("an-app-id", 222223)).To(BeTrue())
 			})
 		})
 
 		Context("when the app passes cooldown period", func() {
 			BeforeEach(func() {
-				err = sdb.UpdateScalingCooldownExpireTime("an-app-id", time.Now().Add(-10*time.Second).UnixNano())
+				err = sdb.UpdateScalingCooldownExpireTime("an-app-id", 111111)
 				Expect(err).NotTo(HaveOccurred())
 			})
-			It("returns false", func() {
+
+			It("scales and stores the new cooldown", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(canScale).To(BeTrue())
+				Expect(scaleCalled).To(BeTrue())
+				Expect(hasScalingCooldownRecord("an-app-id", 111111)).To(BeFalse())
+				Expect(hasScalingCooldownRecord("an-app-id", 333333)).To(BeTrue())
+			})
+
+			Context("when scale does not set a cooldown", func() {
+				BeforeEach(func() {
+					expireAt = 0
+				})
+
+				It("keeps the previous cooldown", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(scaleCalled).To(BeTrue())
+					Expect(hasScalingCooldownRecord("an-app-id", 111111)).To(BeTrue())
+				})
+			})
+
+			Context("when scale fails", func() {
+				BeforeEach(func() {
+					scaleErr = errors.New("an error")
+				})
+
+				It("returns the error and keeps the previous cooldown", func() {
+					Expect(err).To(MatchError("an error"))
+					Expect(hasScalingCooldownRecord("an-app-id", 111111)).To(BeTrue())
+					Expect(hasScalingCooldownRecord("an-app-id", 333333)).To(BeFalse())
+				})
+			})
+		})
+
+		Context("when there is database error", func() {
+			BeforeEach(func() {
+				sdb.Close()
+			})
+
+			It("should error without scaling", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(scaleCalled).To(BeFalse())
+			})
+		})
+
+		Context("while scaling", func() {
+			It("keeps other callers out without blocking them", func() {
+				var (
+					claimed       bool
+					claimedErr    error
+					scalingExpiry int64
+				)
+				canScale, err = sdb.ScaleWithCooldown("another-app-id", 222222, func() (int64, error) {
+					claimed, claimedErr = sdb.ScaleWithCooldown("another-app-id", 222223, func() (int64, error) {
+						return 444444, nil
+					})
+					scalingExpiry = getScalingCooldownExpireAt("another-app-id")
+					return 333333, nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(canScale).To(BeTrue())
+				Expect(claimedErr).NotTo(HaveOccurred())
+				Expect(claimed).To(BeFalse())
+				Expect(scalingExpiry).To(BeNumerically(">", 222223))
+				Expect(hasScalingCooldownRecord("another-app-id", 333333)).To(BeTrue())
+			})
+		})
+
+		Context("when there are concurrent callers", func() {
+			It("lets only one of them scale within the cooldown", func() {
+				var scaled int32
+				wg := sync.WaitGroup{}
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						_, e := sdb.ScaleWithCooldown("another-app-id", 222222, func() (int64, error) {
+							atomic.AddInt32(&scaled, 1)
+							return 444444, nil
+						})
+						Expect(e).NotTo(HaveOccurred())
+					}()
+				}
+				wg.Wait()
+
+				Expect(atomic.LoadInt32(&scaled)).To(Equal(int32(1)))
+				Expect(hasScalingCooldownRecord("another-app-id", 444444)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("RestartWithCooldown", func() {
+		var (
+			canRestart    bool
+			restartCalled bool
+			restartErr    error
+			expireAt      int64
+		)
+
+		BeforeEach(func() {
+			sdb, err = NewScalingEngineSQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+			cleanScalingCooldownTable()
+			restartCalled = false
+			restartErr = nil
+			expireAt = 333333
+		})
+
+		AfterEach(func() {
+			err = sdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			canRestart, err = sdb.RestartWithCooldown("an-app-id", 1, 222222, func() (int64, error) {
+				restartCalled = true
+				return expireAt, restartErr
+			})
+		})
+
+		Context("when there is no cooldown record before", func() {
+			It("restarts and stores the new cooldown of the instance", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(canRestart).To(BeTrue())
+				Expect(restartCalled).To(BeTrue())
+				Expect(hasRestartCooldownRecord("an-app-id", 1, 333333)).To(BeTrue())
+				Expect(hasScalingCooldownRecord("an-app-id", 333333)).To(BeFalse())
+			})
+		})
+
+		Context("when the instance is still in cooldown period", func() {
+			BeforeEach(func() {
+				_, err = sdb.RestartWithCooldown("an-app-id", 1, 111111, func() (int64, error) { return 222223, nil })
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("does not restart", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(canRestart).To(BeFalse())
+				Expect(restartCalled).To(BeFalse())
+				Expect(hasRestartCooldownRecord("an-app-id", 1, 222223)).To(BeTrue())
+			})
+		})
+
+		Context("when another instance is in cooldown period", func() {
+			BeforeEach(func() {
+				_, err = sdb.RestartWithCooldown("an-app-id", 0, 111111, func() (int64, error) { return 222223, nil })
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("restarts the instance", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(canRestart).To(BeTrue())
+				Expect(restartCalled).To(BeTrue())
+			})
+		})
+
+		Context("when the app is in scaling cooldown period", func() {
+			BeforeEach(func() {
+				err = sdb.UpdateScalingCooldownExpireTime("an-app-id", 222223)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("restarts the instance", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(canRestart).To(BeTrue())
+				Expect(restartCalled).To(BeTrue())
+			})
+		})
+
+		Context("when restart fails", func() {
+			BeforeEach(func() {
+				restartErr = errors.New("an error")
+			})
+
+			It("returns the error without storing a cooldown", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(hasRestartCooldownRecord("an-app-id", 1, 333333)).To(BeFalse())
+			})
+		})
+
+		Context("when there is database error", func() {
+			BeforeEach(func() {
+				sdb.Close()
+			})
+
+			It("should error without restarting", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(restartCalled).To(BeFalse())
 			})
 		})
 	})
@@ -605,6 +944,30 @@ var _ = Describe("ScalingEngineSqldb", func() {
 		})
 	})
 
+	Describe("RemoveScalingCooldown", func() {
+		BeforeEach(func() {
+			sdb, err = NewScalingEngineSQLDB(url, logger)
+			Expect(err).NotTo(HaveOccurred())
+			cleanScalingCooldownTable()
+			Expect(sdb.UpdateScalingCooldownExpireTime("an-app-id", 111111)).To(Succeed())
+			Expect(sdb.UpdateScalingCooldownExpireTime("another-app-id", 222222)).To(Succeed())
+			_, err = sdb.RestartWithCooldown("an-app-id", 0, 1, func() (int64, error) { return 111111, nil })
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			err = sdb.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("removes the cooldown records of the app only", func() {
+			Expect(sdb.RemoveScalingCooldown("an-app-id")).To(Succeed())
+			Expect(hasScalingCooldownRecord("an-app-id", 111111)).To(BeFalse())
+			Expect(hasRestartCooldownRecord("an-app-id", 0, 111111)).To(BeFalse())
+			Expect(hasScalingCooldownRecord("another-app-id", 222222)).To(BeTrue())
+		})
+	})
+
 	Describe("RemoveActiveSchedule", func() {
 		BeforeEach(func() {
 			sdb, err = NewScalingEngineSQLDB(url, logger)
@@ -697,6 +1060,33 @@ var _ = Describe("ScalingEngineSqldb", func() {
 			})
 		})
 
+		Context("when there are concurrent writers", func() {
+			It("keeps one of the active schedules", func() {
+				wg := sync.WaitGroup{}
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer GinkgoRecover()
+						defer wg.Done()
+						schedule := &models.ActiveSchedule{
+							ScheduleId:         fmt.Sprintf("schedule-id-%d", i),
+							InstanceMin:        i,
+							InstanceMax:        i + 1,
+							InstanceMinInitial: i,
+						}
+						Expect(sdb.SetActiveSchedule("another-app-id", schedule)).To(Succeed())
+					}(i)
+				}
+				wg.Wait()
+
+				schedule, err := sdb.GetActiveSchedule("another-app-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(schedule).NotTo(BeNil())
+				Expect(schedule.ScheduleId).To(Equal(fmt.Sprintf("schedule-id-%d", schedule.InstanceMin)))
+				Expect(schedule.InstanceMax).To(Equal(schedule.InstanceMin + 1))
+			})
+		})
+
 		Context("when there is database error", func() {
 			BeforeEach(func() {
 				sdb.Close()
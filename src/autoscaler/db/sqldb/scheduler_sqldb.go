@@ -9,6 +9,7 @@ import (
 
 	"database/sql"
 	"strconv"
+	"time"
 )
 
 type SchedulerSQLDB struct {
@@ -47,7 +48,16 @@ func (sdb *SchedulerSQLDB) Close() error {
 	return nil
 }
 
+func (sdb *SchedulerSQLDB) Ping() error {
+	err := sdb.sqldb.Ping()
+	if err != nil {
+		sdb.logger.Error("ping-scheduler-db", err, lager.Data{"url": sdb.url})
+	}
+	return err
+}
+
 func (sdb *SchedulerSQLDB) GetActiveSchedules() (map[string]*models.ActiveSchedule, error) {
+	defer observeQuery("scheduler", "get-active-schedules", time.Now())
 	query := "SELECT id, app_id, instance_min_count, instance_max_count, initial_min_instance_count FROM app_scaling_active_schedule"
 	rows, err := sdb.sqldb.Query(query)
 	if err != nil {
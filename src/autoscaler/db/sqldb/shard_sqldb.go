@@ -0,0 +1,102 @@
+package sqldb
+
+import (
+	"code.cloudfoundry.org/lager"
+	_ "github.com/lib/pq"
+
+	"autoscaler/db"
+
+	"database/sql"
+	"time"
+)
+
+type ShardSQLDB struct {
+	url    string
+	logger lager.Logger
+	sqldb  *sql.DB
+}
+
+func NewShardSQLDB(url string, logger lager.Logger) (*ShardSQLDB, error) {
+	sqldb, err := sql.Open(db.PostgresDriverName, url)
+	if err != nil {
+		logger.Error("open-shard-db", err, lager.Data{"url": url})
+		return nil, err
+	}
+
+	err = sqldb.Ping()
+	if err != nil {
+		sqldb.Close()
+		logger.Error("ping-shard-db", err, lager.Data{"url": url})
+		return nil, err
+	}
+
+	return &ShardSQLDB{
+		url:    url,
+		logger: logger,
+		sqldb:  sqldb,
+	}, nil
+}
+
+func (sdb *ShardSQLDB) Close() error {
+	err := sdb.sqldb.Close()
+	if err != nil {
+		sdb.logger.Error("close-shard-db", err, lager.Data{"url": sdb.url})
+		return err
+	}
+	return nil
+}
+
+func (sdb *ShardSQLDB) Ping() error {
+	err := sdb.sqldb.Ping()
+	if err != nil {
+		sdb.logger.Error("ping-shard-db", err, lager.Data{"url": sdb.url})
+	}
+	return err
+}
+
+// RegisterShardMember registers the member or extends its registration by ttl
+// from the current time of the database.
+func (sdb *ShardSQLDB) RegisterShardMember(group string, memberId string, ttl time.Duration) error {
+	defer observeQuery("shard", "register-shard-member", time.Now())
+	query := "INSERT INTO shardmembers(groupname, memberid, expireat) VALUES($1, $2, " + dbNow + " + $3) " +
+		" ON CONFLICT (groupname, memberid) DO UPDATE SET expireat = EXCLUDED.expireat"
+	_, err := sdb.sqldb.Exec(query, group, memberId, ttl.Nanoseconds())
+	if err != nil {
+		sdb.logger.Error("register-shard-member", err, lager.Data{"query": query, "group": group, "memberid": memberId})
+	}
+	return err
+}
+
+// RetrieveShardMembers returns the members of the group that have not expired
+// at the current time of the database, ordered by id.
+func (sdb *ShardSQLDB) RetrieveShardMembers(group string) ([]string, error) {
+	defer observeQuery("shard", "retrieve-shard-members", time.Now())
+	query := "SELECT memberid FROM shardmembers WHERE groupname = $1 AND expireat > " + dbNow + " ORDER BY memberid"
+	rows, err := sdb.sqldb.Query(query, group)
+	if err != nil {
+		sdb.logger.Error("retrieve-shard-members-query", err, lager.Data{"query": query, "group": group})
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []string{}
+	var memberId string
+	for rows.Next() {
+		if err = rows.Scan(&memberId); err != nil {
+			sdb.logger.Error("retrieve-shard-members-scan", err)
+			return nil, err
+		}
+		members = append(members, memberId)
+	}
+	return members, nil
+}
+
+func (sdb *ShardSQLDB) RemoveShardMember(group string, memberId string) error {
+	defer observeQuery("shard", "remove-shard-member", time.Now())
+	query := "DELETE FROM shardmembers WHERE groupname = $1 AND memberid = $2"
+	_, err := sdb.sqldb.Exec(query, group, memberId)
+	if err != nil {
+		sdb.logger.Error("remove-shard-member", err, lager.Data{"query": query, "group": group, "memberid": memberId})
+	}
+	return err
+}
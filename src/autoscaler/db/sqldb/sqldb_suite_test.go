@@ -74,6 +74,62 @@ func cleanPolicyTable() {
 	if e != nil {
 		Fail("can not clean table policy_json: " + e.Error())
 	}
+	_, e = dbHelper.Exec("DELETE from orphaned_apps")
+	if e != nil {
+		Fail("can not clean table orphaned_apps: " + e.Error())
+	}
+	_, e = dbHelper.Exec("DELETE from deleted_policies")
+	if e != nil {
+		Fail("can not clean table deleted_policies: " + e.Error())
+	}
+	_, e = dbHelper.Exec("DELETE from observed_apps")
+	if e != nil {
+		Fail("can not clean table observed_apps: " + e.Error())
+	}
+	_, e = dbHelper.Exec("DELETE from app_group_member")
+	if e != nil {
+		Fail("can not clean table app_group_member: " + e.Error())
+	}
+}
+
+func getObservedAppExpireAt(appId string) (int64, bool) {
+	var expireAt int64
+	e := dbHelper.QueryRow("SELECT expire_at FROM observed_apps WHERE app_id = $1", appId).Scan(&expireAt)
+	if e == sql.ErrNoRows {
+		return 0, false
+	}
+	if e != nil {
+		Fail("can not query table observed_apps: " + e.Error())
+	}
+	return expireAt, true
+}
+
+func insertOrphanedApp(appId string, orphanedAt int64) {
+	_, e := dbHelper.Exec("INSERT INTO orphaned_apps(app_id, orphaned_at) VALUES($1, $2)", appId, orphanedAt)
+	if e != nil {
+		Fail("can not insert data to table orphaned_apps: " + e.Error())
+	}
+}
+
+func getOrphanedAt(appId string) (int64, bool) {
+	var orphanedAt int64
+	e := dbHelper.QueryRow("SELECT orphaned_at FROM orphaned_apps WHERE app_id = $1", appId).Scan(&orphanedAt)
+	if e == sql.ErrNoRows {
+		return 0, false
+	}
+	if e != nil {
+		Fail("can not query table orphaned_apps: " + e.Error())
+	}
+	return orphanedAt, true
+}
+
+func hasPolicy(appId string) bool {
+	rows, e := dbHelper.Query("SELECT * FROM policy_json WHERE app_id = $1", appId)
+	if e != nil {
+		Fail("can not query table policy_json: " + e.Error())
+	}
+	defer rows.Close()
+	return rows.Next()
 }
 
 func insertPolicy(appId string, scalingPolicy *models.ScalingPolicy) {
@@ -147,6 +203,10 @@ func cleanScalingCooldownTable() {
 	if e != nil {
 		Fail("can not clean table scalingcooldown: " + e.Error())
 	}
+	_, e = dbHelper.Exec("DELETE from restartcooldown")
+	if e != nil {
+		Fail("can not clean table restartcooldown: " + e.Error())
+	}
 }
 
 func hasScalingCooldownRecord(appId string, expireAt int64) bool {
@@ -158,6 +218,38 @@ func hasScalingCooldownRecord(appId string, expireAt int64) bool {
 	defer rows.Close()
 	return rows.Next()
 }
+
+func getScalingCooldownExpireAt(appId string) int64 {
+	var expireAt int64
+	e := dbHelper.QueryRow("SELECT expireat FROM scalingcooldown WHERE appid = $1", appId).Scan(&expireAt)
+	if e == sql.ErrNoRows {
+		return -1
+	}
+	if e != nil {
+		Fail("can not query table scalingcooldown: " + e.Error())
+	}
+	return expireAt
+}
+
+func hasRestartCooldownRecord(appId string, instanceIndex int, expireAt int64) bool {
+	query := "SELECT * FROM restartcooldown WHERE appid = $1 AND instanceindex = $2 AND expireat = $3"
+	rows, e := dbHelper.Query(query, appId, instanceIndex, expireAt)
+	if e != nil {
+		Fail("can not query table restartcooldown: " + e.Error())
+	}
+	defer rows.Close()
+	return rows.Next()
+}
+
+func getNumberOfScalingCooldownRecords(appId string) int {
+	var num int
+	e := dbHelper.QueryRow("SELECT COUNT(*) FROM scalingcooldown WHERE appid = $1", appId).Scan(&num)
+	if e != nil {
+		Fail("can not count the number of records in table scalingcooldown: " + e.Error())
+	}
+	return num
+}
+
 func GetInt64Pointer(value int64) *int64 {
 	tmp := value
 	return &tmp
@@ -194,3 +286,67 @@ func insertSchedulerActiveSchedule(id int, appId string, startJobIdentifier int,
 	}
 	return e
 }
+
+func cleanShardMembersTable() {
+	_, e := dbHelper.Exec("DELETE FROM shardmembers")
+	if e != nil {
+		Fail("can not clean table shardmembers: " + e.Error())
+	}
+}
+
+func insertShardMember(group string, memberId string, expireAt int64) {
+	_, e := dbHelper.Exec("INSERT INTO shardmembers(groupname, memberid, expireat) VALUES($1, $2, $3)", group, memberId, expireAt)
+	if e != nil {
+		Fail("can not insert data to table shardmembers: " + e.Error())
+	}
+}
+
+func getShardMemberExpireAt(group string, memberId string) int64 {
+	var expireAt int64
+	e := dbHelper.QueryRow("SELECT expireat FROM shardmembers WHERE groupname = $1 AND memberid = $2", group, memberId).Scan(&expireAt)
+	if e == sql.ErrNoRows {
+		return -1
+	}
+	if e != nil {
+		Fail("can not query table shardmembers: " + e.Error())
+	}
+	return expireAt
+}
+
+func cleanLeasesTable() {
+	_, e := dbHelper.Exec("DELETE FROM leases")
+	if e != nil {
+		Fail("can not clean table leases: " + e.Error())
+	}
+}
+
+func insertLease(name string, owner string, expireAt int64) {
+	_, e := dbHelper.Exec("INSERT INTO leases(name, owner, expireat) VALUES($1, $2, $3)", name, owner, expireAt)
+	if e != nil {
+		Fail("can not insert data to table leases: " + e.Error())
+	}
+}
+
+func getLeaseExpireAt(name string) int64 {
+	var expireAt int64
+	e := dbHelper.QueryRow("SELECT expireat FROM leases WHERE name = $1", name).Scan(&expireAt)
+	if e == sql.ErrNoRows {
+		return -1
+	}
+	if e != nil {
+		Fail("can not query table leases: " + e.Error())
+	}
+	return expireAt
+}
+
+func getLeaseOwner(name string) string {
+	var owner string
+	e := dbHelper.QueryRow("SELECT owner FROM leases WHERE name = $1", name).Scan(&owner)
+	if e == sql.ErrNoRows {
+		return ""
+	}
+	if e != nil {
+		Fail("can not query table leases: " + e.Error())
+	}
+	return owner
+}
@@ -0,0 +1,6 @@
+package sqldb
+
+// dbNow is the current time of the database in nanoseconds since the epoch.
+// Expirations shared between nodes are computed and compared with it, so that
+// they do not depend on the clocks of the nodes.
+const dbNow = "(EXTRACT(EPOCH FROM now()) * 1000000000)::bigint"
@@ -2,12 +2,19 @@ package aggregator
 
 import (
 	"autoscaler/models"
+	"autoscaler/sharding"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 )
 
+// MaxSkippedTicks is the number of consecutive ticks skipped while the app
+// monitors of the previous round are still queued. The round after that is
+// run anyway so that apps which are not pending are still polled.
+const MaxSkippedTicks = 3
+
 type Aggregator struct {
 	logger                    lager.Logger
 	doneChan                  chan bool
@@ -16,17 +23,24 @@ type Aggregator struct {
 	cclock                    clock.Clock
 	aggregatorExecuteInterval time.Duration
 	getPolicies               models.GetPolicies
+	getShard                  func() sharding.Shard
+	wg                        sync.WaitGroup
+	pending                   map[string]bool
+	pendingLock               sync.Mutex
+	skippedTicks              int
 }
 
 func NewAggregator(logger lager.Logger, clock clock.Clock, aggregatorExecuteInterval time.Duration,
-	appMonitorChan chan *models.AppMonitor, getPolicies models.GetPolicies) (*Aggregator, error) {
+	appMonitorChan chan *models.AppMonitor, getPolicies models.GetPolicies, getShard func() sharding.Shard) (*Aggregator, error) {
 	aggregator := &Aggregator{
-		logger:   logger.Session("Aggregator"),
-		doneChan: make(chan bool),
-		appChan:  appMonitorChan,
-		cclock:   clock,
+		logger:                    logger.Session("Aggregator"),
+		doneChan:                  make(chan bool),
+		appChan:                   appMonitorChan,
+		cclock:                    clock,
 		aggregatorExecuteInterval: aggregatorExecuteInterval,
 		getPolicies:               getPolicies,
+		getShard:                  getShard,
+		pending:                   map[string]bool{},
 	}
 	return aggregator, nil
 }
@@ -35,8 +49,14 @@ func (a *Aggregator) getAppMonitors(policyMap map[string]*models.AppPolicy) []*m
 	if policyMap == nil {
 		return nil
 	}
+	shard := a.getShard()
+	apps := 0
 	appMonitors := make([]*models.AppMonitor, 0, len(policyMap))
 	for appId, appPolicy := range policyMap {
+		if !shard.Owns(appId) {
+			continue
+		}
+		apps++
 		for _, rule := range appPolicy.ScalingPolicy.ScalingRules {
 			appMonitors = append(appMonitors, &models.AppMonitor{
 				AppId:      appId,
@@ -45,11 +65,13 @@ func (a *Aggregator) getAppMonitors(policyMap map[string]*models.AppPolicy) []*m
 			})
 		}
 	}
+	assignedApps.Set(float64(apps))
 
 	return appMonitors
 }
 
 func (a *Aggregator) Start() {
+	a.wg.Add(1)
 	go a.startWork()
 
 	a.logger.Info("started")
@@ -60,10 +82,12 @@ func (a *Aggregator) Stop() {
 		metricPoller.Stop()
 	}
 	close(a.doneChan)
+	a.wg.Wait()
 	a.logger.Info("stopped")
 }
 
 func (a *Aggregator) startWork() {
+	defer a.wg.Done()
 	ticker := a.cclock.NewTicker(a.aggregatorExecuteInterval)
 	defer ticker.Stop()
 	for {
@@ -71,10 +95,66 @@ func (a *Aggregator) startWork() {
 		case <-a.doneChan:
 			return
 		case <-ticker.C():
-			appMonitors := a.getAppMonitors(a.getPolicies())
-			for _, monitor := range appMonitors {
-				a.appChan <- monitor
+			if len(a.appChan) > 0 && a.skippedTicks < MaxSkippedTicks {
+				a.skippedTicks++
+				skippedAggregatorTicks.Inc()
+				a.logger.Info("skip-tick", lager.Data{"queued": len(a.appChan), "skippedTicks": a.skippedTicks})
+				continue
 			}
+			a.skippedTicks = 0
+			a.enqueue(a.getAppMonitors(a.getPolicies()))
+		}
+	}
+}
+
+// enqueue sends the app monitors to the metric pollers without blocking. App
+// monitors which are still pending from a previous round are skipped and the
+// ones that do not fit into the channel are dropped.
+func (a *Aggregator) enqueue(appMonitors []*models.AppMonitor) {
+	pending, dropped := 0, 0
+	for _, monitor := range appMonitors {
+		key := appMonitorKey(monitor)
+		if !a.markPending(key) {
+			pending++
+			droppedAppMonitors.Inc("pending")
+			continue
 		}
+
+		select {
+		case a.appChan <- monitor:
+		default:
+			a.unmarkPending(key)
+			dropped++
+			droppedAppMonitors.Inc("queue_full")
+		}
+	}
+	if pending > 0 || dropped > 0 {
+		a.logger.Info("app-monitors-not-enqueued", lager.Data{"pending": pending, "dropped": dropped})
 	}
 }
+
+// AppMonitorDone is called by the metric pollers after the metric of an app
+// monitor was retrieved so that it can be enqueued again.
+func (a *Aggregator) AppMonitorDone(monitor *models.AppMonitor) {
+	a.unmarkPending(appMonitorKey(monitor))
+}
+
+func (a *Aggregator) markPending(key string) bool {
+	a.pendingLock.Lock()
+	defer a.pendingLock.Unlock()
+	if a.pending[key] {
+		return false
+	}
+	a.pending[key] = true
+	return true
+}
+
+func (a *Aggregator) unmarkPending(key string) {
+	a.pendingLock.Lock()
+	defer a.pendingLock.Unlock()
+	delete(a.pending, key)
+}
+
+func appMonitorKey(monitor *models.AppMonitor) string {
+	return monitor.AppId + "#" + monitor.MetricType
+}
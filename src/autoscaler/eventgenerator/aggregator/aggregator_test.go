@@ -2,7 +2,10 @@ package aggregator_test
 
 import (
 	. "autoscaler/eventgenerator/aggregator"
+	"autoscaler/metrics"
 	"autoscaler/models"
+	"autoscaler/sharding"
+	"fmt"
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
@@ -14,6 +17,7 @@ import (
 var _ = Describe("Aggregator", func() {
 	var (
 		getPolicies      models.GetPolicies
+		shard            sharding.Shard
 		aggregator       *Aggregator
 		clock            *fakeclock.FakeClock
 		logger           lager.Logger
@@ -47,6 +51,8 @@ var _ = Describe("Aggregator", func() {
 			return policyMap
 		}
 
+		shard = sharding.AllApps
+
 		clock = fakeclock.NewFakeClock(time.Now())
 		logger = lager.NewLogger("Aggregator-test")
 
@@ -61,7 +67,8 @@ var _ = Describe("Aggregator", func() {
 	Describe("Start", func() {
 		JustBeforeEach(func() {
 			var err error
-			aggregator, err = NewAggregator(logger, clock, testAggregatorExecuteInterval, appMonitorsChan, getPolicies)
+			aggregator, err = NewAggregator(logger, clock, testAggregatorExecuteInterval, appMonitorsChan, getPolicies,
+				func() sharding.Shard { return shard })
 			Expect(err).NotTo(HaveOccurred())
 			aggregator.Start()
 			Eventually(clock.WatcherCount).Should(Equal(1))
@@ -75,12 +82,131 @@ var _ = Describe("Aggregator", func() {
 			clock.Increment(1 * fakeWaitDuration)
 			Eventually(appMonitorsChan).Should(Receive())
 		})
+
+		Context("when the aggregator is sharded", func() {
+			var ownedApps []string
+
+			BeforeEach(func() {
+				shardedPolicyMap := map[string]*models.AppPolicy{}
+				for i := 0; i < 10; i++ {
+					appId := fmt.Sprintf("app-id-%d", i)
+					shardedPolicyMap[appId] = &models.AppPolicy{
+						AppId:         appId,
+						ScalingPolicy: policyMap[testAppId].ScalingPolicy,
+					}
+				}
+				getPolicies = func() map[string]*models.AppPolicy {
+					return shardedPolicyMap
+				}
+
+				shard = sharding.Shard{Index: 1, Count: 3}
+				ownedApps = []string{}
+				for appId := range shardedPolicyMap {
+					if shard.Owns(appId) {
+						ownedApps = append(ownedApps, appId)
+					}
+				}
+				Expect(ownedApps).NotTo(BeEmpty())
+				Expect(len(ownedApps)).To(BeNumerically("<", len(shardedPolicyMap)))
+			})
+
+			It("should send appMonitors of the apps in its shard only", func() {
+				clock.Increment(1 * fakeWaitDuration)
+
+				monitoredApps := []string{}
+				for range ownedApps {
+					var monitor *models.AppMonitor
+					Eventually(appMonitorsChan).Should(Receive(&monitor))
+					monitoredApps = append(monitoredApps, monitor.AppId)
+				}
+				Expect(monitoredApps).To(ConsistOf(ownedApps))
+				Consistently(appMonitorsChan).ShouldNot(Receive())
+			})
+
+			It("should report the number of assigned apps", func() {
+				clock.Increment(1 * fakeWaitDuration)
+				Eventually(func() float64 {
+					return metrics.DefaultRegistry.Value("autoscaler_eventgenerator_assigned_apps")
+				}).Should(Equal(float64(len(ownedApps))))
+			})
+		})
+
+		Context("when an app monitor is still pending", func() {
+			var monitor *models.AppMonitor
+
+			JustBeforeEach(func() {
+				clock.Increment(1 * fakeWaitDuration)
+				Eventually(appMonitorsChan).Should(Receive(&monitor))
+			})
+
+			It("should not send it again until it is done", func() {
+				dropped := metrics.DefaultRegistry.Value(`autoscaler_eventgenerator_app_monitors_dropped_total{reason="pending"}`)
+				clock.Increment(1 * fakeWaitDuration)
+				Eventually(func() float64 {
+					return metrics.DefaultRegistry.Value(`autoscaler_eventgenerator_app_monitors_dropped_total{reason="pending"}`)
+				}).Should(Equal(dropped + 1))
+				Expect(appMonitorsChan).NotTo(Receive())
+
+				aggregator.AppMonitorDone(monitor)
+				clock.Increment(1 * fakeWaitDuration)
+				Eventually(appMonitorsChan).Should(Receive(Equal(monitor)))
+			})
+		})
+
+		Context("when the appMonitor channel is full", func() {
+			var dropped, skipped float64
+
+			BeforeEach(func() {
+				appMonitorsChan = make(chan *models.AppMonitor, 1)
+				morePolicies := map[string]*models.AppPolicy{}
+				for i := 0; i < 3; i++ {
+					appId := fmt.Sprintf("app-id-%d", i)
+					morePolicies[appId] = &models.AppPolicy{
+						AppId:         appId,
+						ScalingPolicy: policyMap[testAppId].ScalingPolicy,
+					}
+				}
+				getPolicies = func() map[string]*models.AppPolicy {
+					return morePolicies
+				}
+				dropped = metrics.DefaultRegistry.Value(`autoscaler_eventgenerator_app_monitors_dropped_total{reason="queue_full"}`)
+				skipped = metrics.DefaultRegistry.Value("autoscaler_eventgenerator_aggregator_ticks_skipped_total")
+			})
+
+			JustBeforeEach(func() {
+				clock.Increment(1 * fakeWaitDuration)
+				Eventually(func() float64 {
+					return metrics.DefaultRegistry.Value(`autoscaler_eventgenerator_app_monitors_dropped_total{reason="queue_full"}`)
+				}).Should(Equal(dropped + 2))
+			})
+
+			It("should drop the app monitors that do not fit", func() {
+				Expect(appMonitorsChan).To(HaveLen(1))
+			})
+
+			It("should skip the ticks until the channel is drained", func() {
+				for i := 1; i <= MaxSkippedTicks; i++ {
+					clock.Increment(1 * fakeWaitDuration)
+					Eventually(func() float64 {
+						return metrics.DefaultRegistry.Value("autoscaler_eventgenerator_aggregator_ticks_skipped_total")
+					}).Should(Equal(skipped + float64(i)))
+				}
+				Expect(metrics.DefaultRegistry.Value(`autoscaler_eventgenerator_app_monitors_dropped_total{reason="queue_full"}`)).To(Equal(dropped + 2))
+
+				clock.Increment(1 * fakeWaitDuration)
+				Eventually(func() float64 {
+					return metrics.DefaultRegistry.Value(`autoscaler_eventgenerator_app_monitors_dropped_total{reason="queue_full"}`)
+				}).Should(Equal(dropped + 4))
+				Expect(metrics.DefaultRegistry.Value("autoscaler_eventgenerator_aggregator_ticks_skipped_total")).To(Equal(skipped + MaxSkippedTicks))
+			})
+		})
 	})
 
 	Describe("Stop", func() {
 		JustBeforeEach(func() {
 			var err error
-			aggregator, err = NewAggregator(logger, clock, testAggregatorExecuteInterval, appMonitorsChan, getPolicies)
+			aggregator, err = NewAggregator(logger, clock, testAggregatorExecuteInterval, appMonitorsChan, getPolicies,
+				func() sharding.Shard { return shard })
 			Expect(err).NotTo(HaveOccurred())
 			aggregator.Start()
 			Eventually(clock.WatcherCount).Should(Equal(1))
@@ -94,4 +220,27 @@ var _ = Describe("Aggregator", func() {
 			Consistently(appMonitorsChan).ShouldNot(Receive())
 		})
 	})
+
+	Describe("Stop when the appMonitor channel is full", func() {
+		BeforeEach(func() {
+			appMonitorsChan = make(chan *models.AppMonitor)
+		})
+
+		JustBeforeEach(func() {
+			aggregator, _ = NewAggregator(logger, clock, testAggregatorExecuteInterval, appMonitorsChan, getPolicies,
+				func() sharding.Shard { return shard })
+			aggregator.Start()
+			clock.WaitForWatcherAndIncrement(testAggregatorExecuteInterval)
+		})
+
+		It("should not block on sending appMonitors", func() {
+			stopped := make(chan struct{})
+			go func() {
+				aggregator.Stop()
+				close(stopped)
+			}()
+			Eventually(stopped).Should(BeClosed())
+			Expect(appMonitorsChan).NotTo(Receive())
+		})
+	})
 })
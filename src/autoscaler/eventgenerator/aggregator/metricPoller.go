@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -17,43 +18,106 @@ type MetricPoller struct {
 	logger             lager.Logger
 	metricCollectorUrl string
 	doneChan           chan bool
+	drainChan          chan (<-chan struct{})
+	stopOnce           sync.Once
+	wg                 sync.WaitGroup
 	appChan            chan *models.AppMonitor
+	appMonitorDone     func(*models.AppMonitor)
 	httpClient         *http.Client
 	appMetricDB        db.AppMetricDB
+	outlierDetector    *OutlierDetector
 }
 
-func NewMetricPoller(logger lager.Logger, metricCollectorUrl string, appChan chan *models.AppMonitor, httpClient *http.Client, appMetricDB db.AppMetricDB) *MetricPoller {
+func NewMetricPoller(logger lager.Logger, metricCollectorUrl string, appChan chan *models.AppMonitor,
+	appMonitorDone func(*models.AppMonitor), httpClient *http.Client, appMetricDB db.AppMetricDB, outlierDetector *OutlierDetector) *MetricPoller {
 	return &MetricPoller{
 		metricCollectorUrl: metricCollectorUrl,
 		logger:             logger.Session("MetricPoller"),
 		appChan:            appChan,
+		appMonitorDone:     appMonitorDone,
 		doneChan:           make(chan bool),
+		drainChan:          make(chan (<-chan struct{}), 1),
 		httpClient:         httpClient,
 		appMetricDB:        appMetricDB,
+		outlierDetector:    outlierDetector,
 	}
 }
 
 func (m *MetricPoller) Start() {
+	m.wg.Add(1)
 	go m.startMetricRetrieve()
 	m.logger.Info("started")
 }
 
+// Drain makes the poller retrieve the metrics of the app monitors left in the
+// channel and stop once it is empty or deadline is closed. It returns after the
+// poller has stopped, including any retrieval that was in progress.
+func (m *MetricPoller) Drain(deadline <-chan struct{}) {
+	select {
+	case m.drainChan <- deadline:
+	default:
+	}
+	m.wg.Wait()
+	m.logger.Info("drained")
+}
+
+// Stop stops the poller and waits for the retrieval in progress.
 func (m *MetricPoller) Stop() {
-	close(m.doneChan)
+	m.stopOnce.Do(func() {
+		close(m.doneChan)
+	})
+	m.wg.Wait()
 	m.logger.Info("stopped")
 }
 
 func (m *MetricPoller) startMetricRetrieve() {
+	defer m.wg.Done()
+	for {
+		// a pending drain takes precedence over the items in the channel so
+		// that the deadline is honoured
+		select {
+		case deadline := <-m.drainChan:
+			m.drain(deadline)
+			return
+		default:
+		}
+
+		select {
+		case <-m.doneChan:
+			return
+		case deadline := <-m.drainChan:
+			m.drain(deadline)
+			return
+		case app := <-m.appChan:
+			m.process(app)
+		}
+	}
+}
+
+func (m *MetricPoller) drain(deadline <-chan struct{}) {
 	for {
 		select {
 		case <-m.doneChan:
 			return
+		case <-deadline:
+			return
+		default:
+		}
+
+		select {
 		case app := <-m.appChan:
-			m.retrieveMetric(app)
+			m.process(app)
+		default:
+			return
 		}
 	}
 }
 
+func (m *MetricPoller) process(app *models.AppMonitor) {
+	m.retrieveMetric(app)
+	m.appMonitorDone(app)
+}
+
 func (m *MetricPoller) retrieveMetric(app *models.AppMonitor) {
 	appId := app.AppId
 	metricType := app.MetricType
@@ -90,6 +154,10 @@ func (m *MetricPoller) retrieveMetric(app *models.AppMonitor) {
 		return
 	}
 
+	if m.outlierDetector != nil {
+		metrics = m.outlierDetector.Exclude(appId, metricType, metrics)
+	}
+
 	avgMetric := m.aggregate(appId, metricType, metrics)
 	if avgMetric == nil {
 		return
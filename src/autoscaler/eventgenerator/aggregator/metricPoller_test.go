@@ -6,6 +6,7 @@ import (
 	"autoscaler/models"
 	"autoscaler/routes"
 	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
 	"errors"
 	. "github.com/onsi/ginkgo"
@@ -26,6 +27,7 @@ var _ = Describe("MetricPoller", func() {
 	var metricPoller *MetricPoller
 	var httpClient *http.Client
 	var metricServer *ghttp.Server
+	var outlierDetector *OutlierDetector
 	var metrics []*models.AppInstanceMetric = []*models.AppInstanceMetric{
 		&models.AppInstanceMetric{
 			AppId:         testAppId,
@@ -66,12 +68,15 @@ var _ = Describe("MetricPoller", func() {
 		},
 	}
 	var urlPath string
+	var doneMonitors chan *models.AppMonitor
 	BeforeEach(func() {
 		logger = lagertest.NewTestLogger("MetricPoller-test")
 		httpClient = cfhttp.NewClient()
 		appMonitorsChan = make(chan *models.AppMonitor, 1)
 		appMetricDatabase = &fakes.FakeAppMetricDB{}
 		metricServer = nil
+		outlierDetector = nil
+		doneMonitors = make(chan *models.AppMonitor, 10)
 
 		path, err := routes.MetricsCollectorRoutes().Get(routes.MemoryMetricHistoryRoute).URLPath("appid", testAppId)
 		Expect(err).NotTo(HaveOccurred())
@@ -94,7 +99,9 @@ var _ = Describe("MetricPoller", func() {
 		})
 
 		JustBeforeEach(func() {
-			metricPoller = NewMetricPoller(logger, metricServer.URL(), appMonitorsChan, httpClient, appMetricDatabase)
+			metricPoller = NewMetricPoller(logger, metricServer.URL(), appMonitorsChan, func(monitor *models.AppMonitor) {
+				doneMonitors <- monitor
+			}, httpClient, appMetricDatabase, outlierDetector)
 			metricPoller.Start()
 
 			appMonitorsChan <- appMonitor
@@ -133,6 +140,48 @@ var _ = Describe("MetricPoller", func() {
 					Unit:       "bytes",
 					Timestamp:  timestamp}))
 			})
+
+			It("reports the app monitor as done", func() {
+				Eventually(doneMonitors).Should(Receive(Equal(appMonitor)))
+			})
+		})
+
+		Context("when an instance is an outlier", func() {
+			var anomalyStore *AnomalyStore
+
+			BeforeEach(func() {
+				fclock := fakeclock.NewFakeClock(time.Now())
+				anomalyStore = NewAnomalyStore(time.Minute, fclock)
+				outlierDetector = NewOutlierDetector(logger, 3.5, 3, fclock, anomalyStore)
+
+				instanceMetrics := []*models.AppInstanceMetric{}
+				for index, value := range []string{"100", "110", "90", "105", "1000"} {
+					instanceMetrics = append(instanceMetrics, &models.AppInstanceMetric{
+						AppId:         testAppId,
+						InstanceIndex: uint32(index),
+						CollectedAt:   111111,
+						Name:          metricType,
+						Unit:          models.UnitBytes,
+						Value:         value,
+						Timestamp:     111100,
+					})
+				}
+				metricServer.RouteToHandler("GET", urlPath, ghttp.RespondWithJSONEncoded(http.StatusOK,
+					&instanceMetrics))
+			})
+
+			It("saves the average metrics without the outlier", func() {
+				Eventually(appMetricDatabase.SaveAppMetricCallCount).Should(Equal(1))
+				Expect(*appMetricDatabase.SaveAppMetricArgsForCall(0).Value).To(Equal(int64(101)))
+			})
+
+			It("records the outlier", func() {
+				Eventually(appMetricDatabase.SaveAppMetricCallCount).Should(Equal(1))
+				instances := anomalyStore.GetAnomalousInstances(testAppId)
+				Expect(instances).To(HaveLen(1))
+				Expect(instances[0].InstanceIndex).To(Equal(uint32(4)))
+				Expect(instances[0].Value).To(Equal(int64(1000)))
+			})
 		})
 
 		Context("when the metrics are not valid JSON", func() {
@@ -145,6 +194,10 @@ var _ = Describe("MetricPoller", func() {
 				Eventually(logger.Buffer).Should(Say("Failed to parse response"))
 			})
 
+			It("reports the app monitor as done", func() {
+				Eventually(doneMonitors).Should(Receive(Equal(appMonitor)))
+			})
+
 			It("does not save any metrics", func() {
 				Consistently(appMetricDatabase.SaveAppMetricCallCount).Should(BeZero())
 			})
@@ -206,7 +259,9 @@ var _ = Describe("MetricPoller", func() {
 			metricServer.RouteToHandler("GET", urlPath, ghttp.RespondWithJSONEncoded(http.StatusOK,
 				&metrics))
 
-			metricPoller = NewMetricPoller(logger, metricServer.URL(), appMonitorsChan, httpClient, appMetricDatabase)
+			metricPoller = NewMetricPoller(logger, metricServer.URL(), appMonitorsChan, func(monitor *models.AppMonitor) {
+				doneMonitors <- monitor
+			}, httpClient, appMetricDatabase, outlierDetector)
 			metricPoller.Start()
 			metricPoller.Stop()
 
@@ -221,4 +276,102 @@ var _ = Describe("MetricPoller", func() {
 			Consistently(appMetricDatabase.SaveAppMetricCallCount).Should(Or(Equal(0), Equal(1)))
 		})
 	})
+
+	Context("when a retrieval is in progress", func() {
+		var (
+			appMonitor *models.AppMonitor
+			release    chan struct{}
+		)
+
+		BeforeEach(func() {
+			appMonitor = &models.AppMonitor{
+				AppId:      testAppId,
+				MetricType: metricType,
+				StatWindow: 10,
+			}
+			release = make(chan struct{})
+
+			metricServer = ghttp.NewServer()
+			metricServer.RouteToHandler("GET", urlPath, ghttp.CombineHandlers(
+				func(w http.ResponseWriter, req *http.Request) {
+					<-release
+				},
+				ghttp.RespondWithJSONEncoded(http.StatusOK, &metrics),
+			))
+
+			metricPoller = NewMetricPoller(logger, metricServer.URL(), appMonitorsChan, func(monitor *models.AppMonitor) {
+				doneMonitors <- monitor
+			}, httpClient, appMetricDatabase, outlierDetector)
+			metricPoller.Start()
+
+			appMonitorsChan <- appMonitor
+			Eventually(metricServer.ReceivedRequests).Should(HaveLen(1))
+		})
+
+		AfterEach(func() {
+			metricServer.Close()
+		})
+
+		Context("Drain", func() {
+			var (
+				deadline chan struct{}
+				drained  chan struct{}
+			)
+
+			BeforeEach(func() {
+				deadline = make(chan struct{})
+				drained = make(chan struct{})
+				appMonitorsChan <- appMonitor
+			})
+
+			JustBeforeEach(func() {
+				go func() {
+					metricPoller.Drain(deadline)
+					close(drained)
+				}()
+			})
+
+			It("retrieves the metrics of the app monitors left in the channel before stopping", func() {
+				Consistently(drained).ShouldNot(BeClosed())
+				close(release)
+
+				Eventually(drained).Should(BeClosed())
+				Expect(appMetricDatabase.SaveAppMetricCallCount()).To(Equal(2))
+				Expect(appMonitorsChan).To(BeEmpty())
+				Expect(logger.Buffer()).To(Say("drained"))
+
+				appMonitorsChan <- appMonitor
+				Consistently(appMonitorsChan).Should(HaveLen(1))
+			})
+
+			Context("when the deadline has passed", func() {
+				BeforeEach(func() {
+					close(deadline)
+				})
+
+				It("leaves the app monitors in the channel", func() {
+					close(release)
+
+					Eventually(drained).Should(BeClosed())
+					Expect(appMetricDatabase.SaveAppMetricCallCount()).To(Equal(1))
+					Expect(appMonitorsChan).To(HaveLen(1))
+				})
+			})
+		})
+
+		Context("Stop", func() {
+			It("waits for the retrieval to finish", func() {
+				stopped := make(chan struct{})
+				go func() {
+					metricPoller.Stop()
+					close(stopped)
+				}()
+				Consistently(stopped).ShouldNot(BeClosed())
+
+				close(release)
+				Eventually(stopped).Should(BeClosed())
+				Expect(appMetricDatabase.SaveAppMetricCallCount()).To(Equal(1))
+			})
+		})
+	})
 })
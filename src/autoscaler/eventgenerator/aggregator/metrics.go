@@ -0,0 +1,29 @@
+package aggregator
+
+import (
+	"autoscaler/metrics"
+)
+
+var (
+	assignedApps = metrics.NewGauge(
+		"autoscaler_eventgenerator_assigned_apps",
+		"Number of apps with a policy assigned to the shard of this eventgenerator.",
+	)
+	droppedAppMonitors = metrics.NewCounter(
+		"autoscaler_eventgenerator_app_monitors_dropped_total",
+		"Number of app monitors not enqueued for the metric pollers, by reason.",
+		"reason",
+	)
+	skippedAggregatorTicks = metrics.NewCounter(
+		"autoscaler_eventgenerator_aggregator_ticks_skipped_total",
+		"Number of aggregator ticks skipped because the app monitors of the previous round were not drained.",
+	)
+	anomalousInstances = metrics.NewCounter(
+		"autoscaler_eventgenerator_anomalous_instances_total",
+		"Number of instance metric series excluded from the aggregation as outliers.",
+	)
+)
+
+func init() {
+	metrics.MustRegister(assignedApps, droppedAppMonitors, skippedAggregatorTicks, anomalousInstances)
+}
@@ -0,0 +1,197 @@
+package aggregator
+
+import (
+	"autoscaler/models"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+// madScale makes the median absolute deviation comparable to the standard
+// deviation of normally distributed values.
+const madScale = 0.6745
+
+// OutlierDetector finds the instances of an app whose mean metric over the stat
+// window deviates from the other instances by more than Threshold, measured as
+// the modified z-score based on the median absolute deviation. Apps with less
+// than MinInstances instances are not checked.
+type OutlierDetector struct {
+	logger       lager.Logger
+	threshold    float64
+	minInstances int
+	clock        clock.Clock
+	store        *AnomalyStore
+}
+
+func NewOutlierDetector(logger lager.Logger, threshold float64, minInstances int, clock clock.Clock, store *AnomalyStore) *OutlierDetector {
+	return &OutlierDetector{
+		logger:       logger.Session("OutlierDetector"),
+		threshold:    threshold,
+		minInstances: minInstances,
+		clock:        clock,
+		store:        store,
+	}
+}
+
+// Exclude returns the metrics without the ones of the anomalous instances, and
+// records the anomalous instances of the app for the metric type, replacing the
+// ones recorded before. Values that cannot be parsed are kept for the
+// aggregation to report them.
+func (d *OutlierDetector) Exclude(appId string, metricType string, metrics []*models.AppInstanceMetric) []*models.AppInstanceMetric {
+	sums := map[uint32]int64{}
+	counts := map[uint32]int64{}
+	for _, metric := range metrics {
+		value, err := strconv.ParseInt(metric.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		sums[metric.InstanceIndex] += value
+		counts[metric.InstanceIndex]++
+	}
+
+	now := d.clock.Now().UnixNano()
+	anomalies := d.detect(appId, metricType, sums, counts, now)
+	d.store.Set(appId, metricType, anomalies, now)
+	if len(anomalies) == 0 {
+		return metrics
+	}
+
+	excluded := map[uint32]bool{}
+	for _, anomaly := range anomalies {
+		excluded[anomaly.InstanceIndex] = true
+	}
+	anomalousInstances.Add(float64(len(anomalies)))
+	d.logger.Info("anomalous-instances", lager.Data{"appId": appId, "metricType": metricType, "instances": anomalies})
+
+	kept := make([]*models.AppInstanceMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		if !excluded[metric.InstanceIndex] {
+			kept = append(kept, metric)
+		}
+	}
+	return kept
+}
+
+func (d *OutlierDetector) detect(appId string, metricType string, sums map[uint32]int64, counts map[uint32]int64, now int64) []*models.AnomalousInstance {
+	if len(sums) < d.minInstances {
+		return nil
+	}
+
+	means := make(map[uint32]int64, len(sums))
+	values := make([]float64, 0, len(sums))
+	for index, sum := range sums {
+		means[index] = sum / counts[index]
+		values = append(values, float64(means[index]))
+	}
+	median := medianOf(values)
+
+	deviations := make([]float64, 0, len(values))
+	for _, value := range values {
+		deviations = append(deviations, math.Abs(value-median))
+	}
+	mad := medianOf(deviations)
+	if mad == 0 {
+		// more than half of the instances have the same value, any other one
+		// would get an infinite score
+		return nil
+	}
+
+	anomalies := []*models.AnomalousInstance{}
+	for index, mean := range means {
+		score := madScale * (float64(mean) - median) / mad
+		if math.Abs(score) > d.threshold {
+			anomalies = append(anomalies, &models.AnomalousInstance{
+				AppId:         appId,
+				InstanceIndex: index,
+				MetricType:    metricType,
+				Value:         mean,
+				Median:        int64(median),
+				Score:         score,
+				Timestamp:     now,
+			})
+		}
+	}
+	sort.Slice(anomalies, func(i, j int) bool { return anomalies[i].InstanceIndex < anomalies[j].InstanceIndex })
+	return anomalies
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+type anomalyRecord struct {
+	instances []*models.AnomalousInstance
+	updatedAt int64
+}
+
+// AnomalyStore keeps the anomalous instances last detected for each app and
+// metric type. Records not updated within maxAge are dropped, as the app is no
+// longer checked by this eventgenerator.
+type AnomalyStore struct {
+	maxAge  time.Duration
+	clock   clock.Clock
+	lock    sync.Mutex
+	records map[string]map[string]*anomalyRecord
+}
+
+func NewAnomalyStore(maxAge time.Duration, clock clock.Clock) *AnomalyStore {
+	return &AnomalyStore{
+		maxAge:  maxAge,
+		clock:   clock,
+		records: map[string]map[string]*anomalyRecord{},
+	}
+}
+
+func (s *AnomalyStore) Set(appId string, metricType string, instances []*models.AnomalousInstance, updatedAt int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(instances) == 0 {
+		delete(s.records[appId], metricType)
+		if len(s.records[appId]) == 0 {
+			delete(s.records, appId)
+		}
+		return
+	}
+	if s.records[appId] == nil {
+		s.records[appId] = map[string]*anomalyRecord{}
+	}
+	s.records[appId][metricType] = &anomalyRecord{instances: instances, updatedAt: updatedAt}
+}
+
+// GetAnomalousInstances returns the anomalous instances of the app ordered by
+// metric type and instance index.
+func (s *AnomalyStore) GetAnomalousInstances(appId string) []*models.AnomalousInstance {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	expiredBefore := s.clock.Now().Add(-s.maxAge).UnixNano()
+	metricTypes := []string{}
+	for metricType, record := range s.records[appId] {
+		if record.updatedAt < expiredBefore {
+			delete(s.records[appId], metricType)
+			continue
+		}
+		metricTypes = append(metricTypes, metricType)
+	}
+	if len(s.records[appId]) == 0 {
+		delete(s.records, appId)
+	}
+	sort.Strings(metricTypes)
+
+	instances := []*models.AnomalousInstance{}
+	for _, metricType := range metricTypes {
+		instances = append(instances, s.records[appId][metricType].instances...)
+	}
+	return instances
+}
@@ -0,0 +1,142 @@
+package aggregator_test
+
+import (
+	. "autoscaler/eventgenerator/aggregator"
+	"autoscaler/models"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OutlierDetector", func() {
+	var (
+		testAppId  string = "testAppId"
+		metricType string = "MemoryUsage"
+		fclock     *fakeclock.FakeClock
+		store      *AnomalyStore
+		detector   *OutlierDetector
+		metrics    []*models.AppInstanceMetric
+		result     []*models.AppInstanceMetric
+	)
+
+	instanceMetrics := func(values ...string) []*models.AppInstanceMetric {
+		instanceMetrics := []*models.AppInstanceMetric{}
+		for index, value := range values {
+			instanceMetrics = append(instanceMetrics, &models.AppInstanceMetric{
+				AppId:         testAppId,
+				InstanceIndex: uint32(index),
+				Name:          metricType,
+				Unit:          models.UnitBytes,
+				Value:         value,
+			})
+		}
+		return instanceMetrics
+	}
+
+	BeforeEach(func() {
+		fclock = fakeclock.NewFakeClock(time.Now())
+		store = NewAnomalyStore(time.Minute, fclock)
+		detector = NewOutlierDetector(lagertest.NewTestLogger("OutlierDetector-test"), 3.5, 3, fclock, store)
+	})
+
+	JustBeforeEach(func() {
+		result = detector.Exclude(testAppId, metricType, metrics)
+	})
+
+	Context("when no instance deviates", func() {
+		BeforeEach(func() {
+			metrics = instanceMetrics("100", "110", "90", "105")
+		})
+
+		It("keeps all the metrics", func() {
+			Expect(result).To(Equal(metrics))
+			Expect(store.GetAnomalousInstances(testAppId)).To(BeEmpty())
+		})
+	})
+
+	Context("when an instance deviates", func() {
+		BeforeEach(func() {
+			metrics = instanceMetrics("100", "110", "90", "105", "1000")
+		})
+
+		It("excludes the metrics of the instance", func() {
+			Expect(result).To(Equal(metrics[0:4]))
+		})
+
+		It("records the instance", func() {
+			instances := store.GetAnomalousInstances(testAppId)
+			Expect(instances).To(HaveLen(1))
+			Expect(instances[0].AppId).To(Equal(testAppId))
+			Expect(instances[0].MetricType).To(Equal(metricType))
+			Expect(instances[0].InstanceIndex).To(Equal(uint32(4)))
+			Expect(instances[0].Value).To(Equal(int64(1000)))
+			Expect(instances[0].Median).To(Equal(int64(105)))
+			Expect(instances[0].Score).To(BeNumerically("~", 0.6745*895/5, 0.001))
+			Expect(instances[0].Timestamp).To(Equal(fclock.Now().UnixNano()))
+		})
+
+		Context("when the instance no longer deviates", func() {
+			It("clears the record", func() {
+				detector.Exclude(testAppId, metricType, instanceMetrics("100", "110", "90", "105", "95"))
+				Expect(store.GetAnomalousInstances(testAppId)).To(BeEmpty())
+			})
+		})
+
+		Context("when the record is not updated within the max age", func() {
+			It("drops the record", func() {
+				fclock.Increment(2 * time.Minute)
+				Expect(store.GetAnomalousInstances(testAppId)).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when an instance has several samples", func() {
+		BeforeEach(func() {
+			metrics = append(instanceMetrics("100", "110", "90", "105", "100"), instanceMetrics("100", "110", "90", "105", "1900")...)
+		})
+
+		It("compares the mean of the samples", func() {
+			Expect(result).To(HaveLen(8))
+			instances := store.GetAnomalousInstances(testAppId)
+			Expect(instances).To(HaveLen(1))
+			Expect(instances[0].InstanceIndex).To(Equal(uint32(4)))
+			Expect(instances[0].Value).To(Equal(int64(1000)))
+		})
+	})
+
+	Context("when there are less instances than the minimum", func() {
+		BeforeEach(func() {
+			metrics = instanceMetrics("100", "1000")
+		})
+
+		It("keeps all the metrics", func() {
+			Expect(result).To(Equal(metrics))
+			Expect(store.GetAnomalousInstances(testAppId)).To(BeEmpty())
+		})
+	})
+
+	Context("when most of the instances have the same value", func() {
+		BeforeEach(func() {
+			metrics = instanceMetrics("100", "100", "100", "1000")
+		})
+
+		It("keeps all the metrics", func() {
+			Expect(result).To(Equal(metrics))
+			Expect(store.GetAnomalousInstances(testAppId)).To(BeEmpty())
+		})
+	})
+
+	Context("when a value cannot be parsed", func() {
+		BeforeEach(func() {
+			metrics = instanceMetrics("100", "110", "90", "105", "1000", "garbage")
+		})
+
+		It("keeps the metric for the aggregation", func() {
+			Expect(result).To(HaveLen(5))
+			Expect(result[4].Value).To(Equal("garbage"))
+		})
+	})
+})
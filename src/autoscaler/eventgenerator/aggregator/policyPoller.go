@@ -12,6 +12,18 @@ import (
 
 type Consumer func(map[string]*models.AppPolicy, chan *models.AppMonitor)
 
+// VersionSafetyWindow is how many versions below the highest one seen are
+// retrieved again every cycle. Versions are taken from a sequence when a change
+// is written, so a change can commit after a higher version was already seen;
+// it is picked up by the next cycle as long as fewer versions were taken while
+// it was in flight.
+const VersionSafetyWindow = 1000
+
+// FullReloadCycles is the number of cycles after which all the policies are
+// retrieved instead of the changed ones only, as a backstop for the changes
+// that fall out of the VersionSafetyWindow.
+const FullReloadCycles = 15
+
 type PolicyPoller struct {
 	logger    lager.Logger
 	interval  time.Duration
@@ -19,7 +31,11 @@ type PolicyPoller struct {
 	clock     clock.Clock
 	doneChan  chan bool
 	policyMap map[string]*models.AppPolicy
+	versions  map[string]int64
+	version   int64
+	cycles    int
 	lock      sync.Mutex
+	lastTick  time.Time
 }
 
 func NewPolicyPoller(logger lager.Logger, clock clock.Clock, interval time.Duration, database db.PolicyDB) *PolicyPoller {
@@ -30,6 +46,7 @@ func NewPolicyPoller(logger lager.Logger, clock clock.Clock, interval time.Durat
 		database:  database,
 		doneChan:  make(chan bool),
 		policyMap: make(map[string]*models.AppPolicy),
+		versions:  make(map[string]int64),
 	}
 }
 func (p *PolicyPoller) GetPolicies() map[string]*models.AppPolicy {
@@ -37,6 +54,12 @@ func (p *PolicyPoller) GetPolicies() map[string]*models.AppPolicy {
 	defer p.lock.Unlock()
 	return p.policyMap
 }
+func (p *PolicyPoller) LastTickTime() time.Time {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.lastTick
+}
+
 func (p *PolicyPoller) Start() {
 	go p.startPolicyRetrieve()
 	p.logger.Info("started", lager.Data{"interval": p.interval})
@@ -51,40 +74,134 @@ func (p *PolicyPoller) startPolicyRetrieve() {
 	tick := p.clock.NewTicker(p.interval)
 	defer tick.Stop()
 
+	changes, err := p.database.SubscribePolicyChanges()
+	if err != nil {
+		p.logger.Error("subscribe-policy-changes", err, lager.Data{"interval": p.interval})
+	}
+
 	for {
-		policyJsons, err := p.retrievePolicies()
-		if err != nil {
-			continue
-		}
-		policies := p.computePolicies(policyJsons)
 		p.lock.Lock()
-		p.policyMap = policies
+		p.lastTick = p.clock.Now()
 		p.lock.Unlock()
+
+		p.refreshPolicies()
 		select {
 		case <-p.doneChan:
 			return
 		case <-tick.C():
+		case appId, ok := <-changes:
+			if !ok {
+				p.logger.Info("policy-changes-closed")
+				changes = nil
+				continue
+			}
+			pending := drainPolicyChanges(changes)
+			p.logger.Debug("policy-changed", lager.Data{"appId": appId, "pending": pending})
+		}
+	}
+}
 
+// drainPolicyChanges discards the changes queued meanwhile as they are all
+// covered by the next retrieval.
+func drainPolicyChanges(changes <-chan string) int {
+	for drained := 0; ; drained++ {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return drained
+			}
+		default:
+			return drained
 		}
 	}
 }
 
-func (p *PolicyPoller) retrievePolicies() ([]*models.PolicyJson, error) {
-	policyJsons, err := p.database.RetrievePolicies()
+// refreshPolicies retrieves the policies changed since the VersionSafetyWindow
+// below the last version seen, or all of them every FullReloadCycles cycles,
+// and applies them to a copy of the policy map as the current one is shared
+// with its readers.
+func (p *PolicyPoller) refreshPolicies() {
+	full := p.cycles%FullReloadCycles == 0
+	since := p.version - VersionSafetyWindow
+	if full || since < 0 {
+		since = 0
+	}
+
+	policyJsons, err := p.database.RetrievePoliciesSince(since)
 	if err != nil {
-		p.logger.Error("retrieve policyJsons", err)
-		return nil, err
+		p.logger.Error("retrieve-policies", err, lager.Data{"since": since})
+		return
 	}
-	p.logger.Info("policy count", lager.Data{"count": len(policyJsons)})
-	return policyJsons, nil
+	p.cycles++
+	for _, policyJson := range policyJsons {
+		if policyJson.Version > p.version {
+			p.version = policyJson.Version
+		}
+	}
+
+	if !full {
+		policyJsons = p.unappliedChanges(policyJsons)
+		if len(policyJsons) == 0 {
+			return
+		}
+	}
+
+	policies := p.applyChanges(policyJsons, full)
+	p.lock.Lock()
+	p.policyMap = policies
+	p.lock.Unlock()
+}
+
+// unappliedChanges leaves out the policies retrieved again within the
+// VersionSafetyWindow whose version is already applied.
+func (p *PolicyPoller) unappliedChanges(policyJsons []*models.PolicyJson) []*models.PolicyJson {
+	changes := []*models.PolicyJson{}
+	for _, policyJson := range policyJsons {
+		version, exists := p.versions[policyJson.AppId]
+		if policyJson.Deleted && !exists {
+			continue
+		}
+		if !policyJson.Deleted && exists && version == policyJson.Version {
+			continue
+		}
+		changes = append(changes, policyJson)
+	}
+	return changes
 }
 
-func (p *PolicyPoller) computePolicies(policyJsons []*models.PolicyJson) map[string]*models.AppPolicy {
-	policyMap := make(map[string]*models.AppPolicy)
-	for _, policyRow := range policyJsons {
-		tmpPolicy := policyRow.GetAppPolicy()
-		policyMap[policyRow.AppId] = tmpPolicy
+// applyChanges builds the new policy map. Policies whose version did not change
+// are taken over without parsing them again.
+func (p *PolicyPoller) applyChanges(policyJsons []*models.PolicyJson, full bool) map[string]*models.AppPolicy {
+	current := p.GetPolicies()
+	policyMap := make(map[string]*models.AppPolicy, len(current))
+	versions := make(map[string]int64, len(p.versions))
+	if !full {
+		for appId, policy := range current {
+			policyMap[appId] = policy
+			versions[appId] = p.versions[appId]
+		}
+	}
+
+	parsed := 0
+	for _, policyJson := range policyJsons {
+		appId := policyJson.AppId
+		if policyJson.Deleted {
+			delete(policyMap, appId)
+			delete(versions, appId)
+			continue
+		}
+
+		policy, exists := current[appId]
+		if !exists || p.versions[appId] != policyJson.Version {
+			policy = policyJson.GetAppPolicy()
+			parsed++
+		}
+		policyMap[appId] = policy
+		versions[appId] = policyJson.Version
 	}
-	p.logger.Info("policy count", lager.Data{"count": len(policyMap)})
+	p.versions = versions
+
+	p.logger.Info("policies-refreshed", lager.Data{"full": full, "changes": len(policyJsons), "parsed": parsed,
+		"count": len(policyMap), "version": p.version})
 	return policyMap
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"sync"
 	"time"
 )
 
@@ -56,28 +57,34 @@ var _ = Describe("PolicyPoller", func() {
 
 		Context("when the poller is started", func() {
 			BeforeEach(func() {
-				database.RetrievePoliciesStub = func() ([]*models.PolicyJson, error) {
-					return []*models.PolicyJson{&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1}}, nil
+				database.RetrievePoliciesSinceStub = func(version int64) ([]*models.PolicyJson, error) {
+					return []*models.PolicyJson{&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1, Version: 1}}, nil
 				}
 
 			})
 			It("should retrieve policies for every interval", func() {
-				Eventually(database.RetrievePoliciesCallCount).Should(Equal(1))
+				Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(1))
 				clock.Increment(2 * testPolicyPollerInterval * time.Second)
-				Eventually(database.RetrievePoliciesCallCount).Should(BeNumerically(">=", 2))
+				Eventually(database.RetrievePoliciesSinceCallCount).Should(BeNumerically(">=", 2))
+			})
+
+			It("records the time of the last poll", func() {
+				Eventually(poller.LastTickTime).Should(Equal(clock.Now()))
+				clock.Increment(testPolicyPollerInterval * time.Second)
+				Eventually(poller.LastTickTime).Should(Equal(clock.Now()))
 			})
 
 			Context("when retrieve policies and compute triggers successfully", func() {
 				BeforeEach(func() {
-					database.RetrievePoliciesStub = func() ([]*models.PolicyJson, error) {
-						return []*models.PolicyJson{&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1}}, nil
+					database.RetrievePoliciesSinceStub = func(version int64) ([]*models.PolicyJson, error) {
+						return []*models.PolicyJson{&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1, Version: 1}}, nil
 					}
 				})
 				It("should call the consumer with the new triggers for every interval", func() {
 					Eventually(clock.WatcherCount).Should(Equal(1))
 					clock.Increment(1 * testPolicyPollerInterval)
 					clock.Increment(1 * testPolicyPollerInterval)
-					Eventually(database.RetrievePoliciesCallCount).Should(BeNumerically(">=", 2))
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(BeNumerically(">=", 2))
 					policyMap := poller.GetPolicies()
 					Expect(policyMap[testAppId1]).To(Equal(&models.AppPolicy{
 						AppId: testAppId1,
@@ -98,7 +105,7 @@ var _ = Describe("PolicyPoller", func() {
 			})
 			Context("when return error when retrieve policies from database", func() {
 				BeforeEach(func() {
-					database.RetrievePoliciesStub = func() ([]*models.PolicyJson, error) {
+					database.RetrievePoliciesSinceStub = func(version int64) ([]*models.PolicyJson, error) {
 						return nil, errors.New("error when retrieve policies from database")
 					}
 				})
@@ -108,6 +115,136 @@ var _ = Describe("PolicyPoller", func() {
 					Expect(len(policyMap)).To(Equal(0))
 				})
 			})
+
+			Context("when only some of the policies change", func() {
+				var (
+					tableLock sync.Mutex
+					table     []*models.PolicyJson
+				)
+
+				setTable := func(rows ...*models.PolicyJson) {
+					tableLock.Lock()
+					defer tableLock.Unlock()
+					table = rows
+				}
+
+				BeforeEach(func() {
+					setTable(
+						&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1, Version: 5001},
+						&models.PolicyJson{AppId: "another-app-id", PolicyStr: policyStr1, Version: 5002},
+					)
+					database.RetrievePoliciesSinceStub = func(version int64) ([]*models.PolicyJson, error) {
+						tableLock.Lock()
+						defer tableLock.Unlock()
+						rows := []*models.PolicyJson{}
+						for _, row := range table {
+							if row.Version > version {
+								rows = append(rows, row)
+							}
+						}
+						return rows, nil
+					}
+				})
+
+				It("should apply the changes within the safety window below the last retrieved version", func() {
+					Eventually(poller.GetPolicies).Should(HaveLen(2))
+					cached := poller.GetPolicies()[testAppId1]
+
+					setTable(
+						&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1, Version: 5001},
+						&models.PolicyJson{AppId: "another-app-id", Version: 5003, Deleted: true},
+						&models.PolicyJson{AppId: "new-app-id", PolicyStr: policyStr1, Version: 5004},
+					)
+					clock.Increment(testPolicyPollerInterval)
+					Eventually(poller.GetPolicies).Should(And(HaveKey(testAppId1), HaveKey("new-app-id"), Not(HaveKey("another-app-id"))))
+					Expect(database.RetrievePoliciesSinceArgsForCall(1)).To(Equal(int64(5002 - VersionSafetyWindow)))
+					Expect(poller.GetPolicies()[testAppId1]).To(BeIdenticalTo(cached))
+
+					clock.Increment(testPolicyPollerInterval)
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(3))
+					Expect(database.RetrievePoliciesSinceArgsForCall(2)).To(Equal(int64(5004 - VersionSafetyWindow)))
+				})
+
+				It("should apply a change committed after a higher version was retrieved", func() {
+					Eventually(poller.GetPolicies).Should(HaveLen(2))
+
+					setTable(
+						&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1, Version: 5001},
+						&models.PolicyJson{AppId: "another-app-id", PolicyStr: policyStr1, Version: 5002},
+						&models.PolicyJson{AppId: "new-app-id", PolicyStr: policyStr1, Version: 5004},
+					)
+					clock.Increment(testPolicyPollerInterval)
+					Eventually(poller.GetPolicies).Should(HaveKey("new-app-id"))
+
+					setTable(
+						&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1, Version: 5001},
+						&models.PolicyJson{AppId: "another-app-id", PolicyStr: policyStr1, Version: 5002},
+						&models.PolicyJson{AppId: "late-app-id", PolicyStr: policyStr1, Version: 5003},
+						&models.PolicyJson{AppId: "new-app-id", PolicyStr: policyStr1, Version: 5004},
+					)
+					clock.Increment(testPolicyPollerInterval)
+					Eventually(poller.GetPolicies).Should(HaveKey("late-app-id"))
+					Expect(poller.GetPolicies()).To(HaveLen(4))
+				})
+
+				It("should retrieve all the policies every FullReloadCycles cycles", func() {
+					Eventually(poller.GetPolicies).Should(HaveLen(2))
+					cached := poller.GetPolicies()[testAppId1]
+
+					setTable(&models.PolicyJson{AppId: testAppId1, PolicyStr: policyStr1, Version: 5001})
+					for i := 1; i < FullReloadCycles; i++ {
+						Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(i))
+						clock.Increment(testPolicyPollerInterval)
+					}
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(FullReloadCycles))
+					Expect(poller.GetPolicies()).To(HaveKey("another-app-id"))
+
+					clock.Increment(testPolicyPollerInterval)
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(FullReloadCycles + 1))
+					Expect(database.RetrievePoliciesSinceArgsForCall(FullReloadCycles)).To(Equal(int64(0)))
+
+					Eventually(poller.GetPolicies).ShouldNot(HaveKey("another-app-id"))
+					Expect(poller.GetPolicies()[testAppId1]).To(BeIdenticalTo(cached))
+				})
+			})
+
+			Context("when policy changes are notified", func() {
+				var changes chan string
+
+				BeforeEach(func() {
+					changes = make(chan string, 10)
+					database.SubscribePolicyChangesReturns(changes, nil)
+				})
+
+				It("should retrieve the policies without waiting for the interval", func() {
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(1))
+
+					changes <- testAppId1
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(2))
+					Consistently(database.RetrievePoliciesSinceCallCount).Should(Equal(2))
+				})
+
+				It("should keep polling when the changes are closed", func() {
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(1))
+					close(changes)
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(2))
+
+					clock.Increment(testPolicyPollerInterval)
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(3))
+				})
+			})
+
+			Context("when subscribing to policy changes fails", func() {
+				BeforeEach(func() {
+					database.SubscribePolicyChangesReturns(nil, errors.New("an error"))
+				})
+
+				It("should keep polling", func() {
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(1))
+					clock.Increment(testPolicyPollerInterval)
+					Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(2))
+				})
+			})
 		})
 	})
 
@@ -115,14 +252,14 @@ var _ = Describe("PolicyPoller", func() {
 		BeforeEach(func() {
 			poller = NewPolicyPoller(logger, clock, testPolicyPollerInterval, database)
 			poller.Start()
-			Eventually(database.RetrievePoliciesCallCount).Should(Equal(1))
+			Eventually(database.RetrievePoliciesSinceCallCount).Should(Equal(1))
 
 			poller.Stop()
 		})
 
 		It("stops the polling", func() {
 			clock.Increment(5 * testPolicyPollerInterval)
-			Consistently(database.RetrievePoliciesCallCount).Should(Or(Equal(1), Equal(2)))
+			Consistently(database.RetrievePoliciesSinceCallCount).Should(Or(Equal(1), Equal(2)))
 		})
 	})
 })
@@ -7,6 +7,7 @@ import (
 	"autoscaler/db"
 	"autoscaler/eventgenerator/config"
 	"autoscaler/models"
+	"autoscaler/sharding"
 	"database/sql"
 	"io/ioutil"
 	"net/http"
@@ -200,6 +201,7 @@ func initConfig() {
 				CACertFile: filepath.Join(testCertDir, "autoscaler-ca.crt"),
 			},
 		},
+		Sharding: sharding.DefaultShardingConfig,
 	}
 	configFile = writeConfig(conf)
 }
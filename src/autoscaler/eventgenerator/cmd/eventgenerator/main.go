@@ -6,12 +6,17 @@ import (
 	"autoscaler/eventgenerator/aggregator"
 	"autoscaler/eventgenerator/config"
 	"autoscaler/eventgenerator/generator"
+	"autoscaler/eventgenerator/server"
+	"autoscaler/healthendpoint"
+	"autoscaler/metrics"
 	"autoscaler/models"
+	"autoscaler/sharding"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"sync"
 
 	"code.cloudfoundry.org/cfhttp"
 	"code.cloudfoundry.org/clock"
@@ -56,30 +61,79 @@ func main() {
 
 	policyPoller := aggregator.NewPolicyPoller(logger, egClock, conf.Aggregator.PolicyPollerInterval, policyDB)
 
+	var shardDB db.ShardDB
+	var membership *sharding.Membership
+	getShard := func() sharding.Shard {
+		return sharding.Shard{Index: conf.Sharding.Index, Count: conf.Sharding.Count}
+	}
+	if conf.Sharding.Discovery {
+		shardDB, err = sqldb.NewShardSQLDB(conf.DB.AppMetricDBUrl, logger.Session("shard-db"))
+		if err != nil {
+			logger.Error("failed to connect shard database", err, lager.Data{"url": conf.DB.AppMetricDBUrl})
+			os.Exit(1)
+		}
+		defer shardDB.Close()
+
+		memberId := conf.Sharding.MemberId
+		if memberId == "" {
+			memberId, err = os.Hostname()
+			if err != nil {
+				logger.Error("failed to get hostname as sharding member id", err)
+				os.Exit(1)
+			}
+		}
+		membership = sharding.NewMembership(logger.Session("membership"), shardDB, "eventgenerator", memberId,
+			conf.Sharding.HeartbeatInterval, conf.Sharding.MemberTTL, egClock)
+		getShard = membership.Shard
+	}
+
 	triggersChan := make(chan []*models.Trigger, conf.Evaluator.TriggerArrayChannelSize)
-	evaluators, err := createEvaluators(logger, conf, triggersChan, appMetricDB)
+	evaluationManager, err := generator.NewAppEvaluationManager(logger, conf.Evaluator.EvaluationManagerInterval, egClock,
+		triggersChan, policyPoller.GetPolicies, getShard)
 	if err != nil {
-		logger.Error("failed to create Evaluators", err)
+		logger.Error("failed to create Evaluation Manager", err)
 		os.Exit(1)
 	}
 
-	evaluationManager, err := generator.NewAppEvaluationManager(logger, conf.Evaluator.EvaluationManagerInterval, egClock,
-		triggersChan, policyPoller.GetPolicies)
+	evaluators, err := createEvaluators(logger, conf, triggersChan, evaluationManager.TriggersDone, appMetricDB)
 	if err != nil {
-		logger.Error("failed to create Evaluation Manager", err)
+		logger.Error("failed to create Evaluators", err)
 		os.Exit(1)
 	}
 
+	// anomalies of apps no longer polled by this eventgenerator expire after a
+	// few aggregation rounds
+	anomalyStore := aggregator.NewAnomalyStore(3*conf.Aggregator.AggregatorExecuteInterval, egClock)
+	var outlierDetector *aggregator.OutlierDetector
+	if conf.Aggregator.OutlierDetection.Enabled {
+		outlierDetector = aggregator.NewOutlierDetector(logger, conf.Aggregator.OutlierDetection.Threshold,
+			conf.Aggregator.OutlierDetection.MinInstances, egClock, anomalyStore)
+	}
+
 	appMonitorsChan := make(chan *models.AppMonitor, conf.Aggregator.AppMonitorChannelSize)
-	metricPollers, err := createMetricPollers(logger, conf, appMonitorsChan, appMetricDB)
 	aggregator, err := aggregator.NewAggregator(logger, egClock, conf.Aggregator.AggregatorExecuteInterval,
-		appMonitorsChan, policyPoller.GetPolicies)
+		appMonitorsChan, policyPoller.GetPolicies, getShard)
 	if err != nil {
 		logger.Error("failed to create Aggregator", err)
 		os.Exit(1)
 	}
 
+	metricPollers, err := createMetricPollers(logger, conf, appMonitorsChan, aggregator.AppMonitorDone, appMetricDB, outlierDetector)
+	if err != nil {
+		logger.Error("failed to create MetricPollers", err)
+		os.Exit(1)
+	}
+
+	httpServer, err := server.NewServer(logger.Session("http_server"), conf, anomalyStore.GetAnomalousInstances)
+	if err != nil {
+		logger.Error("failed to create http server", err)
+		os.Exit(1)
+	}
+
 	eventGeneratorServer := ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		if membership != nil {
+			membership.Start()
+		}
 		policyPoller.Start()
 
 		for _, evaluator := range evaluators {
@@ -97,13 +151,83 @@ func main() {
 		<-signals
 		aggregator.Stop()
 		evaluationManager.Stop()
+
+		deadline := make(chan struct{})
+		drainTimer := egClock.NewTimer(conf.DrainTimeout)
+		go func() {
+			<-drainTimer.C()
+			close(deadline)
+		}()
+
+		var wg sync.WaitGroup
+		for _, metricPoller := range metricPollers {
+			wg.Add(1)
+			go func(drain func(<-chan struct{})) {
+				defer wg.Done()
+				drain(deadline)
+			}(metricPoller.Drain)
+		}
+		for _, evaluator := range evaluators {
+			wg.Add(1)
+			go func(drain func(<-chan struct{})) {
+				defer wg.Done()
+				drain(deadline)
+			}(evaluator.Drain)
+		}
+		wg.Wait()
+		drainTimer.Stop()
+
+		logger.Info("shutdown-dropped", lager.Data{
+			"app_monitors":   len(appMonitorsChan),
+			"trigger_arrays": len(triggersChan),
+		})
+
+		for _, metricPoller := range metricPollers {
+			metricPoller.Stop()
+		}
+		for _, evaluator := range evaluators {
+			evaluator.Stop()
+		}
 		policyPoller.Stop()
+		if membership != nil {
+			membership.Stop()
+		}
 
 		return nil
 	})
 
+	metrics.MustRegister(
+		metrics.NewGaugeFunc("autoscaler_eventgenerator_app_monitors_queue_depth",
+			"Number of app monitors waiting to be picked up by the metric pollers.",
+			func() float64 { return float64(len(appMonitorsChan)) }),
+		metrics.NewGaugeFunc("autoscaler_eventgenerator_triggers_queue_depth",
+			"Number of trigger arrays waiting to be picked up by the evaluators.",
+			func() float64 { return float64(len(triggersChan)) }),
+	)
+
 	members := grouper.Members{
 		{"eventGeneratorServer", eventGeneratorServer},
+		{"http_server", httpServer},
+	}
+
+	if conf.Health.Port != 0 {
+		liveness := healthendpoint.Checks{
+			"policy_poller": healthendpoint.TickCheck(policyPoller.LastTickTime, 3*conf.Aggregator.PolicyPollerInterval, egClock),
+		}
+		readiness := healthendpoint.Checks{
+			"policy_db":      healthendpoint.PingCheck(policyDB),
+			"app_metrics_db": healthendpoint.PingCheck(appMetricDB),
+		}
+		if shardDB != nil {
+			readiness["shard_db"] = healthendpoint.PingCheck(shardDB)
+			readiness["shard_membership"] = membership.Check
+		}
+		healthServer, err := healthendpoint.NewServer(logger.Session("health-server"), conf.Health.Port, metrics.DefaultRegistry, liveness, readiness)
+		if err != nil {
+			logger.Error("failed to create health server", err)
+			os.Exit(1)
+		}
+		members = append(members, grouper.Member{Name: "health_server", Runner: healthServer})
 	}
 
 	monitor := ifrit.Invoke(sigmon.New(grouper.NewOrdered(os.Interrupt, members)))
@@ -171,7 +295,8 @@ func loadConfig(path string) (*config.Config, error) {
 	return conf, nil
 }
 
-func createEvaluators(logger lager.Logger, conf *config.Config, triggersChan chan []*models.Trigger, database db.AppMetricDB) ([]*generator.Evaluator, error) {
+func createEvaluators(logger lager.Logger, conf *config.Config, triggersChan chan []*models.Trigger,
+	triggersDone func([]*models.Trigger), database db.AppMetricDB) ([]*generator.Evaluator, error) {
 	count := conf.Evaluator.EvaluatorCount
 	scalingEngineUrl := conf.ScalingEngine.ScalingEngineUrl
 
@@ -190,23 +315,45 @@ func createEvaluators(logger lager.Logger, conf *config.Config, triggersChan cha
 		client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
 	}
 
+	metricCollectorHttpClient, err := createMetricCollectorHttpClient(conf, count)
+	if err != nil {
+		return nil, err
+	}
+	metricCollectorClient := generator.NewMetricCollectorClient(logger, conf.MetricCollector.MetricCollectorUrl, metricCollectorHttpClient)
+
 	evaluators := make([]*generator.Evaluator, count)
 	for i := 0; i < count; i++ {
-		evaluators[i] = generator.NewEvaluator(logger, client, scalingEngineUrl, triggersChan, database)
+		evaluators[i] = generator.NewEvaluator(logger, client, scalingEngineUrl, triggersChan, triggersDone, database,
+			metricCollectorClient.GetInstanceMetrics)
 	}
 
 	return evaluators, nil
 }
 
-func createMetricPollers(logger lager.Logger, conf *config.Config, appChan chan *models.AppMonitor, database db.AppMetricDB) ([]*aggregator.MetricPoller, error) {
+func createMetricPollers(logger lager.Logger, conf *config.Config, appChan chan *models.AppMonitor,
+	appMonitorDone func(*models.AppMonitor), database db.AppMetricDB, outlierDetector *aggregator.OutlierDetector) ([]*aggregator.MetricPoller, error) {
+	count := conf.Aggregator.MetricPollerCount
+	client, err := createMetricCollectorHttpClient(conf, count)
+	if err != nil {
+		return nil, err
+	}
+
+	pollers := make([]*aggregator.MetricPoller, count)
+	for i := 0; i < count; i++ {
+		pollers[i] = aggregator.NewMetricPoller(logger, conf.MetricCollector.MetricCollectorUrl, appChan, appMonitorDone, client, database, outlierDetector)
+	}
+
+	return pollers, nil
+}
+
+func createMetricCollectorHttpClient(conf *config.Config, maxIdleConnsPerHost int) (*http.Client, error) {
 	tlsCerts := &conf.MetricCollector.TLSClientCerts
 	if tlsCerts.CertFile == "" || tlsCerts.KeyFile == "" {
 		tlsCerts = nil
 	}
 
-	count := conf.Aggregator.MetricPollerCount
 	client := cfhttp.NewClient()
-	client.Transport.(*http.Transport).MaxIdleConnsPerHost = count
+	client.Transport.(*http.Transport).MaxIdleConnsPerHost = maxIdleConnsPerHost
 	if tlsCerts != nil {
 		tlsConfig, err := cfhttp.NewTLSConfig(tlsCerts.CertFile, tlsCerts.KeyFile, tlsCerts.CACertFile)
 		if err != nil {
@@ -214,11 +361,5 @@ func createMetricPollers(logger lager.Logger, conf *config.Config, appChan chan
 		}
 		client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
 	}
-
-	pollers := make([]*aggregator.MetricPoller, count)
-	for i := 0; i < count; i++ {
-		pollers[i] = aggregator.NewMetricPoller(logger, conf.MetricCollector.MetricCollectorUrl, appChan, client, database)
-	}
-
-	return pollers, nil
+	return client, nil
 }
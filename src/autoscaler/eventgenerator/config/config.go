@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"autoscaler/models"
+	"autoscaler/sharding"
 )
 
 const (
@@ -19,16 +20,24 @@ const (
 	DefaultEvaluationExecuteInterval time.Duration = 40 * time.Second
 	DefaultEvaluatorCount            int           = 20
 	DefaultTriggerArrayChannelSize   int           = 200
+	DefaultDrainTimeout              time.Duration = 10 * time.Second
+	DefaultOutlierThreshold          float64       = 3.5
+	DefaultOutlierMinInstances       int           = 3
 )
 
 type ServerConfig struct {
-	Port int `yaml:"port"`
+	Port int             `yaml:"port"`
+	TLS  models.TLSCerts `yaml:"tls"`
 }
 
 type LoggingConfig struct {
 	Level string `yaml:"level"`
 }
 
+type HealthConfig struct {
+	Port int `yaml:"port"`
+}
+
 type DBConfig struct {
 	PolicyDBUrl    string `yaml:"policy_db_url"`
 	AppMetricDBUrl string `yaml:"app_metrics_db_url"`
@@ -39,6 +48,16 @@ type AggregatorConfig struct {
 	AppMonitorChannelSize     int           `yaml:"app_monitor_channel_size"`
 	AggregatorExecuteInterval time.Duration `yaml:"aggregator_execute_interval"`
 	PolicyPollerInterval      time.Duration `yaml:"policy_poller_interval"`
+	OutlierDetection          OutlierConfig `yaml:"outlier_detection"`
+}
+
+// OutlierConfig configures the exclusion of outlier instances from the app
+// metric. An instance is an outlier when the modified z-score of its metric
+// exceeds Threshold among at least MinInstances instances.
+type OutlierConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	Threshold    float64 `yaml:"threshold"`
+	MinInstances int     `yaml:"min_instances"`
 }
 
 type EvaluatorConfig struct {
@@ -58,13 +77,16 @@ type MetricCollectorConfig struct {
 }
 
 type Config struct {
-	Server          ServerConfig          `yaml:"server"`
-	Logging         LoggingConfig         `yaml:"logging"`
-	DB              DBConfig              `yaml:"db"`
-	Aggregator      AggregatorConfig      `yaml:"aggregator"`
-	Evaluator       EvaluatorConfig       `yaml:"evaluator"`
-	ScalingEngine   ScalingEngineConfig   `yaml:"scalingEngine"`
-	MetricCollector MetricCollectorConfig `yaml:"metricCollector"`
+	Server          ServerConfig            `yaml:"server"`
+	Logging         LoggingConfig           `yaml:"logging"`
+	DB              DBConfig                `yaml:"db"`
+	Aggregator      AggregatorConfig        `yaml:"aggregator"`
+	Evaluator       EvaluatorConfig         `yaml:"evaluator"`
+	ScalingEngine   ScalingEngineConfig     `yaml:"scalingEngine"`
+	MetricCollector MetricCollectorConfig   `yaml:"metricCollector"`
+	Health          HealthConfig            `yaml:"health"`
+	Sharding        sharding.ShardingConfig `yaml:"sharding"`
+	DrainTimeout    time.Duration           `yaml:"drain_timeout"`
 }
 
 func LoadConfig(bytes []byte) (*Config, error) {
@@ -80,12 +102,19 @@ func LoadConfig(bytes []byte) (*Config, error) {
 			PolicyPollerInterval:      DefaultPolicyPollerInterval,
 			MetricPollerCount:         DefaultMetricPollerCount,
 			AppMonitorChannelSize:     DefaultAppMonitorChannelSize,
+			OutlierDetection: OutlierConfig{
+				Enabled:      true,
+				Threshold:    DefaultOutlierThreshold,
+				MinInstances: DefaultOutlierMinInstances,
+			},
 		},
 		Evaluator: EvaluatorConfig{
 			EvaluationManagerInterval: DefaultEvaluationExecuteInterval,
 			EvaluatorCount:            DefaultEvaluatorCount,
 			TriggerArrayChannelSize:   DefaultTriggerArrayChannelSize,
 		},
+		Sharding:     sharding.DefaultShardingConfig,
+		DrainTimeout: DefaultDrainTimeout,
 	}
 	err := yaml.Unmarshal(bytes, &conf)
 	if err != nil {
@@ -123,6 +152,14 @@ func (c *Config) Validate() error {
 	if c.Aggregator.AppMonitorChannelSize <= 0 {
 		return fmt.Errorf("Configuration error: appMonitor channel size is less-equal than 0")
 	}
+	if c.Aggregator.OutlierDetection.Enabled {
+		if c.Aggregator.OutlierDetection.Threshold <= 0 {
+			return fmt.Errorf("Configuration error: outlier threshold is less-equal than 0")
+		}
+		if c.Aggregator.OutlierDetection.MinInstances < 3 {
+			return fmt.Errorf("Configuration error: outlier min instances is less than 3")
+		}
+	}
 	if c.Evaluator.EvaluationManagerInterval <= time.Duration(0) {
 		return fmt.Errorf("Configuration error: evalution manager execeute interval is less-equal than 0")
 	}
@@ -132,6 +169,15 @@ func (c *Config) Validate() error {
 	if c.Evaluator.TriggerArrayChannelSize <= 0 {
 		return fmt.Errorf("Configuration error: trigger-array channel size is less-equal than 0")
 	}
+	if c.Health.Port < 0 || c.Health.Port > 65535 {
+		return fmt.Errorf("Configuration error: health port is less than 0 or more than 65535")
+	}
+	if c.DrainTimeout < time.Duration(0) {
+		return fmt.Errorf("Configuration error: drain timeout is less than 0")
+	}
+	if err := c.Sharding.Validate(); err != nil {
+		return err
+	}
 	return nil
 
 }
@@ -3,6 +3,7 @@ package config_test
 import (
 	. "autoscaler/eventgenerator/config"
 	"autoscaler/models"
+	"autoscaler/sharding"
 
 	"time"
 
@@ -39,6 +40,10 @@ aggregator:
   policy_poller_interval: 30s
   metric_poller_count: 10
   app_monitor_channel_size: 100
+  outlier_detection:
+    enabled: false
+    threshold: 5
+    min_instances: 4
 evaluator:
   evaluation_manager_execute_interval: 30s
   evaluator_count: 10
@@ -55,6 +60,12 @@ metricCollector:
     key_file: /var/vcap/jobs/autoscaler/config/certs/mc.key
     cert_file: /var/vcap/jobs/autoscaler/config/certs/mc.crt
     ca_file: /var/vcap/jobs/autoscaler/config/certs/autoscaler-ca.crt
+health:
+  port: 9999
+sharding:
+  index: 1
+  count: 2
+drain_timeout: 20s
 `)
 			})
 
@@ -67,7 +78,12 @@ metricCollector:
 						AggregatorExecuteInterval: 30 * time.Second,
 						PolicyPollerInterval:      30 * time.Second,
 						MetricPollerCount:         10,
-						AppMonitorChannelSize:     100},
+						AppMonitorChannelSize:     100,
+						OutlierDetection: OutlierConfig{
+							Enabled:      false,
+							Threshold:    5,
+							MinInstances: 4,
+						}},
 					Evaluator: EvaluatorConfig{
 						EvaluationManagerInterval: 30 * time.Second,
 						EvaluatorCount:            10,
@@ -88,6 +104,14 @@ metricCollector:
 							CACertFile: "/var/vcap/jobs/autoscaler/config/certs/autoscaler-ca.crt",
 						},
 					},
+					Health: HealthConfig{Port: 9999},
+					Sharding: sharding.ShardingConfig{
+						Index:             1,
+						Count:             2,
+						HeartbeatInterval: sharding.DefaultHeartbeatInterval,
+						MemberTTL:         sharding.DefaultMemberTTL,
+					},
+					DrainTimeout: 20 * time.Second,
 				}))
 			})
 		})
@@ -394,14 +418,21 @@ metricCollector:
 					Aggregator: AggregatorConfig{AggregatorExecuteInterval: DefaultAggregatorExecuteInterval,
 						PolicyPollerInterval:  DefaultPolicyPollerInterval,
 						MetricPollerCount:     DefaultMetricPollerCount,
-						AppMonitorChannelSize: DefaultAppMonitorChannelSize},
+						AppMonitorChannelSize: DefaultAppMonitorChannelSize,
+						OutlierDetection: OutlierConfig{
+							Enabled:      true,
+							Threshold:    DefaultOutlierThreshold,
+							MinInstances: DefaultOutlierMinInstances,
+						}},
 					Evaluator: EvaluatorConfig{EvaluationManagerInterval: DefaultEvaluationExecuteInterval,
 						EvaluatorCount:          DefaultEvaluatorCount,
 						TriggerArrayChannelSize: DefaultTriggerArrayChannelSize},
 					ScalingEngine: ScalingEngineConfig{
 						ScalingEngineUrl: "http://localhost:8082"},
 					MetricCollector: MetricCollectorConfig{
-						MetricCollectorUrl: "http://localhost:8083"}}))
+						MetricCollectorUrl: "http://localhost:8083"},
+					Sharding:     sharding.DefaultShardingConfig,
+					DrainTimeout: DefaultDrainTimeout}))
 			})
 		})
 
@@ -424,7 +455,8 @@ metricCollector:
 				ScalingEngine: ScalingEngineConfig{
 					ScalingEngineUrl: "http://localhost:8082"},
 				MetricCollector: MetricCollectorConfig{
-					MetricCollectorUrl: "http://localhost:8083"}}
+					MetricCollectorUrl: "http://localhost:8083"},
+				Sharding: sharding.DefaultShardingConfig}
 		})
 
 		JustBeforeEach(func() {
@@ -527,6 +559,34 @@ metricCollector:
 			})
 		})
 
+		Context("when outlier detection is enabled", func() {
+			BeforeEach(func() {
+				conf.Aggregator.OutlierDetection = OutlierConfig{Enabled: true, Threshold: 3.5, MinInstances: 3}
+			})
+
+			It("should not error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			Context("when the threshold <= 0", func() {
+				BeforeEach(func() {
+					conf.Aggregator.OutlierDetection.Threshold = 0
+				})
+				It("should error", func() {
+					Expect(err).To(MatchError(MatchRegexp("Configuration error: outlier threshold is less-equal than 0")))
+				})
+			})
+
+			Context("when min instances < 3", func() {
+				BeforeEach(func() {
+					conf.Aggregator.OutlierDetection.MinInstances = 2
+				})
+				It("should error", func() {
+					Expect(err).To(MatchError(MatchRegexp("Configuration error: outlier min instances is less than 3")))
+				})
+			})
+		})
+
 		Context("when EvaluationManagerInterval <= 0", func() {
 			BeforeEach(func() {
 				conf.Evaluator.EvaluationManagerInterval = 0
@@ -554,5 +614,33 @@ metricCollector:
 			})
 		})
 
+		Context("when health port is out of range", func() {
+			BeforeEach(func() {
+				conf.Health.Port = 70000
+			})
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: health port is less than 0 or more than 65535")))
+			})
+		})
+
+		Context("when drain timeout is negative", func() {
+			BeforeEach(func() {
+				conf.DrainTimeout = -1 * time.Second
+			})
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: drain timeout is less than 0")))
+			})
+		})
+
+		Context("when sharding config is not valid", func() {
+			BeforeEach(func() {
+				conf.Sharding.Count = 0
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: sharding count is less than 1")))
+			})
+		})
+
 	})
 })
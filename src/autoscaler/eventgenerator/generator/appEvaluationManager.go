@@ -2,12 +2,19 @@ package generator
 
 import (
 	"autoscaler/models"
+	"autoscaler/sharding"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 )
 
+// MaxSkippedTicks is the number of consecutive ticks skipped while the trigger
+// arrays of the previous round are still queued. The round after that is run
+// anyway so that apps which are not pending are still evaluated.
+const MaxSkippedTicks = 3
+
 type ConsumeAppMonitorMap func(map[string][]*models.Trigger, chan []*models.Trigger)
 type AppEvaluationManager struct {
 	evaluateInterval time.Duration
@@ -16,10 +23,15 @@ type AppEvaluationManager struct {
 	doneChan         chan bool
 	triggerChan      chan []*models.Trigger
 	getPolicies      models.GetPolicies
+	getShard         func() sharding.Shard
+	wg               sync.WaitGroup
+	pending          map[string]bool
+	pendingLock      sync.Mutex
+	skippedTicks     int
 }
 
 func NewAppEvaluationManager(logger lager.Logger, evaluateInterval time.Duration, cclock clock.Clock,
-	triggerChan chan []*models.Trigger, getPolicies models.GetPolicies) (*AppEvaluationManager, error) {
+	triggerChan chan []*models.Trigger, getPolicies models.GetPolicies, getShard func() sharding.Shard) (*AppEvaluationManager, error) {
 	return &AppEvaluationManager{
 		evaluateInterval: evaluateInterval,
 		logger:           logger.Session("AppEvaluationManager"),
@@ -27,6 +39,8 @@ func NewAppEvaluationManager(logger lager.Logger, evaluateInterval time.Duration
 		doneChan:         make(chan bool),
 		triggerChan:      triggerChan,
 		getPolicies:      getPolicies,
+		getShard:         getShard,
+		pending:          map[string]bool{},
 	}, nil
 }
 
@@ -35,10 +49,14 @@ func (a *AppEvaluationManager) getTriggers(policyMap map[string]*models.AppPolic
 		return nil
 	}
 
+	shard := a.getShard()
 	triggersByType := make(map[string][]*models.Trigger)
 	for appId, policy := range policyMap {
+		if !shard.Owns(appId) {
+			continue
+		}
 		for _, rule := range policy.ScalingPolicy.ScalingRules {
-			triggerKey := appId + "#" + rule.MetricType
+			triggerKey := triggerArrayKey(appId, rule.MetricType)
 			triggers, exist := triggersByType[triggerKey]
 			if !exist {
 				triggers = []*models.Trigger{}
@@ -51,6 +69,9 @@ func (a *AppEvaluationManager) getTriggers(policyMap map[string]*models.AppPolic
 				Threshold:             rule.Threshold,
 				Operator:              rule.Operator,
 				Adjustment:            rule.Adjustment,
+				Action:                rule.Action,
+				MemoryAdjustment:      rule.MemoryAdjustment,
+				DiskAdjustment:        rule.DiskAdjustment,
 			})
 			triggersByType[triggerKey] = triggers
 		}
@@ -59,15 +80,18 @@ func (a *AppEvaluationManager) getTriggers(policyMap map[string]*models.AppPolic
 }
 
 func (a *AppEvaluationManager) Start() {
+	a.wg.Add(1)
 	go a.doEvaluate()
 }
 
 func (a *AppEvaluationManager) Stop() {
 	close(a.doneChan)
+	a.wg.Wait()
 	a.logger.Info("stopped")
 }
 
 func (a *AppEvaluationManager) doEvaluate() {
+	defer a.wg.Done()
 	ticker := a.cclock.NewTicker(a.evaluateInterval)
 	defer ticker.Stop()
 	for {
@@ -75,11 +99,69 @@ func (a *AppEvaluationManager) doEvaluate() {
 		case <-a.doneChan:
 			return
 		case <-ticker.C():
-			triggers := a.getTriggers(a.getPolicies())
-			for _, triggerArray := range triggers {
-				a.triggerChan <- triggerArray
+			if len(a.triggerChan) > 0 && a.skippedTicks < MaxSkippedTicks {
+				a.skippedTicks++
+				skippedEvaluationTicks.Inc()
+				a.logger.Info("skip-tick", lager.Data{"queued": len(a.triggerChan), "skippedTicks": a.skippedTicks})
+				continue
 			}
+			a.skippedTicks = 0
+			a.enqueue(a.getTriggers(a.getPolicies()))
 		}
 	}
 	a.logger.Info("started")
 }
+
+// enqueue sends the trigger arrays to the evaluators without blocking. Trigger
+// arrays which are still pending from a previous round are skipped and the ones
+// that do not fit into the channel are dropped.
+func (a *AppEvaluationManager) enqueue(triggers map[string][]*models.Trigger) {
+	pending, dropped := 0, 0
+	for key, triggerArray := range triggers {
+		if !a.markPending(key) {
+			pending++
+			droppedTriggerArrays.Inc("pending")
+			continue
+		}
+
+		select {
+		case a.triggerChan <- triggerArray:
+		default:
+			a.unmarkPending(key)
+			dropped++
+			droppedTriggerArrays.Inc("queue_full")
+		}
+	}
+	if pending > 0 || dropped > 0 {
+		a.logger.Info("trigger-arrays-not-enqueued", lager.Data{"pending": pending, "dropped": dropped})
+	}
+}
+
+// TriggersDone is called by the evaluators after a trigger array was evaluated
+// so that it can be enqueued again.
+func (a *AppEvaluationManager) TriggersDone(triggerArray []*models.Trigger) {
+	if len(triggerArray) == 0 {
+		return
+	}
+	a.unmarkPending(triggerArrayKey(triggerArray[0].AppId, triggerArray[0].MetricType))
+}
+
+func (a *AppEvaluationManager) markPending(key string) bool {
+	a.pendingLock.Lock()
+	defer a.pendingLock.Unlock()
+	if a.pending[key] {
+		return false
+	}
+	a.pending[key] = true
+	return true
+}
+
+func (a *AppEvaluationManager) unmarkPending(key string) {
+	a.pendingLock.Lock()
+	defer a.pendingLock.Unlock()
+	delete(a.pending, key)
+}
+
+func triggerArrayKey(appId string, metricType string) string {
+	return appId + "#" + metricType
+}
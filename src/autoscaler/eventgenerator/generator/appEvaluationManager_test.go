@@ -4,6 +4,7 @@ import (
 	"autoscaler/eventgenerator/aggregator/fakes"
 	. "autoscaler/eventgenerator/generator"
 	"autoscaler/models"
+	"autoscaler/sharding"
 	"net/http"
 	"regexp"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/ghttp"
 )
 
@@ -20,7 +22,9 @@ var _ = Describe("AppEvaluationManager", func() {
 
 	var (
 		getPolicies          models.GetPolicies
+		shard                sharding.Shard
 		logger               lager.Logger
+		buffer               *gbytes.Buffer
 		fclock               *fakeclock.FakeClock
 		manager              *AppEvaluationManager
 		testEvaluateInterval time.Duration
@@ -73,9 +77,12 @@ var _ = Describe("AppEvaluationManager", func() {
 	)
 
 	BeforeEach(func() {
+		shard = sharding.AllApps
 		fclock = fakeclock.NewFakeClock(time.Now())
 		testEvaluateInterval = 1 * time.Second
-		logger = lagertest.NewTestLogger("ApplicationManager-test")
+		testLogger := lagertest.NewTestLogger("ApplicationManager-test")
+		logger = testLogger
+		buffer = testLogger.Buffer()
 		triggerArrayChan = make(chan []*models.Trigger, 10)
 		fakeScalingEngine = ghttp.NewServer()
 		fakeScalingEngine.RouteToHandler("POST", regPath, ghttp.RespondWith(http.StatusOK, "successful"))
@@ -86,7 +93,8 @@ var _ = Describe("AppEvaluationManager", func() {
 	Describe("Start", func() {
 		JustBeforeEach(func() {
 			var err error
-			manager, err = NewAppEvaluationManager(logger, testEvaluateInterval, fclock, triggerArrayChan, getPolicies)
+			manager, err = NewAppEvaluationManager(logger, testEvaluateInterval, fclock, triggerArrayChan, getPolicies,
+				func() sharding.Shard { return shard })
 			Expect(err).NotTo(HaveOccurred())
 			manager.Start()
 			Eventually(fclock.WatcherCount).Should(Equal(1))
@@ -134,6 +142,69 @@ var _ = Describe("AppEvaluationManager", func() {
 			})
 		})
 
+		Context("when a rule restarts instances", func() {
+			BeforeEach(func() {
+				getPolicies = func() map[string]*models.AppPolicy {
+					return map[string]*models.AppPolicy{
+						testAppId: &models.AppPolicy{
+							AppId: testAppId,
+							ScalingPolicy: &models.ScalingPolicy{
+								InstanceMax: 5,
+								InstanceMin: 1,
+								ScalingRules: []*models.ScalingRule{
+									&models.ScalingRule{
+										MetricType:            "MemoryUsage",
+										StatWindowSeconds:     200,
+										BreachDurationSeconds: 200,
+										CoolDownSeconds:       200,
+										Threshold:             900,
+										Operator:              ">",
+										Action:                models.ActionRestartInstance,
+									},
+								},
+							},
+						},
+					}
+				}
+			})
+
+			It("should add the trigger with the action", func() {
+				fclock.Increment(10 * testEvaluateInterval)
+				Eventually(triggerArrayChan).Should(Receive(Equal([]*models.Trigger{&models.Trigger{
+					AppId:                 testAppId,
+					MetricType:            testMetricType,
+					BreachDurationSeconds: 200,
+					CoolDownSeconds:       200,
+					Threshold:             900,
+					Operator:              ">",
+					Action:                models.ActionRestartInstance,
+				}})))
+			})
+		})
+
+		Context("when the manager is sharded", func() {
+			BeforeEach(func() {
+				getPolicies = func() map[string]*models.AppPolicy {
+					return policyMap
+				}
+				for i := 0; i < 3; i++ {
+					if (sharding.Shard{Index: i, Count: 3}).Owns(testAppId) {
+						shard = sharding.Shard{Index: i, Count: 3}
+					}
+				}
+				Expect(shard.Owns(testAppId2)).To(BeFalse())
+			})
+
+			It("should add triggers of the apps in its shard only", func() {
+				fclock.Increment(10 * testEvaluateInterval)
+				var arr []*models.Trigger
+				Eventually(triggerArrayChan).Should(Receive(&arr))
+				Expect(arr).To(HaveLen(1))
+				Expect(arr[0].AppId).To(Equal(testAppId))
+				Consistently(triggerArrayChan).ShouldNot(Receive())
+			})
+		})
+
 		Context("when there is no trigger", func() {
 			BeforeEach(func() {
 				getPolicies = func() map[string]*models.AppPolicy {
@@ -146,6 +217,58 @@ var _ = Describe("AppEvaluationManager", func() {
 				Consistently(triggerArrayChan).ShouldNot(Receive())
 			})
 		})
+
+		Context("when the trigger arrays are still pending", func() {
+			var first, second []*models.Trigger
+
+			BeforeEach(func() {
+				getPolicies = func() map[string]*models.AppPolicy {
+					return policyMap
+				}
+			})
+
+			JustBeforeEach(func() {
+				fclock.Increment(testEvaluateInterval)
+				Eventually(triggerArrayChan).Should(Receive(&first))
+				Eventually(triggerArrayChan).Should(Receive(&second))
+			})
+
+			It("should not add them again until they are done", func() {
+				fclock.Increment(testEvaluateInterval)
+				Eventually(buffer).Should(gbytes.Say("trigger-arrays-not-enqueued"))
+				Consistently(triggerArrayChan).ShouldNot(Receive())
+
+				manager.TriggersDone(first)
+				fclock.Increment(testEvaluateInterval)
+				Eventually(triggerArrayChan).Should(Receive(Equal(first)))
+				Consistently(triggerArrayChan).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the trigger array channel is full", func() {
+			BeforeEach(func() {
+				triggerArrayChan = make(chan []*models.Trigger, 1)
+				getPolicies = func() map[string]*models.AppPolicy {
+					return policyMap
+				}
+			})
+
+			JustBeforeEach(func() {
+				fclock.Increment(testEvaluateInterval)
+				Eventually(buffer).Should(gbytes.Say(`trigger-arrays-not-enqueued.*"dropped":1`))
+			})
+
+			It("should skip the ticks until the channel is drained", func() {
+				for i := 0; i < MaxSkippedTicks; i++ {
+					fclock.Increment(testEvaluateInterval)
+					Eventually(buffer).Should(gbytes.Say("skip-tick"))
+				}
+
+				fclock.Increment(testEvaluateInterval)
+				Eventually(buffer).Should(gbytes.Say(`trigger-arrays-not-enqueued.*"dropped":1,"pending":1`))
+				Expect(triggerArrayChan).To(HaveLen(1))
+			})
+		})
 	})
 
 	Describe("Stop", func() {
@@ -155,7 +278,8 @@ var _ = Describe("AppEvaluationManager", func() {
 			}
 
 			var err error
-			manager, err = NewAppEvaluationManager(logger, testEvaluateInterval, fclock, triggerArrayChan, getPolicies)
+			manager, err = NewAppEvaluationManager(logger, testEvaluateInterval, fclock, triggerArrayChan, getPolicies,
+				func() sharding.Shard { return shard })
 			Expect(err).NotTo(HaveOccurred())
 			manager.Start()
 			Eventually(fclock.WatcherCount).Should(Equal(1))
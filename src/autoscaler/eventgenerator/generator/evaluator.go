@@ -9,55 +9,124 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
 var validOperators []string = []string{">", ">=", "<", "<="}
 
 type Evaluator struct {
-	logger           lager.Logger
-	httpClient       *http.Client
-	scalingEngineUrl string
-	triggerChan      chan []*models.Trigger
-	doneChan         chan bool
-	database         db.AppMetricDB
+	logger             lager.Logger
+	httpClient         *http.Client
+	scalingEngineUrl   string
+	triggerChan        chan []*models.Trigger
+	triggersDone       func([]*models.Trigger)
+	doneChan           chan bool
+	drainChan          chan (<-chan struct{})
+	stopOnce           sync.Once
+	wg                 sync.WaitGroup
+	database           db.AppMetricDB
+	getInstanceMetrics GetInstanceMetricsFunc
 }
 
-func NewEvaluator(logger lager.Logger, httpClient *http.Client, scalingEngineUrl string, triggerChan chan []*models.Trigger, database db.AppMetricDB) *Evaluator {
+func NewEvaluator(logger lager.Logger, httpClient *http.Client, scalingEngineUrl string, triggerChan chan []*models.Trigger,
+	triggersDone func([]*models.Trigger), database db.AppMetricDB, getInstanceMetrics GetInstanceMetricsFunc) *Evaluator {
 	return &Evaluator{
-		logger:           logger.Session("Evaluator"),
-		httpClient:       httpClient,
-		scalingEngineUrl: scalingEngineUrl,
-		triggerChan:      triggerChan,
-		doneChan:         make(chan bool),
-		database:         database,
+		logger:             logger.Session("Evaluator"),
+		httpClient:         httpClient,
+		scalingEngineUrl:   scalingEngineUrl,
+		triggerChan:        triggerChan,
+		triggersDone:       triggersDone,
+		doneChan:           make(chan bool),
+		drainChan:          make(chan (<-chan struct{}), 1),
+		database:           database,
+		getInstanceMetrics: getInstanceMetrics,
 	}
 }
 
 func (e *Evaluator) Start() {
+	e.wg.Add(1)
 	go e.start()
 	e.logger.Info("started")
 }
 
 func (e *Evaluator) start() {
+	defer e.wg.Done()
+	for {
+		// a pending drain takes precedence over the items in the channel so
+		// that the deadline is honoured
+		select {
+		case deadline := <-e.drainChan:
+			e.drain(deadline)
+			return
+		default:
+		}
+
+		select {
+		case <-e.doneChan:
+			return
+		case deadline := <-e.drainChan:
+			e.drain(deadline)
+			return
+		case triggerArray := <-e.triggerChan:
+			e.process(triggerArray)
+		}
+	}
+}
+
+// drain evaluates the trigger arrays left in the channel until it is empty, the
+// deadline is closed or the evaluator is stopped.
+func (e *Evaluator) drain(deadline <-chan struct{}) {
 	for {
 		select {
 		case <-e.doneChan:
 			return
+		case <-deadline:
+			return
+		default:
+		}
+
+		select {
 		case triggerArray := <-e.triggerChan:
-			e.doEvaluate(triggerArray)
+			e.process(triggerArray)
+		default:
+			return
 		}
 	}
 }
 
+// Drain makes the evaluator evaluate the trigger arrays left in the channel
+// and stop once it is empty or deadline is closed. It returns after the
+// evaluator has stopped, including any trigger alarm that was being sent.
+func (e *Evaluator) Drain(deadline <-chan struct{}) {
+	select {
+	case e.drainChan <- deadline:
+	default:
+	}
+	e.wg.Wait()
+	e.logger.Info("drained")
+}
+
+// Stop stops the evaluator and waits for the evaluation in progress.
 func (e *Evaluator) Stop() {
-	close(e.doneChan)
+	e.stopOnce.Do(func() {
+		close(e.doneChan)
+	})
+	e.wg.Wait()
 	e.logger.Info("stopped")
 }
 
+func (e *Evaluator) process(triggerArray []*models.Trigger) {
+	e.doEvaluate(triggerArray)
+	e.triggersDone(triggerArray)
+}
+
 func (e *Evaluator) doEvaluate(triggerArray []*models.Trigger) {
 
 	for _, trigger := range triggerArray {
+		evaluations.Inc()
 		threshold := trigger.Threshold
 		operator := trigger.Operator
 
@@ -66,6 +135,11 @@ func (e *Evaluator) doEvaluate(triggerArray []*models.Trigger) {
 			continue
 		}
 
+		if trigger.GetAction() == models.ActionRestartInstance {
+			e.evaluateInstances(trigger)
+			continue
+		}
+
 		appMetricList, err := e.retrieveAppMetrics(trigger)
 		if err != nil {
 			return
@@ -79,26 +153,9 @@ func (e *Evaluator) doEvaluate(triggerArray []*models.Trigger) {
 				e.logger.Debug("should not send trigger alarm to scaling engine because there is nil-value metric", lager.Data{"trigger": trigger, "appMetric": appMetric})
 				return
 			}
-			if operator == ">" {
-				if *appMetric.Value <= threshold {
-					e.logger.Debug("should not send trigger alarm to scaling engine", lager.Data{"trigger": trigger, "appMetric": appMetric})
-					return
-				}
-			} else if operator == ">=" {
-				if *appMetric.Value < threshold {
-					e.logger.Debug("should not send trigger alarm to scaling engine", lager.Data{"trigger": trigger, "appMetric": appMetric})
-					return
-				}
-			} else if operator == "<" {
-				if *appMetric.Value >= threshold {
-					e.logger.Debug("should not send trigger alarm to scaling engine", lager.Data{"trigger": trigger, "appMetric": appMetric})
-					return
-				}
-			} else if operator == "<=" {
-				if *appMetric.Value > threshold {
-					e.logger.Debug("should not send trigger alarm to scaling engine", lager.Data{"trigger": trigger, "appMetric": appMetric})
-					return
-				}
+			if !breaches(*appMetric.Value, threshold, operator) {
+				e.logger.Debug("should not send trigger alarm to scaling engine", lager.Data{"trigger": trigger, "appMetric": appMetric})
+				return
 			}
 		}
 
@@ -109,6 +166,100 @@ func (e *Evaluator) doEvaluate(triggerArray []*models.Trigger) {
 
 }
 
+// evaluateInstances asks the scaling engine to restart the instances whose own
+// metric breaches the trigger over the whole breach duration. An instance
+// breaches only when all its samples breach and they span the breach duration,
+// so that an instance which just started is not judged on a few samples. At
+// most a minority of the instances seen is restarted per evaluation, lowest
+// index first, and at least one so that single instance apps are remediated.
+func (e *Evaluator) evaluateInstances(trigger *models.Trigger) {
+	endTime := time.Now()
+	startTime := endTime.Add(0 - trigger.BreachDuration())
+	metrics, err := e.getInstanceMetrics(trigger.AppId, trigger.MetricType, startTime.UnixNano(), endTime.UnixNano())
+	if err != nil {
+		e.logger.Error("retrieve instance metrics", err, lager.Data{"trigger": trigger})
+		return
+	}
+
+	samples := map[uint32]*instanceSamples{}
+	for _, metric := range metrics {
+		s, seen := samples[metric.InstanceIndex]
+		if !seen {
+			s = &instanceSamples{first: metric.Timestamp, last: metric.Timestamp, breaching: true}
+			samples[metric.InstanceIndex] = s
+		}
+		s.add(metric.Timestamp)
+
+		value, err := strconv.ParseInt(metric.Value, 10, 64)
+		if err != nil {
+			e.logger.Debug("should not restart instance because there is unparsable metric", lager.Data{"trigger": trigger, "instanceMetric": metric})
+			s.breaching = false
+			continue
+		}
+		s.breaching = s.breaching && breaches(value, trigger.Threshold, trigger.Operator)
+	}
+
+	indexes := []int{}
+	for index, s := range samples {
+		if !s.breaching {
+			continue
+		}
+		if !s.spans(startTime.UnixNano(), endTime.UnixNano()) {
+			e.logger.Debug("should not restart instance because its metrics do not span the breach duration", lager.Data{"trigger": trigger, "instanceIndex": index})
+			continue
+		}
+		indexes = append(indexes, int(index))
+	}
+	if len(indexes) == 0 {
+		e.logger.Debug("should not send restart alarm to scaling engine", lager.Data{"trigger": trigger})
+		return
+	}
+
+	sort.Ints(indexes)
+	limit := (len(samples) - 1) / 2
+	if limit < 1 {
+		limit = 1
+	}
+	if len(indexes) > limit {
+		e.logger.Info("limit restarts to a minority of the instances", lager.Data{"trigger": trigger, "breaching": indexes, "limit": limit})
+		indexes = indexes[:limit]
+	}
+	for _, index := range indexes {
+		e.logger.Info("send restart alarm to scaling engine", lager.Data{"trigger": trigger, "instanceIndex": index})
+		path, _ := routes.ScalingEngineRoutes().Get(routes.RestartInstanceRoute).URLPath("appid", trigger.AppId, "index", strconv.Itoa(index))
+		e.postAlarm(path.Path, trigger)
+	}
+}
+
+// instanceSamples summarizes the samples of an instance within the breach
+// duration.
+type instanceSamples struct {
+	first     int64
+	last      int64
+	count     int
+	breaching bool
+}
+
+func (s *instanceSamples) add(timestamp int64) {
+	if timestamp < s.first {
+		s.first = timestamp
+	}
+	if timestamp > s.last {
+		s.last = timestamp
+	}
+	s.count++
+}
+
+// spans tells whether the samples cover the time from start to end, allowing a
+// gap up to the average interval between the samples at either end.
+func (s *instanceSamples) spans(start int64, end int64) bool {
+	if s.count < 2 {
+		return false
+	}
+	gap := (s.last - s.first) / int64(s.count-1)
+	return s.first-start <= gap && end-s.last <= gap
+}
+
 func (e *Evaluator) retrieveAppMetrics(trigger *models.Trigger) ([]*models.AppMetric, error) {
 	endTime := time.Now()
 	startTime := endTime.Add(0 - trigger.BreachDuration())
@@ -122,20 +273,31 @@ func (e *Evaluator) retrieveAppMetrics(trigger *models.Trigger) ([]*models.AppMe
 }
 
 func (e *Evaluator) sendTriggerAlarm(trigger *models.Trigger) {
+	route := routes.ScaleRoute
+	if trigger.GetAction() == models.ActionResize {
+		route = routes.ResizeRoute
+	}
+	path, _ := routes.ScalingEngineRoutes().Get(route).URLPath("appid", trigger.AppId)
+	e.postAlarm(path.Path, trigger)
+}
+
+func (e *Evaluator) postAlarm(path string, trigger *models.Trigger) {
 	jsonBytes, jsonEncodeError := json.Marshal(trigger)
 	if jsonEncodeError != nil {
 		e.logger.Error("failed to json.Marshal trigger", jsonEncodeError)
 	}
-	path, _ := routes.ScalingEngineRoutes().Get(routes.ScaleRoute).URLPath("appid", trigger.AppId)
-	resp, respErr := e.httpClient.Post(e.scalingEngineUrl+path.Path, "", bytes.NewReader(jsonBytes))
+	resp, respErr := e.httpClient.Post(e.scalingEngineUrl+path, "", bytes.NewReader(jsonBytes))
 	if respErr != nil {
 		e.logger.Error("http reqeust error,failed to send trigger alarm", respErr, lager.Data{"trigger": trigger})
+		alarmsSent.Inc("failed")
 		return
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusOK {
 		e.logger.Info("successfully send trigger alarm", lager.Data{"trigger": trigger})
+		alarmsSent.Inc("succeeded")
 	} else {
+		alarmsSent.Inc("failed")
 		respBody, readError := ioutil.ReadAll(resp.Body)
 		if readError != nil {
 			e.logger.Error("failed to read body from scaling engine's response", readError)
@@ -143,6 +305,22 @@ func (e *Evaluator) sendTriggerAlarm(trigger *models.Trigger) {
 		e.logger.Error("scaling engine error,failed to send trigger alarm", nil, lager.Data{"responseCode": resp.StatusCode, "responseBody": respBody})
 	}
 }
+
+// breaches reports whether value is beyond threshold according to operator.
+func breaches(value int64, threshold int64, operator string) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
 func (e *Evaluator) isValidOperator(operator string) bool {
 	for _, o := range validOperators {
 		if o == operator {
@@ -27,7 +27,13 @@ var _ = Describe("Evaluator", func() {
 		testAppId      string = "testAppId"
 		testMetricType string = "MemoryUsage"
 		urlPath        string
-		triggerArrayGT []*models.Trigger = []*models.Trigger{&models.Trigger{
+		doneTriggers   chan []*models.Trigger
+
+		instanceMetrics     []*models.AppInstanceMetric
+		instanceMetricsErr  error
+		getInstanceMetrics  GetInstanceMetricsFunc
+		instanceMetricsArgs chan []interface{}
+		triggerArrayGT      []*models.Trigger = []*models.Trigger{&models.Trigger{
 			AppId:                 testAppId,
 			MetricType:            testMetricType,
 			BreachDurationSeconds: 300,
@@ -211,6 +217,7 @@ var _ = Describe("Evaluator", func() {
 		logger = lagertest.NewTestLogger("Evaluator-test")
 		httpClient = cfhttp.NewClient()
 		triggerChan = make(chan []*models.Trigger, 1)
+		doneTriggers = make(chan []*models.Trigger, 10)
 		database = &fakes.FakeAppMetricDB{}
 		scalingEngine = ghttp.NewServer()
 
@@ -218,11 +225,20 @@ var _ = Describe("Evaluator", func() {
 		Expect(err).NotTo(HaveOccurred())
 		urlPath = path.Path
 
+		instanceMetrics = nil
+		instanceMetricsErr = nil
+		instanceMetricsArgs = make(chan []interface{}, 10)
+		getInstanceMetrics = func(appId string, metricType string, start int64, end int64) ([]*models.AppInstanceMetric, error) {
+			instanceMetricsArgs <- []interface{}{appId, metricType}
+			return instanceMetrics, instanceMetricsErr
+		}
 	})
 
 	Context("Start", func() {
 		JustBeforeEach(func() {
-			evaluator = NewEvaluator(logger, httpClient, scalingEngine.URL(), triggerChan, database)
+			evaluator = NewEvaluator(logger, httpClient, scalingEngine.URL(), triggerChan, func(triggerArray []*models.Trigger) {
+				doneTriggers <- triggerArray
+			}, database, getInstanceMetrics)
 			evaluator.Start()
 		})
 
@@ -245,6 +261,10 @@ var _ = Describe("Evaluator", func() {
 				It("should retrieve appMetrics from database for each trigger", func() {
 					Eventually(database.RetrieveAppMetricsCallCount).Should(Equal(1))
 				})
+
+				It("should report the trigger array as done", func() {
+					Eventually(doneTriggers).Should(Receive(Equal(triggerArrayGT)))
+				})
 			})
 			Context("operators", func() {
 				BeforeEach(func() {
@@ -547,6 +567,196 @@ var _ = Describe("Evaluator", func() {
 				})
 			})
 
+			Context("when the trigger restarts instances", func() {
+				var restartPath0, restartPath2 string
+				var restartTriggerArray []*models.Trigger
+
+				BeforeEach(func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.RestartInstanceRoute).URLPath("appid", testAppId, "index", "0")
+					Expect(err).NotTo(HaveOccurred())
+					restartPath0 = path.Path
+					path, err = routes.ScalingEngineRoutes().Get(routes.RestartInstanceRoute).URLPath("appid", testAppId, "index", "2")
+					Expect(err).NotTo(HaveOccurred())
+					restartPath2 = path.Path
+
+					scalingEngine.RouteToHandler("POST", restartPath0, ghttp.RespondWith(http.StatusOK, "successful"))
+					scalingEngine.RouteToHandler("POST", restartPath2, ghttp.RespondWith(http.StatusOK, "successful"))
+
+					restartTriggerArray = []*models.Trigger{&models.Trigger{
+						AppId:                 testAppId,
+						MetricType:            testMetricType,
+						BreachDurationSeconds: 300,
+						CoolDownSeconds:       300,
+						Threshold:             500,
+						Operator:              ">",
+						Action:                models.ActionRestartInstance,
+					}}
+				})
+
+				JustBeforeEach(func() {
+					Expect(triggerChan).To(BeSent(restartTriggerArray))
+				})
+
+				sample := func(index uint32, value string, age time.Duration) *models.AppInstanceMetric {
+					return &models.AppInstanceMetric{AppId: testAppId, InstanceIndex: index, Name: testMetricType, Unit: "mb",
+						Value: value, Timestamp: time.Now().Add(-age).UnixNano()}
+				}
+
+				samples := func(index uint32, values ...string) []*models.AppInstanceMetric {
+					metrics := []*models.AppInstanceMetric{}
+					for i, value := range values {
+						metrics = append(metrics, sample(index, value, 290*time.Second-time.Duration(i)*140*time.Second))
+					}
+					return metrics
+				}
+
+				Context("when some instances breach the trigger for the whole breach duration", func() {
+					BeforeEach(func() {
+						instanceMetrics = nil
+						instanceMetrics = append(instanceMetrics, samples(0, "600", "650", "700")...)
+						instanceMetrics = append(instanceMetrics, samples(1, "600", "400", "600")...)
+						instanceMetrics = append(instanceMetrics, samples(2, "700", "700", "700")...)
+						instanceMetrics = append(instanceMetrics, samples(3, "400", "400", "400")...)
+						instanceMetrics = append(instanceMetrics, samples(4, "400", "400", "400")...)
+					})
+
+					It("retrieves the instance metrics rather than the app metrics", func() {
+						Eventually(instanceMetricsArgs).Should(Receive(Equal([]interface{}{testAppId, testMetricType})))
+						Consistently(database.RetrieveAppMetricsCallCount).Should(Equal(0))
+					})
+
+					It("sends a restart alarm for each breaching instance", func() {
+						Eventually(scalingEngine.ReceivedRequests).Should(HaveLen(2))
+						Consistently(scalingEngine.ReceivedRequests).Should(HaveLen(2))
+						requests := scalingEngine.ReceivedRequests()
+						Expect(requests[0].URL.Path).To(Equal(restartPath0))
+						Expect(requests[1].URL.Path).To(Equal(restartPath2))
+					})
+
+					It("should report the trigger array as done", func() {
+						Eventually(doneTriggers).Should(Receive(Equal(restartTriggerArray)))
+					})
+				})
+
+				Context("when more than a minority of the instances breach the trigger", func() {
+					BeforeEach(func() {
+						instanceMetrics = nil
+						instanceMetrics = append(instanceMetrics, samples(0, "600", "600", "600")...)
+						instanceMetrics = append(instanceMetrics, samples(1, "600", "600", "600")...)
+						instanceMetrics = append(instanceMetrics, samples(2, "600", "600", "600")...)
+						instanceMetrics = append(instanceMetrics, samples(3, "400", "400", "400")...)
+					})
+
+					It("sends restart alarms for at most a minority of the instances", func() {
+						Eventually(scalingEngine.ReceivedRequests).Should(HaveLen(1))
+						Consistently(scalingEngine.ReceivedRequests).Should(HaveLen(1))
+						Expect(scalingEngine.ReceivedRequests()[0].URL.Path).To(Equal(restartPath0))
+					})
+				})
+
+				Context("when the only instance breaches the trigger", func() {
+					BeforeEach(func() {
+						instanceMetrics = samples(0, "600", "600", "600")
+					})
+
+					It("sends a restart alarm for it", func() {
+						Eventually(scalingEngine.ReceivedRequests).Should(HaveLen(1))
+						Expect(scalingEngine.ReceivedRequests()[0].URL.Path).To(Equal(restartPath0))
+					})
+				})
+
+				Context("when the metrics of a breaching instance do not span the breach duration", func() {
+					BeforeEach(func() {
+						instanceMetrics = []*models.AppInstanceMetric{
+							sample(0, "600", 60*time.Second),
+							sample(0, "600", 10*time.Second),
+						}
+						instanceMetrics = append(instanceMetrics, samples(1, "400", "400", "400")...)
+						instanceMetrics = append(instanceMetrics, samples(2, "400", "400", "400")...)
+					})
+
+					It("should not send restart alarm", func() {
+						Consistently(scalingEngine.ReceivedRequests).Should(HaveLen(0))
+						Eventually(logger.LogMessages).Should(ContainElement(ContainSubstring("do not span the breach duration")))
+					})
+				})
+
+				Context("when a breaching instance has a single metric", func() {
+					BeforeEach(func() {
+						instanceMetrics = []*models.AppInstanceMetric{sample(0, "600", 290*time.Second)}
+					})
+
+					It("should not send restart alarm", func() {
+						Consistently(scalingEngine.ReceivedRequests).Should(HaveLen(0))
+					})
+				})
+
+				Context("when an instance has an unparsable metric", func() {
+					BeforeEach(func() {
+						instanceMetrics = samples(0, "600", "not-a-number", "600")
+					})
+
+					It("should not send restart alarm", func() {
+						Consistently(scalingEngine.ReceivedRequests).Should(HaveLen(0))
+					})
+				})
+
+				Context("when no instance breaches the trigger", func() {
+					BeforeEach(func() {
+						instanceMetrics = samples(0, "400", "400", "400")
+						instanceMetrics = append(instanceMetrics, samples(1, "300", "300", "300")...)
+					})
+
+					It("should not send restart alarm", func() {
+						Consistently(scalingEngine.ReceivedRequests).Should(HaveLen(0))
+						Eventually(logger.LogMessages).Should(ContainElement(ContainSubstring("should not send restart alarm to scaling engine")))
+					})
+				})
+
+				Context("when retrieving instance metrics fails", func() {
+					BeforeEach(func() {
+						instanceMetricsErr = errors.New("an error")
+					})
+
+					It("should not send restart alarm", func() {
+						Consistently(scalingEngine.ReceivedRequests).Should(HaveLen(0))
+						Eventually(logger.LogMessages).Should(ContainElement(ContainSubstring("retrieve instance metrics")))
+					})
+				})
+			})
+
+			Context("when the trigger resizes the app", func() {
+				var resizePath string
+				var resizeTriggerArray []*models.Trigger
+
+				BeforeEach(func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.ResizeRoute).URLPath("appid", testAppId)
+					Expect(err).NotTo(HaveOccurred())
+					resizePath = path.Path
+					scalingEngine.RouteToHandler("POST", resizePath, ghttp.RespondWith(http.StatusOK, "successful"))
+
+					resizeTriggerArray = []*models.Trigger{&models.Trigger{
+						AppId:                 testAppId,
+						MetricType:            testMetricType,
+						BreachDurationSeconds: 300,
+						CoolDownSeconds:       300,
+						Threshold:             500,
+						Operator:              ">",
+						Action:                models.ActionResize,
+						MemoryAdjustment:      "+256",
+					}}
+					database.RetrieveAppMetricsStub = func(appId string, metricType string, start int64, end int64) ([]*models.AppMetric, error) {
+						return appMetricGTUpper, nil
+					}
+					Expect(triggerChan).To(BeSent(resizeTriggerArray))
+				})
+
+				It("sends the trigger alarm to the resize route of scaling engine", func() {
+					Eventually(scalingEngine.ReceivedRequests).Should(HaveLen(1))
+					Expect(scalingEngine.ReceivedRequests()[0].URL.Path).To(Equal(resizePath))
+				})
+			})
+
 		})
 	})
 
@@ -555,7 +765,9 @@ var _ = Describe("Evaluator", func() {
 			database.RetrieveAppMetricsStub = func(appId string, metricType string, start int64, end int64) ([]*models.AppMetric, error) {
 				return nil, errors.New("no alarm")
 			}
-			evaluator = NewEvaluator(logger, httpClient, scalingEngine.URL(), triggerChan, database)
+			evaluator = NewEvaluator(logger, httpClient, scalingEngine.URL(), triggerChan, func(triggerArray []*models.Trigger) {
+				doneTriggers <- triggerArray
+			}, database, getInstanceMetrics)
 			evaluator.Start()
 			Expect(triggerChan).To(BeSent(triggerArrayGT))
 			Eventually(database.RetrieveAppMetricsCallCount).Should(Equal(1))
@@ -567,4 +779,89 @@ var _ = Describe("Evaluator", func() {
 			Eventually(triggerChan).ShouldNot(BeSent(triggerArrayGT))
 		})
 	})
+
+	Context("when a trigger alarm is being sent", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			scalingEngine.RouteToHandler("POST", urlPath, ghttp.CombineHandlers(
+				func(w http.ResponseWriter, req *http.Request) {
+					<-release
+				},
+				ghttp.RespondWith(http.StatusOK, "successful"),
+			))
+			database.RetrieveAppMetricsStub = func(appId string, metricType string, start int64, end int64) ([]*models.AppMetric, error) {
+				return appMetricGTUpper, nil
+			}
+
+			evaluator = NewEvaluator(logger, httpClient, scalingEngine.URL(), triggerChan, func(triggerArray []*models.Trigger) {
+				doneTriggers <- triggerArray
+			}, database, getInstanceMetrics)
+			evaluator.Start()
+			Expect(triggerChan).To(BeSent(triggerArrayGT))
+			Eventually(scalingEngine.ReceivedRequests).Should(HaveLen(1))
+		})
+
+		AfterEach(func() {
+			scalingEngine.Close()
+		})
+
+		Context("Drain", func() {
+			var (
+				deadline chan struct{}
+				drained  chan struct{}
+			)
+
+			BeforeEach(func() {
+				deadline = make(chan struct{})
+				drained = make(chan struct{})
+				Expect(triggerChan).To(BeSent(triggerArrayGT))
+			})
+
+			JustBeforeEach(func() {
+				go func() {
+					evaluator.Drain(deadline)
+					close(drained)
+				}()
+			})
+
+			It("should evaluate the trigger arrays left in the channel before stopping", func() {
+				Consistently(drained).ShouldNot(BeClosed())
+				close(release)
+
+				Eventually(drained).Should(BeClosed())
+				Expect(scalingEngine.ReceivedRequests()).To(HaveLen(2))
+				Expect(triggerChan).To(BeEmpty())
+			})
+
+			Context("when the deadline has passed", func() {
+				BeforeEach(func() {
+					close(deadline)
+				})
+
+				It("should leave the trigger arrays in the channel", func() {
+					close(release)
+
+					Eventually(drained).Should(BeClosed())
+					Expect(scalingEngine.ReceivedRequests()).To(HaveLen(1))
+					Expect(triggerChan).To(HaveLen(1))
+				})
+			})
+		})
+
+		Context("Stop", func() {
+			It("should wait for the trigger alarm to be sent", func() {
+				stopped := make(chan struct{})
+				go func() {
+					evaluator.Stop()
+					close(stopped)
+				}()
+				Consistently(stopped).ShouldNot(BeClosed())
+
+				close(release)
+				Eventually(stopped).Should(BeClosed())
+			})
+		})
+	})
 })
@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"autoscaler/models"
+	"autoscaler/routes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// GetInstanceMetricsFunc retrieves the metrics of every instance of an app
+// within the time range [start, end].
+type GetInstanceMetricsFunc func(appId string, metricType string, start int64, end int64) ([]*models.AppInstanceMetric, error)
+
+type MetricCollectorClient struct {
+	logger             lager.Logger
+	metricCollectorUrl string
+	httpClient         *http.Client
+}
+
+func NewMetricCollectorClient(logger lager.Logger, metricCollectorUrl string, httpClient *http.Client) *MetricCollectorClient {
+	return &MetricCollectorClient{
+		logger:             logger.Session("MetricCollectorClient"),
+		metricCollectorUrl: metricCollectorUrl,
+		httpClient:         httpClient,
+	}
+}
+
+func (c *MetricCollectorClient) GetInstanceMetrics(appId string, metricType string, start int64, end int64) ([]*models.AppInstanceMetric, error) {
+	if metricType != "MemoryUsage" {
+		return nil, fmt.Errorf("%s is not supported", metricType)
+	}
+
+	path, _ := routes.MetricsCollectorRoutes().Get(routes.MemoryMetricHistoryRoute).URLPath("appid", appId)
+	parameters := path.Query()
+	parameters.Add("start", strconv.FormatInt(start, 10))
+	parameters.Add("end", strconv.FormatInt(end, 10))
+	url := c.metricCollectorUrl + path.RequestURI() + "?" + parameters.Encode()
+
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		c.logger.Error("get-instance-metrics-request", err, lager.Data{"appId": appId, "metricType": metricType})
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("get-instance-metrics-response", nil, lager.Data{"appId": appId, "metricType": metricType, "statusCode": resp.StatusCode})
+		return nil, fmt.Errorf("failed to retrieve instance metrics from metrics collector: status code %d", resp.StatusCode)
+	}
+
+	var metrics []*models.AppInstanceMetric
+	err = json.NewDecoder(resp.Body).Decode(&metrics)
+	if err != nil {
+		c.logger.Error("get-instance-metrics-parse-response", err, lager.Data{"appId": appId, "metricType": metricType})
+		return nil, err
+	}
+	return metrics, nil
+}
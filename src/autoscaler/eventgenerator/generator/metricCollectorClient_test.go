@@ -0,0 +1,95 @@
+package generator_test
+
+import (
+	. "autoscaler/eventgenerator/generator"
+	"autoscaler/models"
+	"autoscaler/routes"
+	"net/http"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("MetricCollectorClient", func() {
+	var (
+		metricServer *ghttp.Server
+		client       *MetricCollectorClient
+		urlPath      string
+		metricType   string
+		metrics      []*models.AppInstanceMetric
+		err          error
+	)
+
+	BeforeEach(func() {
+		metricServer = ghttp.NewServer()
+		client = NewMetricCollectorClient(lagertest.NewTestLogger("metric-collector-client-test"), metricServer.URL(), cfhttp.NewClient())
+		metricType = "MemoryUsage"
+
+		path, err := routes.MetricsCollectorRoutes().Get(routes.MemoryMetricHistoryRoute).URLPath("appid", "an-app-id")
+		Expect(err).NotTo(HaveOccurred())
+		urlPath = path.Path
+	})
+
+	AfterEach(func() {
+		metricServer.Close()
+	})
+
+	JustBeforeEach(func() {
+		metrics, err = client.GetInstanceMetrics("an-app-id", metricType, 111, 222)
+	})
+
+	Context("when metrics collector returns the instance metrics", func() {
+		BeforeEach(func() {
+			metricServer.RouteToHandler("GET", urlPath, ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", urlPath, "end=222&start=111"),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, []*models.AppInstanceMetric{
+					{AppId: "an-app-id", InstanceIndex: 0, Name: "MemoryUsage", Unit: "bytes", Value: "100", Timestamp: 150},
+					{AppId: "an-app-id", InstanceIndex: 1, Name: "MemoryUsage", Unit: "bytes", Value: "200", Timestamp: 160},
+				}),
+			))
+		})
+
+		It("returns the instance metrics", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metrics).To(Equal([]*models.AppInstanceMetric{
+				{AppId: "an-app-id", InstanceIndex: 0, Name: "MemoryUsage", Unit: "bytes", Value: "100", Timestamp: 150},
+				{AppId: "an-app-id", InstanceIndex: 1, Name: "MemoryUsage", Unit: "bytes", Value: "200", Timestamp: 160},
+			}))
+		})
+	})
+
+	Context("when metrics collector returns an error status", func() {
+		BeforeEach(func() {
+			metricServer.RouteToHandler("GET", urlPath, ghttp.RespondWithJSONEncoded(http.StatusInternalServerError,
+				models.ErrorResponse{Code: "Interal-Server-Error", Message: "an error"}))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("status code 500")))
+		})
+	})
+
+	Context("when the response can not be parsed", func() {
+		BeforeEach(func() {
+			metricServer.RouteToHandler("GET", urlPath, ghttp.RespondWith(http.StatusOK, "not-json"))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the metric type is not supported", func() {
+		BeforeEach(func() {
+			metricType = "CPUUsage"
+		})
+
+		It("returns an error without querying metrics collector", func() {
+			Expect(err).To(MatchError("CPUUsage is not supported"))
+			Expect(metricServer.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,30 @@
+package generator
+
+import (
+	"autoscaler/metrics"
+)
+
+var (
+	evaluations = metrics.NewCounter(
+		"autoscaler_eventgenerator_evaluations_total",
+		"Number of trigger evaluations performed by the evaluators.",
+	)
+	alarmsSent = metrics.NewCounter(
+		"autoscaler_eventgenerator_alarms_sent_total",
+		"Number of trigger alarms sent to the scaling engine, by result.",
+		"result",
+	)
+	droppedTriggerArrays = metrics.NewCounter(
+		"autoscaler_eventgenerator_trigger_arrays_dropped_total",
+		"Number of trigger arrays not enqueued for the evaluators, by reason.",
+		"reason",
+	)
+	skippedEvaluationTicks = metrics.NewCounter(
+		"autoscaler_eventgenerator_evaluation_ticks_skipped_total",
+		"Number of evaluation manager ticks skipped because the trigger arrays of the previous round were not drained.",
+	)
+)
+
+func init() {
+	metrics.MustRegister(evaluations, alarmsSent, droppedTriggerArrays, skippedEvaluationTicks)
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/lager"
+
+	"net/http"
+)
+
+type GetAnomalousInstancesFunc func(appId string) []*models.AnomalousInstance
+
+// AnomalyHandler lists the instances currently excluded from the app metrics
+// as outliers.
+type AnomalyHandler struct {
+	logger                lager.Logger
+	getAnomalousInstances GetAnomalousInstancesFunc
+}
+
+func NewAnomalyHandler(logger lager.Logger, getAnomalousInstances GetAnomalousInstancesFunc) *AnomalyHandler {
+	return &AnomalyHandler{
+		logger:                logger,
+		getAnomalousInstances: getAnomalousInstances,
+	}
+}
+
+func (h *AnomalyHandler) GetAnomalousInstances(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	appId := vars["appid"]
+	instances := h.getAnomalousInstances(appId)
+	h.logger.Debug("get-anomalous-instances", lager.Data{"appid": appId, "count": len(instances)})
+	handlers.WriteJSONResponse(w, http.StatusOK, instances)
+}
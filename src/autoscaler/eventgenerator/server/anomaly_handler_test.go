@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	. "autoscaler/eventgenerator/server"
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+var _ = Describe("AnomalyHandler", func() {
+
+	var (
+		instances   []*models.AnomalousInstance
+		requestedId string
+		handler     *AnomalyHandler
+		resp        *httptest.ResponseRecorder
+		req         *http.Request
+		err         error
+	)
+
+	BeforeEach(func() {
+		instances = []*models.AnomalousInstance{}
+		resp = httptest.NewRecorder()
+		handler = NewAnomalyHandler(lager.NewLogger("handler-test"), func(appId string) []*models.AnomalousInstance {
+			requestedId = appId
+			return instances
+		})
+	})
+
+	JustBeforeEach(func() {
+		req, err = http.NewRequest(http.MethodGet, "http://localhost/v1/apps/an-app-id/anomalous_instances", nil)
+		Expect(err).NotTo(HaveOccurred())
+		handler.GetAnomalousInstances(resp, req, map[string]string{"appid": "an-app-id"})
+	})
+
+	Context("when the app has no anomalous instance", func() {
+		It("returns an empty list", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(requestedId).To(Equal("an-app-id"))
+			Expect(resp.Body.String()).To(MatchJSON("[]"))
+		})
+	})
+
+	Context("when the app has anomalous instances", func() {
+		BeforeEach(func() {
+			instances = []*models.AnomalousInstance{
+				{
+					AppId:         "an-app-id",
+					InstanceIndex: 2,
+					MetricType:    "MemoryUsage",
+					Value:         900,
+					Median:        200,
+					Score:         47.2,
+					Timestamp:     12345,
+				},
+			}
+		})
+
+		It("returns the anomalous instances", func() {
+			Expect(resp.Code).To(Equal(http.StatusOK))
+
+			result := []*models.AnomalousInstance{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &result)).To(Succeed())
+			Expect(result).To(Equal(instances))
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"autoscaler/eventgenerator/config"
+	"autoscaler/routes"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/http_server"
+)
+
+type VarsFunc func(w http.ResponseWriter, r *http.Request, vars map[string]string)
+
+func (vh VarsFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	vh(w, r, vars)
+}
+
+func NewServer(logger lager.Logger, conf *config.Config, getAnomalousInstances GetAnomalousInstancesFunc) (ifrit.Runner, error) {
+	ah := NewAnomalyHandler(logger, getAnomalousInstances)
+
+	r := routes.EventGeneratorRoutes()
+	r.Get(routes.AnomalousInstancesRoute).Methods(http.MethodGet).Handler(VarsFunc(ah.GetAnomalousInstances))
+
+	addr := fmt.Sprintf("0.0.0.0:%d", conf.Server.Port)
+	logger.Info("new-http-server", lager.Data{"serverConfig": conf.Server})
+
+	if (conf.Server.TLS.KeyFile != "") && (conf.Server.TLS.CertFile != "") {
+		tlsConfig, err := cfhttp.NewTLSConfig(conf.Server.TLS.CertFile, conf.Server.TLS.KeyFile, conf.Server.TLS.CACertFile)
+		if err != nil {
+			logger.Error("failed-new-server-new-tls-config", err, lager.Data{"tls": conf.Server.TLS})
+			return nil, err
+		}
+		return http_server.NewTLSServer(addr, r, tlsConfig), nil
+	}
+
+	return http_server.New(addr, r), nil
+}
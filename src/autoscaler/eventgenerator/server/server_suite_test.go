@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"autoscaler/eventgenerator/config"
+	"autoscaler/eventgenerator/server"
+	"autoscaler/models"
+	"net/url"
+	"strconv"
+
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	"testing"
+)
+
+var (
+	serverProcess ifrit.Process
+	serverUrl     *url.URL
+)
+
+func TestServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Server Suite")
+}
+
+var _ = BeforeSuite(func() {
+	port := 1112
+	conf := &config.Config{
+		Server: config.ServerConfig{
+			Port: port,
+		},
+	}
+	getAnomalousInstances := func(appId string) []*models.AnomalousInstance {
+		return []*models.AnomalousInstance{}
+	}
+
+	httpServer, err := server.NewServer(lager.NewLogger("test"), conf, getAnomalousInstances)
+	Expect(err).NotTo(HaveOccurred())
+
+	serverUrl, err = url.Parse("http://127.0.0.1:" + strconv.Itoa(port))
+	Expect(err).ToNot(HaveOccurred())
+
+	serverProcess = ginkgomon.Invoke(httpServer)
+})
+
+var _ = AfterSuite(func() {
+	ginkgomon.Interrupt(serverProcess)
+})
@@ -0,0 +1,49 @@
+package server_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"net/http"
+)
+
+const TestPathAnomalousInstances = "/v1/apps/an-app-id/anomalous_instances"
+
+var _ = Describe("Server", func() {
+	var (
+		rsp *http.Response
+		err error
+	)
+
+	Context("when retrieving anomalous instances", func() {
+		BeforeEach(func() {
+			serverUrl.Path = TestPathAnomalousInstances
+		})
+
+		JustBeforeEach(func() {
+			rsp, err = http.Get(serverUrl.String())
+		})
+
+		It("should return 200", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+			rsp.Body.Close()
+		})
+	})
+
+	Context("when requesting the wrong path", func() {
+		BeforeEach(func() {
+			serverUrl.Path = "/not-exist-path"
+		})
+
+		JustBeforeEach(func() {
+			rsp, err = http.Get(serverUrl.String())
+		})
+
+		It("should return 404", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+			rsp.Body.Close()
+		})
+	})
+})
@@ -0,0 +1,52 @@
+package healthendpoint
+
+import (
+	"autoscaler/cf"
+
+	"code.cloudfoundry.org/clock"
+
+	"fmt"
+	"time"
+)
+
+type Check func() error
+
+type Checks map[string]Check
+
+type Pinger interface {
+	Ping() error
+}
+
+func PingCheck(p Pinger) Check {
+	return p.Ping
+}
+
+// TickCheck fails when the loop reporting lastTick has not ticked within maxAge.
+// Until the first tick the time the check was created is used instead.
+func TickCheck(lastTick func() time.Time, maxAge time.Duration, clk clock.Clock) Check {
+	created := clk.Now()
+	return func() error {
+		last := lastTick()
+		if last.IsZero() {
+			last = created
+		}
+		age := clk.Since(last)
+		if age > maxAge {
+			return fmt.Errorf("last tick was %s ago, exceeding %s", age, maxAge)
+		}
+		return nil
+	}
+}
+
+// CfTokenCheck refreshes the cloud foundry access token when it is about to
+// expire, as a component without cloud foundry requests would otherwise let it
+// expire. It fails only when refreshing fails and the token has expired.
+func CfTokenCheck(cfc cf.CfClient) Check {
+	return func() error {
+		_, err := cfc.GetTokensWithRefresh()
+		if err != nil && !cfc.IsTokenValid() {
+			return fmt.Errorf("cloud foundry access token is not valid: %s", err)
+		}
+		return nil
+	}
+}
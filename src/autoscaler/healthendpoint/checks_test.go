@@ -0,0 +1,88 @@
+package healthendpoint_test
+
+import (
+	"autoscaler/cf"
+	. "autoscaler/healthendpoint"
+	"autoscaler/metricscollector/fakes"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"errors"
+	"time"
+)
+
+var _ = Describe("Checks", func() {
+	Describe("TickCheck", func() {
+		var (
+			fclock   *fakeclock.FakeClock
+			lastTick time.Time
+			check    Check
+		)
+
+		BeforeEach(func() {
+			fclock = fakeclock.NewFakeClock(time.Now())
+			lastTick = time.Time{}
+			check = TickCheck(func() time.Time { return lastTick }, 30*time.Second, fclock)
+		})
+
+		Context("when the loop has not ticked yet", func() {
+			It("should pass within maxAge of the check being created", func() {
+				fclock.Increment(30 * time.Second)
+				Expect(check()).To(Succeed())
+			})
+
+			It("should fail after maxAge of the check being created", func() {
+				fclock.Increment(31 * time.Second)
+				Expect(check()).To(HaveOccurred())
+			})
+		})
+
+		Context("when the loop has ticked", func() {
+			BeforeEach(func() {
+				fclock.Increment(time.Minute)
+				lastTick = fclock.Now()
+			})
+
+			It("should pass within maxAge of the last tick", func() {
+				fclock.Increment(30 * time.Second)
+				Expect(check()).To(Succeed())
+			})
+
+			It("should fail after maxAge of the last tick", func() {
+				fclock.Increment(31 * time.Second)
+				Expect(check()).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("CfTokenCheck", func() {
+		var cfc *fakes.FakeCfClient
+
+		BeforeEach(func() {
+			cfc = &fakes.FakeCfClient{}
+		})
+
+		It("should refresh the token and succeed", func() {
+			Expect(CfTokenCheck(cfc)()).To(Succeed())
+			Expect(cfc.GetTokensWithRefreshCallCount()).To(Equal(1))
+		})
+
+		Context("when refreshing the token fails", func() {
+			BeforeEach(func() {
+				cfc.GetTokensWithRefreshReturns(cf.Tokens{}, errors.New("an error"))
+			})
+
+			It("should succeed while the token is still valid", func() {
+				cfc.IsTokenValidReturns(true)
+				Expect(CfTokenCheck(cfc)()).To(Succeed())
+			})
+
+			It("should fail when the token has expired", func() {
+				cfc.IsTokenValidReturns(false)
+				Expect(CfTokenCheck(cfc)()).To(MatchError("cloud foundry access token is not valid: an error"))
+			})
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package healthendpoint_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHealthendpoint(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Healthendpoint Suite")
+}
@@ -0,0 +1,79 @@
+package healthendpoint
+
+import (
+	"autoscaler/metrics"
+	"autoscaler/routes"
+
+	"code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/http_server"
+
+	"fmt"
+	"net/http"
+)
+
+const (
+	StatusUp   = "UP"
+	StatusDown = "DOWN"
+)
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type healthHandler struct {
+	logger lager.Logger
+	checks Checks
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{
+		Status: StatusUp,
+		Checks: make(map[string]CheckResult, len(h.checks)),
+	}
+	for name, check := range h.checks {
+		err := check()
+		if err != nil {
+			h.logger.Error("check-failed", err, lager.Data{"check": name})
+			response.Status = StatusDown
+			response.Checks[name] = CheckResult{Status: StatusDown, Error: err.Error()}
+			continue
+		}
+		response.Checks[name] = CheckResult{Status: StatusUp}
+	}
+
+	status := http.StatusOK
+	if response.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	handlers.WriteJSONResponse(w, status, response)
+}
+
+// NewServer serves the metrics in registry along with /health, which runs the
+// liveness checks, and /ready, which runs both the liveness and readiness checks.
+func NewServer(logger lager.Logger, port int, registry *metrics.Registry, liveness Checks, readiness Checks) (ifrit.Runner, error) {
+	all := Checks{}
+	for name, check := range liveness {
+		all[name] = check
+	}
+	for name, check := range readiness {
+		all[name] = check
+	}
+
+	r := routes.HealthRoutes()
+	r.Get(routes.MetricsRoute).Methods(http.MethodGet).Handler(registry)
+	r.Get(routes.HealthRoute).Methods(http.MethodGet).Handler(&healthHandler{logger: logger.Session("health"), checks: liveness})
+	r.Get(routes.ReadyRoute).Methods(http.MethodGet).Handler(&healthHandler{logger: logger.Session("ready"), checks: all})
+
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+	logger.Info("new-health-server", lager.Data{"addr": addr})
+
+	return http_server.New(addr, r), nil
+}
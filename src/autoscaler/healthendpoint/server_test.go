@@ -0,0 +1,163 @@
+package healthendpoint_test
+
+import (
+	. "autoscaler/healthendpoint"
+	"autoscaler/metrics"
+
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		server       ifrit.Process
+		serverUrl    string
+		registry     *metrics.Registry
+		counter      *metrics.Counter
+		liveness     Checks
+		readiness    Checks
+		livenessErr  error
+		readinessErr error
+		rsp          *http.Response
+		err          error
+	)
+
+	BeforeEach(func() {
+		registry = metrics.NewRegistry()
+		counter = metrics.NewCounter("test_requests_total", "Number of test requests.")
+		registry.MustRegister(counter)
+
+		livenessErr = nil
+		readinessErr = nil
+		liveness = Checks{"loop": func() error { return livenessErr }}
+		readiness = Checks{"db": func() error { return readinessErr }}
+
+		port := 2323 + GinkgoParallelNode()
+		healthServer, err := NewServer(lager.NewLogger("test"), port, registry, liveness, readiness)
+		Expect(err).NotTo(HaveOccurred())
+		server = ginkgomon.Invoke(healthServer)
+		serverUrl = fmt.Sprintf("http://127.0.0.1:%d", port)
+	})
+
+	AfterEach(func() {
+		ginkgomon.Interrupt(server)
+	})
+
+	Context("when retrieving metrics", func() {
+		JustBeforeEach(func() {
+			counter.Add(3)
+			rsp, err = http.Get(serverUrl + "/metrics")
+		})
+
+		It("should return 200 with the metrics in text exposition format", func() {
+			Expect(err).NotTo(HaveOccurred())
+			defer rsp.Body.Close()
+
+			Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+			Expect(rsp.Header.Get("Content-Type")).To(Equal(metrics.ContentTypeText))
+
+			body, err := ioutil.ReadAll(rsp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal("# HELP test_requests_total Number of test requests.\n" +
+				"# TYPE test_requests_total counter\n" +
+				"test_requests_total 3\n"))
+		})
+	})
+
+	Context("when using wrong method to retrieve metrics", func() {
+		JustBeforeEach(func() {
+			rsp, err = http.Post(serverUrl+"/metrics", "text/plain", nil)
+		})
+
+		It("should not return 200", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rsp.StatusCode).NotTo(Equal(http.StatusOK))
+			rsp.Body.Close()
+		})
+	})
+
+	Context("when checking health", func() {
+		var health HealthResponse
+
+		JustBeforeEach(func() {
+			rsp, err = http.Get(serverUrl + "/health")
+			Expect(err).NotTo(HaveOccurred())
+			defer rsp.Body.Close()
+			Expect(json.NewDecoder(rsp.Body).Decode(&health)).To(Succeed())
+		})
+
+		Context("when the liveness checks pass", func() {
+			BeforeEach(func() {
+				readinessErr = errors.New("db is down")
+			})
+
+			It("should return 200 regardless of the readiness checks", func() {
+				Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+				Expect(health).To(Equal(HealthResponse{
+					Status: StatusUp,
+					Checks: map[string]CheckResult{"loop": {Status: StatusUp}},
+				}))
+			})
+		})
+
+		Context("when a liveness check fails", func() {
+			BeforeEach(func() {
+				livenessErr = errors.New("loop is stuck")
+			})
+
+			It("should return 503 with the failed check", func() {
+				Expect(rsp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(health).To(Equal(HealthResponse{
+					Status: StatusDown,
+					Checks: map[string]CheckResult{"loop": {Status: StatusDown, Error: "loop is stuck"}},
+				}))
+			})
+		})
+	})
+
+	Context("when checking readiness", func() {
+		var health HealthResponse
+
+		JustBeforeEach(func() {
+			rsp, err = http.Get(serverUrl + "/ready")
+			Expect(err).NotTo(HaveOccurred())
+			defer rsp.Body.Close()
+			Expect(json.NewDecoder(rsp.Body).Decode(&health)).To(Succeed())
+		})
+
+		Context("when all checks pass", func() {
+			It("should return 200", func() {
+				Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+				Expect(health).To(Equal(HealthResponse{
+					Status: StatusUp,
+					Checks: map[string]CheckResult{
+						"loop": {Status: StatusUp},
+						"db":   {Status: StatusUp},
+					},
+				}))
+			})
+		})
+
+		Context("when a readiness check fails", func() {
+			BeforeEach(func() {
+				readinessErr = errors.New("db is down")
+			})
+
+			It("should return 503 with the failed check", func() {
+				Expect(rsp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(health.Status).To(Equal(StatusDown))
+				Expect(health.Checks["db"]).To(Equal(CheckResult{Status: StatusDown, Error: "db is down"}))
+				Expect(health.Checks["loop"]).To(Equal(CheckResult{Status: StatusUp}))
+			})
+		})
+	})
+})
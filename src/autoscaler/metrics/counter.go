@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const labelValueSeparator = "\xff"
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+type seriesSet struct {
+	labelNames []string
+	lock       sync.Mutex
+	series     map[string]*series
+}
+
+func newSeriesSet(labelNames []string) *seriesSet {
+	return &seriesSet{
+		labelNames: labelNames,
+		series:     make(map[string]*series),
+	}
+}
+
+func (ss *seriesSet) get(labelValues []string) *series {
+	if len(labelValues) != len(ss.labelNames) {
+		panic(fmt.Sprintf("expected %d label values but got %d", len(ss.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, labelValueSeparator)
+	s, exist := ss.series[key]
+	if !exist {
+		s = &series{labelValues: append([]string{}, labelValues...)}
+		ss.series[key] = s
+	}
+	return s
+}
+
+func (ss *seriesSet) add(delta float64, labelValues []string) {
+	ss.lock.Lock()
+	ss.get(labelValues).value += delta
+	ss.lock.Unlock()
+}
+
+func (ss *seriesSet) set(v float64, labelValues []string) {
+	ss.lock.Lock()
+	ss.get(labelValues).value = v
+	ss.lock.Unlock()
+}
+
+func (ss *seriesSet) value(labelValues []string) float64 {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	return ss.get(labelValues).value
+}
+
+func (ss *seriesSet) samples() []Sample {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	keys := make([]string, 0, len(ss.series))
+	for key := range ss.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	samples := make([]Sample, 0, len(keys))
+	for _, key := range keys {
+		s := ss.series[key]
+		samples = append(samples, Sample{
+			Labels: labelPairs(ss.labelNames, s.labelValues),
+			Value:  s.value,
+		})
+	}
+	return samples
+}
+
+func labelPairs(names []string, values []string) []LabelPair {
+	pairs := make([]LabelPair, len(names))
+	for i := range names {
+		pairs[i] = LabelPair{Name: names[i], Value: values[i]}
+	}
+	return pairs
+}
+
+type Counter struct {
+	name string
+	help string
+	set  *seriesSet
+}
+
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		name: name,
+		help: help,
+		set:  newSeriesSet(labelNames),
+	}
+	if len(labelNames) == 0 {
+		c.set.add(0, nil)
+	}
+	return c
+}
+
+func (c *Counter) Name() string {
+	return c.name
+}
+
+func (c *Counter) Help() string {
+	return c.help
+}
+
+func (c *Counter) Type() string {
+	return "counter"
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.set.add(1, labelValues)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("counter cannot decrease in value")
+	}
+	c.set.add(delta, labelValues)
+}
+
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.set.value(labelValues)
+}
+
+func (c *Counter) Samples() []Sample {
+	return c.set.samples()
+}
@@ -0,0 +1,85 @@
+package metrics
+
+type Gauge struct {
+	name string
+	help string
+	set  *seriesSet
+}
+
+func NewGauge(name string, help string, labelNames ...string) *Gauge {
+	g := &Gauge{
+		name: name,
+		help: help,
+		set:  newSeriesSet(labelNames),
+	}
+	if len(labelNames) == 0 {
+		g.set.add(0, nil)
+	}
+	return g
+}
+
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+func (g *Gauge) Help() string {
+	return g.help
+}
+
+func (g *Gauge) Type() string {
+	return "gauge"
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.set.set(v, labelValues)
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.set.add(1, labelValues)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.set.add(-1, labelValues)
+}
+
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.set.add(delta, labelValues)
+}
+
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.set.value(labelValues)
+}
+
+func (g *Gauge) Samples() []Sample {
+	return g.set.samples()
+}
+
+type GaugeFunc struct {
+	name     string
+	help     string
+	function func() float64
+}
+
+func NewGaugeFunc(name string, help string, function func() float64) *GaugeFunc {
+	return &GaugeFunc{
+		name:     name,
+		help:     help,
+		function: function,
+	}
+}
+
+func (g *GaugeFunc) Name() string {
+	return g.name
+}
+
+func (g *GaugeFunc) Help() string {
+	return g.help
+}
+
+func (g *GaugeFunc) Type() string {
+	return "gauge"
+}
+
+func (g *GaugeFunc) Samples() []Sample {
+	return []Sample{{Value: g.function()}}
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type histogramSeries struct {
+	labelValues  []string
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+type Histogram struct {
+	name       string
+	help       string
+	buckets    []float64
+	labelNames []string
+	lock       sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+func NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	if math.IsInf(sorted[len(sorted)-1], 1) {
+		sorted = sorted[:len(sorted)-1]
+	}
+
+	h := &Histogram{
+		name:       name,
+		help:       help,
+		buckets:    sorted,
+		labelNames: labelNames,
+		series:     make(map[string]*histogramSeries),
+	}
+	if len(labelNames) == 0 {
+		h.lock.Lock()
+		h.get(nil)
+		h.lock.Unlock()
+	}
+	return h
+}
+
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+func (h *Histogram) Help() string {
+	return h.help
+}
+
+func (h *Histogram) Type() string {
+	return "histogram"
+}
+
+func (h *Histogram) get(labelValues []string) *histogramSeries {
+	if len(labelValues) != len(h.labelNames) {
+		panic("unexpected number of label values for histogram " + h.name)
+	}
+	key := strings.Join(labelValues, labelValueSeparator)
+	s, exist := h.series[key]
+	if !exist {
+		s = &histogramSeries{
+			labelValues:  append([]string{}, labelValues...),
+			bucketCounts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	return s
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s := h.get(labelValues)
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.bucketCounts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.get(labelValues).count
+}
+
+func (h *Histogram) Sum(labelValues ...string) float64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.get(labelValues).sum
+}
+
+func (h *Histogram) Samples() []Sample {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	samples := []Sample{}
+	for _, key := range keys {
+		s := h.series[key]
+		labels := labelPairs(h.labelNames, s.labelValues)
+		for i, upper := range h.buckets {
+			samples = append(samples, Sample{
+				Suffix: "_bucket",
+				Labels: append(labels[:len(labels):len(labels)], LabelPair{Name: "le", Value: formatValue(upper)}),
+				Value:  float64(s.bucketCounts[i]),
+			})
+		}
+		samples = append(samples, Sample{
+			Suffix: "_bucket",
+			Labels: append(labels[:len(labels):len(labels)], LabelPair{Name: "le", Value: "+Inf"}),
+			Value:  float64(s.count),
+		})
+		samples = append(samples, Sample{
+			Suffix: "_sum",
+			Labels: labels,
+			Value:  s.sum,
+		})
+		samples = append(samples, Sample{
+			Suffix: "_count",
+			Labels: labels,
+			Value:  float64(s.count),
+		})
+	}
+	return samples
+}
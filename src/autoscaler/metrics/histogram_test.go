@@ -0,0 +1,54 @@
+package metrics_test
+
+import (
+	. "autoscaler/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Histogram", func() {
+	var histogram *Histogram
+
+	Context("when observing values without labels", func() {
+		BeforeEach(func() {
+			histogram = NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1})
+			histogram.Observe(0.05)
+			histogram.Observe(0.5)
+			histogram.Observe(5)
+		})
+
+		It("should count and sum the observations", func() {
+			Expect(histogram.Count()).To(Equal(uint64(3)))
+			Expect(histogram.Sum()).To(BeNumerically("~", 5.55, 0.0001))
+		})
+
+		It("should report cumulative buckets in ascending order", func() {
+			samples := histogram.Samples()
+			Expect(samples).To(HaveLen(5))
+			Expect(samples[0]).To(Equal(Sample{Suffix: "_bucket", Labels: []LabelPair{{Name: "le", Value: "0.1"}}, Value: 1}))
+			Expect(samples[1]).To(Equal(Sample{Suffix: "_bucket", Labels: []LabelPair{{Name: "le", Value: "1"}}, Value: 2}))
+			Expect(samples[2]).To(Equal(Sample{Suffix: "_bucket", Labels: []LabelPair{{Name: "le", Value: "+Inf"}}, Value: 3}))
+			Expect(samples[3].Suffix).To(Equal("_sum"))
+			Expect(samples[4]).To(Equal(Sample{Suffix: "_count", Labels: []LabelPair{}, Value: 3}))
+		})
+	})
+
+	Context("when observing values with labels", func() {
+		BeforeEach(func() {
+			histogram = NewHistogram("query_seconds", "Query latency.", []float64{1}, "query")
+			histogram.Observe(0.5, "select")
+			histogram.Observe(2, "delete")
+		})
+
+		It("should keep a series per label value", func() {
+			Expect(histogram.Count("select")).To(Equal(uint64(1)))
+			Expect(histogram.Count("delete")).To(Equal(uint64(1)))
+			Expect(histogram.Samples()).To(ContainElement(Sample{Suffix: "_bucket", Labels: []LabelPair{{Name: "query", Value: "delete"}, {Name: "le", Value: "1"}}, Value: 0}))
+		})
+
+		It("should panic when the number of label values is wrong", func() {
+			Expect(func() { histogram.Observe(1) }).To(Panic())
+		})
+	})
+})
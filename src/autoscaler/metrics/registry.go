@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const ContentTypeText = "text/plain; version=0.0.4; charset=utf-8"
+
+type Metric interface {
+	Name() string
+	Help() string
+	Type() string
+	Samples() []Sample
+}
+
+type Sample struct {
+	Suffix string
+	Labels []LabelPair
+	Value  float64
+}
+
+type LabelPair struct {
+	Name  string
+	Value string
+}
+
+type Registry struct {
+	lock    sync.Mutex
+	metrics map[string]Metric
+}
+
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]Metric),
+	}
+}
+
+func (r *Registry) Register(m Metric) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exist := r.metrics[m.Name()]; exist {
+		return fmt.Errorf("metric %s is already registered", m.Name())
+	}
+	r.metrics[m.Name()] = m
+	return nil
+}
+
+func (r *Registry) MustRegister(metrics ...Metric) {
+	for _, m := range metrics {
+		if err := r.Register(m); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	delete(r.metrics, name)
+	r.lock.Unlock()
+}
+
+func MustRegister(metrics ...Metric) {
+	DefaultRegistry.MustRegister(metrics...)
+}
+
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]Metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.lock.Unlock()
+
+	buf := &bytes.Buffer{}
+	for _, m := range metrics {
+		fmt.Fprintf(buf, "# HELP %s %s\n", m.Name(), escapeHelp(m.Help()))
+		fmt.Fprintf(buf, "# TYPE %s %s\n", m.Name(), m.Type())
+		for _, s := range m.Samples() {
+			buf.WriteString(m.Name())
+			buf.WriteString(s.Suffix)
+			writeLabels(buf, s.Labels)
+			buf.WriteString(" ")
+			buf.WriteString(formatValue(s.Value))
+			buf.WriteString("\n")
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+// Value returns the value of a sample as written by WriteTo, identified by the
+// metric name followed by the sample suffix and labels, e.g.
+// `calls_total{status="failed"}`. It returns 0 for a sample that has not been
+// written yet, like a labelled counter that was never incremented.
+func (r *Registry) Value(sample string) float64 {
+	r.lock.Lock()
+	metrics := make([]Metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.lock.Unlock()
+
+	for _, m := range metrics {
+		if !strings.HasPrefix(sample, m.Name()) {
+			continue
+		}
+		for _, s := range m.Samples() {
+			buf := &bytes.Buffer{}
+			buf.WriteString(m.Name())
+			buf.WriteString(s.Suffix)
+			writeLabels(buf, s.Labels)
+			if buf.String() == sample {
+				return s.Value
+			}
+		}
+	}
+	return 0
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentTypeText)
+	r.WriteTo(w)
+}
+
+func writeLabels(buf *bytes.Buffer, labels []LabelPair) {
+	if len(labels) == 0 {
+		return
+	}
+	buf.WriteString("{")
+	for i, l := range labels {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(l.Name)
+		buf.WriteString(`="`)
+		buf.WriteString(escapeLabelValue(l.Value))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString("}")
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,148 @@
+package metrics_test
+
+import (
+	. "autoscaler/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		registry *Registry
+		buffer   *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		registry = NewRegistry()
+		buffer = &bytes.Buffer{}
+	})
+
+	Describe("Register", func() {
+		It("should fail to register two metrics with the same name", func() {
+			Expect(registry.Register(NewCounter("test_total", "help"))).To(Succeed())
+			Expect(registry.Register(NewGauge("test_total", "help"))).To(MatchError("metric test_total is already registered"))
+		})
+
+		It("should allow registering a name again after unregistering it", func() {
+			registry.MustRegister(NewCounter("test_total", "help"))
+			registry.Unregister("test_total")
+			Expect(registry.Register(NewCounter("test_total", "help"))).To(Succeed())
+		})
+	})
+
+	Describe("WriteTo", func() {
+		JustBeforeEach(func() {
+			_, err := registry.WriteTo(buffer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when there are no metrics", func() {
+			It("should write nothing", func() {
+				Expect(buffer.String()).To(BeEmpty())
+			})
+		})
+
+		Context("when there are unlabelled metrics", func() {
+			BeforeEach(func() {
+				gauge := NewGauge("b_gauge", "A gauge.")
+				gauge.Set(2.5)
+				counter := NewCounter("a_total", "A counter.")
+				counter.Inc()
+				registry.MustRegister(gauge, counter)
+			})
+
+			It("should write the metrics sorted by name", func() {
+				Expect(buffer.String()).To(Equal("# HELP a_total A counter.\n" +
+					"# TYPE a_total counter\n" +
+					"a_total 1\n" +
+					"# HELP b_gauge A gauge.\n" +
+					"# TYPE b_gauge gauge\n" +
+					"b_gauge 2.5\n"))
+			})
+		})
+
+		Context("when there are labelled metrics", func() {
+			BeforeEach(func() {
+				counter := NewCounter("calls_total", "Calls.", "status", "type")
+				counter.Inc("succeeded", "dynamic")
+				counter.Add(2, "failed", "schedule")
+				registry.MustRegister(counter)
+			})
+
+			It("should write one sample per label set", func() {
+				Expect(buffer.String()).To(Equal("# HELP calls_total Calls.\n" +
+					"# TYPE calls_total counter\n" +
+					`calls_total{status="failed",type="schedule"} 2` + "\n" +
+					`calls_total{status="succeeded",type="dynamic"} 1` + "\n"))
+			})
+		})
+
+		Context("when help text and label values contain special characters", func() {
+			BeforeEach(func() {
+				gauge := NewGauge("special", "line one\nback\\slash", "name")
+				gauge.Set(math.Inf(1), `quote"d`)
+				registry.MustRegister(gauge)
+			})
+
+			It("should escape them", func() {
+				Expect(buffer.String()).To(Equal("# HELP special line one\\nback\\\\slash\n" +
+					"# TYPE special gauge\n" +
+					`special{name="quote\"d"} +Inf` + "\n"))
+			})
+		})
+
+		Context("when there is a gauge func", func() {
+			BeforeEach(func() {
+				registry.MustRegister(NewGaugeFunc("queue_depth", "Queue depth.", func() float64 { return 7 }))
+			})
+
+			It("should write the current value of the function", func() {
+				Expect(buffer.String()).To(ContainSubstring("queue_depth 7\n"))
+			})
+		})
+	})
+
+	Describe("Value", func() {
+		BeforeEach(func() {
+			counter := NewCounter("calls_total", "Calls.", "status")
+			counter.Add(2, "failed")
+			gauge := NewGauge("a_gauge", "A gauge.")
+			gauge.Set(2.5)
+			registry.MustRegister(counter, gauge)
+		})
+
+		It("should return the value of an unlabelled sample", func() {
+			Expect(registry.Value("a_gauge")).To(Equal(2.5))
+		})
+
+		It("should return the value of a labelled sample", func() {
+			Expect(registry.Value(`calls_total{status="failed"}`)).To(Equal(float64(2)))
+		})
+
+		It("should return 0 for a sample not written yet", func() {
+			Expect(registry.Value(`calls_total{status="succeeded"}`)).To(BeZero())
+			Expect(registry.Value("unknown_total")).To(BeZero())
+		})
+	})
+
+	Describe("ServeHTTP", func() {
+		It("should serve the metrics with the text exposition content type", func() {
+			registry.MustRegister(NewCounter("a_total", "A counter."))
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+			Expect(err).NotTo(HaveOccurred())
+			registry.ServeHTTP(recorder, req)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal(ContentTypeText))
+			Expect(recorder.Body.String()).To(ContainSubstring("a_total 0\n"))
+		})
+	})
+})
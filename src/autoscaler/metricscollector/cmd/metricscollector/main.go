@@ -1,7 +1,6 @@
 package main
 
 import (
-	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
@@ -10,9 +9,12 @@ import (
 	"autoscaler/cf"
 	"autoscaler/db"
 	"autoscaler/db/sqldb"
+	"autoscaler/healthendpoint"
+	"autoscaler/metrics"
 	"autoscaler/metricscollector/collector"
 	"autoscaler/metricscollector/config"
 	"autoscaler/metricscollector/server"
+	"autoscaler/sharding"
 
 	"code.cloudfoundry.org/cfhttp"
 	"code.cloudfoundry.org/clock"
@@ -57,7 +59,11 @@ func main() {
 	logger := initLoggerFromConfig(&conf.Logging)
 	mcClock := clock.NewClock()
 
-	cfClient := cf.NewCfClient(&conf.Cf, logger.Session("cf"), mcClock)
+	cfClient, err := cf.NewCfClient(&conf.Cf, logger.Session("cf"), mcClock)
+	if err != nil {
+		logger.Error("failed to create cloud foundry client", err, lager.Data{"Api": conf.Cf.Api})
+		os.Exit(1)
+	}
 	err = cfClient.Login()
 	if err != nil {
 		logger.Error("failed to login cloud foundry", err, lager.Data{"Api": conf.Cf.Api})
@@ -66,7 +72,11 @@ func main() {
 
 	dopplerUrl := cfClient.GetEndpoints().DopplerEndpoint
 	logger.Info("create-noaa-client", map[string]interface{}{"dopplerUrl": dopplerUrl})
-	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	tlsConfig, err := conf.Cf.TLSConfig()
+	if err != nil {
+		logger.Error("failed to create tls config for doppler", err, lager.Data{"caCertFile": conf.Cf.CACertFile})
+		os.Exit(1)
+	}
 	noaa := consumer.New(dopplerUrl, tlsConfig, nil)
 	noaa.RefreshTokenFrom(cfClient)
 
@@ -86,23 +96,65 @@ func main() {
 	}
 	defer policyDB.Close()
 
-	createPoller := func(appId string) collector.AppPoller {
-		return collector.NewAppPoller(logger.Session("app-poller"), appId, conf.Collector.PollInterval, cfClient, noaa, instanceMetricsDB, mcClock)
+	var maxPollInterval time.Duration
+	if conf.Collector.AdaptivePolling {
+		maxPollInterval = conf.Collector.MaxPollInterval
+	}
+	createPoller := func(appId string, pollInterval time.Duration) collector.AppPoller {
+		return collector.NewAppPoller(logger.Session("app-poller"), appId, pollInterval, maxPollInterval, cfClient, noaa, instanceMetricsDB, policyDB, mcClock)
+	}
+	pollIntervals := collector.PollIntervals{
+		Default:              conf.Collector.PollInterval,
+		Min:                  conf.Collector.MinPollInterval,
+		Max:                  conf.Collector.MaxPollInterval,
+		SamplesPerStatWindow: conf.Collector.SamplesPerStatWindow,
+	}
+
+	var shardDB db.ShardDB
+	var membership *sharding.Membership
+	getShard := func() sharding.Shard {
+		return sharding.Shard{Index: conf.Sharding.Index, Count: conf.Sharding.Count}
+	}
+	if conf.Sharding.Discovery {
+		shardDB, err = sqldb.NewShardSQLDB(conf.Db.InstanceMetricsDbUrl, logger.Session("shard-db"))
+		if err != nil {
+			logger.Error("failed to connect shard database", err, lager.Data{"url": conf.Db.InstanceMetricsDbUrl})
+			os.Exit(1)
+		}
+		defer shardDB.Close()
+
+		memberId := conf.Sharding.MemberId
+		if memberId == "" {
+			memberId, err = os.Hostname()
+			if err != nil {
+				logger.Error("failed to get hostname as sharding member id", err)
+				os.Exit(1)
+			}
+		}
+		membership = sharding.NewMembership(logger.Session("membership"), shardDB, "metricscollector", memberId,
+			conf.Sharding.HeartbeatInterval, conf.Sharding.MemberTTL, mcClock)
+		getShard = membership.Shard
 	}
 
+	mc := collector.NewCollector(conf.Collector.RefreshInterval, pollIntervals, logger.Session("collector"), policyDB, mcClock, createPoller, getShard)
 	collectServer := ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
-		mc := collector.NewCollector(conf.Collector.RefreshInterval, logger.Session("collector"), policyDB, mcClock, createPoller)
+		if membership != nil {
+			membership.Start()
+		}
 		mc.Start()
 
 		close(ready)
 
 		<-signals
 		mc.Stop()
+		if membership != nil {
+			membership.Stop()
+		}
 
 		return nil
 	})
 
-	httpServer, err := server.NewServer(logger, conf, cfClient, noaa, instanceMetricsDB)
+	httpServer, err := server.NewServer(logger, conf, cfClient, noaa, instanceMetricsDB, policyDB, mcClock)
 	if err != nil {
 		logger.Error("failed to create http server", err)
 		os.Exit(1)
@@ -113,6 +165,27 @@ func main() {
 		{"http_server", httpServer},
 	}
 
+	if conf.Health.Port != 0 {
+		liveness := healthendpoint.Checks{
+			"collector": healthendpoint.TickCheck(mc.LastTickTime, 3*conf.Collector.RefreshInterval, mcClock),
+		}
+		readiness := healthendpoint.Checks{
+			"policy_db":           healthendpoint.PingCheck(policyDB),
+			"instance_metrics_db": healthendpoint.PingCheck(instanceMetricsDB),
+			"cf_token":            healthendpoint.CfTokenCheck(cfClient),
+		}
+		if shardDB != nil {
+			readiness["shard_db"] = healthendpoint.PingCheck(shardDB)
+			readiness["shard_membership"] = membership.Check
+		}
+		healthServer, err := healthendpoint.NewServer(logger.Session("health-server"), conf.Health.Port, metrics.DefaultRegistry, liveness, readiness)
+		if err != nil {
+			logger.Error("failed to create health server", err)
+			os.Exit(1)
+		}
+		members = append(members, grouper.Member{Name: "health_server", Runner: healthServer})
+	}
+
 	monitor := ifrit.Invoke(sigmon.New(grouper.NewOrdered(os.Interrupt, members)))
 
 	logger.Info("started")
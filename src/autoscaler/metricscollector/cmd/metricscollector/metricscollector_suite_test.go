@@ -27,6 +27,7 @@ import (
 	"autoscaler/cf"
 	"autoscaler/db"
 	"autoscaler/metricscollector/config"
+	"autoscaler/sharding"
 )
 
 var (
@@ -116,6 +117,13 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 
 	cfg.Collector.PollInterval = 10 * time.Second
 	cfg.Collector.RefreshInterval = 30 * time.Second
+	cfg.Collector.MinPollInterval = config.DefaultMinPollInterval
+	cfg.Collector.MaxPollInterval = config.DefaultMaxPollInterval
+	cfg.Collector.SamplesPerStatWindow = config.DefaultSamplesPerStatWindow
+	cfg.Collector.ObserveDuration = config.DefaultObserveDuration
+	cfg.Collector.MaxObserveDuration = config.DefaultMaxObserveDuration
+
+	cfg.Sharding = sharding.DefaultShardingConfig
 
 	configFile = writeConfig(&cfg)
 
@@ -11,35 +11,50 @@ import (
 
 	"github.com/cloudfoundry/sonde-go/events"
 
+	"reflect"
 	"time"
 )
 
+// AppExistenceCheckInterval is the minimum time between two checks whether an
+// app without container metrics still exists.
+const AppExistenceCheckInterval = 5 * time.Minute
+
 type AppPoller interface {
 	Start()
 	Stop()
 }
 
 type appPoller struct {
-	appId        string
-	pollInterval time.Duration
-	logger       lager.Logger
-	cfc          cf.CfClient
-	noaaConsumer noaa.NoaaConsumer
-	database     db.InstanceMetricsDB
-	pclock       clock.Clock
-	doneChan     chan bool
+	appId           string
+	pollInterval    time.Duration
+	maxPollInterval time.Duration
+	logger          lager.Logger
+	cfc             cf.CfClient
+	noaaConsumer    noaa.NoaaConsumer
+	database        db.InstanceMetricsDB
+	policyDB        db.PolicyDB
+	pclock          clock.Clock
+	doneChan        chan bool
+	lastChecked     time.Time
+	lastValues      map[uint32]string
 }
 
-func NewAppPoller(logger lager.Logger, appId string, pollInterval time.Duration, cfc cf.CfClient, noaaConsumer noaa.NoaaConsumer, database db.InstanceMetricsDB, pclock clock.Clock) AppPoller {
+// NewAppPoller creates a poller that polls the metrics of the app with
+// pollInterval. While the metrics do not change, the interval is doubled up to
+// maxPollInterval; a maxPollInterval not greater than pollInterval disables this
+// backoff.
+func NewAppPoller(logger lager.Logger, appId string, pollInterval time.Duration, maxPollInterval time.Duration, cfc cf.CfClient, noaaConsumer noaa.NoaaConsumer, database db.InstanceMetricsDB, policyDB db.PolicyDB, pclock clock.Clock) AppPoller {
 	return &appPoller{
-		appId:        appId,
-		pollInterval: pollInterval,
-		logger:       logger,
-		cfc:          cfc,
-		noaaConsumer: noaaConsumer,
-		database:     database,
-		pclock:       pclock,
-		doneChan:     make(chan bool),
+		appId:           appId,
+		pollInterval:    pollInterval,
+		maxPollInterval: maxPollInterval,
+		logger:          logger,
+		cfc:             cfc,
+		noaaConsumer:    noaaConsumer,
+		database:        database,
+		policyDB:        policyDB,
+		pclock:          pclock,
+		doneChan:        make(chan bool),
 	}
 
 }
@@ -56,9 +71,10 @@ func (ap *appPoller) Stop() {
 }
 
 func (ap *appPoller) startPollMetrics() {
+	interval := ap.pollInterval
 	for {
-		ap.pollMetric()
-		timer := ap.pclock.NewTimer(ap.pollInterval)
+		interval = ap.nextPollInterval(interval, ap.pollMetric())
+		timer := ap.pclock.NewTimer(interval)
 		select {
 		case <-ap.doneChan:
 			timer.Stop()
@@ -68,7 +84,26 @@ func (ap *appPoller) startPollMetrics() {
 	}
 }
 
-func (ap *appPoller) pollMetric() {
+// nextPollInterval backs off while the metrics are unchanged and goes back to
+// the configured interval as soon as they change.
+func (ap *appPoller) nextPollInterval(interval time.Duration, changed bool) time.Duration {
+	if changed || ap.maxPollInterval <= ap.pollInterval {
+		return ap.pollInterval
+	}
+	next := interval * 2
+	if next > ap.maxPollInterval {
+		next = ap.maxPollInterval
+	}
+	if next != interval {
+		ap.logger.Debug("back-off-poll-interval", lager.Data{"appid": ap.appId, "poll-interval": next})
+	}
+	return next
+}
+
+// pollMetric polls and saves the metrics of the app, and reports whether they
+// changed since the last poll. Failed polls count as changed so that they are
+// retried with the configured interval.
+func (ap *appPoller) pollMetric() bool {
 	logger := ap.logger.WithData(lager.Data{"appId": ap.appId})
 	logger.Debug("poll-metric")
 
@@ -78,24 +113,64 @@ func (ap *appPoller) pollMetric() {
 	for attempt := 0; attempt < 3; attempt++ {
 		logger.Debug("poll-metric-from-noaa-retry", lager.Data{"attempt": attempt + 1})
 
-		containerEnvelopes, err = ap.noaaConsumer.ContainerEnvelopes(ap.appId, "bearer "+ap.cfc.GetTokens().AccessToken)
+		start := ap.pclock.Now()
+		containerEnvelopes, err = noaa.GetContainerEnvelopes(logger, ap.noaaConsumer, ap.cfc, ap.appId)
+		envelopeFetchDuration.Observe(ap.pclock.Since(start).Seconds())
 		if err == nil {
 			break
 		}
+		envelopeFetchErrors.Inc()
 	}
 
 	if err != nil {
 		logger.Error("poll-metric-from-noaa", err)
-		return
+		return true
+	}
+
+	if len(containerEnvelopes) == 0 {
+		ap.checkAppExistence(logger)
+		return true
 	}
 
 	metrics := models.GetInstanceMemoryMetricFromContainerEnvelopes(ap.pclock.Now().UnixNano(), ap.appId, containerEnvelopes)
 	logger.Debug("poll-metric-get-memory-metric", lager.Data{"metrics": metrics})
 
+	values := make(map[uint32]string, len(metrics))
 	for _, metric := range metrics {
+		values[metric.InstanceIndex] = metric.Value
 		err = ap.database.SaveMetric(metric)
 		if err != nil {
 			logger.Error("poll-metric-save", err, lager.Data{"metric": metric})
 		}
 	}
+
+	changed := !reflect.DeepEqual(values, ap.lastValues)
+	ap.lastValues = values
+	return changed
+}
+
+// checkAppExistence marks the app as orphaned when it has been deleted, so
+// that the metrics collector stops polling it once the app list is refreshed.
+func (ap *appPoller) checkAppExistence(logger lager.Logger) {
+	now := ap.pclock.Now()
+	if !ap.lastChecked.IsZero() && now.Sub(ap.lastChecked) < AppExistenceCheckInterval {
+		return
+	}
+	ap.lastChecked = now
+
+	exists, err := ap.cfc.AppExists(ap.appId)
+	if err != nil {
+		logger.Error("check-app-existence", err)
+		return
+	}
+	if exists {
+		return
+	}
+
+	err = ap.policyDB.MarkAppOrphaned(ap.appId, now.UnixNano())
+	if err != nil {
+		logger.Error("mark-app-orphaned", err)
+		return
+	}
+	logger.Info("app-marked-orphaned")
 }
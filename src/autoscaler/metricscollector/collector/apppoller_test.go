@@ -2,12 +2,14 @@ package collector_test
 
 import (
 	"autoscaler/cf"
+	"autoscaler/metrics"
 	. "autoscaler/metricscollector/collector"
 	"autoscaler/metricscollector/fakes"
 	"autoscaler/models"
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
+	noaaerrors "github.com/cloudfoundry/noaa/errors"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/gogo/protobuf/proto"
 	. "github.com/onsi/ginkgo"
@@ -25,6 +27,7 @@ var _ = Describe("Apppoller", func() {
 		cfc       *fakes.FakeCfClient
 		noaa      *fakes.FakeNoaaConsumer
 		database  *fakes.FakeInstanceMetricsDB
+		policyDB  *fakes.FakePolicyDB
 		poller    AppPoller
 		fclock    *fakeclock.FakeClock
 		buffer    *gbytes.Buffer
@@ -35,12 +38,13 @@ var _ = Describe("Apppoller", func() {
 		cfc = &fakes.FakeCfClient{}
 		noaa = &fakes.FakeNoaaConsumer{}
 		database = &fakes.FakeInstanceMetricsDB{}
+		policyDB = &fakes.FakePolicyDB{}
 
 		logger := lagertest.NewTestLogger("apppoller-test")
 		buffer = logger.Buffer()
 
 		fclock = fakeclock.NewFakeClock(time.Now())
-		poller = NewAppPoller(logger, "test-app-id", TestPollInterval, cfc, noaa, database, fclock)
+		poller = NewAppPoller(logger, "test-app-id", TestPollInterval, 0, cfc, noaa, database, policyDB, fclock)
 		timestamp = 111111
 	})
 
@@ -67,7 +71,7 @@ var _ = Describe("Apppoller", func() {
 		Context("when retrieving container metrics all succeeds", func() {
 
 			BeforeEach(func() {
-				cfc.GetTokensReturns(cf.Tokens{AccessToken: "test-access-token"})
+				cfc.GetTokensWithRefreshReturns(cf.Tokens{AccessToken: "test-access-token"}, nil)
 			})
 
 			Context("when container envelopes are not empty", func() {
@@ -134,6 +138,49 @@ var _ = Describe("Apppoller", func() {
 					Consistently(database.SaveMetricCallCount).Should(BeZero())
 				})
 
+				Context("when the app exists", func() {
+					BeforeEach(func() {
+						cfc.AppExistsReturns(true, nil)
+					})
+
+					It("checks the existence of the app at most once per check interval", func() {
+						Eventually(cfc.AppExistsCallCount).Should(Equal(1))
+						Expect(cfc.AppExistsArgsForCall(0)).To(Equal("test-app-id"))
+
+						fclock.Increment(TestPollInterval)
+						Eventually(noaa.ContainerEnvelopesCallCount).Should(Equal(2))
+						Consistently(cfc.AppExistsCallCount).Should(Equal(1))
+
+						fclock.Increment(AppExistenceCheckInterval)
+						Eventually(cfc.AppExistsCallCount).Should(Equal(2))
+						Expect(policyDB.MarkAppOrphanedCallCount()).To(BeZero())
+					})
+				})
+
+				Context("when the app does not exist", func() {
+					BeforeEach(func() {
+						cfc.AppExistsReturns(false, nil)
+					})
+
+					It("marks the app as orphaned", func() {
+						Eventually(policyDB.MarkAppOrphanedCallCount).Should(Equal(1))
+						appId, orphanedAt := policyDB.MarkAppOrphanedArgsForCall(0)
+						Expect(appId).To(Equal("test-app-id"))
+						Expect(orphanedAt).To(Equal(fclock.Now().UnixNano()))
+						Eventually(buffer).Should(gbytes.Say("app-marked-orphaned"))
+					})
+				})
+
+				Context("when checking the existence of the app fails", func() {
+					BeforeEach(func() {
+						cfc.AppExistsReturns(false, errors.New("an error"))
+					})
+
+					It("does not mark the app as orphaned", func() {
+						Eventually(buffer).Should(gbytes.Say("check-app-existence"))
+						Expect(policyDB.MarkAppOrphanedCallCount()).To(BeZero())
+					})
+				})
 			})
 
 			Context("when container envelopes are partially empty", func() {
@@ -203,9 +250,50 @@ var _ = Describe("Apppoller", func() {
 			})
 		})
 
+		Context("when doppler rejects the access token", func() {
+			BeforeEach(func() {
+				cfc.GetTokensWithRefreshReturns(cf.Tokens{AccessToken: "test-access-token"}, nil)
+				cfc.ReauthenticateReturns(cf.Tokens{AccessToken: "test-access-token-renewed"}, nil)
+
+				noaa.ContainerEnvelopesStub = func(appid string, token string) ([]*events.Envelope, error) {
+					if token != "bearer test-access-token-renewed" {
+						return nil, noaaerrors.NewUnauthorizedError("invalid token")
+					}
+					return []*events.Envelope{
+						&events.Envelope{
+							ContainerMetric: &events.ContainerMetric{
+								ApplicationId: proto.String("test-app-id"),
+								InstanceIndex: proto.Int32(0),
+								MemoryBytes:   proto.Uint64(1234),
+							},
+						},
+					}, nil
+				}
+			})
+
+			It("re-authenticates and retries with the renewed token", func() {
+				Eventually(database.SaveMetricCallCount).Should(Equal(1))
+				Expect(noaa.ContainerEnvelopesCallCount()).To(Equal(2))
+				Expect(cfc.ReauthenticateCallCount()).To(Equal(1))
+				Expect(cfc.ReauthenticateArgsForCall(0)).To(Equal("test-access-token"))
+			})
+		})
+
+		Context("when doppler still rejects the renewed access token", func() {
+			BeforeEach(func() {
+				noaa.ContainerEnvelopesReturns(nil, noaaerrors.NewUnauthorizedError("invalid token"))
+			})
+
+			It("logs an authentication error", func() {
+				Eventually(buffer).Should(gbytes.Say("poll-metric-from-noaa"))
+				Eventually(buffer).Should(gbytes.Say("authentication failed"))
+				Expect(database.SaveMetricCallCount()).To(BeZero())
+			})
+		})
+
 		Context("when retrieving container envelopes fail, the metrics collector retries", func() {
 			BeforeEach(func() {
-				cfc.GetTokensReturns(cf.Tokens{AccessToken: "test-access-token"})
+				cfc.GetTokensWithRefreshReturns(cf.Tokens{AccessToken: "test-access-token"}, nil)
 
 				noaa.ContainerEnvelopesStub = func(appid string, token string) ([]*events.Envelope, error) {
 					Expect(appid).To(Equal("test-app-id"))
@@ -237,8 +325,72 @@ var _ = Describe("Apppoller", func() {
 				Eventually(noaa.ContainerEnvelopesCallCount).Should(Equal(3))
 
 			})
+
+			Context("when reporting metrics", func() {
+				var errorsBefore, fetchesBefore float64
+
+				BeforeEach(func() {
+					errorsBefore = metrics.DefaultRegistry.Value("autoscaler_metricscollector_envelope_fetch_errors_total")
+					fetchesBefore = metrics.DefaultRegistry.Value("autoscaler_metricscollector_envelope_fetch_duration_seconds_count")
+				})
+
+				It("counts the failed attempts and observes the latency of every attempt", func() {
+					Eventually(buffer).Should(gbytes.Say("poll-metric-get-memory-metric"))
+					Expect(metrics.DefaultRegistry.Value("autoscaler_metricscollector_envelope_fetch_errors_total") - errorsBefore).To(Equal(float64(2)))
+					Expect(metrics.DefaultRegistry.Value("autoscaler_metricscollector_envelope_fetch_duration_seconds_count") - fetchesBefore).To(Equal(float64(3)))
+				})
+			})
+		})
+
+	})
+
+	Describe("adaptive polling", func() {
+		BeforeEach(func() {
+			poller = NewAppPoller(lagertest.NewTestLogger("apppoller-test"), "test-app-id", TestPollInterval, 4*TestPollInterval,
+				cfc, noaa, database, policyDB, fclock)
+
+			noaa.ContainerEnvelopesStub = func(appid string, token string) ([]*events.Envelope, error) {
+				memory := uint64(1234)
+				if noaa.ContainerEnvelopesCallCount() > 3 {
+					memory = 5678
+				}
+				return []*events.Envelope{
+					&events.Envelope{
+						ContainerMetric: &events.ContainerMetric{
+							ApplicationId: proto.String("test-app-id"),
+							InstanceIndex: proto.Int32(0),
+							MemoryBytes:   proto.Uint64(memory),
+						},
+						Timestamp: &timestamp,
+					},
+				}, nil
+			}
+			poller.Start()
+		})
+
+		AfterEach(func() {
+			poller.Stop()
 		})
 
+		It("backs off while the metrics are unchanged and resets when they change", func() {
+			Eventually(noaa.ContainerEnvelopesCallCount).Should(Equal(1))
+
+			fclock.WaitForWatcherAndIncrement(TestPollInterval)
+			Eventually(noaa.ContainerEnvelopesCallCount).Should(Equal(2))
+
+			fclock.WaitForWatcherAndIncrement(TestPollInterval)
+			Consistently(noaa.ContainerEnvelopesCallCount).Should(Equal(2))
+			fclock.Increment(TestPollInterval)
+			Eventually(noaa.ContainerEnvelopesCallCount).Should(Equal(3))
+
+			fclock.WaitForWatcherAndIncrement(3 * TestPollInterval)
+			Consistently(noaa.ContainerEnvelopesCallCount).Should(Equal(3))
+			fclock.Increment(TestPollInterval)
+			Eventually(noaa.ContainerEnvelopesCallCount).Should(Equal(4))
+
+			fclock.WaitForWatcherAndIncrement(TestPollInterval)
+			Eventually(noaa.ContainerEnvelopesCallCount).Should(Equal(5))
+		})
 	})
 
 	Describe("Stop", func() {
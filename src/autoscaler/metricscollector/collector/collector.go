@@ -2,6 +2,8 @@ package collector
 
 import (
 	"autoscaler/db"
+	"autoscaler/models"
+	"autoscaler/sharding"
 
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
@@ -9,27 +11,66 @@ import (
 	"time"
 )
 
+// PollIntervals configures how often the metrics of an app are polled.
+type PollIntervals struct {
+	Default              time.Duration
+	Min                  time.Duration
+	Max                  time.Duration
+	SamplesPerStatWindow int
+}
+
+// ForPolicy returns the poll interval that gives SamplesPerStatWindow samples
+// in the shortest stat window of the scaling rules, within Min and Max. Apps
+// without scaling rules are polled with the default interval.
+func (p PollIntervals) ForPolicy(policy *models.ScalingPolicy) time.Duration {
+	var statWindow time.Duration
+	for _, rule := range policy.ScalingRules {
+		if rule.StatWindow() > 0 && (statWindow == 0 || rule.StatWindow() < statWindow) {
+			statWindow = rule.StatWindow()
+		}
+	}
+	if statWindow == 0 || p.SamplesPerStatWindow <= 0 {
+		return p.Default
+	}
+
+	interval := statWindow / time.Duration(p.SamplesPerStatWindow)
+	if interval < p.Min {
+		return p.Min
+	}
+	if interval > p.Max {
+		return p.Max
+	}
+	return interval
+}
+
 type Collector struct {
 	refreshInterval time.Duration
+	pollIntervals   PollIntervals
 	logger          lager.Logger
 	database        db.PolicyDB
 	cclock          clock.Clock
-	createPoller    func(string) AppPoller
+	createPoller    func(string, time.Duration) AppPoller
+	getShard        func() sharding.Shard
 	doneChan        chan bool
 	pollers         map[string]AppPoller
+	intervals       map[string]time.Duration
 	ticker          clock.Ticker
 	lock            *sync.Mutex
+	lastTick        time.Time
 }
 
-func NewCollector(refreshInterval time.Duration, logger lager.Logger, database db.PolicyDB, cclock clock.Clock, createPoller func(string) AppPoller) *Collector {
+func NewCollector(refreshInterval time.Duration, pollIntervals PollIntervals, logger lager.Logger, database db.PolicyDB, cclock clock.Clock, createPoller func(string, time.Duration) AppPoller, getShard func() sharding.Shard) *Collector {
 	return &Collector{
 		refreshInterval: refreshInterval,
+		pollIntervals:   pollIntervals,
 		logger:          logger,
 		database:        database,
 		cclock:          cclock,
 		createPoller:    createPoller,
+		getShard:        getShard,
 		doneChan:        make(chan bool),
 		pollers:         make(map[string]AppPoller),
+		intervals:       make(map[string]time.Duration),
 		lock:            &sync.Mutex{},
 	}
 }
@@ -41,47 +82,117 @@ func (c *Collector) Start() {
 }
 
 func (c *Collector) startAppRefresh() {
+	changes, err := c.database.SubscribePolicyChanges()
+	if err != nil {
+		c.logger.Error("subscribe-policy-changes", err, lager.Data{"refreshInterval": c.refreshInterval})
+	}
+
 	for {
 		c.refreshApps()
+		c.recordTick()
 		select {
 		case <-c.doneChan:
 			return
 		case <-c.ticker.C():
+		case appId, ok := <-changes:
+			if !ok {
+				c.logger.Info("policy-changes-closed")
+				changes = nil
+				continue
+			}
+			pending := drainPolicyChanges(changes)
+			c.logger.Debug("policy-changed", lager.Data{"appId": appId, "pending": pending})
+		}
+	}
+}
+
+// drainPolicyChanges discards the changes queued meanwhile as they are all
+// covered by the next refresh.
+func drainPolicyChanges(changes <-chan string) int {
+	for drained := 0; ; drained++ {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return drained
+			}
+		default:
+			return drained
 		}
 	}
 }
 
+// refreshApps polls the apps with a policy and the apps observed without one
+// that are owned by the shard. The poll interval of an app with a policy is
+// derived from the policy even while it is observed.
 func (c *Collector) refreshApps() {
-	appIds, err := c.database.GetAppIds()
+	policyJsons, err := c.database.RetrievePolicies()
 	if err != nil {
 		c.logger.Error("refresh-apps", err)
 		return
 	}
 
-	c.logger.Debug("refresh-apps", lager.Data{"appIds": appIds})
+	observedApps, err := c.database.RetrieveObservedApps(c.cclock.Now().UnixNano())
+	if err != nil {
+		c.logger.Error("refresh-apps-retrieve-observed-apps", err)
+		return
+	}
+
+	shard := c.getShard()
+	shardApps := make(map[string]time.Duration)
+	for _, appId := range observedApps {
+		if shard.Owns(appId) {
+			shardApps[appId] = c.pollIntervals.Default
+		}
+	}
+	for _, policyJson := range policyJsons {
+		if shard.Owns(policyJson.AppId) {
+			shardApps[policyJson.AppId] = c.pollIntervals.ForPolicy(policyJson.GetAppPolicy().ScalingPolicy)
+		}
+	}
+
+	c.logger.Debug("refresh-apps", lager.Data{"apps": shardApps, "shard": shard})
 
 	c.lock.Lock()
 	for id, ap := range c.pollers {
-		_, exist := appIds[id]
+		interval, exist := shardApps[id]
 		if !exist {
 			c.logger.Debug("refresh-apps-remove", lager.Data{"appId": id})
 			ap.Stop()
 			delete(c.pollers, id)
+			delete(c.intervals, id)
+		} else if interval != c.intervals[id] {
+			c.logger.Debug("refresh-apps-update-poll-interval", lager.Data{"appId": id, "pollInterval": interval})
+			ap.Stop()
+			delete(c.pollers, id)
 		}
 	}
 
-	for id, _ := range appIds {
+	for id, interval := range shardApps {
 		_, exist := c.pollers[id]
 		if !exist {
-			c.logger.Debug("refresh-apps-add", lager.Data{"appId": id})
-			ap := c.createPoller(id)
+			c.logger.Debug("refresh-apps-add", lager.Data{"appId": id, "pollInterval": interval})
+			ap := c.createPoller(id, interval)
 			ap.Start()
 			c.pollers[id] = ap
+			c.intervals[id] = interval
 		}
 	}
+	activePollers.Set(float64(len(c.pollers)))
 	c.lock.Unlock()
 }
 
+func (c *Collector) recordTick() {
+	c.lock.Lock()
+	c.lastTick = c.cclock.Now()
+	c.lock.Unlock()
+}
+
+func (c *Collector) LastTickTime() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lastTick
+}
+
 func (c *Collector) Stop() {
 	if c.ticker != nil {
 		c.ticker.Stop()
@@ -91,6 +202,7 @@ func (c *Collector) Stop() {
 		for _, ap := range c.pollers {
 			ap.Stop()
 		}
+		activePollers.Set(0)
 		c.lock.Unlock()
 	}
 	c.logger.Info("collector-stopped")
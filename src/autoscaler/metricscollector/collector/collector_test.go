@@ -1,8 +1,11 @@
 package collector_test
 
 import (
+	"autoscaler/metrics"
 	. "autoscaler/metricscollector/collector"
 	"autoscaler/metricscollector/fakes"
+	"autoscaler/models"
+	"autoscaler/sharding"
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
@@ -12,9 +15,18 @@ import (
 	"github.com/onsi/gomega/gbytes"
 
 	"errors"
+	"fmt"
 	"time"
 )
 
+func policiesOf(appIds ...string) []*models.PolicyJson {
+	policies := []*models.PolicyJson{}
+	for _, appId := range appIds {
+		policies = append(policies, &models.PolicyJson{AppId: appId, PolicyStr: `{"instance_min_count":1,"instance_max_count":5}`})
+	}
+	return policies
+}
+
 var _ = Describe("Collector", func() {
 
 	var (
@@ -23,6 +35,7 @@ var _ = Describe("Collector", func() {
 		fclock   *fakeclock.FakeClock
 		poller   *fakes.FakeAppPoller
 		buffer   *gbytes.Buffer
+		shard    sharding.Shard
 	)
 
 	BeforeEach(func() {
@@ -33,10 +46,14 @@ var _ = Describe("Collector", func() {
 
 		fclock = fakeclock.NewFakeClock(time.Now())
 		poller = &fakes.FakeAppPoller{}
-		createPoller := func(appId string) AppPoller {
+		createPoller := func(appId string, pollInterval time.Duration) AppPoller {
 			return poller
 		}
-		coll = NewCollector(TestRefreshInterval, logger, database, fclock, createPoller)
+		shard = sharding.AllApps
+		getShard := func() sharding.Shard {
+			return shard
+		}
+		coll = NewCollector(TestRefreshInterval, PollIntervals{Default: TestPollInterval}, logger, database, fclock, createPoller, getShard)
 	})
 
 	Describe("Start", func() {
@@ -49,21 +66,28 @@ var _ = Describe("Collector", func() {
 		})
 
 		It("refreshes the apps with given interval", func() {
-			Eventually(database.GetAppIdsCallCount).Should(Equal(1))
+			Eventually(database.RetrievePoliciesCallCount).Should(Equal(1))
 
 			fclock.Increment(TestRefreshInterval)
-			Eventually(database.GetAppIdsCallCount).Should(Equal(2))
+			Eventually(database.RetrievePoliciesCallCount).Should(Equal(2))
 
 			fclock.Increment(TestRefreshInterval)
-			Eventually(database.GetAppIdsCallCount).Should(Equal(3))
+			Eventually(database.RetrievePoliciesCallCount).Should(Equal(3))
+
+		})
+
+		It("records the time of the last refresh", func() {
+			Eventually(coll.LastTickTime).Should(Equal(fclock.Now()))
 
+			fclock.Increment(TestRefreshInterval)
+			Eventually(coll.LastTickTime).Should(Equal(fclock.Now()))
 		})
 
 		Context("when getting apps from policy database succeeds", func() {
 
 			Context("when no apps in policy database", func() {
 				BeforeEach(func() {
-					database.GetAppIdsReturns(make(map[string]bool), nil)
+					database.RetrievePoliciesReturns([]*models.PolicyJson{}, nil)
 				})
 
 				It("does nothing", func() {
@@ -76,7 +100,7 @@ var _ = Describe("Collector", func() {
 
 			Context("when refresh does not have app changes", func() {
 				BeforeEach(func() {
-					database.GetAppIdsReturns(map[string]bool{"app-id-1": true, "app-id-2": true, "app-id-3": true}, nil)
+					database.RetrievePoliciesReturns(policiesOf("app-id-1", "app-id-2", "app-id-3"), nil)
 				})
 
 				It("should always poll the same set of apps", func() {
@@ -87,18 +111,25 @@ var _ = Describe("Collector", func() {
 					Consistently(poller.StartCallCount).Should(Equal(3))
 					Consistently(coll.GetPollerAppIds).Should(ConsistOf("app-id-1", "app-id-2", "app-id-3"))
 				})
+
+				It("reports the number of active pollers", func() {
+					Eventually(coll.GetPollerAppIds).Should(HaveLen(3))
+					Eventually(func() float64 {
+						return metrics.DefaultRegistry.Value("autoscaler_metricscollector_active_pollers")
+					}).Should(Equal(float64(3)))
+				})
 			})
 
 			Context("when refresh has new apps", func() {
 				BeforeEach(func() {
-					database.GetAppIdsStub = func() (map[string]bool, error) {
-						switch database.GetAppIdsCallCount() {
+					database.RetrievePoliciesStub = func() ([]*models.PolicyJson, error) {
+						switch database.RetrievePoliciesCallCount() {
 						case 1:
-							return map[string]bool{"app-id-1": true}, nil
+							return policiesOf("app-id-1"), nil
 						case 2:
-							return map[string]bool{"app-id-1": true, "app-id-2": true}, nil
+							return policiesOf("app-id-1", "app-id-2"), nil
 						default:
-							return map[string]bool{"app-id-1": true, "app-id-2": true, "app-id-3": true}, nil
+							return policiesOf("app-id-1", "app-id-2", "app-id-3"), nil
 						}
 					}
 				})
@@ -121,14 +152,14 @@ var _ = Describe("Collector", func() {
 
 			Context("when refresh has app removals", func() {
 				BeforeEach(func() {
-					database.GetAppIdsStub = func() (map[string]bool, error) {
-						switch database.GetAppIdsCallCount() {
+					database.RetrievePoliciesStub = func() ([]*models.PolicyJson, error) {
+						switch database.RetrievePoliciesCallCount() {
 						case 1:
-							return map[string]bool{"app-id-1": true, "app-id-2": true, "app-id-3": true}, nil
+							return policiesOf("app-id-1", "app-id-2", "app-id-3"), nil
 						case 2:
-							return map[string]bool{"app-id-2": true, "app-id-3": true}, nil
+							return policiesOf("app-id-2", "app-id-3"), nil
 						default:
-							return map[string]bool{"app-id-3": true}, nil
+							return policiesOf("app-id-3"), nil
 						}
 					}
 				})
@@ -151,14 +182,14 @@ var _ = Describe("Collector", func() {
 
 			Context("when refresh has both new apps and app removals", func() {
 				BeforeEach(func() {
-					database.GetAppIdsStub = func() (map[string]bool, error) {
-						switch database.GetAppIdsCallCount() {
+					database.RetrievePoliciesStub = func() ([]*models.PolicyJson, error) {
+						switch database.RetrievePoliciesCallCount() {
 						case 1:
-							return map[string]bool{"app-id-1": true, "app-id-3": true}, nil
+							return policiesOf("app-id-1", "app-id-3"), nil
 						case 2:
-							return map[string]bool{"app-id-2": true, "app-id-3": true}, nil
+							return policiesOf("app-id-2", "app-id-3"), nil
 						default:
-							return map[string]bool{"app-id-1": true, "app-id-2": true}, nil
+							return policiesOf("app-id-1", "app-id-2"), nil
 						}
 					}
 				})
@@ -181,9 +212,157 @@ var _ = Describe("Collector", func() {
 			})
 		})
 
+		Context("when the policies have scaling rules", func() {
+			var intervals map[string]time.Duration
+
+			BeforeEach(func() {
+				intervals = map[string]time.Duration{}
+				coll = NewCollector(TestRefreshInterval, PollIntervals{
+					Default:              30 * time.Second,
+					Min:                  10 * time.Second,
+					Max:                  2 * time.Minute,
+					SamplesPerStatWindow: 4,
+				}, lagertest.NewTestLogger("collector-test"), database, fclock, func(appId string, pollInterval time.Duration) AppPoller {
+					intervals[appId] = pollInterval
+					return poller
+				}, func() sharding.Shard {
+					return shard
+				})
+
+				database.RetrievePoliciesStub = func() ([]*models.PolicyJson, error) {
+					window := 120
+					if database.RetrievePoliciesCallCount() > 1 {
+						window = 240
+					}
+					return []*models.PolicyJson{
+						{AppId: "app-id-1", PolicyStr: fmt.Sprintf(`{"scaling_rules":[{"stat_window_secs":%d},{"stat_window_secs":300}]}`, window)},
+						{AppId: "app-id-2", PolicyStr: `{"scaling_rules":[{"stat_window_secs":20}]}`},
+					}, nil
+				}
+			})
+
+			It("polls each app with the interval derived from its shortest stat window", func() {
+				Eventually(poller.StartCallCount).Should(Equal(2))
+				Expect(intervals).To(Equal(map[string]time.Duration{
+					"app-id-1": 30 * time.Second,
+					"app-id-2": 10 * time.Second,
+				}))
+			})
+
+			It("restarts the poller of an app when its interval changes", func() {
+				Eventually(poller.StartCallCount).Should(Equal(2))
+
+				fclock.Increment(TestRefreshInterval)
+				Eventually(poller.StartCallCount).Should(Equal(3))
+				Expect(poller.StopCallCount()).To(Equal(1))
+				Expect(intervals["app-id-1"]).To(Equal(time.Minute))
+				Expect(coll.GetPollerAppIds()).To(ConsistOf("app-id-1", "app-id-2"))
+			})
+		})
+
+		Context("when apps are observed without a policy", func() {
+			var intervals map[string]time.Duration
+
+			BeforeEach(func() {
+				intervals = map[string]time.Duration{}
+				coll = NewCollector(TestRefreshInterval, PollIntervals{
+					Default:              30 * time.Second,
+					Min:                  10 * time.Second,
+					Max:                  2 * time.Minute,
+					SamplesPerStatWindow: 4,
+				}, lagertest.NewTestLogger("collector-test"), database, fclock, func(appId string, pollInterval time.Duration) AppPoller {
+					intervals[appId] = pollInterval
+					return poller
+				}, func() sharding.Shard {
+					return shard
+				})
+
+				database.RetrievePoliciesReturns([]*models.PolicyJson{
+					{AppId: "app-id-1", PolicyStr: `{"scaling_rules":[{"stat_window_secs":20}]}`},
+				}, nil)
+				database.RetrieveObservedAppsStub = func(now int64) ([]string, error) {
+					if database.RetrieveObservedAppsCallCount() > 1 {
+						return []string{"app-id-1"}, nil
+					}
+					return []string{"app-id-1", "app-id-2"}, nil
+				}
+			})
+
+			It("polls the observed apps with the default interval", func() {
+				Eventually(poller.StartCallCount).Should(Equal(2))
+				Expect(database.RetrieveObservedAppsArgsForCall(0)).To(Equal(fclock.Now().UnixNano()))
+				Expect(intervals).To(Equal(map[string]time.Duration{
+					"app-id-1": 10 * time.Second,
+					"app-id-2": 30 * time.Second,
+				}))
+			})
+
+			It("stops polling the apps whose observation expired", func() {
+				Eventually(coll.GetPollerAppIds).Should(ConsistOf("app-id-1", "app-id-2"))
+
+				fclock.Increment(TestRefreshInterval)
+				Eventually(coll.GetPollerAppIds).Should(ConsistOf("app-id-1"))
+				Expect(poller.StopCallCount()).To(Equal(1))
+			})
+
+			Context("when getting the observed apps fails", func() {
+				BeforeEach(func() {
+					database.RetrieveObservedAppsStub = nil
+					database.RetrieveObservedAppsReturns(nil, errors.New("test observed apps error"))
+				})
+
+				It("does not poll and logs the error", func() {
+					Eventually(database.RetrieveObservedAppsCallCount).Should(Equal(1))
+					Consistently(coll.GetPollerAppIds).Should(BeEmpty())
+				})
+			})
+		})
+
+		Context("when the collector is sharded", func() {
+			var appIds []string
+
+			ownedApps := func(s sharding.Shard) []string {
+				owned := []string{}
+				for _, id := range appIds {
+					if s.Owns(id) {
+						owned = append(owned, id)
+					}
+				}
+				return owned
+			}
+
+			BeforeEach(func() {
+				appIds = []string{}
+				for i := 0; i < 10; i++ {
+					appIds = append(appIds, fmt.Sprintf("app-id-%d", i))
+				}
+				database.RetrievePoliciesReturns(policiesOf(appIds...), nil)
+				shard = sharding.Shard{Index: 1, Count: 2}
+			})
+
+			It("polls only the apps of its shard", func() {
+				Expect(ownedApps(shard)).NotTo(BeEmpty())
+				Expect(len(ownedApps(shard))).To(BeNumerically("<", len(appIds)))
+				Eventually(coll.GetPollerAppIds).Should(ConsistOf(ownedApps(shard)))
+			})
+
+			Context("when the shard changes", func() {
+				JustBeforeEach(func() {
+					Eventually(coll.GetPollerAppIds).Should(ConsistOf(ownedApps(shard)))
+					shard = sharding.Shard{Index: 0, Count: 2}
+					fclock.Increment(TestRefreshInterval)
+				})
+
+				It("rebalances the pollers on the next refresh", func() {
+					Eventually(coll.GetPollerAppIds).Should(ConsistOf(ownedApps(sharding.Shard{Index: 0, Count: 2})))
+					Expect(poller.StopCallCount()).To(Equal(len(ownedApps(sharding.Shard{Index: 1, Count: 2}))))
+				})
+			})
+		})
+
 		Context("when getting apps from policy database fails", func() {
 			BeforeEach(func() {
-				database.GetAppIdsReturns(nil, errors.New("test collector error"))
+				database.RetrievePoliciesReturns(nil, errors.New("test collector error"))
 			})
 
 			It("does not poll and logs the error", func() {
@@ -191,31 +370,97 @@ var _ = Describe("Collector", func() {
 				Consistently(coll.GetPollerAppIds).Should(BeEmpty())
 
 				fclock.Increment(TestRefreshInterval)
-				Eventually(database.GetAppIdsCallCount).Should(Equal(2))
+				Eventually(database.RetrievePoliciesCallCount).Should(Equal(2))
 				Eventually(buffer).Should(gbytes.Say("test collector error"))
 				Consistently(coll.GetPollerAppIds).Should(BeEmpty())
 			})
 
 		})
 
+		Context("when policy changes are notified", func() {
+			var changes chan string
+
+			BeforeEach(func() {
+				changes = make(chan string, 10)
+				database.SubscribePolicyChangesReturns(changes, nil)
+				database.RetrievePoliciesReturns(policiesOf("app-id-1"), nil)
+			})
+
+			It("refreshes the apps without waiting for the interval", func() {
+				Eventually(database.RetrievePoliciesCallCount).Should(Equal(1))
+
+				changes <- "app-id-1"
+				Eventually(database.RetrievePoliciesCallCount).Should(Equal(2))
+				Consistently(database.RetrievePoliciesCallCount).Should(Equal(2))
+				Expect(coll.GetPollerAppIds()).To(ConsistOf("app-id-1"))
+			})
+		})
+
+		Context("when subscribing to policy changes fails", func() {
+			BeforeEach(func() {
+				database.SubscribePolicyChangesReturns(nil, errors.New("an error"))
+			})
+
+			It("logs the error and keeps refreshing with the given interval", func() {
+				Eventually(buffer).Should(gbytes.Say("subscribe-policy-changes"))
+
+				fclock.Increment(TestRefreshInterval)
+				Eventually(database.RetrievePoliciesCallCount).Should(Equal(2))
+			})
+		})
+
 	})
 
 	Describe("Stop", func() {
 		BeforeEach(func() {
-			database.GetAppIdsReturns(map[string]bool{"app-id-1": true, "app-id-2": true, "app-id-3": true}, nil)
+			database.RetrievePoliciesReturns(policiesOf("app-id-1", "app-id-2", "app-id-3"), nil)
 			coll.Start()
 		})
 
 		It("stops the collecting", func() {
 
 			fclock.Increment(TestRefreshInterval)
-			Eventually(database.GetAppIdsCallCount).Should(Equal(2))
+			Eventually(database.RetrievePoliciesCallCount).Should(Equal(2))
 
 			coll.Stop()
 			Eventually(poller.StopCallCount).Should(Equal(3))
 
 			fclock.Increment(TestRefreshInterval)
-			Consistently(database.GetAppIdsCallCount).Should(Equal(2))
+			Consistently(database.RetrievePoliciesCallCount).Should(Equal(2))
+		})
+	})
+
+	Describe("PollIntervals", func() {
+		var pollIntervals PollIntervals
+
+		BeforeEach(func() {
+			pollIntervals = PollIntervals{
+				Default:              30 * time.Second,
+				Min:                  10 * time.Second,
+				Max:                  2 * time.Minute,
+				SamplesPerStatWindow: 4,
+			}
+		})
+
+		policyWithWindows := func(windows ...int) *models.ScalingPolicy {
+			policy := &models.ScalingPolicy{}
+			for _, window := range windows {
+				policy.ScalingRules = append(policy.ScalingRules, &models.ScalingRule{StatWindowSeconds: window})
+			}
+			return policy
+		}
+
+		It("returns the default interval when there are no scaling rules", func() {
+			Expect(pollIntervals.ForPolicy(policyWithWindows())).To(Equal(30 * time.Second))
+		})
+
+		It("takes the given number of samples in the shortest stat window", func() {
+			Expect(pollIntervals.ForPolicy(policyWithWindows(300, 80))).To(Equal(20 * time.Second))
+		})
+
+		It("limits the interval to the min and the max interval", func() {
+			Expect(pollIntervals.ForPolicy(policyWithWindows(20))).To(Equal(10 * time.Second))
+			Expect(pollIntervals.ForPolicy(policyWithWindows(3600))).To(Equal(2 * time.Minute))
 		})
 	})
 
@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"autoscaler/metrics"
+)
+
+var (
+	activePollers = metrics.NewGauge(
+		"autoscaler_metricscollector_active_pollers",
+		"Number of app pollers currently running in the collector.",
+	)
+	envelopeFetchDuration = metrics.NewHistogram(
+		"autoscaler_metricscollector_envelope_fetch_duration_seconds",
+		"Latency of fetching container envelopes from noaa.",
+		nil,
+	)
+	envelopeFetchErrors = metrics.NewCounter(
+		"autoscaler_metricscollector_envelope_fetch_errors_total",
+		"Number of failed attempts to fetch container envelopes from noaa.",
+	)
+)
+
+func init() {
+	metrics.MustRegister(activePollers, envelopeFetchDuration, envelopeFetchErrors)
+}
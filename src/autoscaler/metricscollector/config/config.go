@@ -11,16 +11,25 @@ import (
 
 	"autoscaler/cf"
 	"autoscaler/models"
+	"autoscaler/sharding"
 )
 
 const (
-	DefaultLoggingLevel                  = "info"
-	DefaultRefreshInterval time.Duration = 60 * time.Second
-	DefaultPollInterval    time.Duration = 30 * time.Second
+	DefaultLoggingLevel                     = "info"
+	DefaultRefreshInterval    time.Duration = 60 * time.Second
+	DefaultPollInterval       time.Duration = 30 * time.Second
+	DefaultMinPollInterval    time.Duration = 10 * time.Second
+	DefaultMaxPollInterval    time.Duration = 2 * time.Minute
+	DefaultObserveDuration    time.Duration = time.Hour
+	DefaultMaxObserveDuration time.Duration = 24 * time.Hour
 )
 
+const DefaultSamplesPerStatWindow = 4
+
 var defaultCfConfig = cf.CfConfig{
-	GrantType: cf.GrantTypePassword,
+	GrantType:      cf.GrantTypePassword,
+	Retry:          cf.DefaultRetryConfig,
+	CircuitBreaker: cf.DefaultCircuitBreakerConfig,
 }
 
 type ServerConfig struct {
@@ -40,27 +49,45 @@ var defaultLoggingConfig = LoggingConfig{
 	Level: DefaultLoggingLevel,
 }
 
+type HealthConfig struct {
+	Port int `yaml:"port"`
+}
+
 type DbConfig struct {
 	PolicyDbUrl          string `yaml:"policy_db_url"`
 	InstanceMetricsDbUrl string `yaml:"instance_metrics_db_url"`
 }
 
 type CollectorConfig struct {
-	RefreshInterval time.Duration `yaml:"refresh_interval"`
-	PollInterval    time.Duration `yaml:"poll_interval"`
+	RefreshInterval      time.Duration `yaml:"refresh_interval"`
+	PollInterval         time.Duration `yaml:"poll_interval"`
+	MinPollInterval      time.Duration `yaml:"min_poll_interval"`
+	MaxPollInterval      time.Duration `yaml:"max_poll_interval"`
+	SamplesPerStatWindow int           `yaml:"samples_per_stat_window"`
+	AdaptivePolling      bool          `yaml:"adaptive_polling"`
+	ObserveDuration      time.Duration `yaml:"observe_duration"`
+	MaxObserveDuration   time.Duration `yaml:"max_observe_duration"`
 }
 
 var defaultCollectorConfig = CollectorConfig{
-	RefreshInterval: DefaultRefreshInterval,
-	PollInterval:    DefaultPollInterval,
+	RefreshInterval:      DefaultRefreshInterval,
+	PollInterval:         DefaultPollInterval,
+	MinPollInterval:      DefaultMinPollInterval,
+	MaxPollInterval:      DefaultMaxPollInterval,
+	SamplesPerStatWindow: DefaultSamplesPerStatWindow,
+	AdaptivePolling:      true,
+	ObserveDuration:      DefaultObserveDuration,
+	MaxObserveDuration:   DefaultMaxObserveDuration,
 }
 
 type Config struct {
-	Cf        cf.CfConfig     `yaml:"cf"`
-	Logging   LoggingConfig   `yaml:"logging"`
-	Server    ServerConfig    `yaml:"server"`
-	Db        DbConfig        `yaml:"db"`
-	Collector CollectorConfig `yaml:"collector"`
+	Cf        cf.CfConfig             `yaml:"cf"`
+	Logging   LoggingConfig           `yaml:"logging"`
+	Server    ServerConfig            `yaml:"server"`
+	Db        DbConfig                `yaml:"db"`
+	Collector CollectorConfig         `yaml:"collector"`
+	Health    HealthConfig            `yaml:"health"`
+	Sharding  sharding.ShardingConfig `yaml:"sharding"`
 }
 
 func LoadConfig(reader io.Reader) (*Config, error) {
@@ -69,6 +96,7 @@ func LoadConfig(reader io.Reader) (*Config, error) {
 		Logging:   defaultLoggingConfig,
 		Server:    defaultServerConfig,
 		Collector: defaultCollectorConfig,
+		Sharding:  sharding.DefaultShardingConfig,
 	}
 
 	bytes, err := ioutil.ReadAll(reader)
@@ -101,6 +129,35 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("Configuration error: InstanceMetrics DB url is empty")
 	}
 
+	if c.Health.Port < 0 || c.Health.Port > 65535 {
+		return fmt.Errorf("Configuration error: health port is less than 0 or more than 65535")
+	}
+
+	if c.Collector.MinPollInterval <= 0 {
+		return fmt.Errorf("Configuration error: min poll interval is less than or equal to 0")
+	}
+
+	if c.Collector.MaxPollInterval < c.Collector.MinPollInterval {
+		return fmt.Errorf("Configuration error: max poll interval is less than min poll interval")
+	}
+
+	if c.Collector.SamplesPerStatWindow <= 0 {
+		return fmt.Errorf("Configuration error: samples per stat window is less than or equal to 0")
+	}
+
+	if c.Collector.ObserveDuration <= 0 {
+		return fmt.Errorf("Configuration error: observe duration is less than or equal to 0")
+	}
+
+	if c.Collector.MaxObserveDuration < c.Collector.ObserveDuration {
+		return fmt.Errorf("Configuration error: max observe duration is less than observe duration")
+	}
+
+	err = c.Sharding.Validate()
+	if err != nil {
+		return err
+	}
+
 	return nil
 
 }
@@ -36,6 +36,12 @@ type FakeAppMetricDB struct {
 	pruneAppMetricsReturns struct {
 		result1 error
 	}
+	PingStub        func() error
+	pingMutex       sync.RWMutex
+	pingArgsForCall []struct{}
+	pingReturns     struct {
+		result1 error
+	}
 	CloseStub        func() error
 	closeMutex       sync.RWMutex
 	closeArgsForCall []struct{}
@@ -149,6 +155,31 @@ func (fake *FakeAppMetricDB) PruneAppMetricsReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeAppMetricDB) Ping() error {
+	fake.pingMutex.Lock()
+	fake.pingArgsForCall = append(fake.pingArgsForCall, struct{}{})
+	fake.recordInvocation("Ping", []interface{}{})
+	fake.pingMutex.Unlock()
+	if fake.PingStub != nil {
+		return fake.PingStub()
+	} else {
+		return fake.pingReturns.result1
+	}
+}
+
+func (fake *FakeAppMetricDB) PingCallCount() int {
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	return len(fake.pingArgsForCall)
+}
+
+func (fake *FakeAppMetricDB) PingReturns(result1 error) {
+	fake.PingStub = nil
+	fake.pingReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeAppMetricDB) Close() error {
 	fake.closeMutex.Lock()
 	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
@@ -183,6 +214,8 @@ func (fake *FakeAppMetricDB) Invocations() map[string][][]interface{} {
 	defer fake.retrieveAppMetricsMutex.RUnlock()
 	fake.pruneAppMetricsMutex.RLock()
 	defer fake.pruneAppMetricsMutex.RUnlock()
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
 	return fake.invocations
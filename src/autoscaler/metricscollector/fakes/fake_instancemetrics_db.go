@@ -36,6 +36,12 @@ type FakeInstanceMetricsDB struct {
 	pruneInstanceMetricsReturns struct {
 		result1 error
 	}
+	PingStub        func() error
+	pingMutex       sync.RWMutex
+	pingArgsForCall []struct{}
+	pingReturns     struct {
+		result1 error
+	}
 	CloseStub        func() error
 	closeMutex       sync.RWMutex
 	closeArgsForCall []struct{}
@@ -149,6 +155,31 @@ func (fake *FakeInstanceMetricsDB) PruneInstanceMetricsReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeInstanceMetricsDB) Ping() error {
+	fake.pingMutex.Lock()
+	fake.pingArgsForCall = append(fake.pingArgsForCall, struct{}{})
+	fake.recordInvocation("Ping", []interface{}{})
+	fake.pingMutex.Unlock()
+	if fake.PingStub != nil {
+		return fake.PingStub()
+	} else {
+		return fake.pingReturns.result1
+	}
+}
+
+func (fake *FakeInstanceMetricsDB) PingCallCount() int {
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	return len(fake.pingArgsForCall)
+}
+
+func (fake *FakeInstanceMetricsDB) PingReturns(result1 error) {
+	fake.PingStub = nil
+	fake.pingReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeInstanceMetricsDB) Close() error {
 	fake.closeMutex.Lock()
 	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
@@ -183,6 +214,8 @@ func (fake *FakeInstanceMetricsDB) Invocations() map[string][][]interface{} {
 	defer fake.saveMetricMutex.RUnlock()
 	fake.pruneInstanceMetricsMutex.RLock()
 	defer fake.pruneInstanceMetricsMutex.RUnlock()
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
 	return fake.invocations
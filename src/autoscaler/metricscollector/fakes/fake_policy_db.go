@@ -31,6 +31,122 @@ type FakePolicyDB struct {
 		result1 []*models.PolicyJson
 		result2 error
 	}
+	RetrievePoliciesSinceStub        func(version int64) ([]*models.PolicyJson, error)
+	retrievePoliciesSinceMutex       sync.RWMutex
+	retrievePoliciesSinceArgsForCall []struct {
+		version int64
+	}
+	retrievePoliciesSinceReturns struct {
+		result1 []*models.PolicyJson
+		result2 error
+	}
+	MarkAppOrphanedStub        func(appId string, orphanedAt int64) error
+	markAppOrphanedMutex       sync.RWMutex
+	markAppOrphanedArgsForCall []struct {
+		appId      string
+		orphanedAt int64
+	}
+	markAppOrphanedReturns struct {
+		result1 error
+	}
+	UnmarkAppOrphanedStub        func(appId string) error
+	unmarkAppOrphanedMutex       sync.RWMutex
+	unmarkAppOrphanedArgsForCall []struct {
+		appId string
+	}
+	unmarkAppOrphanedReturns struct {
+		result1 error
+	}
+	RetrieveOrphanedAppsStub        func(before int64) ([]string, error)
+	retrieveOrphanedAppsMutex       sync.RWMutex
+	retrieveOrphanedAppsArgsForCall []struct {
+		before int64
+	}
+	retrieveOrphanedAppsReturns struct {
+		result1 []string
+		result2 error
+	}
+	DeletePolicyStub        func(appId string) error
+	deletePolicyMutex       sync.RWMutex
+	deletePolicyArgsForCall []struct {
+		appId string
+	}
+	deletePolicyReturns struct {
+		result1 error
+	}
+	ObserveAppStub        func(appId string, expireAt int64) error
+	observeAppMutex       sync.RWMutex
+	observeAppArgsForCall []struct {
+		appId    string
+		expireAt int64
+	}
+	observeAppReturns struct {
+		result1 error
+	}
+	UnobserveAppStub        func(appId string) error
+	unobserveAppMutex       sync.RWMutex
+	unobserveAppArgsForCall []struct {
+		appId string
+	}
+	unobserveAppReturns struct {
+		result1 error
+	}
+	RetrieveObservedAppsStub        func(now int64) ([]string, error)
+	retrieveObservedAppsMutex       sync.RWMutex
+	retrieveObservedAppsArgsForCall []struct {
+		now int64
+	}
+	retrieveObservedAppsReturns struct {
+		result1 []string
+		result2 error
+	}
+	GetAppGroupStub        func(groupId string) (*models.AppGroup, error)
+	getAppGroupMutex       sync.RWMutex
+	getAppGroupArgsForCall []struct {
+		groupId string
+	}
+	getAppGroupReturns struct {
+		result1 *models.AppGroup
+		result2 error
+	}
+	GetAppGroupOfAppStub        func(appId string) (*models.AppGroup, error)
+	getAppGroupOfAppMutex       sync.RWMutex
+	getAppGroupOfAppArgsForCall []struct {
+		appId string
+	}
+	getAppGroupOfAppReturns struct {
+		result1 *models.AppGroup
+		result2 error
+	}
+	SaveAppGroupStub        func(group *models.AppGroup) error
+	saveAppGroupMutex       sync.RWMutex
+	saveAppGroupArgsForCall []struct {
+		group *models.AppGroup
+	}
+	saveAppGroupReturns struct {
+		result1 error
+	}
+	DeleteAppGroupStub        func(groupId string) error
+	deleteAppGroupMutex       sync.RWMutex
+	deleteAppGroupArgsForCall []struct {
+		groupId string
+	}
+	deleteAppGroupReturns struct {
+		result1 error
+	}
+	SubscribePolicyChangesStub        func() (<-chan string, error)
+	subscribePolicyChangesMutex       sync.RWMutex
+	subscribePolicyChangesArgsForCall []struct{}
+	subscribePolicyChangesReturns     struct {
+		result1 <-chan string
+		result2 error
+	}
+	PingStub        func() error
+	pingMutex       sync.RWMutex
+	pingArgsForCall []struct{}
+	pingReturns     struct {
+		result1 error
+	}
 	CloseStub        func() error
 	closeMutex       sync.RWMutex
 	closeArgsForCall []struct{}
@@ -127,6 +243,460 @@ func (fake *FakePolicyDB) RetrievePoliciesReturns(result1 []*models.PolicyJson,
 	}{result1, result2}
 }
 
+func (fake *FakePolicyDB) RetrievePoliciesSince(version int64) ([]*models.PolicyJson, error) {
+	fake.retrievePoliciesSinceMutex.Lock()
+	fake.retrievePoliciesSinceArgsForCall = append(fake.retrievePoliciesSinceArgsForCall, struct {
+		version int64
+	}{version})
+	fake.recordInvocation("RetrievePoliciesSince", []interface{}{version})
+	fake.retrievePoliciesSinceMutex.Unlock()
+	if fake.RetrievePoliciesSinceStub != nil {
+		return fake.RetrievePoliciesSinceStub(version)
+	} else {
+		return fake.retrievePoliciesSinceReturns.result1, fake.retrievePoliciesSinceReturns.result2
+	}
+}
+
+func (fake *FakePolicyDB) RetrievePoliciesSinceCallCount() int {
+	fake.retrievePoliciesSinceMutex.RLock()
+	defer fake.retrievePoliciesSinceMutex.RUnlock()
+	return len(fake.retrievePoliciesSinceArgsForCall)
+}
+
+func (fake *FakePolicyDB) RetrievePoliciesSinceArgsForCall(i int) int64 {
+	fake.retrievePoliciesSinceMutex.RLock()
+	defer fake.retrievePoliciesSinceMutex.RUnlock()
+	return fake.retrievePoliciesSinceArgsForCall[i].version
+}
+
+func (fake *FakePolicyDB) RetrievePoliciesSinceReturns(result1 []*models.PolicyJson, result2 error) {
+	fake.RetrievePoliciesSinceStub = nil
+	fake.retrievePoliciesSinceReturns = struct {
+		result1 []*models.PolicyJson
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyDB) MarkAppOrphaned(appId string, orphanedAt int64) error {
+	fake.markAppOrphanedMutex.Lock()
+	fake.markAppOrphanedArgsForCall = append(fake.markAppOrphanedArgsForCall, struct {
+		appId      string
+		orphanedAt int64
+	}{appId, orphanedAt})
+	fake.recordInvocation("MarkAppOrphaned", []interface{}{appId, orphanedAt})
+	fake.markAppOrphanedMutex.Unlock()
+	if fake.MarkAppOrphanedStub != nil {
+		return fake.MarkAppOrphanedStub(appId, orphanedAt)
+	} else {
+		return fake.markAppOrphanedReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) MarkAppOrphanedCallCount() int {
+	fake.markAppOrphanedMutex.RLock()
+	defer fake.markAppOrphanedMutex.RUnlock()
+	return len(fake.markAppOrphanedArgsForCall)
+}
+
+func (fake *FakePolicyDB) MarkAppOrphanedArgsForCall(i int) (string, int64) {
+	fake.markAppOrphanedMutex.RLock()
+	defer fake.markAppOrphanedMutex.RUnlock()
+	return fake.markAppOrphanedArgsForCall[i].appId, fake.markAppOrphanedArgsForCall[i].orphanedAt
+}
+
+func (fake *FakePolicyDB) MarkAppOrphanedReturns(result1 error) {
+	fake.MarkAppOrphanedStub = nil
+	fake.markAppOrphanedReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePolicyDB) UnmarkAppOrphaned(appId string) error {
+	fake.unmarkAppOrphanedMutex.Lock()
+	fake.unmarkAppOrphanedArgsForCall = append(fake.unmarkAppOrphanedArgsForCall, struct {
+		appId string
+	}{appId})
+	fake.recordInvocation("UnmarkAppOrphaned", []interface{}{appId})
+	fake.unmarkAppOrphanedMutex.Unlock()
+	if fake.UnmarkAppOrphanedStub != nil {
+		return fake.UnmarkAppOrphanedStub(appId)
+	} else {
+		return fake.unmarkAppOrphanedReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) UnmarkAppOrphanedCallCount() int {
+	fake.unmarkAppOrphanedMutex.RLock()
+	defer fake.unmarkAppOrphanedMutex.RUnlock()
+	return len(fake.unmarkAppOrphanedArgsForCall)
+}
+
+func (fake *FakePolicyDB) UnmarkAppOrphanedArgsForCall(i int) string {
+	fake.unmarkAppOrphanedMutex.RLock()
+	defer fake.unmarkAppOrphanedMutex.RUnlock()
+	return fake.unmarkAppOrphanedArgsForCall[i].appId
+}
+
+func (fake *FakePolicyDB) UnmarkAppOrphanedReturns(result1 error) {
+	fake.UnmarkAppOrphanedStub = nil
+	fake.unmarkAppOrphanedReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePolicyDB) RetrieveOrphanedApps(before int64) ([]string, error) {
+	fake.retrieveOrphanedAppsMutex.Lock()
+	fake.retrieveOrphanedAppsArgsForCall = append(fake.retrieveOrphanedAppsArgsForCall, struct {
+		before int64
+	}{before})
+	fake.recordInvocation("RetrieveOrphanedApps", []interface{}{before})
+	fake.retrieveOrphanedAppsMutex.Unlock()
+	if fake.RetrieveOrphanedAppsStub != nil {
+		return fake.RetrieveOrphanedAppsStub(before)
+	} else {
+		return fake.retrieveOrphanedAppsReturns.result1, fake.retrieveOrphanedAppsReturns.result2
+	}
+}
+
+func (fake *FakePolicyDB) RetrieveOrphanedAppsCallCount() int {
+	fake.retrieveOrphanedAppsMutex.RLock()
+	defer fake.retrieveOrphanedAppsMutex.RUnlock()
+	return len(fake.retrieveOrphanedAppsArgsForCall)
+}
+
+func (fake *FakePolicyDB) RetrieveOrphanedAppsArgsForCall(i int) int64 {
+	fake.retrieveOrphanedAppsMutex.RLock()
+	defer fake.retrieveOrphanedAppsMutex.RUnlock()
+	return fake.retrieveOrphanedAppsArgsForCall[i].before
+}
+
+func (fake *FakePolicyDB) RetrieveOrphanedAppsReturns(result1 []string, result2 error) {
+	fake.RetrieveOrphanedAppsStub = nil
+	fake.retrieveOrphanedAppsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyDB) DeletePolicy(appId string) error {
+	fake.deletePolicyMutex.Lock()
+	fake.deletePolicyArgsForCall = append(fake.deletePolicyArgsForCall, struct {
+		appId string
+	}{appId})
+	fake.recordInvocation("DeletePolicy", []interface{}{appId})
+	fake.deletePolicyMutex.Unlock()
+	if fake.DeletePolicyStub != nil {
+		return fake.DeletePolicyStub(appId)
+	} else {
+		return fake.deletePolicyReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) DeletePolicyCallCount() int {
+	fake.deletePolicyMutex.RLock()
+	defer fake.deletePolicyMutex.RUnlock()
+	return len(fake.deletePolicyArgsForCall)
+}
+
+func (fake *FakePolicyDB) DeletePolicyArgsForCall(i int) string {
+	fake.deletePolicyMutex.RLock()
+	defer fake.deletePolicyMutex.RUnlock()
+	return fake.deletePolicyArgsForCall[i].appId
+}
+
+func (fake *FakePolicyDB) DeletePolicyReturns(result1 error) {
+	fake.DeletePolicyStub = nil
+	fake.deletePolicyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePolicyDB) ObserveApp(appId string, expireAt int64) error {
+	fake.observeAppMutex.Lock()
+	fake.observeAppArgsForCall = append(fake.observeAppArgsForCall, struct {
+		appId    string
+		expireAt int64
+	}{appId, expireAt})
+	fake.recordInvocation("ObserveApp", []interface{}{appId, expireAt})
+	fake.observeAppMutex.Unlock()
+	if fake.ObserveAppStub != nil {
+		return fake.ObserveAppStub(appId, expireAt)
+	} else {
+		return fake.observeAppReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) ObserveAppCallCount() int {
+	fake.observeAppMutex.RLock()
+	defer fake.observeAppMutex.RUnlock()
+	return len(fake.observeAppArgsForCall)
+}
+
+func (fake *FakePolicyDB) ObserveAppArgsForCall(i int) (string, int64) {
+	fake.observeAppMutex.RLock()
+	defer fake.observeAppMutex.RUnlock()
+	return fake.observeAppArgsForCall[i].appId, fake.observeAppArgsForCall[i].expireAt
+}
+
+func (fake *FakePolicyDB) ObserveAppReturns(result1 error) {
+	fake.ObserveAppStub = nil
+	fake.observeAppReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePolicyDB) UnobserveApp(appId string) error {
+	fake.unobserveAppMutex.Lock()
+	fake.unobserveAppArgsForCall = append(fake.unobserveAppArgsForCall, struct {
+		appId string
+	}{appId})
+	fake.recordInvocation("UnobserveApp", []interface{}{appId})
+	fake.unobserveAppMutex.Unlock()
+	if fake.UnobserveAppStub != nil {
+		return fake.UnobserveAppStub(appId)
+	} else {
+		return fake.unobserveAppReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) UnobserveAppCallCount() int {
+	fake.unobserveAppMutex.RLock()
+	defer fake.unobserveAppMutex.RUnlock()
+	return len(fake.unobserveAppArgsForCall)
+}
+
+func (fake *FakePolicyDB) UnobserveAppArgsForCall(i int) string {
+	fake.unobserveAppMutex.RLock()
+	defer fake.unobserveAppMutex.RUnlock()
+	return fake.unobserveAppArgsForCall[i].appId
+}
+
+func (fake *FakePolicyDB) UnobserveAppReturns(result1 error) {
+	fake.UnobserveAppStub = nil
+	fake.unobserveAppReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePolicyDB) RetrieveObservedApps(now int64) ([]string, error) {
+	fake.retrieveObservedAppsMutex.Lock()
+	fake.retrieveObservedAppsArgsForCall = append(fake.retrieveObservedAppsArgsForCall, struct {
+		now int64
+	}{now})
+	fake.recordInvocation("RetrieveObservedApps", []interface{}{now})
+	fake.retrieveObservedAppsMutex.Unlock()
+	if fake.RetrieveObservedAppsStub != nil {
+		return fake.RetrieveObservedAppsStub(now)
+	} else {
+		return fake.retrieveObservedAppsReturns.result1, fake.retrieveObservedAppsReturns.result2
+	}
+}
+
+func (fake *FakePolicyDB) RetrieveObservedAppsCallCount() int {
+	fake.retrieveObservedAppsMutex.RLock()
+	defer fake.retrieveObservedAppsMutex.RUnlock()
+	return len(fake.retrieveObservedAppsArgsForCall)
+}
+
+func (fake *FakePolicyDB) RetrieveObservedAppsArgsForCall(i int) int64 {
+	fake.retrieveObservedAppsMutex.RLock()
+	defer fake.retrieveObservedAppsMutex.RUnlock()
+	return fake.retrieveObservedAppsArgsForCall[i].now
+}
+
+func (fake *FakePolicyDB) RetrieveObservedAppsReturns(result1 []string, result2 error) {
+	fake.RetrieveObservedAppsStub = nil
+	fake.retrieveObservedAppsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyDB) GetAppGroup(groupId string) (*models.AppGroup, error) {
+	fake.getAppGroupMutex.Lock()
+	fake.getAppGroupArgsForCall = append(fake.getAppGroupArgsForCall, struct {
+		groupId string
+	}{groupId})
+	fake.recordInvocation("GetAppGroup", []interface{}{groupId})
+	fake.getAppGroupMutex.Unlock()
+	if fake.GetAppGroupStub != nil {
+		return fake.GetAppGroupStub(groupId)
+	} else {
+		return fake.getAppGroupReturns.result1, fake.getAppGroupReturns.result2
+	}
+}
+
+func (fake *FakePolicyDB) GetAppGroupCallCount() int {
+	fake.getAppGroupMutex.RLock()
+	defer fake.getAppGroupMutex.RUnlock()
+	return len(fake.getAppGroupArgsForCall)
+}
+
+func (fake *FakePolicyDB) GetAppGroupArgsForCall(i int) string {
+	fake.getAppGroupMutex.RLock()
+	defer fake.getAppGroupMutex.RUnlock()
+	return fake.getAppGroupArgsForCall[i].groupId
+}
+
+func (fake *FakePolicyDB) GetAppGroupReturns(result1 *models.AppGroup, result2 error) {
+	fake.GetAppGroupStub = nil
+	fake.getAppGroupReturns = struct {
+		result1 *models.AppGroup
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyDB) GetAppGroupOfApp(appId string) (*models.AppGroup, error) {
+	fake.getAppGroupOfAppMutex.Lock()
+	fake.getAppGroupOfAppArgsForCall = append(fake.getAppGroupOfAppArgsForCall, struct {
+		appId string
+	}{appId})
+	fake.recordInvocation("GetAppGroupOfApp", []interface{}{appId})
+	fake.getAppGroupOfAppMutex.Unlock()
+	if fake.GetAppGroupOfAppStub != nil {
+		return fake.GetAppGroupOfAppStub(appId)
+	} else {
+		return fake.getAppGroupOfAppReturns.result1, fake.getAppGroupOfAppReturns.result2
+	}
+}
+
+func (fake *FakePolicyDB) GetAppGroupOfAppCallCount() int {
+	fake.getAppGroupOfAppMutex.RLock()
+	defer fake.getAppGroupOfAppMutex.RUnlock()
+	return len(fake.getAppGroupOfAppArgsForCall)
+}
+
+func (fake *FakePolicyDB) GetAppGroupOfAppArgsForCall(i int) string {
+	fake.getAppGroupOfAppMutex.RLock()
+	defer fake.getAppGroupOfAppMutex.RUnlock()
+	return fake.getAppGroupOfAppArgsForCall[i].appId
+}
+
+func (fake *FakePolicyDB) GetAppGroupOfAppReturns(result1 *models.AppGroup, result2 error) {
+	fake.GetAppGroupOfAppStub = nil
+	fake.getAppGroupOfAppReturns = struct {
+		result1 *models.AppGroup
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyDB) SaveAppGroup(group *models.AppGroup) error {
+	fake.saveAppGroupMutex.Lock()
+	fake.saveAppGroupArgsForCall = append(fake.saveAppGroupArgsForCall, struct {
+		group *models.AppGroup
+	}{group})
+	fake.recordInvocation("SaveAppGroup", []interface{}{group})
+	fake.saveAppGroupMutex.Unlock()
+	if fake.SaveAppGroupStub != nil {
+		return fake.SaveAppGroupStub(group)
+	} else {
+		return fake.saveAppGroupReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) SaveAppGroupCallCount() int {
+	fake.saveAppGroupMutex.RLock()
+	defer fake.saveAppGroupMutex.RUnlock()
+	return len(fake.saveAppGroupArgsForCall)
+}
+
+func (fake *FakePolicyDB) SaveAppGroupArgsForCall(i int) *models.AppGroup {
+	fake.saveAppGroupMutex.RLock()
+	defer fake.saveAppGroupMutex.RUnlock()
+	return fake.saveAppGroupArgsForCall[i].group
+}
+
+func (fake *FakePolicyDB) SaveAppGroupReturns(result1 error) {
+	fake.SaveAppGroupStub = nil
+	fake.saveAppGroupReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePolicyDB) DeleteAppGroup(groupId string) error {
+	fake.deleteAppGroupMutex.Lock()
+	fake.deleteAppGroupArgsForCall = append(fake.deleteAppGroupArgsForCall, struct {
+		groupId string
+	}{groupId})
+	fake.recordInvocation("DeleteAppGroup", []interface{}{groupId})
+	fake.deleteAppGroupMutex.Unlock()
+	if fake.DeleteAppGroupStub != nil {
+		return fake.DeleteAppGroupStub(groupId)
+	} else {
+		return fake.deleteAppGroupReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) DeleteAppGroupCallCount() int {
+	fake.deleteAppGroupMutex.RLock()
+	defer fake.deleteAppGroupMutex.RUnlock()
+	return len(fake.deleteAppGroupArgsForCall)
+}
+
+func (fake *FakePolicyDB) DeleteAppGroupArgsForCall(i int) string {
+	fake.deleteAppGroupMutex.RLock()
+	defer fake.deleteAppGroupMutex.RUnlock()
+	return fake.deleteAppGroupArgsForCall[i].groupId
+}
+
+func (fake *FakePolicyDB) DeleteAppGroupReturns(result1 error) {
+	fake.DeleteAppGroupStub = nil
+	fake.deleteAppGroupReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePolicyDB) SubscribePolicyChanges() (<-chan string, error) {
+	fake.subscribePolicyChangesMutex.Lock()
+	fake.subscribePolicyChangesArgsForCall = append(fake.subscribePolicyChangesArgsForCall, struct{}{})
+	fake.recordInvocation("SubscribePolicyChanges", []interface{}{})
+	fake.subscribePolicyChangesMutex.Unlock()
+	if fake.SubscribePolicyChangesStub != nil {
+		return fake.SubscribePolicyChangesStub()
+	} else {
+		return fake.subscribePolicyChangesReturns.result1, fake.subscribePolicyChangesReturns.result2
+	}
+}
+
+func (fake *FakePolicyDB) SubscribePolicyChangesCallCount() int {
+	fake.subscribePolicyChangesMutex.RLock()
+	defer fake.subscribePolicyChangesMutex.RUnlock()
+	return len(fake.subscribePolicyChangesArgsForCall)
+}
+
+func (fake *FakePolicyDB) SubscribePolicyChangesReturns(result1 <-chan string, result2 error) {
+	fake.SubscribePolicyChangesStub = nil
+	fake.subscribePolicyChangesReturns = struct {
+		result1 <-chan string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyDB) Ping() error {
+	fake.pingMutex.Lock()
+	fake.pingArgsForCall = append(fake.pingArgsForCall, struct{}{})
+	fake.recordInvocation("Ping", []interface{}{})
+	fake.pingMutex.Unlock()
+	if fake.PingStub != nil {
+		return fake.PingStub()
+	} else {
+		return fake.pingReturns.result1
+	}
+}
+
+func (fake *FakePolicyDB) PingCallCount() int {
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	return len(fake.pingArgsForCall)
+}
+
+func (fake *FakePolicyDB) PingReturns(result1 error) {
+	fake.PingStub = nil
+	fake.pingReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakePolicyDB) Close() error {
 	fake.closeMutex.Lock()
 	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
@@ -161,6 +731,34 @@ func (fake *FakePolicyDB) Invocations() map[string][][]interface{} {
 	defer fake.getAppPolicyMutex.RUnlock()
 	fake.retrievePoliciesMutex.RLock()
 	defer fake.retrievePoliciesMutex.RUnlock()
+	fake.retrievePoliciesSinceMutex.RLock()
+	defer fake.retrievePoliciesSinceMutex.RUnlock()
+	fake.markAppOrphanedMutex.RLock()
+	defer fake.markAppOrphanedMutex.RUnlock()
+	fake.unmarkAppOrphanedMutex.RLock()
+	defer fake.unmarkAppOrphanedMutex.RUnlock()
+	fake.retrieveOrphanedAppsMutex.RLock()
+	defer fake.retrieveOrphanedAppsMutex.RUnlock()
+	fake.deletePolicyMutex.RLock()
+	defer fake.deletePolicyMutex.RUnlock()
+	fake.observeAppMutex.RLock()
+	defer fake.observeAppMutex.RUnlock()
+	fake.unobserveAppMutex.RLock()
+	defer fake.unobserveAppMutex.RUnlock()
+	fake.retrieveObservedAppsMutex.RLock()
+	defer fake.retrieveObservedAppsMutex.RUnlock()
+	fake.getAppGroupMutex.RLock()
+	defer fake.getAppGroupMutex.RUnlock()
+	fake.getAppGroupOfAppMutex.RLock()
+	defer fake.getAppGroupOfAppMutex.RUnlock()
+	fake.saveAppGroupMutex.RLock()
+	defer fake.saveAppGroupMutex.RUnlock()
+	fake.deleteAppGroupMutex.RLock()
+	defer fake.deleteAppGroupMutex.RUnlock()
+	fake.subscribePolicyChangesMutex.RLock()
+	defer fake.subscribePolicyChangesMutex.RUnlock()
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
 	return fake.invocations
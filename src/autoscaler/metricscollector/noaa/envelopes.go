@@ -0,0 +1,36 @@
+package noaa
+
+import (
+	"autoscaler/cf"
+
+	"code.cloudfoundry.org/lager"
+	noaaerrors "github.com/cloudfoundry/noaa/errors"
+	"github.com/cloudfoundry/sonde-go/events"
+)
+
+// GetContainerEnvelopes gets the container envelopes of the app with the access
+// token of cfc. When doppler rejects the token, cfc re-authenticates and the
+// request is retried once.
+func GetContainerEnvelopes(logger lager.Logger, consumer NoaaConsumer, cfc cf.CfClient, appId string) ([]*events.Envelope, error) {
+	tokens, err := cfc.GetTokensWithRefresh()
+	if err != nil && tokens.AccessToken == "" {
+		return nil, err
+	}
+
+	envelopes, err := consumer.ContainerEnvelopes(appId, cf.TokenTypeBearer+" "+tokens.AccessToken)
+	if _, ok := err.(*noaaerrors.UnauthorizedError); !ok {
+		return envelopes, err
+	}
+
+	logger.Info("unauthorized-reauthenticate", lager.Data{"appId": appId})
+	tokens, err = cfc.Reauthenticate(tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopes, err = consumer.ContainerEnvelopes(appId, cf.TokenTypeBearer+" "+tokens.AccessToken)
+	if _, ok := err.(*noaaerrors.UnauthorizedError); ok {
+		return nil, &cf.AuthError{Err: err}
+	}
+	return envelopes, err
+}
@@ -15,8 +15,6 @@ import (
 	"time"
 )
 
-const TokenTypeBearer = "bearer"
-
 type MemoryMetricHandler struct {
 	cfClient     cf.CfClient
 	logger       lager.Logger
@@ -38,7 +36,7 @@ func (h *MemoryMetricHandler) GetMemoryMetric(w http.ResponseWriter, r *http.Req
 
 	w.Header().Set("Content-Type", "application/json")
 
-	containerEnvelopes, err := h.noaaConsumer.ContainerEnvelopes(appId, TokenTypeBearer+" "+h.cfClient.GetTokens().AccessToken)
+	containerEnvelopes, err := noaa.GetContainerEnvelopes(h.logger, h.noaaConsumer, h.cfClient, appId)
 	if err != nil {
 		h.logger.Error("Get-memory-metric-from-noaa", err, lager.Data{"appId": appId})
 
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"autoscaler/db"
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ObservationHandler registers apps to have their metrics collected for a
+// bounded time without a scaling policy, so that their metric histories are
+// available before a policy is defined.
+type ObservationHandler struct {
+	logger          lager.Logger
+	database        db.PolicyDB
+	clock           clock.Clock
+	defaultDuration time.Duration
+	maxDuration     time.Duration
+}
+
+func NewObservationHandler(logger lager.Logger, database db.PolicyDB, clock clock.Clock, defaultDuration time.Duration, maxDuration time.Duration) *ObservationHandler {
+	return &ObservationHandler{
+		logger:          logger,
+		database:        database,
+		clock:           clock,
+		defaultDuration: defaultDuration,
+		maxDuration:     maxDuration,
+	}
+}
+
+// ObserveApp starts or extends the observation of the app. The duration is
+// taken from the optional request body and defaults to the configured one.
+func (h *ObservationHandler) ObserveApp(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	appId := vars["appid"]
+	logger := h.logger.Session("observe-app", lager.Data{"appid": appId})
+
+	observation := &models.AppObservation{}
+	err := json.NewDecoder(r.Body).Decode(observation)
+	if err != nil && err != io.EOF {
+		logger.Error("failed-to-decode", err)
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: "Incorrect observation in request body"})
+		return
+	}
+
+	duration := h.defaultDuration
+	if observation.DurationSeconds != 0 {
+		duration = time.Duration(observation.DurationSeconds) * time.Second
+	}
+	if duration <= 0 || duration > h.maxDuration {
+		logger.Info("invalid-duration", lager.Data{"duration": duration, "maxDuration": h.maxDuration})
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: "Observation duration must be greater than 0 and at most " + h.maxDuration.String()})
+		return
+	}
+
+	expireAt := h.clock.Now().Add(duration).UnixNano()
+	err = h.database.ObserveApp(appId, expireAt)
+	if err != nil {
+		logger.Error("failed-to-observe-app", err, lager.Data{"expireAt": expireAt})
+		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    "Interal-Server-Error",
+			Message: "Error registering the app for observation"})
+		return
+	}
+
+	logger.Info("app-observed", lager.Data{"expireAt": expireAt})
+	handlers.WriteJSONResponse(w, http.StatusOK, models.AppObservation{
+		DurationSeconds: int(duration / time.Second),
+		ExpireAt:        expireAt,
+	})
+}
+
+func (h *ObservationHandler) UnobserveApp(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	appId := vars["appid"]
+	logger := h.logger.Session("unobserve-app", lager.Data{"appid": appId})
+
+	err := h.database.UnobserveApp(appId)
+	if err != nil {
+		logger.Error("failed-to-unobserve-app", err)
+		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    "Interal-Server-Error",
+			Message: "Error removing the app from observation"})
+		return
+	}
+
+	logger.Info("app-unobserved")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,144 @@
+package server_test
+
+import (
+	"autoscaler/metricscollector/fakes"
+	. "autoscaler/metricscollector/server"
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+var _ = Describe("ObservationHandler", func() {
+
+	var (
+		database *fakes.FakePolicyDB
+		fclock   *fakeclock.FakeClock
+		handler  *ObservationHandler
+		resp     *httptest.ResponseRecorder
+		req      *http.Request
+		body     []byte
+		err      error
+	)
+
+	BeforeEach(func() {
+		database = &fakes.FakePolicyDB{}
+		fclock = fakeclock.NewFakeClock(time.Now())
+		resp = httptest.NewRecorder()
+		handler = NewObservationHandler(lager.NewLogger("handler-test"), database, fclock, time.Hour, 24*time.Hour)
+		body = nil
+	})
+
+	Describe("ObserveApp", func() {
+		JustBeforeEach(func() {
+			req, err = http.NewRequest(http.MethodPut, "http://localhost/v1/apps/an-app-id/observation", bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			handler.ObserveApp(resp, req, map[string]string{"appid": "an-app-id"})
+		})
+
+		Context("when the request has no body", func() {
+			It("observes the app for the default duration", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+
+				appId, expireAt := database.ObserveAppArgsForCall(0)
+				Expect(appId).To(Equal("an-app-id"))
+				Expect(expireAt).To(Equal(fclock.Now().Add(time.Hour).UnixNano()))
+
+				observation := &models.AppObservation{}
+				Expect(json.Unmarshal(resp.Body.Bytes(), observation)).To(Succeed())
+				Expect(observation).To(Equal(&models.AppObservation{DurationSeconds: 3600, ExpireAt: expireAt}))
+			})
+		})
+
+		Context("when the request has a duration", func() {
+			BeforeEach(func() {
+				body = []byte(`{"duration_secs":600}`)
+			})
+
+			It("observes the app for the given duration", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				_, expireAt := database.ObserveAppArgsForCall(0)
+				Expect(expireAt).To(Equal(fclock.Now().Add(10 * time.Minute).UnixNano()))
+			})
+		})
+
+		Context("when the duration is greater than the max duration", func() {
+			BeforeEach(func() {
+				body = []byte(`{"duration_secs":90000}`)
+			})
+
+			It("returns a 400", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(database.ObserveAppCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the duration is negative", func() {
+			BeforeEach(func() {
+				body = []byte(`{"duration_secs":-1}`)
+			})
+
+			It("returns a 400", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(database.ObserveAppCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the request body is not valid", func() {
+			BeforeEach(func() {
+				body = []byte(`not-json`)
+			})
+
+			It("returns a 400", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+
+				errJson := &models.ErrorResponse{}
+				Expect(json.Unmarshal(resp.Body.Bytes(), errJson)).To(Succeed())
+				Expect(errJson).To(Equal(&models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "Incorrect observation in request body",
+				}))
+			})
+		})
+
+		Context("when registering the app fails", func() {
+			BeforeEach(func() {
+				database.ObserveAppReturns(errors.New("an error"))
+			})
+
+			It("returns a 500", func() {
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	Describe("UnobserveApp", func() {
+		JustBeforeEach(func() {
+			handler.UnobserveApp(resp, nil, map[string]string{"appid": "an-app-id"})
+		})
+
+		It("removes the observation of the app", func() {
+			Expect(resp.Code).To(Equal(http.StatusNoContent))
+			Expect(database.UnobserveAppArgsForCall(0)).To(Equal("an-app-id"))
+		})
+
+		Context("when removing the observation fails", func() {
+			BeforeEach(func() {
+				database.UnobserveAppReturns(errors.New("an error"))
+			})
+
+			It("returns a 500", func() {
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+})
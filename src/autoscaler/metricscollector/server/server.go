@@ -11,6 +11,7 @@ import (
 	"autoscaler/routes"
 
 	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 	"github.com/gorilla/mux"
 	"github.com/tedsuo/ifrit"
@@ -24,12 +25,15 @@ func (vh VarsFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	vh(w, r, vars)
 }
 
-func NewServer(logger lager.Logger, conf *config.Config, cfc cf.CfClient, consumer noaa.NoaaConsumer, database db.InstanceMetricsDB) (ifrit.Runner, error) {
+func NewServer(logger lager.Logger, conf *config.Config, cfc cf.CfClient, consumer noaa.NoaaConsumer, database db.InstanceMetricsDB, policyDB db.PolicyDB, sclock clock.Clock) (ifrit.Runner, error) {
 	mmh := NewMemoryMetricHandler(logger, cfc, consumer, database)
+	oh := NewObservationHandler(logger, policyDB, sclock, conf.Collector.ObserveDuration, conf.Collector.MaxObserveDuration)
 
 	r := routes.MetricsCollectorRoutes()
 	r.Get(routes.MemoryMetricRoute).Methods(http.MethodGet).Handler(VarsFunc(mmh.GetMemoryMetric))
 	r.Get(routes.MemoryMetricHistoryRoute).Methods(http.MethodGet).Handler(VarsFunc(mmh.GetMemoryMetricHistories))
+	r.Get(routes.ObserveAppRoute).Methods(http.MethodPut).Handler(VarsFunc(oh.ObserveApp))
+	r.Get(routes.UnobserveAppRoute).Methods(http.MethodDelete).Handler(VarsFunc(oh.UnobserveApp))
 
 	addr := fmt.Sprintf("0.0.0.0:%d", conf.Server.Port)
 	logger.Info("new-http-server", lager.Data{"serverConfig": conf.Server})
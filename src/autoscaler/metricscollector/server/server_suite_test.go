@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"strconv"
 
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -34,10 +35,15 @@ var _ = BeforeSuite(func() {
 		Server: config.ServerConfig{
 			Port: port,
 		},
+		Collector: config.CollectorConfig{
+			ObserveDuration:    config.DefaultObserveDuration,
+			MaxObserveDuration: config.DefaultMaxObserveDuration,
+		},
 	}
 	database := &fakes.FakeInstanceMetricsDB{}
+	policyDB := &fakes.FakePolicyDB{}
 
-	httpServer, err := server.NewServer(lager.NewLogger("test"), conf, cfc, consumer, database)
+	httpServer, err := server.NewServer(lager.NewLogger("test"), conf, cfc, consumer, database, policyDB, clock.NewClock())
 	Expect(err).NotTo(HaveOccurred())
 
 	serverUrl, err = url.Parse("http://127.0.0.1:" + strconv.Itoa(port))
@@ -9,6 +9,7 @@ import (
 
 const TestPathMemoryMetrics = "/v1/apps/an-app-id/metrics/memory"
 const TestPathMemoryMetricHistories = "/v1/apps/an-app-id/metric_histories/memory"
+const TestPathObservation = "/v1/apps/an-app-id/observation"
 
 var _ = Describe("Server", func() {
 	var (
@@ -48,6 +49,44 @@ var _ = Describe("Server", func() {
 		})
 	})
 
+	Context("when observing an app", func() {
+		BeforeEach(func() {
+			serverUrl.Path = TestPathObservation
+		})
+
+		JustBeforeEach(func() {
+			var req *http.Request
+			req, err = http.NewRequest(http.MethodPut, serverUrl.String(), nil)
+			Expect(err).NotTo(HaveOccurred())
+			rsp, err = http.DefaultClient.Do(req)
+		})
+
+		It("should return 200", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+			rsp.Body.Close()
+		})
+	})
+
+	Context("when stopping the observation of an app", func() {
+		BeforeEach(func() {
+			serverUrl.Path = TestPathObservation
+		})
+
+		JustBeforeEach(func() {
+			var req *http.Request
+			req, err = http.NewRequest(http.MethodDelete, serverUrl.String(), nil)
+			Expect(err).NotTo(HaveOccurred())
+			rsp, err = http.DefaultClient.Do(req)
+		})
+
+		It("should return 204", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(http.StatusNoContent))
+			rsp.Body.Close()
+		})
+	})
+
 	Context("when requesting the wrong path", func() {
 		BeforeEach(func() {
 			serverUrl.Path = "/not-exist-path"
@@ -7,7 +7,73 @@ type AppInfo struct {
 }
 
 type AppEntity struct {
-	Instances int `json:"instances"`
+	Instances    int    `json:"instances"`
+	State        string `json:"state,omitempty"`
+	PackageState string `json:"package_state,omitempty"`
+	Memory       int    `json:"memory,omitempty"`
+	DiskQuota    int    `json:"disk_quota,omitempty"`
+	SpaceGuid    string `json:"space_guid,omitempty"`
+}
+
+const (
+	AppStateStarted     = "STARTED"
+	AppStateStopped     = "STOPPED"
+	PackageStatePending = "PENDING"
+	PackageStateStaged  = "STAGED"
+)
+
+const (
+	InstanceStateRunning = "RUNNING"
+	InstanceStateCrashed = "CRASHED"
+)
+
+type InstanceStats struct {
+	Index int    `json:"index"`
+	State string `json:"state"`
+}
+
+// AppStatus summarizes the state of an app and the health of the instances of
+// one of its processes. Instance counts are only known for started apps that
+// are not staging.
+type AppStatus struct {
+	State            string
+	Staging          bool
+	Instances        int
+	CrashedInstances int
+}
+
+const DefaultProcessType = "web"
+
+type Process struct {
+	Guid       string `json:"guid"`
+	Type       string `json:"type"`
+	Instances  int    `json:"instances"`
+	MemoryInMb int    `json:"memory_in_mb,omitempty"`
+	DiskInMb   int    `json:"disk_in_mb,omitempty"`
+}
+
+// InstanceLimits are the memory and disk limits in MB of each instance of an
+// app process.
+type InstanceLimits struct {
+	MemoryMb int
+	DiskMb   int
+}
+
+const UnlimitedMemory = -1
+
+// MemoryQuota is a memory limit in MB and how much of it is in use. The limit
+// is UnlimitedMemory when there is no limit.
+type MemoryQuota struct {
+	Limit int
+	Used  int
+}
+
+// AppQuotas holds the memory in MB of one instance of an app process, and the
+// memory quotas of the org and the space of the app.
+type AppQuotas struct {
+	InstanceMemory int
+	Org            MemoryQuota
+	Space          MemoryQuota
 }
 
 type ScalingType int
@@ -16,12 +82,19 @@ type ScalingStatus int
 const (
 	ScalingTypeDynamic ScalingType = iota
 	ScalingTypeSchedule
+	// ScalingTypeRemediation is a dynamic action restarting an unhealthy
+	// instance, it does not change the number of instances.
+	ScalingTypeRemediation
+	// ScalingTypeVertical is a dynamic action changing the memory and disk
+	// limits of the instances, it does not change the number of instances.
+	ScalingTypeVertical
 )
 
 const (
 	ScalingStatusSucceeded ScalingStatus = iota
 	ScalingStatusFailed
 	ScalingStatusIgnored
+	ScalingStatusUnavailable
 )
 
 type AppScalingHistory struct {
@@ -34,6 +107,25 @@ type AppScalingHistory struct {
 	Reason       string
 	Message      string
 	Error        string
+	// PolicyRevision is the revision of the policy that was active, or 0 when
+	// the policy could not be retrieved.
+	PolicyRevision int64
+	// The memory and disk limits in MB of each instance before and after a
+	// vertical scaling, they are 0 for the other scaling types.
+	OldMemoryMb int
+	NewMemoryMb int
+	OldDiskMb   int
+	NewDiskMb   int
+	// GroupId is the app group when the app was scaled together with the other
+	// members of its group.
+	GroupId string
+}
+
+// AppObservation requests the metrics of an app to be collected for a while
+// without a scaling policy, and tells until when they are collected.
+type AppObservation struct {
+	DurationSeconds int   `json:"duration_secs,omitempty"`
+	ExpireAt        int64 `json:"expire_at,omitempty"`
 }
 
 type AppMonitor struct {
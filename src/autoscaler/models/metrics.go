@@ -26,6 +26,20 @@ type AppInstanceMetric struct {
 	Timestamp     int64  `json:"timestamp"`
 }
 
+// AnomalousInstance is an instance whose metric deviates from the other
+// instances of the app so much that it is left out of the app metric. Value is
+// the mean of the instance over the stat window, Median is the median of those
+// means over all the instances, and Score is the modified z-score of Value.
+type AnomalousInstance struct {
+	AppId         string  `json:"app_id"`
+	InstanceIndex uint32  `json:"instance_index"`
+	MetricType    string  `json:"metric_type"`
+	Value         int64   `json:"value"`
+	Median        int64   `json:"median"`
+	Score         float64 `json:"score"`
+	Timestamp     int64   `json:"timestamp"`
+}
+
 func GetInstanceMemoryMetricFromContainerEnvelopes(collectAt int64, appId string, containerEnvelopes []*events.Envelope) []*AppInstanceMetric {
 	metrics := []*AppInstanceMetric{}
 	for _, e := range containerEnvelopes {
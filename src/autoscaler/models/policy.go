@@ -15,6 +15,11 @@ type AppPolicy struct {
 type PolicyJson struct {
 	AppId     string
 	PolicyStr string
+	// Version increases with every change of the policy or the orphan mark of
+	// the app. Deleted is set for policies that were deleted or whose app is
+	// orphaned, they come without PolicyStr.
+	Version int64
+	Deleted bool
 }
 
 func (p1 *PolicyJson) Equals(p2 *PolicyJson) bool {
@@ -39,9 +44,39 @@ func (p *PolicyJson) GetAppPolicy() *AppPolicy {
 type ScalingPolicy struct {
 	InstanceMin  int            `json:"instance_min_count"`
 	InstanceMax  int            `json:"instance_max_count"`
+	ProcessType  string         `json:"process_type,omitempty"`
 	ScalingRules []*ScalingRule `json:"scaling_rules"`
+	// The bounds in MB of the memory and disk limits of each instance, which
+	// the resize action keeps the limits within.
+	InstanceMemoryMin int `json:"instance_memory_min_mb,omitempty"`
+	InstanceMemoryMax int `json:"instance_memory_max_mb,omitempty"`
+	InstanceDiskMin   int `json:"instance_disk_min_mb,omitempty"`
+	InstanceDiskMax   int `json:"instance_disk_max_mb,omitempty"`
+	// Revision is the policy history revision the policy was stored with,
+	// it is not part of the policy document.
+	Revision int64 `json:"-"`
 }
 
+// GetProcessType returns the process type of the app to scale, which is the
+// web process unless the policy names another one.
+func (p *ScalingPolicy) GetProcessType() string {
+	if p.ProcessType == "" {
+		return DefaultProcessType
+	}
+	return p.ProcessType
+}
+
+const (
+	ActionScale           = "scale"
+	ActionRestartInstance = "restart_instance"
+	ActionResize          = "resize"
+)
+
+// ScalingRule scales the app by Adjustment when the app metric breaches the
+// threshold. With the restart_instance action, the rule restarts each instance
+// whose own metric breaches the threshold instead, and has no Adjustment. With
+// the resize action, the rule changes the memory and disk limits of each
+// instance by MemoryAdjustment and DiskAdjustment in MB.
 type ScalingRule struct {
 	MetricType            string `json:"metric_type"`
 	StatWindowSeconds     int    `json:"stat_window_secs"`
@@ -50,6 +85,18 @@ type ScalingRule struct {
 	Operator              string `json:"operator"`
 	CoolDownSeconds       int    `json:"cool_down_secs"`
 	Adjustment            string `json:"adjustment"`
+	Action                string `json:"action,omitempty"`
+	MemoryAdjustment      string `json:"memory_adjustment,omitempty"`
+	DiskAdjustment        string `json:"disk_adjustment,omitempty"`
+}
+
+// GetAction returns the action of the rule, which is scaling unless the rule
+// names another one.
+func (r *ScalingRule) GetAction() string {
+	if r.Action == "" {
+		return ActionScale
+	}
+	return r.Action
 }
 
 func (r *ScalingRule) StatWindow() time.Duration {
@@ -72,6 +119,17 @@ type Trigger struct {
 	Operator              string `json:"operator"`
 	CoolDownSeconds       int    `json:"cool_down_secs"`
 	Adjustment            string `json:"adjustment"`
+	Action                string `json:"action,omitempty"`
+	MemoryAdjustment      string `json:"memory_adjustment,omitempty"`
+	DiskAdjustment        string `json:"disk_adjustment,omitempty"`
+}
+
+// GetAction returns the action to take on the trigger, see ScalingRule.GetAction.
+func (t Trigger) GetAction() string {
+	if t.Action == "" {
+		return ActionScale
+	}
+	return t.Action
 }
 
 func (t Trigger) BreachDuration() time.Duration {
@@ -88,3 +146,41 @@ type ActiveSchedule struct {
 	InstanceMax        int `json:"instance_max_count"`
 	InstanceMinInitial int `json:"initial_min_instance_count"`
 }
+
+// AppGroup is a set of apps scaled together. A trigger on any member scales
+// every member so that their instances keep the ratios of the group.
+type AppGroup struct {
+	GroupId string            `json:"group_id"`
+	Members []*AppGroupMember `json:"members"`
+}
+
+// Member returns the member of the group with the given app id, or nil when
+// the app is not a member.
+func (g *AppGroup) Member(appId string) *AppGroupMember {
+	for _, m := range g.Members {
+		if m.AppId == appId {
+			return m
+		}
+	}
+	return nil
+}
+
+// AppGroupMember is an app of a group. The instances of the members are
+// proportional to their Ratio, and the instances of each member are kept within
+// its own InstanceMin and InstanceMax.
+type AppGroupMember struct {
+	AppId       string `json:"app_id"`
+	Ratio       int    `json:"ratio"`
+	InstanceMin int    `json:"instance_min_count"`
+	InstanceMax int    `json:"instance_max_count"`
+	ProcessType string `json:"process_type,omitempty"`
+}
+
+// GetProcessType returns the process type of the member to scale, which is the
+// web process unless another one is named.
+func (m *AppGroupMember) GetProcessType() string {
+	if m.ProcessType == "" {
+		return DefaultProcessType
+	}
+	return m.ProcessType
+}
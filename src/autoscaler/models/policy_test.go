@@ -118,6 +118,100 @@ var _ = Describe("Policy", func() {
 						}}}}))
 		})
 
+		Context("when the policy resizes the instances", func() {
+			BeforeEach(func() {
+				policyJson = &PolicyJson{AppId: testAppId, PolicyStr: `
+   {
+   "instance_min_count":1,
+   "instance_max_count":5,
+   "instance_memory_min_mb":256,
+   "instance_memory_max_mb":1024,
+   "scaling_rules":[
+      {
+         "metric_type":"MemoryUsage",
+         "stat_window_secs":300,
+         "breach_duration_secs":300,
+         "threshold":90,
+         "operator":">",
+         "cool_down_secs":300,
+         "action":"resize",
+         "memory_adjustment":"+256"
+      }
+   ]
+}`}
+				policy = policyJson.GetAppPolicy()
+			})
+
+			It("should return a policy with the memory bounds and adjustment", func() {
+				Expect(policy.ScalingPolicy.InstanceMemoryMin).To(Equal(256))
+				Expect(policy.ScalingPolicy.InstanceMemoryMax).To(Equal(1024))
+				Expect(policy.ScalingPolicy.InstanceDiskMax).To(BeZero())
+				Expect(policy.ScalingPolicy.ScalingRules[0].GetAction()).To(Equal(ActionResize))
+				Expect(policy.ScalingPolicy.ScalingRules[0].MemoryAdjustment).To(Equal("+256"))
+				Expect(policy.ScalingPolicy.ScalingRules[0].DiskAdjustment).To(BeEmpty())
+			})
+		})
+	})
+	Context("ScalingPolicy.GetProcessType", func() {
+		It("should return the web process type by default", func() {
+			Expect((&ScalingPolicy{}).GetProcessType()).To(Equal("web"))
+		})
+
+		It("should return the process type in the policy", func() {
+			Expect((&ScalingPolicy{ProcessType: "worker"}).GetProcessType()).To(Equal("worker"))
+		})
+	})
+
+	Context("ScalingRule.GetAction", func() {
+		It("should return the scale action by default", func() {
+			Expect((&ScalingRule{}).GetAction()).To(Equal(ActionScale))
+		})
+
+		It("should return the action in the rule", func() {
+			Expect((&ScalingRule{Action: ActionRestartInstance}).GetAction()).To(Equal("restart_instance"))
+		})
+	})
+
+	Context("Trigger.GetAction", func() {
+		It("should return the scale action by default", func() {
+			Expect(Trigger{}.GetAction()).To(Equal(ActionScale))
+		})
+
+		It("should return the action in the trigger", func() {
+			Expect(Trigger{Action: ActionRestartInstance}.GetAction()).To(Equal("restart_instance"))
+		})
+	})
+
+	Context("AppGroup.Member", func() {
+		var group *AppGroup
+
+		BeforeEach(func() {
+			group = &AppGroup{
+				GroupId: "a-group-id",
+				Members: []*AppGroupMember{
+					{AppId: "app-a", Ratio: 2, InstanceMin: 2, InstanceMax: 10},
+					{AppId: "app-b", Ratio: 1, InstanceMin: 1, InstanceMax: 5},
+				},
+			}
+		})
+
+		It("should return the member with the app id", func() {
+			Expect(group.Member("app-b")).To(Equal(&AppGroupMember{AppId: "app-b", Ratio: 1, InstanceMin: 1, InstanceMax: 5}))
+		})
+
+		It("should return nil when the app is not a member", func() {
+			Expect(group.Member("app-c")).To(BeNil())
+		})
+	})
+
+	Context("AppGroupMember.GetProcessType", func() {
+		It("should return the web process type by default", func() {
+			Expect((&AppGroupMember{}).GetProcessType()).To(Equal("web"))
+		})
+
+		It("should return the process type of the member", func() {
+			Expect((&AppGroupMember{ProcessType: "worker"}).GetProcessType()).To(Equal("worker"))
+		})
 	})
 
 })
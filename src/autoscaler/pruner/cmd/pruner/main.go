@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"os"
 
+	"autoscaler/db"
+	"autoscaler/db/leaderelection"
 	"autoscaler/db/sqldb"
+	"autoscaler/healthendpoint"
+	"autoscaler/metrics"
 	"autoscaler/pruner"
 	"autoscaler/pruner/config"
 
@@ -81,10 +85,54 @@ func main() {
 	scalingEngineDbPruner := pruner.NewScalingEngineDbPruner(scalingEngineDb, conf.ScalingEngineDb.CutoffDays, prClock, logger.Session(prunerLoggerSessionName))
 	scalingEngineDbPrunerRunner := pruner.NewDbPrunerRunner(scalingEngineDbPruner, conf.ScalingEngineDb.RefreshInterval, prClock, logger.Session(prunerLoggerSessionName))
 
+	var leaseDB db.LeaseDB
+	elect := func(name string, runner ifrit.Runner) ifrit.Runner {
+		return runner
+	}
+	if conf.LeaderElection.Enabled {
+		leaseDB, err = sqldb.NewLeaseSQLDB(conf.ScalingEngineDb.DbUrl, logger.Session("lease-db"))
+		if err != nil {
+			logger.Error("failed to connect lease db", err, lager.Data{"url": conf.ScalingEngineDb.DbUrl})
+			os.Exit(1)
+		}
+		defer leaseDB.Close()
+
+		ownerId := conf.LeaderElection.OwnerId
+		if ownerId == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				logger.Error("failed to get hostname as leader election owner id", err)
+				os.Exit(1)
+			}
+			ownerId = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+		}
+		elect = func(name string, runner ifrit.Runner) ifrit.Runner {
+			return leaderelection.NewRunner(logger.Session(name+"-leader-election"), leaseDB, "pruner-"+name, ownerId,
+				conf.LeaderElection.LeaseTTL, conf.LeaderElection.RetryInterval, prClock, runner)
+		}
+	}
+
 	members := grouper.Members{
-		{"instancemetrics-dbpruner", instanceMetricsDbPrunerRunner},
-		{"appmetrics-dbpruner", appMetricsDbPrunerRunner},
-		{"scalingEngine-dbpruner", scalingEngineDbPrunerRunner},
+		{"instancemetrics-dbpruner", elect("instancemetrics-dbpruner", instanceMetricsDbPrunerRunner)},
+		{"appmetrics-dbpruner", elect("appmetrics-dbpruner", appMetricsDbPrunerRunner)},
+		{"scalingEngine-dbpruner", elect("scalingengine-dbpruner", scalingEngineDbPrunerRunner)},
+	}
+
+	if conf.Health.Port != 0 {
+		readiness := healthendpoint.Checks{
+			"instance_metrics_db": healthendpoint.PingCheck(instanceMetricsDb),
+			"app_metrics_db":      healthendpoint.PingCheck(appMetricsDb),
+			"scaling_engine_db":   healthendpoint.PingCheck(scalingEngineDb),
+		}
+		if leaseDB != nil {
+			readiness["lease_db"] = healthendpoint.PingCheck(leaseDB)
+		}
+		healthServer, err := healthendpoint.NewServer(logger.Session("health-server"), conf.Health.Port, metrics.DefaultRegistry, nil, readiness)
+		if err != nil {
+			logger.Error("failed to create health server", err)
+			os.Exit(1)
+		}
+		members = append(members, grouper.Member{Name: "health_server", Runner: healthServer})
 	}
 
 	monitor := ifrit.Invoke(sigmon.New(grouper.NewOrdered(os.Interrupt, members)))
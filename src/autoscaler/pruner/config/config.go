@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v2"
+
+	"autoscaler/db/leaderelection"
 )
 
 const (
@@ -20,6 +22,10 @@ type LoggingConfig struct {
 	Level string `yaml:"level"`
 }
 
+type HealthConfig struct {
+	Port int `yaml:"port"`
+}
+
 type InstanceMetricsDbPrunerConfig struct {
 	DbUrl           string        `yaml:"db_url"`
 	RefreshInterval time.Duration `yaml:"refresh_interval"`
@@ -39,10 +45,12 @@ type ScalingEngineDbPrunerConfig struct {
 }
 
 type Config struct {
-	Logging           LoggingConfig                 `yaml:"logging"`
-	InstanceMetricsDb InstanceMetricsDbPrunerConfig `yaml:"instance_metrics_db"`
-	AppMetricsDb      AppMetricsDbPrunerConfig      `yaml:"app_metrics_db"`
-	ScalingEngineDb   ScalingEngineDbPrunerConfig   `yaml:"scaling_engine_db"`
+	Logging           LoggingConfig                       `yaml:"logging"`
+	InstanceMetricsDb InstanceMetricsDbPrunerConfig       `yaml:"instance_metrics_db"`
+	AppMetricsDb      AppMetricsDbPrunerConfig            `yaml:"app_metrics_db"`
+	ScalingEngineDb   ScalingEngineDbPrunerConfig         `yaml:"scaling_engine_db"`
+	Health            HealthConfig                        `yaml:"health"`
+	LeaderElection    leaderelection.LeaderElectionConfig `yaml:"leader_election"`
 }
 
 var defaultDbConfig = Config{
@@ -59,6 +67,7 @@ var defaultDbConfig = Config{
 		RefreshInterval: DefaultRefreshInterval,
 		CutoffDays:      DefaultCutoffDays,
 	},
+	LeaderElection: leaderelection.DefaultLeaderElectionConfig,
 }
 
 func LoadConfig(reader io.Reader) (*Config, error) {
@@ -117,6 +126,15 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("Configuration error: ScalingEngine DB cutoff days is negative")
 	}
 
+	if c.Health.Port < 0 || c.Health.Port > 65535 {
+		return fmt.Errorf("Configuration error: health port is less than 0 or more than 65535")
+	}
+
+	err := c.LeaderElection.Validate()
+	if err != nil {
+		return err
+	}
+
 	return nil
 
 }
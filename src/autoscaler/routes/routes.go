@@ -7,23 +7,50 @@ import (
 const (
 	memoryMetricPath          = "/v1/apps/{appid}/metrics/memory"
 	memoryMetricHistoriesPath = "/v1/apps/{appid}/metric_histories/memory"
+	observationPath           = "/v1/apps/{appid}/observation"
 
 	MemoryMetricRoute        = "memory-metric"
 	MemoryMetricHistoryRoute = "memory-metric-histories"
+	ObserveAppRoute          = "observe-app"
+	UnobserveAppRoute        = "unobserve-app"
+
+	anomalousInstancesPath = "/v1/apps/{appid}/anomalous_instances"
+
+	AnomalousInstancesRoute = "anomalous-instances"
 
 	scalePath            = "/v1/apps/{appid}/scale"
 	scalingHistoriesPath = "/v1/apps/{appid}/scaling_histories"
 	activeSchedulePath   = "/v1/apps/{appid}/active_schedules/{scheduleid}"
+	restartInstancePath  = "/v1/apps/{appid}/instances/{index}/restart"
+	resizePath           = "/v1/apps/{appid}/resize"
+	appGroupPath         = "/v1/app_groups/{groupid}"
+	groupHistoriesPath   = "/v1/app_groups/{groupid}/scaling_histories"
 
 	ScaleRoute                 = "scale"
 	HistoreisRoute             = "histories"
 	UpdateActiveSchedulesRoute = "updateActiveSchedules"
 	DeleteActiveSchedulesRoute = "deleteActiveSchedules"
+	RestartInstanceRoute       = "restartInstance"
+	ResizeRoute                = "resize"
+	GetAppGroupRoute           = "getAppGroup"
+	SetAppGroupRoute           = "setAppGroup"
+	DeleteAppGroupRoute        = "deleteAppGroup"
+	GroupHistoriesRoute        = "groupHistories"
+
+	metricsPath = "/metrics"
+	healthPath  = "/health"
+	readyPath   = "/ready"
+
+	MetricsRoute = "metrics"
+	HealthRoute  = "health"
+	ReadyRoute   = "ready"
 )
 
 type AutoScalerRoute struct {
 	metricsCollectorRoutes *mux.Router
 	scalingEngineRoutes    *mux.Router
+	eventGeneratorRoutes   *mux.Router
+	healthRoutes           *mux.Router
 }
 
 var autoScalerRouteInstance *AutoScalerRoute = newRouters()
@@ -32,15 +59,31 @@ func newRouters() *AutoScalerRoute {
 	instance := &AutoScalerRoute{
 		metricsCollectorRoutes: mux.NewRouter(),
 		scalingEngineRoutes:    mux.NewRouter(),
+		eventGeneratorRoutes:   mux.NewRouter(),
+		healthRoutes:           mux.NewRouter(),
 	}
 
 	instance.metricsCollectorRoutes.Path(memoryMetricPath).Name(MemoryMetricRoute)
 	instance.metricsCollectorRoutes.Path(memoryMetricHistoriesPath).Name(MemoryMetricHistoryRoute)
+	instance.metricsCollectorRoutes.Path(observationPath).Name(ObserveAppRoute)
+	instance.metricsCollectorRoutes.Path(observationPath).Name(UnobserveAppRoute)
 
 	instance.scalingEngineRoutes.Path(scalePath).Name(ScaleRoute)
 	instance.scalingEngineRoutes.Path(scalingHistoriesPath).Name(HistoreisRoute)
 	instance.scalingEngineRoutes.Path(activeSchedulePath).Name(UpdateActiveSchedulesRoute)
 	instance.scalingEngineRoutes.Path(activeSchedulePath).Name(DeleteActiveSchedulesRoute)
+	instance.scalingEngineRoutes.Path(restartInstancePath).Name(RestartInstanceRoute)
+	instance.scalingEngineRoutes.Path(resizePath).Name(ResizeRoute)
+	instance.scalingEngineRoutes.Path(appGroupPath).Name(GetAppGroupRoute)
+	instance.scalingEngineRoutes.Path(appGroupPath).Name(SetAppGroupRoute)
+	instance.scalingEngineRoutes.Path(appGroupPath).Name(DeleteAppGroupRoute)
+	instance.scalingEngineRoutes.Path(groupHistoriesPath).Name(GroupHistoriesRoute)
+
+	instance.eventGeneratorRoutes.Path(anomalousInstancesPath).Name(AnomalousInstancesRoute)
+
+	instance.healthRoutes.Path(metricsPath).Name(MetricsRoute)
+	instance.healthRoutes.Path(healthPath).Name(HealthRoute)
+	instance.healthRoutes.Path(readyPath).Name(ReadyRoute)
 
 	return instance
 
@@ -51,3 +94,11 @@ func MetricsCollectorRoutes() *mux.Router {
 func ScalingEngineRoutes() *mux.Router {
 	return autoScalerRouteInstance.scalingEngineRoutes
 }
+
+func EventGeneratorRoutes() *mux.Router {
+	return autoScalerRouteInstance.eventGeneratorRoutes
+}
+
+func HealthRoutes() *mux.Router {
+	return autoScalerRouteInstance.healthRoutes
+}
@@ -65,6 +65,34 @@ var _ = Describe("Routes", func() {
 				})
 			})
 		})
+
+		Context("ObserveAppRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.MetricsCollectorRoutes().Get(routes.ObserveAppRoute).URLPath("appid", testAppId)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/apps/testAppId/observation"))
+				})
+			})
+
+			Context("when provide wrong route variable", func() {
+				It("should return error", func() {
+					_, err := routes.MetricsCollectorRoutes().Get(routes.ObserveAppRoute).URLPath("wrongVariable", testAppId)
+					Expect(err).To(HaveOccurred())
+
+				})
+			})
+		})
+
+		Context("UnobserveAppRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.MetricsCollectorRoutes().Get(routes.UnobserveAppRoute).URLPath("appid", testAppId)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/apps/testAppId/observation"))
+				})
+			})
+		})
 	})
 
 	Describe("ScalingEngineRoutes", func() {
@@ -171,5 +199,151 @@ var _ = Describe("Routes", func() {
 				})
 			})
 		})
+
+		Context("RestartInstanceRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.RestartInstanceRoute).URLPath("appid", testAppId, "index", "2")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/apps/testAppId/instances/2/restart"))
+				})
+			})
+
+			Context("when provide not enough route variable", func() {
+				It("should return error", func() {
+					_, err := routes.ScalingEngineRoutes().Get(routes.RestartInstanceRoute).URLPath("appid", testAppId)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("ResizeRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.ResizeRoute).URLPath("appid", testAppId)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/apps/testAppId/resize"))
+				})
+			})
+
+			Context("when provide wrong route variable", func() {
+				It("should return error", func() {
+					_, err := routes.ScalingEngineRoutes().Get(routes.ResizeRoute).URLPath("wrongVariable", testAppId)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("GetAppGroupRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.GetAppGroupRoute).URLPath("groupid", "testGroupId")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/app_groups/testGroupId"))
+				})
+			})
+
+			Context("when provide wrong route variable", func() {
+				It("should return error", func() {
+					_, err := routes.ScalingEngineRoutes().Get(routes.GetAppGroupRoute).URLPath("wrongVariable", "testGroupId")
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("SetAppGroupRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.SetAppGroupRoute).URLPath("groupid", "testGroupId")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/app_groups/testGroupId"))
+				})
+			})
+
+			Context("when provide wrong route variable", func() {
+				It("should return error", func() {
+					_, err := routes.ScalingEngineRoutes().Get(routes.SetAppGroupRoute).URLPath("wrongVariable", "testGroupId")
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("DeleteAppGroupRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.DeleteAppGroupRoute).URLPath("groupid", "testGroupId")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/app_groups/testGroupId"))
+				})
+			})
+
+			Context("when provide wrong route variable", func() {
+				It("should return error", func() {
+					_, err := routes.ScalingEngineRoutes().Get(routes.DeleteAppGroupRoute).URLPath("wrongVariable", "testGroupId")
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("GroupHistoriesRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.ScalingEngineRoutes().Get(routes.GroupHistoriesRoute).URLPath("groupid", "testGroupId")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/app_groups/testGroupId/scaling_histories"))
+				})
+			})
+
+			Context("when provide wrong route variable", func() {
+				It("should return error", func() {
+					_, err := routes.ScalingEngineRoutes().Get(routes.GroupHistoriesRoute).URLPath("wrongVariable", "testGroupId")
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+	})
+
+	Describe("EventGeneratorRoutes", func() {
+		Context("AnomalousInstancesRoute", func() {
+			Context("when provide correct route variable", func() {
+				It("should return the correct path", func() {
+					path, err := routes.EventGeneratorRoutes().Get(routes.AnomalousInstancesRoute).URLPath("appid", testAppId)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path.Path).To(Equal("/v1/apps/testAppId/anomalous_instances"))
+				})
+			})
+
+			Context("when provide wrong route variable", func() {
+				It("should return error", func() {
+					_, err := routes.EventGeneratorRoutes().Get(routes.AnomalousInstancesRoute).URLPath("wrongVariable", testAppId)
+					Expect(err).To(HaveOccurred())
+
+				})
+			})
+		})
+	})
+
+	Describe("HealthRoutes", func() {
+		Context("MetricsRoute", func() {
+			It("should return the correct path", func() {
+				path, err := routes.HealthRoutes().Get(routes.MetricsRoute).URLPath()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path.Path).To(Equal("/metrics"))
+			})
+		})
+		Context("HealthRoute", func() {
+			It("should return the correct path", func() {
+				path, err := routes.HealthRoutes().Get(routes.HealthRoute).URLPath()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path.Path).To(Equal("/health"))
+			})
+		})
+		Context("ReadyRoute", func() {
+			It("should return the correct path", func() {
+				path, err := routes.HealthRoutes().Get(routes.ReadyRoute).URLPath()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path.Path).To(Equal("/ready"))
+			})
+		})
 	})
 })
@@ -0,0 +1,189 @@
+package scalingengine
+
+import (
+	"autoscaler/db"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+
+	"fmt"
+	"sync"
+	"time"
+)
+
+const appLockPrefix = "scalingengine-app-"
+
+// groupLockPrefix is prepended to the id of an app group to lock the group as a
+// whole with the AppLock, ahead of the locks of its member apps.
+const groupLockPrefix = "group-"
+
+type AppLockTimeoutError struct {
+	AppId   string
+	Timeout time.Duration
+}
+
+func (e *AppLockTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for the lock of app %s", e.Timeout, e.AppId)
+}
+
+// AppLock serializes the scaling of an app across scaling engine instances.
+// Within the process a striped lock is taken first, then a lease named after
+// the app is acquired in the database so that other instances wait for it.
+// The lease expires after ttl in case its holder dies without releasing it, so
+// it is renewed every third of the ttl while it is held.
+type AppLock struct {
+	logger        lager.Logger
+	leaseDB       db.LeaseDB
+	owner         string
+	ttl           time.Duration
+	timeout       time.Duration
+	retryInterval time.Duration
+	clock         clock.Clock
+	localLock     *StripedLock
+	renewals      map[string]*renewal
+	renewalsLock  sync.Mutex
+}
+
+func NewAppLock(logger lager.Logger, leaseDB db.LeaseDB, owner string, ttl time.Duration, timeout time.Duration,
+	retryInterval time.Duration, clock clock.Clock) *AppLock {
+	return &AppLock{
+		logger:        logger.Session("app-lock"),
+		leaseDB:       leaseDB,
+		owner:         owner,
+		ttl:           ttl,
+		timeout:       timeout,
+		retryInterval: retryInterval,
+		clock:         clock,
+		localLock:     NewStripedLock(32),
+		renewals:      map[string]*renewal{},
+	}
+}
+
+// Lock blocks until the lock of the app is held, retrying every retryInterval.
+// It returns an *AppLockTimeoutError when the lock is still held by another
+// instance after timeout.
+func (l *AppLock) Lock(appId string) error {
+	l.localLock.GetLock(appId).Lock()
+
+	err := l.acquireLease(appId)
+	if err != nil {
+		l.localLock.GetLock(appId).Unlock()
+	}
+	return err
+}
+
+func (l *AppLock) Unlock(appId string) {
+	l.releaseLease(appId)
+	l.localLock.GetLock(appId).Unlock()
+}
+
+// LockAll locks the apps together like Lock. Within the process the striped
+// locks of the apps are taken in the order of the stripes, so that callers
+// locking overlapping apps cannot deadlock, then the leases are acquired in the
+// order of appIds. On error none of the apps is left locked.
+func (l *AppLock) LockAll(appIds []string) error {
+	locks := l.localLock.GetLocks(appIds)
+	for _, lock := range locks {
+		lock.Lock()
+	}
+
+	for i, appId := range appIds {
+		err := l.acquireLease(appId)
+		if err != nil {
+			for j := i - 1; j >= 0; j-- {
+				l.releaseLease(appIds[j])
+			}
+			for _, lock := range locks {
+				lock.Unlock()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// UnlockAll unlocks the apps locked by LockAll.
+func (l *AppLock) UnlockAll(appIds []string) {
+	for i := len(appIds) - 1; i >= 0; i-- {
+		l.releaseLease(appIds[i])
+	}
+	for _, lock := range l.localLock.GetLocks(appIds) {
+		lock.Unlock()
+	}
+}
+
+func (l *AppLock) acquireLease(appId string) error {
+	deadline := l.clock.Now().Add(l.timeout)
+	for {
+		now := l.clock.Now()
+		acquired, err := l.leaseDB.AcquireLease(appLockPrefix+appId, l.owner, l.ttl)
+		if err != nil {
+			l.logger.Error("failed-to-acquire-lease", err, lager.Data{"appId": appId})
+			return err
+		}
+		if acquired {
+			l.startRenewal(appId)
+			return nil
+		}
+
+		if !now.Before(deadline) {
+			err = &AppLockTimeoutError{AppId: appId, Timeout: l.timeout}
+			l.logger.Error("failed-to-acquire-lease", err, lager.Data{"appId": appId})
+			return err
+		}
+		l.logger.Debug("waiting-for-lease", lager.Data{"appId": appId})
+		l.clock.Sleep(l.retryInterval)
+	}
+}
+
+func (l *AppLock) releaseLease(appId string) {
+	l.stopRenewal(appId)
+	err := l.leaseDB.ReleaseLease(appLockPrefix+appId, l.owner)
+	if err != nil {
+		l.logger.Error("failed-to-release-lease", err, lager.Data{"appId": appId})
+	}
+}
+
+type renewal struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (l *AppLock) startRenewal(appId string) {
+	r := &renewal{stop: make(chan struct{}), done: make(chan struct{})}
+	l.renewalsLock.Lock()
+	l.renewals[appId] = r
+	l.renewalsLock.Unlock()
+
+	ticker := l.clock.NewTicker(l.ttl / 3)
+	go func() {
+		defer close(r.done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C():
+				acquired, err := l.leaseDB.AcquireLease(appLockPrefix+appId, l.owner, l.ttl)
+				if err != nil {
+					l.logger.Error("failed-to-renew-lease", err, lager.Data{"appId": appId})
+				} else if !acquired {
+					l.logger.Error("failed-to-renew-lease", fmt.Errorf("lease of app %s is held by another owner", appId), lager.Data{"appId": appId})
+				}
+			}
+		}
+	}()
+}
+
+// stopRenewal returns once no renewal of the lease is in flight, so that the
+// lease is not acquired again after it is released.
+func (l *AppLock) stopRenewal(appId string) {
+	l.renewalsLock.Lock()
+	r, ok := l.renewals[appId]
+	delete(l.renewals, appId)
+	l.renewalsLock.Unlock()
+	if ok {
+		close(r.stop)
+		<-r.done
+	}
+}
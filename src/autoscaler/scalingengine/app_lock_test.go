@@ -0,0 +1,269 @@
+package scalingengine_test
+
+import (
+	. "autoscaler/scalingengine"
+	"autoscaler/scalingengine/fakes"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"errors"
+	"sync"
+	"time"
+)
+
+var _ = Describe("AppLock", func() {
+	var (
+		leaseDB *fakes.FakeLeaseDB
+		fclock  *fakeclock.FakeClock
+		buffer  *gbytes.Buffer
+		appLock *AppLock
+	)
+
+	// The lease is switched while the code under test acquires it on another
+	// goroutine, so the stub reads it under a lock.
+	var (
+		leaseLock     sync.Mutex
+		leaseAcquired bool
+		leaseErr      error
+	)
+	setLease := func(acquired bool, err error) {
+		leaseLock.Lock()
+		defer leaseLock.Unlock()
+		leaseAcquired, leaseErr = acquired, err
+	}
+
+	BeforeEach(func() {
+		leaseDB = &fakes.FakeLeaseDB{}
+		setLease(false, nil)
+		leaseDB.AcquireLeaseStub = func(name string, owner string, ttl time.Duration) (bool, error) {
+			leaseLock.Lock()
+			defer leaseLock.Unlock()
+			return leaseAcquired, leaseErr
+		}
+		fclock = fakeclock.NewFakeClock(time.Now())
+		logger := lagertest.NewTestLogger("app-lock-test")
+		buffer = logger.Buffer()
+		appLock = NewAppLock(logger, leaseDB, "an-owner", time.Minute, 10*time.Second, 5*time.Second, fclock)
+	})
+
+	Describe("Lock", func() {
+		Context("when the lease is free", func() {
+			BeforeEach(func() {
+				setLease(true, nil)
+			})
+
+			It("acquires the lease of the app with the ttl", func() {
+				Expect(appLock.Lock("an-app-id")).To(Succeed())
+
+				Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(1))
+				name, owner, ttl := leaseDB.AcquireLeaseArgsForCall(0)
+				Expect(name).To(Equal("scalingengine-app-an-app-id"))
+				Expect(owner).To(Equal("an-owner"))
+				Expect(ttl).To(Equal(time.Minute))
+			})
+
+			It("blocks other callers in the process until unlocked", func() {
+				Expect(appLock.Lock("an-app-id")).To(Succeed())
+
+				locked := make(chan error)
+				go func() {
+					locked <- appLock.Lock("an-app-id")
+				}()
+				Consistently(locked).ShouldNot(Receive())
+
+				appLock.Unlock("an-app-id")
+				Eventually(locked).Should(Receive(BeNil()))
+				Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when the lease is held by another owner", func() {
+			var locked chan error
+
+			BeforeEach(func() {
+				setLease(false, nil)
+				locked = make(chan error)
+			})
+
+			JustBeforeEach(func() {
+				go func() {
+					locked <- appLock.Lock("an-app-id")
+				}()
+			})
+
+			It("retries until the lease is acquired", func() {
+				Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(1))
+				setLease(true, nil)
+
+				fclock.WaitForWatcherAndIncrement(5 * time.Second)
+				Eventually(locked).Should(Receive(BeNil()))
+				Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(2))
+			})
+
+			It("times out", func() {
+				fclock.WaitForWatcherAndIncrement(5 * time.Second)
+				fclock.WaitForWatcherAndIncrement(5 * time.Second)
+
+				var err error
+				Eventually(locked).Should(Receive(&err))
+				Expect(err).To(Equal(&AppLockTimeoutError{AppId: "an-app-id", Timeout: 10 * time.Second}))
+				Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(3))
+				Eventually(buffer).Should(gbytes.Say("failed-to-acquire-lease"))
+
+				setLease(true, nil)
+				Expect(appLock.Lock("an-app-id")).To(Succeed())
+			})
+		})
+
+		Context("when acquiring the lease fails", func() {
+			BeforeEach(func() {
+				setLease(false, errors.New("an error"))
+			})
+
+			It("returns the error and does not keep the app locked", func() {
+				Expect(appLock.Lock("an-app-id")).To(MatchError("an error"))
+				Eventually(buffer).Should(gbytes.Say("failed-to-acquire-lease"))
+
+				setLease(true, nil)
+				Expect(appLock.Lock("an-app-id")).To(Succeed())
+			})
+		})
+	})
+
+	Describe("LockAll", func() {
+		var appIds []string
+
+		BeforeEach(func() {
+			appIds = []string{"group-a-group-id", "an-app-id", "another-app-id"}
+			setLease(true, nil)
+		})
+
+		It("acquires the leases of the apps in order", func() {
+			Expect(appLock.LockAll(appIds)).To(Succeed())
+
+			Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(3))
+			for i, appId := range appIds {
+				name, _, _ := leaseDB.AcquireLeaseArgsForCall(i)
+				Expect(name).To(Equal("scalingengine-app-" + appId))
+			}
+		})
+
+		It("blocks other callers locking one of the apps until unlocked", func() {
+			Expect(appLock.LockAll(appIds)).To(Succeed())
+
+			locked := make(chan error)
+			go func() {
+				locked <- appLock.Lock("another-app-id")
+			}()
+			Consistently(locked).ShouldNot(Receive())
+
+			appLock.UnlockAll(appIds)
+			Eventually(locked).Should(Receive(BeNil()))
+			Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(3))
+			name, _ := leaseDB.ReleaseLeaseArgsForCall(0)
+			Expect(name).To(Equal("scalingengine-app-another-app-id"))
+		})
+
+		Context("when acquiring the lease of an app fails", func() {
+			var acquireLease func(name string, owner string, ttl time.Duration) (bool, error)
+
+			BeforeEach(func() {
+				acquireLease = leaseDB.AcquireLeaseStub
+				leaseDB.AcquireLeaseStub = func(name string, owner string, ttl time.Duration) (bool, error) {
+					if name == "scalingengine-app-another-app-id" {
+						return false, errors.New("an error")
+					}
+					return acquireLease(name, owner, ttl)
+				}
+			})
+
+			It("releases the leases acquired and does not keep any app locked", func() {
+				Expect(appLock.LockAll(appIds)).To(MatchError("an error"))
+				Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(2))
+
+				leaseDB.AcquireLeaseStub = acquireLease
+				Expect(appLock.LockAll(appIds)).To(Succeed())
+			})
+		})
+	})
+
+	Describe("renewing the lease", func() {
+		BeforeEach(func() {
+			setLease(true, nil)
+			Expect(appLock.Lock("an-app-id")).To(Succeed())
+		})
+
+		It("renews the lease every third of the ttl while the lock is held", func() {
+			fclock.WaitForWatcherAndIncrement(20 * time.Second)
+			Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(2))
+			name, owner, ttl := leaseDB.AcquireLeaseArgsForCall(1)
+			Expect(name).To(Equal("scalingengine-app-an-app-id"))
+			Expect(owner).To(Equal("an-owner"))
+			Expect(ttl).To(Equal(time.Minute))
+
+			fclock.Increment(20 * time.Second)
+			Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(3))
+		})
+
+		It("stops renewing the lease once unlocked", func() {
+			appLock.Unlock("an-app-id")
+			fclock.Increment(20 * time.Second)
+			Consistently(leaseDB.AcquireLeaseCallCount).Should(Equal(1))
+		})
+
+		Context("when another owner has taken the lease", func() {
+			BeforeEach(func() {
+				setLease(false, nil)
+			})
+
+			It("logs the error", func() {
+				fclock.WaitForWatcherAndIncrement(20 * time.Second)
+				Eventually(buffer).Should(gbytes.Say("failed-to-renew-lease"))
+			})
+		})
+
+		Context("when renewing the lease fails", func() {
+			BeforeEach(func() {
+				setLease(false, errors.New("an error"))
+			})
+
+			It("logs the error and keeps renewing", func() {
+				fclock.WaitForWatcherAndIncrement(20 * time.Second)
+				Eventually(buffer).Should(gbytes.Say("failed-to-renew-lease"))
+				fclock.Increment(20 * time.Second)
+				Eventually(leaseDB.AcquireLeaseCallCount).Should(Equal(3))
+			})
+		})
+	})
+
+	Describe("Unlock", func() {
+		BeforeEach(func() {
+			setLease(true, nil)
+			Expect(appLock.Lock("an-app-id")).To(Succeed())
+		})
+
+		It("releases the lease of the app", func() {
+			appLock.Unlock("an-app-id")
+			Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(1))
+			name, owner := leaseDB.ReleaseLeaseArgsForCall(0)
+			Expect(name).To(Equal("scalingengine-app-an-app-id"))
+			Expect(owner).To(Equal("an-owner"))
+		})
+
+		Context("when releasing the lease fails", func() {
+			BeforeEach(func() {
+				leaseDB.ReleaseLeaseReturns(errors.New("an error"))
+			})
+
+			It("logs the error and unlocks the app in the process", func() {
+				appLock.Unlock("an-app-id")
+				Eventually(buffer).Should(gbytes.Say("failed-to-release-lease"))
+				Expect(appLock.Lock("an-app-id")).To(Succeed())
+			})
+		})
+	})
+})
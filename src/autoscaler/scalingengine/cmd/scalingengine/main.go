@@ -8,9 +8,13 @@ import (
 
 	"autoscaler/cf"
 	"autoscaler/db"
+	"autoscaler/db/leaderelection"
 	"autoscaler/db/sqldb"
+	"autoscaler/healthendpoint"
+	"autoscaler/metrics"
 	"autoscaler/scalingengine"
 	"autoscaler/scalingengine/config"
+	"autoscaler/scalingengine/orphan"
 	"autoscaler/scalingengine/schedule"
 	"autoscaler/scalingengine/server"
 
@@ -56,7 +60,11 @@ func main() {
 	logger := initLoggerFromConfig(&conf.Logging)
 	eClock := clock.NewClock()
 
-	cfClient := cf.NewCfClient(&conf.Cf, logger.Session("cf"), eClock)
+	cfClient, err := cf.NewCfClient(&conf.Cf, logger.Session("cf"), eClock)
+	if err != nil {
+		logger.Error("failed to create cloud foundry client", err, lager.Data{"Api": conf.Cf.Api})
+		os.Exit(1)
+	}
 	err = cfClient.Login()
 	if err != nil {
 		logger.Error("failed to login cloud foundry", err, lager.Data{"Api": conf.Cf.Api})
@@ -87,8 +95,29 @@ func main() {
 	}
 	defer schedulerDB.Close()
 
-	scalingEngine := scalingengine.NewScalingEngine(logger, cfClient, policyDB, scalingEngineDB, eClock)
-	httpServer, err := server.NewServer(logger.Session("http-server"), conf, scalingEngineDB, scalingEngine)
+	var appLockDB db.LeaseDB
+	appLockDB, err = sqldb.NewLeaseSQLDB(conf.Db.ScalingEngineDbUrl, logger.Session("app-lock-db"))
+	if err != nil {
+		logger.Error("failed to connect app lock database", err, lager.Data{"url": conf.Db.ScalingEngineDbUrl})
+		os.Exit(1)
+	}
+	defer appLockDB.Close()
+
+	appLockOwner := conf.AppLock.OwnerId
+	if appLockOwner == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Error("failed to get hostname as app lock owner id", err)
+			os.Exit(1)
+		}
+		appLockOwner = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	appLock := scalingengine.NewAppLock(logger, appLockDB, appLockOwner, conf.AppLock.TTL, conf.AppLock.Timeout,
+		conf.AppLock.RetryInterval, eClock)
+
+	scalingEngine := scalingengine.NewScalingEngine(logger, cfClient, policyDB, scalingEngineDB, appLock,
+		conf.Scaling.MaxCrashedInstancesRatio, conf.Scaling.InstanceRestartTime, eClock)
+	httpServer, err := server.NewServer(logger.Session("http-server"), conf, policyDB, scalingEngineDB, scalingEngine)
 	if err != nil {
 		logger.Error("failed to create http server", err)
 		os.Exit(1)
@@ -96,9 +125,75 @@ func main() {
 
 	synchronizer := schedule.NewActiveScheduleSychronizer(logger.Session("synchronizer"), schedulerDB, scalingEngineDB, scalingEngine,
 		conf.Synchronizer.ActiveScheduleSyncInterval, eClock)
+	var synchronizerRunner ifrit.Runner = synchronizer
+	synchronizerCheck := healthendpoint.TickCheck(synchronizer.LastTickTime, 3*conf.Synchronizer.ActiveScheduleSyncInterval, eClock)
+
+	reconciler := orphan.NewReconciler(logger.Session("orphan-reconciler"), cfClient, policyDB, scalingEngineDB,
+		conf.OrphanReconciler.Interval, conf.OrphanReconciler.GracePeriod, eClock)
+	var reconcilerRunner ifrit.Runner = reconciler
+
+	var leaseDB db.LeaseDB
+	if conf.LeaderElection.Enabled {
+		leaseDB, err = sqldb.NewLeaseSQLDB(conf.Db.ScalingEngineDbUrl, logger.Session("lease-db"))
+		if err != nil {
+			logger.Error("failed to connect lease database", err, lager.Data{"url": conf.Db.ScalingEngineDbUrl})
+			os.Exit(1)
+		}
+		defer leaseDB.Close()
+
+		ownerId := conf.LeaderElection.OwnerId
+		if ownerId == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				logger.Error("failed to get hostname as leader election owner id", err)
+				os.Exit(1)
+			}
+			ownerId = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+		}
+		electedSynchronizer := leaderelection.NewRunner(logger.Session("synchronizer-leader-election"), leaseDB,
+			"scalingengine-active-schedule-synchronizer", ownerId, conf.LeaderElection.LeaseTTL, conf.LeaderElection.RetryInterval,
+			eClock, synchronizer)
+		synchronizerRunner = electedSynchronizer
+
+		tickCheck := synchronizerCheck
+		synchronizerCheck = func() error {
+			if !electedSynchronizer.IsLeader() {
+				return nil
+			}
+			return tickCheck()
+		}
+
+		reconcilerRunner = leaderelection.NewRunner(logger.Session("orphan-reconciler-leader-election"), leaseDB,
+			"scalingengine-orphan-reconciler", ownerId, conf.LeaderElection.LeaseTTL, conf.LeaderElection.RetryInterval,
+			eClock, reconciler)
+	}
+
 	members := grouper.Members{
 		{"http_server", httpServer},
-		{"schedule_synchronizer", synchronizer},
+		{"schedule_synchronizer", synchronizerRunner},
+		{"orphan_reconciler", reconcilerRunner},
+	}
+
+	if conf.Health.Port != 0 {
+		liveness := healthendpoint.Checks{
+			"schedule_synchronizer": synchronizerCheck,
+		}
+		readiness := healthendpoint.Checks{
+			"policy_db":         healthendpoint.PingCheck(policyDB),
+			"scaling_engine_db": healthendpoint.PingCheck(scalingEngineDB),
+			"scheduler_db":      healthendpoint.PingCheck(schedulerDB),
+			"app_lock_db":       healthendpoint.PingCheck(appLockDB),
+			"cf_token":          healthendpoint.CfTokenCheck(cfClient),
+		}
+		if leaseDB != nil {
+			readiness["lease_db"] = healthendpoint.PingCheck(leaseDB)
+		}
+		healthServer, err := healthendpoint.NewServer(logger.Session("health-server"), conf.Health.Port, metrics.DefaultRegistry, liveness, readiness)
+		if err != nil {
+			logger.Error("failed to create health server", err)
+			os.Exit(1)
+		}
+		members = append(members, grouper.Member{Name: "health_server", Runner: healthServer})
 	}
 
 	monitor := ifrit.Invoke(sigmon.New(grouper.NewOrdered(os.Interrupt, members)))
@@ -84,6 +84,12 @@ var _ = SynchronizedBeforeSuite(
 		conf.Db.ScalingEngineDbUrl = os.Getenv("DBURL")
 		conf.Db.SchedulerDbUrl = os.Getenv("DBURL")
 		conf.Synchronizer.ActiveScheduleSyncInterval = 10 * time.Minute
+		conf.AppLock.TTL = config.DefaultAppLockTTL
+		conf.AppLock.Timeout = config.DefaultAppLockTimeout
+		conf.AppLock.RetryInterval = config.DefaultAppLockRetryInterval
+		conf.OrphanReconciler.Interval = config.DefaultOrphanReconcileInterval
+		conf.OrphanReconciler.GracePeriod = config.DefaultOrphanGracePeriod
+		conf.Scaling.MaxCrashedInstancesRatio = config.DefaultMaxCrashedInstancesRatio
 
 		configFile = writeConfig(&conf)
 
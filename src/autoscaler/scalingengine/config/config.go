@@ -10,13 +10,25 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"autoscaler/cf"
+	"autoscaler/db/leaderelection"
 	"autoscaler/models"
 )
 
-const DefaultActiveScheduleSyncInterval time.Duration = 10 * time.Minute
+const (
+	DefaultActiveScheduleSyncInterval time.Duration = 10 * time.Minute
+	DefaultAppLockTTL                 time.Duration = 2 * time.Minute
+	DefaultAppLockTimeout             time.Duration = 30 * time.Second
+	DefaultAppLockRetryInterval       time.Duration = 1 * time.Second
+	DefaultOrphanReconcileInterval    time.Duration = 1 * time.Hour
+	DefaultOrphanGracePeriod          time.Duration = 24 * time.Hour
+	DefaultMaxCrashedInstancesRatio   float64       = 0.5
+	DefaultInstanceRestartTime        time.Duration = 1 * time.Minute
+)
 
 var defaultCfConfig = cf.CfConfig{
-	GrantType: cf.GrantTypePassword,
+	GrantType:      cf.GrantTypePassword,
+	Retry:          cf.DefaultRetryConfig,
+	CircuitBreaker: cf.DefaultCircuitBreakerConfig,
 }
 
 type ServerConfig struct {
@@ -36,6 +48,10 @@ var defaultLoggingConfig = LoggingConfig{
 	Level: "info",
 }
 
+type HealthConfig struct {
+	Port int `yaml:"port"`
+}
+
 type DbConfig struct {
 	PolicyDbUrl        string `yaml:"policy_db_url"`
 	ScalingEngineDbUrl string `yaml:"scalingengine_db_url"`
@@ -50,20 +66,64 @@ var defaultSynchronizerConfig = SynchronizerConfig{
 	ActiveScheduleSyncInterval: DefaultActiveScheduleSyncInterval,
 }
 
+type AppLockConfig struct {
+	OwnerId       string        `yaml:"owner_id"`
+	TTL           time.Duration `yaml:"ttl"`
+	Timeout       time.Duration `yaml:"timeout"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+var defaultAppLockConfig = AppLockConfig{
+	TTL:           DefaultAppLockTTL,
+	Timeout:       DefaultAppLockTimeout,
+	RetryInterval: DefaultAppLockRetryInterval,
+}
+
+type OrphanReconcilerConfig struct {
+	Interval    time.Duration `yaml:"interval"`
+	GracePeriod time.Duration `yaml:"grace_period"`
+}
+
+var defaultOrphanReconcilerConfig = OrphanReconcilerConfig{
+	Interval:    DefaultOrphanReconcileInterval,
+	GracePeriod: DefaultOrphanGracePeriod,
+}
+
+type ScalingConfig struct {
+	MaxCrashedInstancesRatio float64 `yaml:"max_crashed_instances_ratio"`
+	// InstanceRestartTime is how long an instance is expected to take to
+	// restart, the cooldown of a resize is extended by it for each instance.
+	InstanceRestartTime time.Duration `yaml:"instance_restart_time"`
+}
+
+var defaultScalingConfig = ScalingConfig{
+	MaxCrashedInstancesRatio: DefaultMaxCrashedInstancesRatio,
+	InstanceRestartTime:      DefaultInstanceRestartTime,
+}
+
 type Config struct {
-	Cf           cf.CfConfig        `yaml:"cf"`
-	Logging      LoggingConfig      `yaml:"logging"`
-	Server       ServerConfig       `yaml:"server"`
-	Db           DbConfig           `yaml:"db"`
-	Synchronizer SynchronizerConfig `yaml:"synchronizer"`
+	Cf               cf.CfConfig                         `yaml:"cf"`
+	Logging          LoggingConfig                       `yaml:"logging"`
+	Server           ServerConfig                        `yaml:"server"`
+	Db               DbConfig                            `yaml:"db"`
+	Synchronizer     SynchronizerConfig                  `yaml:"synchronizer"`
+	Health           HealthConfig                        `yaml:"health"`
+	LeaderElection   leaderelection.LeaderElectionConfig `yaml:"leader_election"`
+	AppLock          AppLockConfig                       `yaml:"app_lock"`
+	OrphanReconciler OrphanReconcilerConfig              `yaml:"orphan_reconciler"`
+	Scaling          ScalingConfig                       `yaml:"scaling"`
 }
 
 func LoadConfig(reader io.Reader) (*Config, error) {
 	conf := &Config{
-		Cf:           defaultCfConfig,
-		Logging:      defaultLoggingConfig,
-		Server:       defaultServerConfig,
-		Synchronizer: defaultSynchronizerConfig,
+		Cf:               defaultCfConfig,
+		Logging:          defaultLoggingConfig,
+		Server:           defaultServerConfig,
+		Synchronizer:     defaultSynchronizerConfig,
+		LeaderElection:   leaderelection.DefaultLeaderElectionConfig,
+		AppLock:          defaultAppLockConfig,
+		OrphanReconciler: defaultOrphanReconcilerConfig,
+		Scaling:          defaultScalingConfig,
 	}
 
 	bytes, err := ioutil.ReadAll(reader)
@@ -100,6 +160,43 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("Configuration error: Scheduler DB url is empty")
 	}
 
+	if c.Health.Port < 0 || c.Health.Port > 65535 {
+		return fmt.Errorf("Configuration error: health port is less than 0 or more than 65535")
+	}
+
+	err = c.LeaderElection.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.AppLock.RetryInterval <= 0 {
+		return fmt.Errorf("Configuration error: app lock retry interval is less than or equal to 0")
+	}
+
+	if c.AppLock.Timeout < 0 {
+		return fmt.Errorf("Configuration error: app lock timeout is less than 0")
+	}
+
+	if c.AppLock.TTL <= 0 {
+		return fmt.Errorf("Configuration error: app lock ttl is less than or equal to 0")
+	}
+
+	if c.OrphanReconciler.Interval <= 0 {
+		return fmt.Errorf("Configuration error: orphan reconciler interval is less than or equal to 0")
+	}
+
+	if c.OrphanReconciler.GracePeriod <= 0 {
+		return fmt.Errorf("Configuration error: orphan reconciler grace period is less than or equal to 0")
+	}
+
+	if c.Scaling.MaxCrashedInstancesRatio < 0 || c.Scaling.MaxCrashedInstancesRatio > 1 {
+		return fmt.Errorf("Configuration error: max crashed instances ratio is less than 0 or more than 1")
+	}
+
+	if c.Scaling.InstanceRestartTime < 0 {
+		return fmt.Errorf("Configuration error: instance restart time is less than 0")
+	}
+
 	return nil
 
 }
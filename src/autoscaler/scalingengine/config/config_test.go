@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"autoscaler/cf"
+	"autoscaler/db/leaderelection"
 	. "autoscaler/scalingengine/config"
 
 	. "github.com/onsi/ginkgo"
@@ -61,11 +62,21 @@ server:
 				configBytes = []byte(`
 cf:
   api: https://api.example.com
+  api_version: v3
   grant_type: PassWord
   username: admin
   password: admin
   client_id: client-id
   secret: client-secret
+  skip_ssl_validation: true
+  ca_cert_file: /var/vcap/jobs/autoscaler/config/certs/cf-ca.crt
+  retry:
+    max_retries: 5
+    initial_backoff: 1s
+    max_backoff: 20s
+  circuit_breaker:
+    failure_threshold: 10
+    open_timeout: 1m
 server:
   port: 8989
   tls:
@@ -80,6 +91,24 @@ db:
   scheduler_db_url: test-scheduler-db-url
 synchronizer:
   active_schedule_sync_interval: 300s
+health:
+  port: 9999
+leader_election:
+  enabled: true
+  owner_id: scalingengine-1
+  lease_ttl: 60s
+  retry_interval: 20s
+app_lock:
+  owner_id: scalingengine-1
+  ttl: 90s
+  timeout: 15s
+  retry_interval: 2s
+orphan_reconciler:
+  interval: 30m
+  grace_period: 48h
+scaling:
+  max_crashed_instances_ratio: 0.3
+  instance_restart_time: 90s
 `)
 			})
 
@@ -87,11 +116,23 @@ synchronizer:
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(conf.Cf.Api).To(Equal("https://api.example.com"))
+				Expect(conf.Cf.ApiVersion).To(Equal("v3"))
 				Expect(conf.Cf.GrantType).To(Equal("password"))
 				Expect(conf.Cf.Username).To(Equal("admin"))
 				Expect(conf.Cf.Password).To(Equal("admin"))
 				Expect(conf.Cf.ClientId).To(Equal("client-id"))
 				Expect(conf.Cf.Secret).To(Equal("client-secret"))
+				Expect(conf.Cf.SkipSSLValidation).To(BeTrue())
+				Expect(conf.Cf.CACertFile).To(Equal("/var/vcap/jobs/autoscaler/config/certs/cf-ca.crt"))
+				Expect(conf.Cf.Retry).To(Equal(cf.RetryConfig{
+					MaxRetries:     5,
+					InitialBackoff: time.Second,
+					MaxBackoff:     20 * time.Second,
+				}))
+				Expect(conf.Cf.CircuitBreaker).To(Equal(cf.CircuitBreakerConfig{
+					FailureThreshold: 10,
+					OpenTimeout:      time.Minute,
+				}))
 
 				Expect(conf.Server.Port).To(Equal(8989))
 				Expect(conf.Server.TLS.KeyFile).To(Equal("/var/vcap/jobs/autoscaler/config/certs/server.key"))
@@ -105,6 +146,30 @@ synchronizer:
 				Expect(conf.Db.SchedulerDbUrl).To(Equal("test-scheduler-db-url"))
 
 				Expect(conf.Synchronizer.ActiveScheduleSyncInterval).To(Equal(5 * time.Minute))
+
+				Expect(conf.Health.Port).To(Equal(9999))
+
+				Expect(conf.LeaderElection).To(Equal(leaderelection.LeaderElectionConfig{
+					Enabled:       true,
+					OwnerId:       "scalingengine-1",
+					LeaseTTL:      60 * time.Second,
+					RetryInterval: 20 * time.Second,
+				}))
+
+				Expect(conf.AppLock).To(Equal(AppLockConfig{
+					OwnerId:       "scalingengine-1",
+					TTL:           90 * time.Second,
+					Timeout:       15 * time.Second,
+					RetryInterval: 2 * time.Second,
+				}))
+
+				Expect(conf.OrphanReconciler).To(Equal(OrphanReconcilerConfig{
+					Interval:    30 * time.Minute,
+					GracePeriod: 48 * time.Hour,
+				}))
+
+				Expect(conf.Scaling.MaxCrashedInstancesRatio).To(Equal(0.3))
+				Expect(conf.Scaling.InstanceRestartTime).To(Equal(90 * time.Second))
 			})
 		})
 
@@ -124,9 +189,23 @@ db:
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(conf.Cf.GrantType).To(Equal(cf.GrantTypePassword))
+				Expect(conf.Cf.Retry).To(Equal(cf.DefaultRetryConfig))
+				Expect(conf.Cf.CircuitBreaker).To(Equal(cf.DefaultCircuitBreakerConfig))
 				Expect(conf.Server.Port).To(Equal(8080))
 				Expect(conf.Logging.Level).To(Equal("info"))
 				Expect(conf.Synchronizer.ActiveScheduleSyncInterval).To(Equal(DefaultActiveScheduleSyncInterval))
+				Expect(conf.LeaderElection).To(Equal(leaderelection.DefaultLeaderElectionConfig))
+				Expect(conf.AppLock).To(Equal(AppLockConfig{
+					TTL:           DefaultAppLockTTL,
+					Timeout:       DefaultAppLockTimeout,
+					RetryInterval: DefaultAppLockRetryInterval,
+				}))
+				Expect(conf.OrphanReconciler).To(Equal(OrphanReconcilerConfig{
+					Interval:    DefaultOrphanReconcileInterval,
+					GracePeriod: DefaultOrphanGracePeriod,
+				}))
+				Expect(conf.Scaling.MaxCrashedInstancesRatio).To(Equal(DefaultMaxCrashedInstancesRatio))
+				Expect(conf.Scaling.InstanceRestartTime).To(Equal(DefaultInstanceRestartTime))
 			})
 		})
 
@@ -141,6 +220,11 @@ db:
 			conf.Db.PolicyDbUrl = "test-policy-db-url"
 			conf.Db.ScalingEngineDbUrl = "test-scalingengine-db-url"
 			conf.Db.SchedulerDbUrl = "test-scheduler-db-url"
+			conf.AppLock.TTL = DefaultAppLockTTL
+			conf.AppLock.Timeout = DefaultAppLockTimeout
+			conf.AppLock.RetryInterval = DefaultAppLockRetryInterval
+			conf.OrphanReconciler.Interval = DefaultOrphanReconcileInterval
+			conf.OrphanReconciler.GracePeriod = DefaultOrphanGracePeriod
 		})
 
 		JustBeforeEach(func() {
@@ -193,6 +277,97 @@ db:
 			})
 		})
 
+		Context("when health port is out of range", func() {
+			BeforeEach(func() {
+				conf.Health.Port = 70000
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: health port is less than 0 or more than 65535")))
+			})
+		})
+
+		Context("when leader election config is not valid", func() {
+			BeforeEach(func() {
+				conf.LeaderElection.Enabled = true
+				conf.LeaderElection.RetryInterval = 0
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: leader election retry interval is less than or equal to 0")))
+			})
+		})
+
+		Context("when app lock retry interval is not positive", func() {
+			BeforeEach(func() {
+				conf.AppLock.RetryInterval = 0
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: app lock retry interval is less than or equal to 0")))
+			})
+		})
+
+		Context("when app lock timeout is negative", func() {
+			BeforeEach(func() {
+				conf.AppLock.Timeout = -1 * time.Second
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: app lock timeout is less than 0")))
+			})
+		})
+
+		Context("when app lock ttl is not positive", func() {
+			BeforeEach(func() {
+				conf.AppLock.TTL = 0
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: app lock ttl is less than or equal to 0")))
+			})
+		})
+
+		Context("when orphan reconciler interval is not positive", func() {
+			BeforeEach(func() {
+				conf.OrphanReconciler.Interval = 0
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: orphan reconciler interval is less than or equal to 0")))
+			})
+		})
+
+		Context("when orphan reconciler grace period is not positive", func() {
+			BeforeEach(func() {
+				conf.OrphanReconciler.GracePeriod = 0
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: orphan reconciler grace period is less than or equal to 0")))
+			})
+		})
+
+		Context("when max crashed instances ratio is more than 1", func() {
+			BeforeEach(func() {
+				conf.Scaling.MaxCrashedInstancesRatio = 1.5
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: max crashed instances ratio is less than 0 or more than 1")))
+			})
+		})
+
+		Context("when instance restart time is negative", func() {
+			BeforeEach(func() {
+				conf.Scaling.InstanceRestartTime = -1 * time.Second
+			})
+
+			It("should error", func() {
+				Expect(err).To(MatchError(MatchRegexp("Configuration error: instance restart time is less than 0")))
+			})
+		})
+
 	})
 
 })
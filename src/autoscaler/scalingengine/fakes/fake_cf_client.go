@@ -3,6 +3,7 @@ package fakes
 
 import (
 	"autoscaler/cf"
+	"autoscaler/models"
 	"sync"
 )
 
@@ -20,17 +21,33 @@ type FakeCfClient struct {
 		result1 string
 		result2 error
 	}
+	ReauthenticateStub        func(rejectedAccessToken string) (cf.Tokens, error)
+	reauthenticateMutex       sync.RWMutex
+	reauthenticateArgsForCall []struct {
+		rejectedAccessToken string
+	}
+	reauthenticateReturns struct {
+		result1 cf.Tokens
+		result2 error
+	}
 	GetTokensStub        func() cf.Tokens
 	getTokensMutex       sync.RWMutex
 	getTokensArgsForCall []struct{}
 	getTokensReturns     struct {
 		result1 cf.Tokens
 	}
-	GetTokensWithRefreshStub        func() cf.Tokens
+	GetTokensWithRefreshStub        func() (cf.Tokens, error)
 	getTokensWithRefreshMutex       sync.RWMutex
 	getTokensWithRefreshArgsForCall []struct{}
 	getTokensWithRefreshReturns     struct {
 		result1 cf.Tokens
+		result2 error
+	}
+	IsTokenValidStub        func() bool
+	isTokenValidMutex       sync.RWMutex
+	isTokenValidArgsForCall []struct{}
+	isTokenValidReturns     struct {
+		result1 bool
 	}
 	GetEndpointsStub        func() cf.Endpoints
 	getEndpointsMutex       sync.RWMutex
@@ -38,24 +55,85 @@ type FakeCfClient struct {
 	getEndpointsReturns     struct {
 		result1 cf.Endpoints
 	}
-	GetAppInstancesStub        func(string) (int, error)
+	AppExistsStub        func(appId string) (bool, error)
+	appExistsMutex       sync.RWMutex
+	appExistsArgsForCall []struct {
+		appId string
+	}
+	appExistsReturns struct {
+		result1 bool
+		result2 error
+	}
+	GetAppStatusStub        func(appId string, processType string) (models.AppStatus, error)
+	getAppStatusMutex       sync.RWMutex
+	getAppStatusArgsForCall []struct {
+		appId       string
+		processType string
+	}
+	getAppStatusReturns struct {
+		result1 models.AppStatus
+		result2 error
+	}
+	GetAppQuotasStub        func(appId string, processType string) (models.AppQuotas, error)
+	getAppQuotasMutex       sync.RWMutex
+	getAppQuotasArgsForCall []struct {
+		appId       string
+		processType string
+	}
+	getAppQuotasReturns struct {
+		result1 models.AppQuotas
+		result2 error
+	}
+	GetAppInstancesStub        func(appId string, processType string) (int, error)
 	getAppInstancesMutex       sync.RWMutex
 	getAppInstancesArgsForCall []struct {
-		arg1 string
+		appId       string
+		processType string
 	}
 	getAppInstancesReturns struct {
 		result1 int
 		result2 error
 	}
-	SetAppInstancesStub        func(string, int) error
+	SetAppInstancesStub        func(appId string, processType string, num int) error
 	setAppInstancesMutex       sync.RWMutex
 	setAppInstancesArgsForCall []struct {
-		arg1 string
-		arg2 int
+		appId       string
+		processType string
+		num         int
 	}
 	setAppInstancesReturns struct {
 		result1 error
 	}
+	RestartAppInstanceStub        func(appId string, processType string, index int) error
+	restartAppInstanceMutex       sync.RWMutex
+	restartAppInstanceArgsForCall []struct {
+		appId       string
+		processType string
+		index       int
+	}
+	restartAppInstanceReturns struct {
+		result1 error
+	}
+	GetAppLimitsStub        func(appId string, processType string) (models.InstanceLimits, error)
+	getAppLimitsMutex       sync.RWMutex
+	getAppLimitsArgsForCall []struct {
+		appId       string
+		processType string
+	}
+	getAppLimitsReturns struct {
+		result1 models.InstanceLimits
+		result2 error
+	}
+	SetAppLimitsStub        func(appId string, processType string, limits models.InstanceLimits) error
+	setAppLimitsMutex       sync.RWMutex
+	setAppLimitsArgsForCall []struct {
+		appId       string
+		processType string
+		limits      models.InstanceLimits
+	}
+	setAppLimitsReturns struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -111,6 +189,40 @@ func (fake *FakeCfClient) RefreshAuthTokenReturns(result1 string, result2 error)
 	}{result1, result2}
 }
 
+func (fake *FakeCfClient) Reauthenticate(rejectedAccessToken string) (cf.Tokens, error) {
+	fake.reauthenticateMutex.Lock()
+	fake.reauthenticateArgsForCall = append(fake.reauthenticateArgsForCall, struct {
+		rejectedAccessToken string
+	}{rejectedAccessToken})
+	fake.recordInvocation("Reauthenticate", []interface{}{rejectedAccessToken})
+	fake.reauthenticateMutex.Unlock()
+	if fake.ReauthenticateStub != nil {
+		return fake.ReauthenticateStub(rejectedAccessToken)
+	} else {
+		return fake.reauthenticateReturns.result1, fake.reauthenticateReturns.result2
+	}
+}
+
+func (fake *FakeCfClient) ReauthenticateCallCount() int {
+	fake.reauthenticateMutex.RLock()
+	defer fake.reauthenticateMutex.RUnlock()
+	return len(fake.reauthenticateArgsForCall)
+}
+
+func (fake *FakeCfClient) ReauthenticateArgsForCall(i int) string {
+	fake.reauthenticateMutex.RLock()
+	defer fake.reauthenticateMutex.RUnlock()
+	return fake.reauthenticateArgsForCall[i].rejectedAccessToken
+}
+
+func (fake *FakeCfClient) ReauthenticateReturns(result1 cf.Tokens, result2 error) {
+	fake.ReauthenticateStub = nil
+	fake.reauthenticateReturns = struct {
+		result1 cf.Tokens
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeCfClient) GetTokens() cf.Tokens {
 	fake.getTokensMutex.Lock()
 	fake.getTokensArgsForCall = append(fake.getTokensArgsForCall, struct{}{})
@@ -136,7 +248,7 @@ func (fake *FakeCfClient) GetTokensReturns(result1 cf.Tokens) {
 	}{result1}
 }
 
-func (fake *FakeCfClient) GetTokensWithRefresh() cf.Tokens {
+func (fake *FakeCfClient) GetTokensWithRefresh() (cf.Tokens, error) {
 	fake.getTokensWithRefreshMutex.Lock()
 	fake.getTokensWithRefreshArgsForCall = append(fake.getTokensWithRefreshArgsForCall, struct{}{})
 	fake.recordInvocation("GetTokensWithRefresh", []interface{}{})
@@ -144,7 +256,7 @@ func (fake *FakeCfClient) GetTokensWithRefresh() cf.Tokens {
 	if fake.GetTokensWithRefreshStub != nil {
 		return fake.GetTokensWithRefreshStub()
 	} else {
-		return fake.getTokensWithRefreshReturns.result1
+		return fake.getTokensWithRefreshReturns.result1, fake.getTokensWithRefreshReturns.result2
 	}
 }
 
@@ -154,10 +266,36 @@ func (fake *FakeCfClient) GetTokensWithRefreshCallCount() int {
 	return len(fake.getTokensWithRefreshArgsForCall)
 }
 
-func (fake *FakeCfClient) GetTokensWithRefreshReturns(result1 cf.Tokens) {
+func (fake *FakeCfClient) GetTokensWithRefreshReturns(result1 cf.Tokens, result2 error) {
 	fake.GetTokensWithRefreshStub = nil
 	fake.getTokensWithRefreshReturns = struct {
 		result1 cf.Tokens
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfClient) IsTokenValid() bool {
+	fake.isTokenValidMutex.Lock()
+	fake.isTokenValidArgsForCall = append(fake.isTokenValidArgsForCall, struct{}{})
+	fake.recordInvocation("IsTokenValid", []interface{}{})
+	fake.isTokenValidMutex.Unlock()
+	if fake.IsTokenValidStub != nil {
+		return fake.IsTokenValidStub()
+	} else {
+		return fake.isTokenValidReturns.result1
+	}
+}
+
+func (fake *FakeCfClient) IsTokenValidCallCount() int {
+	fake.isTokenValidMutex.RLock()
+	defer fake.isTokenValidMutex.RUnlock()
+	return len(fake.isTokenValidArgsForCall)
+}
+
+func (fake *FakeCfClient) IsTokenValidReturns(result1 bool) {
+	fake.IsTokenValidStub = nil
+	fake.isTokenValidReturns = struct {
+		result1 bool
 	}{result1}
 }
 
@@ -186,15 +324,120 @@ func (fake *FakeCfClient) GetEndpointsReturns(result1 cf.Endpoints) {
 	}{result1}
 }
 
-func (fake *FakeCfClient) GetAppInstances(arg1 string) (int, error) {
+func (fake *FakeCfClient) AppExists(appId string) (bool, error) {
+	fake.appExistsMutex.Lock()
+	fake.appExistsArgsForCall = append(fake.appExistsArgsForCall, struct {
+		appId string
+	}{appId})
+	fake.recordInvocation("AppExists", []interface{}{appId})
+	fake.appExistsMutex.Unlock()
+	if fake.AppExistsStub != nil {
+		return fake.AppExistsStub(appId)
+	} else {
+		return fake.appExistsReturns.result1, fake.appExistsReturns.result2
+	}
+}
+
+func (fake *FakeCfClient) AppExistsCallCount() int {
+	fake.appExistsMutex.RLock()
+	defer fake.appExistsMutex.RUnlock()
+	return len(fake.appExistsArgsForCall)
+}
+
+func (fake *FakeCfClient) AppExistsArgsForCall(i int) string {
+	fake.appExistsMutex.RLock()
+	defer fake.appExistsMutex.RUnlock()
+	return fake.appExistsArgsForCall[i].appId
+}
+
+func (fake *FakeCfClient) AppExistsReturns(result1 bool, result2 error) {
+	fake.AppExistsStub = nil
+	fake.appExistsReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfClient) GetAppStatus(appId string, processType string) (models.AppStatus, error) {
+	fake.getAppStatusMutex.Lock()
+	fake.getAppStatusArgsForCall = append(fake.getAppStatusArgsForCall, struct {
+		appId       string
+		processType string
+	}{appId, processType})
+	fake.recordInvocation("GetAppStatus", []interface{}{appId, processType})
+	fake.getAppStatusMutex.Unlock()
+	if fake.GetAppStatusStub != nil {
+		return fake.GetAppStatusStub(appId, processType)
+	} else {
+		return fake.getAppStatusReturns.result1, fake.getAppStatusReturns.result2
+	}
+}
+
+func (fake *FakeCfClient) GetAppStatusCallCount() int {
+	fake.getAppStatusMutex.RLock()
+	defer fake.getAppStatusMutex.RUnlock()
+	return len(fake.getAppStatusArgsForCall)
+}
+
+func (fake *FakeCfClient) GetAppStatusArgsForCall(i int) (string, string) {
+	fake.getAppStatusMutex.RLock()
+	defer fake.getAppStatusMutex.RUnlock()
+	return fake.getAppStatusArgsForCall[i].appId, fake.getAppStatusArgsForCall[i].processType
+}
+
+func (fake *FakeCfClient) GetAppStatusReturns(result1 models.AppStatus, result2 error) {
+	fake.GetAppStatusStub = nil
+	fake.getAppStatusReturns = struct {
+		result1 models.AppStatus
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfClient) GetAppQuotas(appId string, processType string) (models.AppQuotas, error) {
+	fake.getAppQuotasMutex.Lock()
+	fake.getAppQuotasArgsForCall = append(fake.getAppQuotasArgsForCall, struct {
+		appId       string
+		processType string
+	}{appId, processType})
+	fake.recordInvocation("GetAppQuotas", []interface{}{appId, processType})
+	fake.getAppQuotasMutex.Unlock()
+	if fake.GetAppQuotasStub != nil {
+		return fake.GetAppQuotasStub(appId, processType)
+	} else {
+		return fake.getAppQuotasReturns.result1, fake.getAppQuotasReturns.result2
+	}
+}
+
+func (fake *FakeCfClient) GetAppQuotasCallCount() int {
+	fake.getAppQuotasMutex.RLock()
+	defer fake.getAppQuotasMutex.RUnlock()
+	return len(fake.getAppQuotasArgsForCall)
+}
+
+func (fake *FakeCfClient) GetAppQuotasArgsForCall(i int) (string, string) {
+	fake.getAppQuotasMutex.RLock()
+	defer fake.getAppQuotasMutex.RUnlock()
+	return fake.getAppQuotasArgsForCall[i].appId, fake.getAppQuotasArgsForCall[i].processType
+}
+
+func (fake *FakeCfClient) GetAppQuotasReturns(result1 models.AppQuotas, result2 error) {
+	fake.GetAppQuotasStub = nil
+	fake.getAppQuotasReturns = struct {
+		result1 models.AppQuotas
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfClient) GetAppInstances(appId string, processType string) (int, error) {
 	fake.getAppInstancesMutex.Lock()
 	fake.getAppInstancesArgsForCall = append(fake.getAppInstancesArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("GetAppInstances", []interface{}{arg1})
+		appId       string
+		processType string
+	}{appId, processType})
+	fake.recordInvocation("GetAppInstances", []interface{}{appId, processType})
 	fake.getAppInstancesMutex.Unlock()
 	if fake.GetAppInstancesStub != nil {
-		return fake.GetAppInstancesStub(arg1)
+		return fake.GetAppInstancesStub(appId, processType)
 	} else {
 		return fake.getAppInstancesReturns.result1, fake.getAppInstancesReturns.result2
 	}
@@ -206,10 +449,10 @@ func (fake *FakeCfClient) GetAppInstancesCallCount() int {
 	return len(fake.getAppInstancesArgsForCall)
 }
 
-func (fake *FakeCfClient) GetAppInstancesArgsForCall(i int) string {
+func (fake *FakeCfClient) GetAppInstancesArgsForCall(i int) (string, string) {
 	fake.getAppInstancesMutex.RLock()
 	defer fake.getAppInstancesMutex.RUnlock()
-	return fake.getAppInstancesArgsForCall[i].arg1
+	return fake.getAppInstancesArgsForCall[i].appId, fake.getAppInstancesArgsForCall[i].processType
 }
 
 func (fake *FakeCfClient) GetAppInstancesReturns(result1 int, result2 error) {
@@ -220,16 +463,17 @@ func (fake *FakeCfClient) GetAppInstancesReturns(result1 int, result2 error) {
 	}{result1, result2}
 }
 
-func (fake *FakeCfClient) SetAppInstances(arg1 string, arg2 int) error {
+func (fake *FakeCfClient) SetAppInstances(appId string, processType string, num int) error {
 	fake.setAppInstancesMutex.Lock()
 	fake.setAppInstancesArgsForCall = append(fake.setAppInstancesArgsForCall, struct {
-		arg1 string
-		arg2 int
-	}{arg1, arg2})
-	fake.recordInvocation("SetAppInstances", []interface{}{arg1, arg2})
+		appId       string
+		processType string
+		num         int
+	}{appId, processType, num})
+	fake.recordInvocation("SetAppInstances", []interface{}{appId, processType, num})
 	fake.setAppInstancesMutex.Unlock()
 	if fake.SetAppInstancesStub != nil {
-		return fake.SetAppInstancesStub(arg1, arg2)
+		return fake.SetAppInstancesStub(appId, processType, num)
 	} else {
 		return fake.setAppInstancesReturns.result1
 	}
@@ -241,10 +485,10 @@ func (fake *FakeCfClient) SetAppInstancesCallCount() int {
 	return len(fake.setAppInstancesArgsForCall)
 }
 
-func (fake *FakeCfClient) SetAppInstancesArgsForCall(i int) (string, int) {
+func (fake *FakeCfClient) SetAppInstancesArgsForCall(i int) (string, string, int) {
 	fake.setAppInstancesMutex.RLock()
 	defer fake.setAppInstancesMutex.RUnlock()
-	return fake.setAppInstancesArgsForCall[i].arg1, fake.setAppInstancesArgsForCall[i].arg2
+	return fake.setAppInstancesArgsForCall[i].appId, fake.setAppInstancesArgsForCall[i].processType, fake.setAppInstancesArgsForCall[i].num
 }
 
 func (fake *FakeCfClient) SetAppInstancesReturns(result1 error) {
@@ -254,6 +498,111 @@ func (fake *FakeCfClient) SetAppInstancesReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeCfClient) RestartAppInstance(appId string, processType string, index int) error {
+	fake.restartAppInstanceMutex.Lock()
+	fake.restartAppInstanceArgsForCall = append(fake.restartAppInstanceArgsForCall, struct {
+		appId       string
+		processType string
+		index       int
+	}{appId, processType, index})
+	fake.recordInvocation("RestartAppInstance", []interface{}{appId, processType, index})
+	fake.restartAppInstanceMutex.Unlock()
+	if fake.RestartAppInstanceStub != nil {
+		return fake.RestartAppInstanceStub(appId, processType, index)
+	} else {
+		return fake.restartAppInstanceReturns.result1
+	}
+}
+
+func (fake *FakeCfClient) RestartAppInstanceCallCount() int {
+	fake.restartAppInstanceMutex.RLock()
+	defer fake.restartAppInstanceMutex.RUnlock()
+	return len(fake.restartAppInstanceArgsForCall)
+}
+
+func (fake *FakeCfClient) RestartAppInstanceArgsForCall(i int) (string, string, int) {
+	fake.restartAppInstanceMutex.RLock()
+	defer fake.restartAppInstanceMutex.RUnlock()
+	return fake.restartAppInstanceArgsForCall[i].appId, fake.restartAppInstanceArgsForCall[i].processType, fake.restartAppInstanceArgsForCall[i].index
+}
+
+func (fake *FakeCfClient) RestartAppInstanceReturns(result1 error) {
+	fake.RestartAppInstanceStub = nil
+	fake.restartAppInstanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCfClient) GetAppLimits(appId string, processType string) (models.InstanceLimits, error) {
+	fake.getAppLimitsMutex.Lock()
+	fake.getAppLimitsArgsForCall = append(fake.getAppLimitsArgsForCall, struct {
+		appId       string
+		processType string
+	}{appId, processType})
+	fake.recordInvocation("GetAppLimits", []interface{}{appId, processType})
+	fake.getAppLimitsMutex.Unlock()
+	if fake.GetAppLimitsStub != nil {
+		return fake.GetAppLimitsStub(appId, processType)
+	} else {
+		return fake.getAppLimitsReturns.result1, fake.getAppLimitsReturns.result2
+	}
+}
+
+func (fake *FakeCfClient) GetAppLimitsCallCount() int {
+	fake.getAppLimitsMutex.RLock()
+	defer fake.getAppLimitsMutex.RUnlock()
+	return len(fake.getAppLimitsArgsForCall)
+}
+
+func (fake *FakeCfClient) GetAppLimitsArgsForCall(i int) (string, string) {
+	fake.getAppLimitsMutex.RLock()
+	defer fake.getAppLimitsMutex.RUnlock()
+	return fake.getAppLimitsArgsForCall[i].appId, fake.getAppLimitsArgsForCall[i].processType
+}
+
+func (fake *FakeCfClient) GetAppLimitsReturns(result1 models.InstanceLimits, result2 error) {
+	fake.GetAppLimitsStub = nil
+	fake.getAppLimitsReturns = struct {
+		result1 models.InstanceLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfClient) SetAppLimits(appId string, processType string, limits models.InstanceLimits) error {
+	fake.setAppLimitsMutex.Lock()
+	fake.setAppLimitsArgsForCall = append(fake.setAppLimitsArgsForCall, struct {
+		appId       string
+		processType string
+		limits      models.InstanceLimits
+	}{appId, processType, limits})
+	fake.recordInvocation("SetAppLimits", []interface{}{appId, processType, limits})
+	fake.setAppLimitsMutex.Unlock()
+	if fake.SetAppLimitsStub != nil {
+		return fake.SetAppLimitsStub(appId, processType, limits)
+	} else {
+		return fake.setAppLimitsReturns.result1
+	}
+}
+
+func (fake *FakeCfClient) SetAppLimitsCallCount() int {
+	fake.setAppLimitsMutex.RLock()
+	defer fake.setAppLimitsMutex.RUnlock()
+	return len(fake.setAppLimitsArgsForCall)
+}
+
+func (fake *FakeCfClient) SetAppLimitsArgsForCall(i int) (string, string, models.InstanceLimits) {
+	fake.setAppLimitsMutex.RLock()
+	defer fake.setAppLimitsMutex.RUnlock()
+	return fake.setAppLimitsArgsForCall[i].appId, fake.setAppLimitsArgsForCall[i].processType, fake.setAppLimitsArgsForCall[i].limits
+}
+
+func (fake *FakeCfClient) SetAppLimitsReturns(result1 error) {
+	fake.SetAppLimitsStub = nil
+	fake.setAppLimitsReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeCfClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -261,16 +610,32 @@ func (fake *FakeCfClient) Invocations() map[string][][]interface{} {
 	defer fake.loginMutex.RUnlock()
 	fake.refreshAuthTokenMutex.RLock()
 	defer fake.refreshAuthTokenMutex.RUnlock()
+	fake.reauthenticateMutex.RLock()
+	defer fake.reauthenticateMutex.RUnlock()
 	fake.getTokensMutex.RLock()
 	defer fake.getTokensMutex.RUnlock()
 	fake.getTokensWithRefreshMutex.RLock()
 	defer fake.getTokensWithRefreshMutex.RUnlock()
+	fake.isTokenValidMutex.RLock()
+	defer fake.isTokenValidMutex.RUnlock()
 	fake.getEndpointsMutex.RLock()
 	defer fake.getEndpointsMutex.RUnlock()
+	fake.appExistsMutex.RLock()
+	defer fake.appExistsMutex.RUnlock()
+	fake.getAppStatusMutex.RLock()
+	defer fake.getAppStatusMutex.RUnlock()
+	fake.getAppQuotasMutex.RLock()
+	defer fake.getAppQuotasMutex.RUnlock()
 	fake.getAppInstancesMutex.RLock()
 	defer fake.getAppInstancesMutex.RUnlock()
 	fake.setAppInstancesMutex.RLock()
 	defer fake.setAppInstancesMutex.RUnlock()
+	fake.restartAppInstanceMutex.RLock()
+	defer fake.restartAppInstanceMutex.RUnlock()
+	fake.getAppLimitsMutex.RLock()
+	defer fake.getAppLimitsMutex.RUnlock()
+	fake.setAppLimitsMutex.RLock()
+	defer fake.setAppLimitsMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -0,0 +1,193 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"autoscaler/db"
+	"sync"
+	"time"
+)
+
+type FakeLeaseDB struct {
+	AcquireLeaseStub        func(name string, owner string, ttl time.Duration) (bool, error)
+	acquireLeaseMutex       sync.RWMutex
+	acquireLeaseArgsForCall []struct {
+		name  string
+		owner string
+		ttl   time.Duration
+	}
+	acquireLeaseReturns struct {
+		result1 bool
+		result2 error
+	}
+	ReleaseLeaseStub        func(name string, owner string) error
+	releaseLeaseMutex       sync.RWMutex
+	releaseLeaseArgsForCall []struct {
+		name  string
+		owner string
+	}
+	releaseLeaseReturns struct {
+		result1 error
+	}
+	PingStub        func() error
+	pingMutex       sync.RWMutex
+	pingArgsForCall []struct{}
+	pingReturns     struct {
+		result1 error
+	}
+	CloseStub        func() error
+	closeMutex       sync.RWMutex
+	closeArgsForCall []struct{}
+	closeReturns     struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeLeaseDB) AcquireLease(name string, owner string, ttl time.Duration) (bool, error) {
+	fake.acquireLeaseMutex.Lock()
+	fake.acquireLeaseArgsForCall = append(fake.acquireLeaseArgsForCall, struct {
+		name  string
+		owner string
+		ttl   time.Duration
+	}{name, owner, ttl})
+	fake.recordInvocation("AcquireLease", []interface{}{name, owner, ttl})
+	fake.acquireLeaseMutex.Unlock()
+	if fake.AcquireLeaseStub != nil {
+		return fake.AcquireLeaseStub(name, owner, ttl)
+	} else {
+		return fake.acquireLeaseReturns.result1, fake.acquireLeaseReturns.result2
+	}
+}
+
+func (fake *FakeLeaseDB) AcquireLeaseCallCount() int {
+	fake.acquireLeaseMutex.RLock()
+	defer fake.acquireLeaseMutex.RUnlock()
+	return len(fake.acquireLeaseArgsForCall)
+}
+
+func (fake *FakeLeaseDB) AcquireLeaseArgsForCall(i int) (string, string, time.Duration) {
+	fake.acquireLeaseMutex.RLock()
+	defer fake.acquireLeaseMutex.RUnlock()
+	return fake.acquireLeaseArgsForCall[i].name, fake.acquireLeaseArgsForCall[i].owner, fake.acquireLeaseArgsForCall[i].ttl
+}
+
+func (fake *FakeLeaseDB) AcquireLeaseReturns(result1 bool, result2 error) {
+	fake.AcquireLeaseStub = nil
+	fake.acquireLeaseReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaseDB) ReleaseLease(name string, owner string) error {
+	fake.releaseLeaseMutex.Lock()
+	fake.releaseLeaseArgsForCall = append(fake.releaseLeaseArgsForCall, struct {
+		name  string
+		owner string
+	}{name, owner})
+	fake.recordInvocation("ReleaseLease", []interface{}{name, owner})
+	fake.releaseLeaseMutex.Unlock()
+	if fake.ReleaseLeaseStub != nil {
+		return fake.ReleaseLeaseStub(name, owner)
+	} else {
+		return fake.releaseLeaseReturns.result1
+	}
+}
+
+func (fake *FakeLeaseDB) ReleaseLeaseCallCount() int {
+	fake.releaseLeaseMutex.RLock()
+	defer fake.releaseLeaseMutex.RUnlock()
+	return len(fake.releaseLeaseArgsForCall)
+}
+
+func (fake *FakeLeaseDB) ReleaseLeaseArgsForCall(i int) (string, string) {
+	fake.releaseLeaseMutex.RLock()
+	defer fake.releaseLeaseMutex.RUnlock()
+	return fake.releaseLeaseArgsForCall[i].name, fake.releaseLeaseArgsForCall[i].owner
+}
+
+func (fake *FakeLeaseDB) ReleaseLeaseReturns(result1 error) {
+	fake.ReleaseLeaseStub = nil
+	fake.releaseLeaseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaseDB) Ping() error {
+	fake.pingMutex.Lock()
+	fake.pingArgsForCall = append(fake.pingArgsForCall, struct{}{})
+	fake.recordInvocation("Ping", []interface{}{})
+	fake.pingMutex.Unlock()
+	if fake.PingStub != nil {
+		return fake.PingStub()
+	} else {
+		return fake.pingReturns.result1
+	}
+}
+
+func (fake *FakeLeaseDB) PingCallCount() int {
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	return len(fake.pingArgsForCall)
+}
+
+func (fake *FakeLeaseDB) PingReturns(result1 error) {
+	fake.PingStub = nil
+	fake.pingReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaseDB) Close() error {
+	fake.closeMutex.Lock()
+	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
+	fake.recordInvocation("Close", []interface{}{})
+	fake.closeMutex.Unlock()
+	if fake.CloseStub != nil {
+		return fake.CloseStub()
+	} else {
+		return fake.closeReturns.result1
+	}
+}
+
+func (fake *FakeLeaseDB) CloseCallCount() int {
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return len(fake.closeArgsForCall)
+}
+
+func (fake *FakeLeaseDB) CloseReturns(result1 error) {
+	fake.CloseStub = nil
+	fake.closeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaseDB) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.acquireLeaseMutex.RLock()
+	defer fake.acquireLeaseMutex.RUnlock()
+	fake.releaseLeaseMutex.RLock()
+	defer fake.releaseLeaseMutex.RUnlock()
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeLeaseDB) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ db.LeaseDB = new(FakeLeaseDB)
@@ -18,6 +18,16 @@ type FakeScalingEngine struct {
 		result1 int
 		result2 error
 	}
+	RestartInstanceStub        func(appId string, instanceIndex int, trigger *models.Trigger) error
+	restartInstanceMutex       sync.RWMutex
+	restartInstanceArgsForCall []struct {
+		appId         string
+		instanceIndex int
+		trigger       *models.Trigger
+	}
+	restartInstanceReturns struct {
+		result1 error
+	}
 	ComputeNewInstancesStub        func(currentInstances int, adjustment string) (int, error)
 	computeNewInstancesMutex       sync.RWMutex
 	computeNewInstancesArgsForCall []struct {
@@ -46,6 +56,15 @@ type FakeScalingEngine struct {
 	removeActiveScheduleReturns struct {
 		result1 error
 	}
+	ResizeStub        func(appId string, trigger *models.Trigger) error
+	resizeMutex       sync.RWMutex
+	resizeArgsForCall []struct {
+		appId   string
+		trigger *models.Trigger
+	}
+	resizeReturns struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -85,6 +104,41 @@ func (fake *FakeScalingEngine) ScaleReturns(result1 int, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeScalingEngine) RestartInstance(appId string, instanceIndex int, trigger *models.Trigger) error {
+	fake.restartInstanceMutex.Lock()
+	fake.restartInstanceArgsForCall = append(fake.restartInstanceArgsForCall, struct {
+		appId         string
+		instanceIndex int
+		trigger       *models.Trigger
+	}{appId, instanceIndex, trigger})
+	fake.recordInvocation("RestartInstance", []interface{}{appId, instanceIndex, trigger})
+	fake.restartInstanceMutex.Unlock()
+	if fake.RestartInstanceStub != nil {
+		return fake.RestartInstanceStub(appId, instanceIndex, trigger)
+	} else {
+		return fake.restartInstanceReturns.result1
+	}
+}
+
+func (fake *FakeScalingEngine) RestartInstanceCallCount() int {
+	fake.restartInstanceMutex.RLock()
+	defer fake.restartInstanceMutex.RUnlock()
+	return len(fake.restartInstanceArgsForCall)
+}
+
+func (fake *FakeScalingEngine) RestartInstanceArgsForCall(i int) (string, int, *models.Trigger) {
+	fake.restartInstanceMutex.RLock()
+	defer fake.restartInstanceMutex.RUnlock()
+	return fake.restartInstanceArgsForCall[i].appId, fake.restartInstanceArgsForCall[i].instanceIndex, fake.restartInstanceArgsForCall[i].trigger
+}
+
+func (fake *FakeScalingEngine) RestartInstanceReturns(result1 error) {
+	fake.RestartInstanceStub = nil
+	fake.restartInstanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeScalingEngine) ComputeNewInstances(currentInstances int, adjustment string) (int, error) {
 	fake.computeNewInstancesMutex.Lock()
 	fake.computeNewInstancesArgsForCall = append(fake.computeNewInstancesArgsForCall, struct {
@@ -188,17 +242,55 @@ func (fake *FakeScalingEngine) RemoveActiveScheduleReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeScalingEngine) Resize(appId string, trigger *models.Trigger) error {
+	fake.resizeMutex.Lock()
+	fake.resizeArgsForCall = append(fake.resizeArgsForCall, struct {
+		appId   string
+		trigger *models.Trigger
+	}{appId, trigger})
+	fake.recordInvocation("Resize", []interface{}{appId, trigger})
+	fake.resizeMutex.Unlock()
+	if fake.ResizeStub != nil {
+		return fake.ResizeStub(appId, trigger)
+	} else {
+		return fake.resizeReturns.result1
+	}
+}
+
+func (fake *FakeScalingEngine) ResizeCallCount() int {
+	fake.resizeMutex.RLock()
+	defer fake.resizeMutex.RUnlock()
+	return len(fake.resizeArgsForCall)
+}
+
+func (fake *FakeScalingEngine) ResizeArgsForCall(i int) (string, *models.Trigger) {
+	fake.resizeMutex.RLock()
+	defer fake.resizeMutex.RUnlock()
+	return fake.resizeArgsForCall[i].appId, fake.resizeArgsForCall[i].trigger
+}
+
+func (fake *FakeScalingEngine) ResizeReturns(result1 error) {
+	fake.ResizeStub = nil
+	fake.resizeReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeScalingEngine) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.scaleMutex.RLock()
 	defer fake.scaleMutex.RUnlock()
+	fake.restartInstanceMutex.RLock()
+	defer fake.restartInstanceMutex.RUnlock()
 	fake.computeNewInstancesMutex.RLock()
 	defer fake.computeNewInstancesMutex.RUnlock()
 	fake.setActiveScheduleMutex.RLock()
 	defer fake.setActiveScheduleMutex.RUnlock()
 	fake.removeActiveScheduleMutex.RLock()
 	defer fake.removeActiveScheduleMutex.RUnlock()
+	fake.resizeMutex.RLock()
+	defer fake.resizeMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -27,6 +27,17 @@ type FakeScalingEngineDB struct {
 		result1 []*models.AppScalingHistory
 		result2 error
 	}
+	RetrieveGroupScalingHistoriesStub        func(groupId string, start int64, end int64) ([]*models.AppScalingHistory, error)
+	retrieveGroupScalingHistoriesMutex       sync.RWMutex
+	retrieveGroupScalingHistoriesArgsForCall []struct {
+		groupId string
+		start   int64
+		end     int64
+	}
+	retrieveGroupScalingHistoriesReturns struct {
+		result1 []*models.AppScalingHistory
+		result2 error
+	}
 	PruneScalingHistoriesStub        func(before int64) error
 	pruneScalingHistoriesMutex       sync.RWMutex
 	pruneScalingHistoriesArgsForCall []struct {
@@ -44,12 +55,34 @@ type FakeScalingEngineDB struct {
 	updateScalingCooldownExpireTimeReturns struct {
 		result1 error
 	}
-	CanScaleAppStub        func(appId string) (bool, error)
-	canScaleAppMutex       sync.RWMutex
-	canScaleAppArgsForCall []struct {
+	RemoveScalingCooldownStub        func(appId string) error
+	removeScalingCooldownMutex       sync.RWMutex
+	removeScalingCooldownArgsForCall []struct {
+		appId string
+	}
+	removeScalingCooldownReturns struct {
+		result1 error
+	}
+	ScaleWithCooldownStub        func(appId string, now int64, scale func() (int64, error)) (bool, error)
+	scaleWithCooldownMutex       sync.RWMutex
+	scaleWithCooldownArgsForCall []struct {
 		appId string
+		now   int64
+		scale func() (int64, error)
 	}
-	canScaleAppReturns struct {
+	scaleWithCooldownReturns struct {
+		result1 bool
+		result2 error
+	}
+	RestartWithCooldownStub        func(appId string, instanceIndex int, now int64, restart func() (int64, error)) (bool, error)
+	restartWithCooldownMutex       sync.RWMutex
+	restartWithCooldownArgsForCall []struct {
+		appId         string
+		instanceIndex int
+		now           int64
+		restart       func() (int64, error)
+	}
+	restartWithCooldownReturns struct {
 		result1 bool
 		result2 error
 	}
@@ -86,6 +119,12 @@ type FakeScalingEngineDB struct {
 	removeActiveScheduleReturns struct {
 		result1 error
 	}
+	PingStub        func() error
+	pingMutex       sync.RWMutex
+	pingArgsForCall []struct{}
+	pingReturns     struct {
+		result1 error
+	}
 	CloseStub        func() error
 	closeMutex       sync.RWMutex
 	closeArgsForCall []struct{}
@@ -165,6 +204,42 @@ func (fake *FakeScalingEngineDB) RetrieveScalingHistoriesReturns(result1 []*mode
 	}{result1, result2}
 }
 
+func (fake *FakeScalingEngineDB) RetrieveGroupScalingHistories(groupId string, start int64, end int64) ([]*models.AppScalingHistory, error) {
+	fake.retrieveGroupScalingHistoriesMutex.Lock()
+	fake.retrieveGroupScalingHistoriesArgsForCall = append(fake.retrieveGroupScalingHistoriesArgsForCall, struct {
+		groupId string
+		start   int64
+		end     int64
+	}{groupId, start, end})
+	fake.recordInvocation("RetrieveGroupScalingHistories", []interface{}{groupId, start, end})
+	fake.retrieveGroupScalingHistoriesMutex.Unlock()
+	if fake.RetrieveGroupScalingHistoriesStub != nil {
+		return fake.RetrieveGroupScalingHistoriesStub(groupId, start, end)
+	} else {
+		return fake.retrieveGroupScalingHistoriesReturns.result1, fake.retrieveGroupScalingHistoriesReturns.result2
+	}
+}
+
+func (fake *FakeScalingEngineDB) RetrieveGroupScalingHistoriesCallCount() int {
+	fake.retrieveGroupScalingHistoriesMutex.RLock()
+	defer fake.retrieveGroupScalingHistoriesMutex.RUnlock()
+	return len(fake.retrieveGroupScalingHistoriesArgsForCall)
+}
+
+func (fake *FakeScalingEngineDB) RetrieveGroupScalingHistoriesArgsForCall(i int) (string, int64, int64) {
+	fake.retrieveGroupScalingHistoriesMutex.RLock()
+	defer fake.retrieveGroupScalingHistoriesMutex.RUnlock()
+	return fake.retrieveGroupScalingHistoriesArgsForCall[i].groupId, fake.retrieveGroupScalingHistoriesArgsForCall[i].start, fake.retrieveGroupScalingHistoriesArgsForCall[i].end
+}
+
+func (fake *FakeScalingEngineDB) RetrieveGroupScalingHistoriesReturns(result1 []*models.AppScalingHistory, result2 error) {
+	fake.RetrieveGroupScalingHistoriesStub = nil
+	fake.retrieveGroupScalingHistoriesReturns = struct {
+		result1 []*models.AppScalingHistory
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeScalingEngineDB) PruneScalingHistories(before int64) error {
 	fake.pruneScalingHistoriesMutex.Lock()
 	fake.pruneScalingHistoriesArgsForCall = append(fake.pruneScalingHistoriesArgsForCall, struct {
@@ -232,35 +307,107 @@ func (fake *FakeScalingEngineDB) UpdateScalingCooldownExpireTimeReturns(result1
 	}{result1}
 }
 
-func (fake *FakeScalingEngineDB) CanScaleApp(appId string) (bool, error) {
-	fake.canScaleAppMutex.Lock()
-	fake.canScaleAppArgsForCall = append(fake.canScaleAppArgsForCall, struct {
+func (fake *FakeScalingEngineDB) RemoveScalingCooldown(appId string) error {
+	fake.removeScalingCooldownMutex.Lock()
+	fake.removeScalingCooldownArgsForCall = append(fake.removeScalingCooldownArgsForCall, struct {
 		appId string
 	}{appId})
-	fake.recordInvocation("CanScaleApp", []interface{}{appId})
-	fake.canScaleAppMutex.Unlock()
-	if fake.CanScaleAppStub != nil {
-		return fake.CanScaleAppStub(appId)
+	fake.recordInvocation("RemoveScalingCooldown", []interface{}{appId})
+	fake.removeScalingCooldownMutex.Unlock()
+	if fake.RemoveScalingCooldownStub != nil {
+		return fake.RemoveScalingCooldownStub(appId)
 	} else {
-		return fake.canScaleAppReturns.result1, fake.canScaleAppReturns.result2
+		return fake.removeScalingCooldownReturns.result1
 	}
 }
 
-func (fake *FakeScalingEngineDB) CanScaleAppCallCount() int {
-	fake.canScaleAppMutex.RLock()
-	defer fake.canScaleAppMutex.RUnlock()
-	return len(fake.canScaleAppArgsForCall)
+func (fake *FakeScalingEngineDB) RemoveScalingCooldownCallCount() int {
+	fake.removeScalingCooldownMutex.RLock()
+	defer fake.removeScalingCooldownMutex.RUnlock()
+	return len(fake.removeScalingCooldownArgsForCall)
+}
+
+func (fake *FakeScalingEngineDB) RemoveScalingCooldownArgsForCall(i int) string {
+	fake.removeScalingCooldownMutex.RLock()
+	defer fake.removeScalingCooldownMutex.RUnlock()
+	return fake.removeScalingCooldownArgsForCall[i].appId
 }
 
-func (fake *FakeScalingEngineDB) CanScaleAppArgsForCall(i int) string {
-	fake.canScaleAppMutex.RLock()
-	defer fake.canScaleAppMutex.RUnlock()
-	return fake.canScaleAppArgsForCall[i].appId
+func (fake *FakeScalingEngineDB) RemoveScalingCooldownReturns(result1 error) {
+	fake.RemoveScalingCooldownStub = nil
+	fake.removeScalingCooldownReturns = struct {
+		result1 error
+	}{result1}
 }
 
-func (fake *FakeScalingEngineDB) CanScaleAppReturns(result1 bool, result2 error) {
-	fake.CanScaleAppStub = nil
-	fake.canScaleAppReturns = struct {
+func (fake *FakeScalingEngineDB) ScaleWithCooldown(appId string, now int64, scale func() (int64, error)) (bool, error) {
+	fake.scaleWithCooldownMutex.Lock()
+	fake.scaleWithCooldownArgsForCall = append(fake.scaleWithCooldownArgsForCall, struct {
+		appId string
+		now   int64
+		scale func() (int64, error)
+	}{appId, now, scale})
+	fake.recordInvocation("ScaleWithCooldown", []interface{}{appId, now, scale})
+	fake.scaleWithCooldownMutex.Unlock()
+	if fake.ScaleWithCooldownStub != nil {
+		return fake.ScaleWithCooldownStub(appId, now, scale)
+	} else {
+		return fake.scaleWithCooldownReturns.result1, fake.scaleWithCooldownReturns.result2
+	}
+}
+
+func (fake *FakeScalingEngineDB) ScaleWithCooldownCallCount() int {
+	fake.scaleWithCooldownMutex.RLock()
+	defer fake.scaleWithCooldownMutex.RUnlock()
+	return len(fake.scaleWithCooldownArgsForCall)
+}
+
+func (fake *FakeScalingEngineDB) ScaleWithCooldownArgsForCall(i int) (string, int64, func() (int64, error)) {
+	fake.scaleWithCooldownMutex.RLock()
+	defer fake.scaleWithCooldownMutex.RUnlock()
+	return fake.scaleWithCooldownArgsForCall[i].appId, fake.scaleWithCooldownArgsForCall[i].now, fake.scaleWithCooldownArgsForCall[i].scale
+}
+
+func (fake *FakeScalingEngineDB) ScaleWithCooldownReturns(result1 bool, result2 error) {
+	fake.ScaleWithCooldownStub = nil
+	fake.scaleWithCooldownReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeScalingEngineDB) RestartWithCooldown(appId string, instanceIndex int, now int64, restart func() (int64, error)) (bool, error) {
+	fake.restartWithCooldownMutex.Lock()
+	fake.restartWithCooldownArgsForCall = append(fake.restartWithCooldownArgsForCall, struct {
+		appId         string
+		instanceIndex int
+		now           int64
+		restart       func() (int64, error)
+	}{appId, instanceIndex, now, restart})
+	fake.recordInvocation("RestartWithCooldown", []interface{}{appId, instanceIndex, now, restart})
+	fake.restartWithCooldownMutex.Unlock()
+	if fake.RestartWithCooldownStub != nil {
+		return fake.RestartWithCooldownStub(appId, instanceIndex, now, restart)
+	} else {
+		return fake.restartWithCooldownReturns.result1, fake.restartWithCooldownReturns.result2
+	}
+}
+
+func (fake *FakeScalingEngineDB) RestartWithCooldownCallCount() int {
+	fake.restartWithCooldownMutex.RLock()
+	defer fake.restartWithCooldownMutex.RUnlock()
+	return len(fake.restartWithCooldownArgsForCall)
+}
+
+func (fake *FakeScalingEngineDB) RestartWithCooldownArgsForCall(i int) (string, int, int64, func() (int64, error)) {
+	fake.restartWithCooldownMutex.RLock()
+	defer fake.restartWithCooldownMutex.RUnlock()
+	return fake.restartWithCooldownArgsForCall[i].appId, fake.restartWithCooldownArgsForCall[i].instanceIndex, fake.restartWithCooldownArgsForCall[i].now, fake.restartWithCooldownArgsForCall[i].restart
+}
+
+func (fake *FakeScalingEngineDB) RestartWithCooldownReturns(result1 bool, result2 error) {
+	fake.RestartWithCooldownStub = nil
+	fake.restartWithCooldownReturns = struct {
 		result1 bool
 		result2 error
 	}{result1, result2}
@@ -393,6 +540,31 @@ func (fake *FakeScalingEngineDB) RemoveActiveScheduleReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeScalingEngineDB) Ping() error {
+	fake.pingMutex.Lock()
+	fake.pingArgsForCall = append(fake.pingArgsForCall, struct{}{})
+	fake.recordInvocation("Ping", []interface{}{})
+	fake.pingMutex.Unlock()
+	if fake.PingStub != nil {
+		return fake.PingStub()
+	} else {
+		return fake.pingReturns.result1
+	}
+}
+
+func (fake *FakeScalingEngineDB) PingCallCount() int {
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	return len(fake.pingArgsForCall)
+}
+
+func (fake *FakeScalingEngineDB) PingReturns(result1 error) {
+	fake.PingStub = nil
+	fake.pingReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeScalingEngineDB) Close() error {
 	fake.closeMutex.Lock()
 	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
@@ -425,12 +597,18 @@ func (fake *FakeScalingEngineDB) Invocations() map[string][][]interface{} {
 	defer fake.saveScalingHistoryMutex.RUnlock()
 	fake.retrieveScalingHistoriesMutex.RLock()
 	defer fake.retrieveScalingHistoriesMutex.RUnlock()
+	fake.retrieveGroupScalingHistoriesMutex.RLock()
+	defer fake.retrieveGroupScalingHistoriesMutex.RUnlock()
 	fake.pruneScalingHistoriesMutex.RLock()
 	defer fake.pruneScalingHistoriesMutex.RUnlock()
 	fake.updateScalingCooldownExpireTimeMutex.RLock()
 	defer fake.updateScalingCooldownExpireTimeMutex.RUnlock()
-	fake.canScaleAppMutex.RLock()
-	defer fake.canScaleAppMutex.RUnlock()
+	fake.removeScalingCooldownMutex.RLock()
+	defer fake.removeScalingCooldownMutex.RUnlock()
+	fake.scaleWithCooldownMutex.RLock()
+	defer fake.scaleWithCooldownMutex.RUnlock()
+	fake.restartWithCooldownMutex.RLock()
+	defer fake.restartWithCooldownMutex.RUnlock()
 	fake.getActiveScheduleMutex.RLock()
 	defer fake.getActiveScheduleMutex.RUnlock()
 	fake.getActiveSchedulesMutex.RLock()
@@ -439,6 +617,8 @@ func (fake *FakeScalingEngineDB) Invocations() map[string][][]interface{} {
 	defer fake.setActiveScheduleMutex.RUnlock()
 	fake.removeActiveScheduleMutex.RLock()
 	defer fake.removeActiveScheduleMutex.RUnlock()
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
 	return fake.invocations
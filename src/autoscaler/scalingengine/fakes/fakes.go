@@ -5,3 +5,4 @@ package fakes
 //go:generate counterfeiter -o ./fake_scalingengine_db.go ../../db ScalingEngineDB
 //go:generate counterfeiter -o ./fake_scheduler_db.go ../../db SchedulerDB
 //go:generate counterfeiter -o ./fake_scalingengine.go ../ ScalingEngine
+//go:generate counterfeiter -o ./fake_lease_db.go ../../db LeaseDB
@@ -0,0 +1,36 @@
+package scalingengine
+
+import (
+	"autoscaler/metrics"
+	"autoscaler/models"
+)
+
+var scalingCalls = metrics.NewCounter(
+	"autoscaler_scalingengine_scaling_calls_total",
+	"Number of scaling calls handled by the scaling engine, by scaling type and status.",
+	"type", "status",
+)
+
+var quotaLimitedScalings = metrics.NewCounter(
+	"autoscaler_scalingengine_quota_limited_scalings_total",
+	"Number of scale-outs limited by the memory quota of the org or the space of the app.",
+	"quota",
+)
+
+var scalingTypeNames = map[models.ScalingType]string{
+	models.ScalingTypeDynamic:     "dynamic",
+	models.ScalingTypeSchedule:    "schedule",
+	models.ScalingTypeRemediation: "remediation",
+	models.ScalingTypeVertical:    "vertical",
+}
+
+var scalingStatusNames = map[models.ScalingStatus]string{
+	models.ScalingStatusSucceeded:   "succeeded",
+	models.ScalingStatusFailed:      "failed",
+	models.ScalingStatusIgnored:     "ignored",
+	models.ScalingStatusUnavailable: "unavailable",
+}
+
+func init() {
+	metrics.MustRegister(scalingCalls, quotaLimitedScalings)
+}
@@ -0,0 +1,13 @@
+package orphan_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestOrphan(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Orphan Suite")
+}
@@ -0,0 +1,125 @@
+package orphan
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+
+	"autoscaler/cf"
+	"autoscaler/db"
+)
+
+// Reconciler removes the policy, the cooldown and the active schedule of apps
+// that have been orphaned for longer than the grace period. The existence of
+// each app is checked again before it is cleaned up, and the orphan mark of an
+// app that exists is removed.
+type Reconciler struct {
+	logger      lager.Logger
+	cfClient    cf.CfClient
+	policyDB    db.PolicyDB
+	engineDB    db.ScalingEngineDB
+	interval    time.Duration
+	gracePeriod time.Duration
+	rClock      clock.Clock
+	lock        sync.Mutex
+	lastTick    time.Time
+}
+
+func NewReconciler(logger lager.Logger, cfClient cf.CfClient, policyDB db.PolicyDB, engineDB db.ScalingEngineDB,
+	interval time.Duration, gracePeriod time.Duration, rClock clock.Clock) *Reconciler {
+	return &Reconciler{
+		logger:      logger,
+		cfClient:    cfClient,
+		policyDB:    policyDB,
+		engineDB:    engineDB,
+		interval:    interval,
+		gracePeriod: gracePeriod,
+		rClock:      rClock,
+	}
+}
+
+func (r *Reconciler) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+	r.logger.Info("started", lager.Data{"interval": r.interval, "gracePeriod": r.gracePeriod})
+	r.recordTick()
+
+	timer := r.rClock.NewTimer(r.interval)
+	for {
+		select {
+		case <-signals:
+			r.logger.Info("stopped")
+			return nil
+		case <-timer.C():
+			r.reconcile()
+			r.recordTick()
+			timer.Reset(r.interval)
+		}
+	}
+}
+
+func (r *Reconciler) recordTick() {
+	r.lock.Lock()
+	r.lastTick = r.rClock.Now()
+	r.lock.Unlock()
+}
+
+func (r *Reconciler) LastTickTime() time.Time {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.lastTick
+}
+
+func (r *Reconciler) reconcile() {
+	r.logger.Info("reconciling-orphaned-apps")
+
+	appIds, err := r.policyDB.RetrieveOrphanedApps(r.rClock.Now().Add(-r.gracePeriod).UnixNano())
+	if err != nil {
+		r.logger.Error("failed-to-retrieve-orphaned-apps", err)
+		return
+	}
+
+	for _, appId := range appIds {
+		r.reconcileApp(appId)
+	}
+}
+
+func (r *Reconciler) reconcileApp(appId string) {
+	logger := r.logger.WithData(lager.Data{"appId": appId})
+
+	exists, err := r.cfClient.AppExists(appId)
+	if err != nil {
+		logger.Error("failed-to-check-app-existence", err)
+		return
+	}
+
+	if exists {
+		logger.Info("orphaned-app-exists")
+		err = r.policyDB.UnmarkAppOrphaned(appId)
+		if err != nil {
+			logger.Error("failed-to-unmark-app-orphaned", err)
+		}
+		return
+	}
+
+	err = r.engineDB.RemoveActiveSchedule(appId)
+	if err != nil {
+		logger.Error("failed-to-remove-active-schedule", err)
+		return
+	}
+
+	err = r.engineDB.RemoveScalingCooldown(appId)
+	if err != nil {
+		logger.Error("failed-to-remove-scaling-cooldown", err)
+		return
+	}
+
+	err = r.policyDB.DeletePolicy(appId)
+	if err != nil {
+		logger.Error("failed-to-delete-policy", err)
+		return
+	}
+	logger.Info("orphaned-app-removed")
+}
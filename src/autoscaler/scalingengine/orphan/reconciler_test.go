@@ -0,0 +1,138 @@
+package orphan_test
+
+import (
+	"autoscaler/scalingengine/fakes"
+	. "autoscaler/scalingengine/orphan"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"errors"
+	"os"
+	"time"
+)
+
+const (
+	TestInterval    time.Duration = 10 * time.Minute
+	TestGracePeriod time.Duration = 24 * time.Hour
+)
+
+var _ = Describe("Reconciler", func() {
+	var (
+		cfc        *fakes.FakeCfClient
+		policyDB   *fakes.FakePolicyDB
+		engineDB   *fakes.FakeScalingEngineDB
+		fclock     *fakeclock.FakeClock
+		buffer     *gbytes.Buffer
+		reconciler *Reconciler
+		signals    chan os.Signal
+	)
+
+	BeforeEach(func() {
+		cfc = &fakes.FakeCfClient{}
+		policyDB = &fakes.FakePolicyDB{}
+		engineDB = &fakes.FakeScalingEngineDB{}
+		logger := lagertest.NewTestLogger("orphan-reconciler-test")
+		buffer = logger.Buffer()
+		fclock = fakeclock.NewFakeClock(time.Now())
+		reconciler = NewReconciler(logger, cfc, policyDB, engineDB, TestInterval, TestGracePeriod, fclock)
+		signals = make(chan os.Signal)
+	})
+
+	JustBeforeEach(func() {
+		go reconciler.Run(signals, make(chan struct{}))
+	})
+
+	AfterEach(func() {
+		signals <- os.Interrupt
+	})
+
+	It("retrieves the apps orphaned for longer than the grace period with the given interval", func() {
+		Eventually(buffer).Should(gbytes.Say("started"))
+		Consistently(policyDB.RetrieveOrphanedAppsCallCount).Should(BeZero())
+
+		fclock.WaitForWatcherAndIncrement(TestInterval)
+		Eventually(policyDB.RetrieveOrphanedAppsCallCount).Should(Equal(1))
+		Expect(policyDB.RetrieveOrphanedAppsArgsForCall(0)).To(Equal(fclock.Now().Add(-TestGracePeriod).UnixNano()))
+
+		fclock.WaitForWatcherAndIncrement(TestInterval)
+		Eventually(policyDB.RetrieveOrphanedAppsCallCount).Should(Equal(2))
+		Eventually(reconciler.LastTickTime).Should(Equal(fclock.Now()))
+	})
+
+	Context("when there are orphaned apps", func() {
+		BeforeEach(func() {
+			policyDB.RetrieveOrphanedAppsReturns([]string{"an-app-id"}, nil)
+		})
+
+		Context("when the app does not exist", func() {
+			BeforeEach(func() {
+				cfc.AppExistsReturns(false, nil)
+			})
+
+			It("removes the active schedule, the cooldown and the policy of the app", func() {
+				fclock.WaitForWatcherAndIncrement(TestInterval)
+				Eventually(policyDB.DeletePolicyCallCount).Should(Equal(1))
+				Expect(policyDB.DeletePolicyArgsForCall(0)).To(Equal("an-app-id"))
+				Expect(engineDB.RemoveActiveScheduleArgsForCall(0)).To(Equal("an-app-id"))
+				Expect(engineDB.RemoveScalingCooldownArgsForCall(0)).To(Equal("an-app-id"))
+				Eventually(buffer).Should(gbytes.Say("orphaned-app-removed"))
+			})
+
+			Context("when removing the cooldown fails", func() {
+				BeforeEach(func() {
+					engineDB.RemoveScalingCooldownReturns(errors.New("an error"))
+				})
+
+				It("keeps the policy so that the app is reconciled again", func() {
+					fclock.WaitForWatcherAndIncrement(TestInterval)
+					Eventually(buffer).Should(gbytes.Say("failed-to-remove-scaling-cooldown"))
+					Expect(policyDB.DeletePolicyCallCount()).To(BeZero())
+				})
+			})
+		})
+
+		Context("when the app exists again", func() {
+			BeforeEach(func() {
+				cfc.AppExistsReturns(true, nil)
+			})
+
+			It("removes the orphan mark and keeps the app data", func() {
+				fclock.WaitForWatcherAndIncrement(TestInterval)
+				Eventually(policyDB.UnmarkAppOrphanedCallCount).Should(Equal(1))
+				Expect(policyDB.UnmarkAppOrphanedArgsForCall(0)).To(Equal("an-app-id"))
+				Expect(policyDB.DeletePolicyCallCount()).To(BeZero())
+				Expect(engineDB.RemoveScalingCooldownCallCount()).To(BeZero())
+				Expect(engineDB.RemoveActiveScheduleCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when checking the existence of the app fails", func() {
+			BeforeEach(func() {
+				cfc.AppExistsReturns(false, errors.New("an error"))
+			})
+
+			It("leaves the app alone", func() {
+				fclock.WaitForWatcherAndIncrement(TestInterval)
+				Eventually(buffer).Should(gbytes.Say("failed-to-check-app-existence"))
+				Expect(policyDB.UnmarkAppOrphanedCallCount()).To(BeZero())
+				Expect(policyDB.DeletePolicyCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Context("when retrieving the orphaned apps fails", func() {
+		BeforeEach(func() {
+			policyDB.RetrieveOrphanedAppsReturns(nil, errors.New("an error"))
+		})
+
+		It("logs the error", func() {
+			fclock.WaitForWatcherAndIncrement(TestInterval)
+			Eventually(buffer).Should(gbytes.Say("failed-to-retrieve-orphaned-apps"))
+			Expect(cfc.AppExistsCallCount()).To(BeZero())
+		})
+	})
+})
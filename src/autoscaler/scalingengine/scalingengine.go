@@ -5,9 +5,12 @@ import (
 	"autoscaler/db"
 	"autoscaler/models"
 
+	"database/sql"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
@@ -15,6 +18,8 @@ import (
 
 type ScalingEngine interface {
 	Scale(appId string, trigger *models.Trigger) (int, error)
+	RestartInstance(appId string, instanceIndex int, trigger *models.Trigger) error
+	Resize(appId string, trigger *models.Trigger) error
 	ComputeNewInstances(currentInstances int, adjustment string) (int, error)
 	SetActiveSchedule(appId string, schedule *models.ActiveSchedule) error
 	RemoveActiveSchedule(appId string, scheduleId string) error
@@ -25,7 +30,9 @@ type scalingEngine struct {
 	cfClient        cf.CfClient
 	policyDB        db.PolicyDB
 	scalingEngineDB db.ScalingEngineDB
-	appLock         *StripedLock
+	appLock         *AppLock
+	maxCrashedRatio float64
+	restartTime     time.Duration
 	clock           clock.Clock
 }
 
@@ -36,22 +43,40 @@ func (ase *ActiveScheduleNotFoundError) Error() string {
 	return fmt.Sprintf("active schedule not found")
 }
 
-func NewScalingEngine(logger lager.Logger, cfClient cf.CfClient, policyDB db.PolicyDB, scalingEngineDB db.ScalingEngineDB, clock clock.Clock) ScalingEngine {
+func NewScalingEngine(logger lager.Logger, cfClient cf.CfClient, policyDB db.PolicyDB, scalingEngineDB db.ScalingEngineDB, appLock *AppLock,
+	maxCrashedRatio float64, restartTime time.Duration, clock clock.Clock) ScalingEngine {
 	return &scalingEngine{
 		logger:          logger.Session("scale"),
 		cfClient:        cfClient,
 		policyDB:        policyDB,
 		scalingEngineDB: scalingEngineDB,
-		appLock:         NewStripedLock(32),
+		appLock:         appLock,
+		maxCrashedRatio: maxCrashedRatio,
+		restartTime:     restartTime,
 		clock:           clock,
 	}
 }
 
+// Scale applies the adjustment of the trigger to the instances of the app. When
+// the app is a member of an app group, every member of the group is scaled.
 func (s *scalingEngine) Scale(appId string, trigger *models.Trigger) (int, error) {
 	logger := s.logger.WithData(lager.Data{"appId": appId})
 
-	s.appLock.GetLock(appId).Lock()
-	defer s.appLock.GetLock(appId).Unlock()
+	group, err := s.policyDB.GetAppGroupOfApp(appId)
+	if err != nil {
+		logger.Error("failed-to-get-app-group", err)
+		return -1, err
+	}
+	if group != nil {
+		return s.scaleGroup(logger, appId, group, trigger)
+	}
+
+	err = s.appLock.Lock(appId)
+	if err != nil {
+		logger.Error("failed-to-lock-app", err)
+		return -1, err
+	}
+	defer s.appLock.Unlock(appId)
 
 	now := s.clock.Now()
 	history := &models.AppScalingHistory{
@@ -63,39 +88,693 @@ func (s *scalingEngine) Scale(appId string, trigger *models.Trigger) (int, error
 		Reason:       getDynamicScalingReason(trigger),
 	}
 
-	defer s.scalingEngineDB.SaveScalingHistory(history)
+	defer s.saveScalingHistory(history)
 
-	instances, err := s.cfClient.GetAppInstances(appId)
+	policy, err := s.policyDB.GetAppPolicy(appId)
 	if err != nil {
-		logger.Error("failed-to-get-app-instances", err)
+		logger.Error("failed-get-app-policy", err)
 		history.Status = models.ScalingStatusFailed
-		history.Error = "failed to get app instances"
+		history.Error = "failed to get scaling policy"
+		return -1, err
+	}
+	history.PolicyRevision = policy.Revision
+	processType := policy.GetProcessType()
+
+	instances, err := s.cfClient.GetAppInstances(appId, processType)
+	if err != nil {
+		logger.Error("failed-to-get-app-instances", err)
+		s.handleCfError(logger, history, err, "failed to get app instances")
 		return -1, err
 	}
 	history.OldInstances = instances
 
-	ok, err := s.scalingEngineDB.CanScaleApp(appId)
+	status, err := s.cfClient.GetAppStatus(appId, processType)
+	if err != nil {
+		logger.Error("failed-to-get-app-status", err)
+		s.handleCfError(logger, history, err, "failed to get app status")
+		return -1, err
+	}
+	if message := s.checkAppStatus(status); message != "" {
+		logger.Info("app-not-scalable", lager.Data{"status": status, "message": message})
+		history.Status = models.ScalingStatusIgnored
+		history.NewInstances = instances
+		history.Message = message
+		return instances, nil
+	}
+
+	cooldownChecked := false
+	var newInstances int
+	var scaleErr error
+	canScale, err := s.scalingEngineDB.ScaleWithCooldown(appId, now.UnixNano(), func() (int64, error) {
+		cooldownChecked = true
+		newInstances, scaleErr = s.scaleWithinLimits(logger, appId, policy, instances, trigger.Adjustment, history)
+		if scaleErr != nil || newInstances == instances {
+			return 0, scaleErr
+		}
+		return now.Add(trigger.CoolDown()).UnixNano(), nil
+	})
+	if scaleErr != nil {
+		return -1, scaleErr
+	}
 	if err != nil {
+		if cooldownChecked {
+			logger.Error("failed-to-update-scaling-cool-down-expire-time", err, lager.Data{"newInstances": newInstances})
+			return newInstances, nil
+		}
 		logger.Error("failed-check-cooldown", err)
 		history.Status = models.ScalingStatusFailed
 		history.Error = "failed to check app cooldown setting"
 		return -1, err
 	}
-	if !ok {
+	if !canScale {
 		history.Status = models.ScalingStatusIgnored
 		history.NewInstances = instances
 		history.Message = "app in cooldown period"
 		return instances, nil
 	}
 
-	newInstances, err := s.ComputeNewInstances(instances, trigger.Adjustment)
+	return newInstances, nil
+}
+
+// groupMember is a member of an app group being scaled, with its own policy,
+// instances and history.
+type groupMember struct {
+	*models.AppGroupMember
+	logger       lager.Logger
+	policy       *models.ScalingPolicy
+	history      *models.AppScalingHistory
+	instances    int
+	newInstances int
+	scaled       bool
+	skipped      bool
+}
+
+// scaleGroup applies the adjustment of the trigger to the app, then scales the
+// other members of its group by the same factor relative to their ratios. The
+// lock of the group is held, then the locks of the members in the order of
+// their app ids, so that no member is scaled on its own meanwhile. Every member
+// is limited, cooled down and recorded in a history of its own. Other members
+// without a scaling policy or whose app no longer exists are skipped.
+func (s *scalingEngine) scaleGroup(logger lager.Logger, appId string, group *models.AppGroup, trigger *models.Trigger) (int, error) {
+	logger = logger.WithData(lager.Data{"groupId": group.GroupId})
+
+	appIds := make([]string, 0, len(group.Members))
+	for _, m := range group.Members {
+		appIds = append(appIds, m.AppId)
+	}
+	sort.Strings(appIds)
+
+	lockIds := append([]string{groupLockPrefix + group.GroupId}, appIds...)
+	err := s.appLock.LockAll(lockIds)
+	if err != nil {
+		logger.Error("failed-to-lock-app-group", err)
+		return -1, err
+	}
+	defer s.appLock.UnlockAll(lockIds)
+
+	now := s.clock.Now()
+	var member *groupMember
+	members := make([]*groupMember, 0, len(appIds))
+	for _, id := range appIds {
+		m := &groupMember{
+			AppGroupMember: group.Member(id),
+			logger:         logger.WithData(lager.Data{"member": id}),
+			instances:      -1,
+			newInstances:   -1,
+			history: &models.AppScalingHistory{
+				AppId:        id,
+				Timestamp:    now.UnixNano(),
+				ScalingType:  models.ScalingTypeDynamic,
+				OldInstances: -1,
+				NewInstances: -1,
+				Reason:       getDynamicScalingReason(trigger),
+				GroupId:      group.GroupId,
+			},
+		}
+		defer s.saveScalingHistory(m.history)
+		members = append(members, m)
+		if id == appId {
+			member = m
+		}
+	}
+
+	scalable := make([]*groupMember, 0, len(members))
+	for _, m := range members {
+		m.policy, err = s.policyDB.GetAppPolicy(m.AppId)
+		if err == sql.ErrNoRows && m != member {
+			m.logger.Info("skip-member-without-policy")
+			skipGroupMember(m, "app has no scaling policy")
+			continue
+		}
+		if err != nil {
+			m.logger.Error("failed-get-app-policy", err)
+			s.failGroup(members, m, err, "failed to get scaling policy")
+			return -1, err
+		}
+		m.history.PolicyRevision = m.policy.Revision
+
+		m.instances, err = s.cfClient.GetAppInstances(m.AppId, m.GetProcessType())
+		if isNotFound(err) && m != member {
+			m.logger.Info("skip-member-not-found", lager.Data{"error": err.Error()})
+			skipGroupMember(m, "app or process does not exist")
+			continue
+		}
+		if err != nil {
+			m.logger.Error("failed-to-get-app-instances", err)
+			s.failGroup(members, m, err, "failed to get app instances")
+			return -1, err
+		}
+		m.history.OldInstances = m.instances
+		scalable = append(scalable, m)
+	}
+	members = scalable
+
+	for _, m := range members {
+		status, err := s.cfClient.GetAppStatus(m.AppId, m.GetProcessType())
+		if err != nil {
+			m.logger.Error("failed-to-get-app-status", err)
+			s.failGroup(members, m, err, "failed to get app status")
+			return -1, err
+		}
+		if message := s.checkAppStatus(status); message != "" {
+			m.logger.Info("app-not-scalable", lager.Data{"status": status, "message": message})
+			ignoreGroup(members, fmt.Sprintf("%s: %s", m.AppId, message))
+			return member.instances, nil
+		}
+	}
+
+	err = s.computeGroupInstances(logger, members, member, trigger.Adjustment)
+	if err != nil {
+		return -1, err
+	}
+
+	changed := []*groupMember{}
+	for _, m := range members {
+		m.history.NewInstances = m.newInstances
+		if m.newInstances != m.instances {
+			changed = append(changed, m)
+		} else {
+			m.history.Status = models.ScalingStatusIgnored
+		}
+	}
+	if len(changed) == 0 {
+		return member.instances, nil
+	}
+
+	scaleCalled := false
+	var scaleErr error
+	inCooldown, err := s.scaleWithGroupCooldown(changed, now.UnixNano(), now.Add(trigger.CoolDown()).UnixNano(), func() {
+		scaleCalled = true
+		scaleErr = s.setGroupInstances(changed)
+	})
+	if scaleErr != nil {
+		return -1, scaleErr
+	}
+	if err != nil {
+		if scaleCalled {
+			logger.Error("failed-to-update-scaling-cool-down-expire-time", err, lager.Data{"newInstances": member.newInstances})
+			return member.newInstances, nil
+		}
+		logger.Error("failed-check-cooldown", err)
+		for _, m := range members {
+			m.history.Status = models.ScalingStatusFailed
+			m.history.Error = "failed to check app cooldown setting"
+		}
+		return -1, err
+	}
+	if inCooldown != "" {
+		ignoreGroup(members, fmt.Sprintf("%s: app in cooldown period", inCooldown))
+		return member.instances, nil
+	}
+
+	return member.newInstances, nil
+}
+
+// computeGroupInstances sets the new instances of every member of the group.
+// The adjustment is applied to the member whose trigger fired, and the other
+// members follow in proportion to their ratios. Each member is kept within the
+// limits of the group and then within those of its own policy, active schedule
+// and quotas, like an app scaled alone.
+func (s *scalingEngine) computeGroupInstances(logger lager.Logger, members []*groupMember, member *groupMember, adjustment string) error {
+	newInstances, err := s.ComputeNewInstances(member.instances, adjustment)
 	if err != nil {
-		logger.Error("failed-compute-new-instance", err, lager.Data{"instances": instances, "adjustment": trigger.Adjustment})
+		logger.Error("failed-compute-new-instance", err, lager.Data{"instances": member.instances, "adjustment": adjustment})
+		s.failGroup(members, member, err, "failed to compute new app instances")
+		return err
+	}
+	member.newInstances, err = s.limitGroupMemberInstances(member, newInstances)
+	if err != nil {
+		s.failGroup(members, member, err, "failed to get active schedule")
+		return err
+	}
+
+	factor := float64(member.newInstances) / float64(member.Ratio)
+	for _, m := range members {
+		if m == member {
+			continue
+		}
+		m.newInstances, err = s.limitGroupMemberInstances(m, int(factor*float64(m.Ratio)+0.5))
+		if err != nil {
+			s.failGroup(members, m, err, "failed to get active schedule")
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *scalingEngine) limitGroupMemberInstances(m *groupMember, newInstances int) (int, error) {
+	if newInstances < m.InstanceMin {
+		newInstances = m.InstanceMin
+	} else if newInstances > m.InstanceMax {
+		newInstances = m.InstanceMax
+	}
+	return s.limitInstances(m.logger, m.AppId, m.GetProcessType(), m.policy, m.instances, newInstances, m.history)
+}
+
+// scaleWithGroupCooldown claims the scaling cooldown of each member in turn and
+// calls scale once all of them are claimed. It returns the id of the first
+// member found in its cooldown period, in which case scale is not called. The
+// cooldown of a member is extended to expireAt when scale changed its instances,
+// and is restored otherwise.
+func (s *scalingEngine) scaleWithGroupCooldown(members []*groupMember, now int64, expireAt int64, scale func()) (string, error) {
+	if len(members) == 0 {
+		scale()
+		return "", nil
+	}
+
+	m := members[0]
+	inCooldown := ""
+	var innerErr error
+	claimed, err := s.scalingEngineDB.ScaleWithCooldown(m.AppId, now, func() (int64, error) {
+		inCooldown, innerErr = s.scaleWithGroupCooldown(members[1:], now, expireAt, scale)
+		if !m.scaled {
+			return 0, nil
+		}
+		return expireAt, nil
+	})
+	if innerErr != nil {
+		return "", innerErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if !claimed {
+		return m.AppId, nil
+	}
+	return inCooldown, nil
+}
+
+// setGroupInstances sets the new instances of the members in turn. It stops at
+// the first member that cannot be scaled; the members scaled before keep their
+// new instances and succeeded histories, and the histories of the others report
+// the failed member.
+func (s *scalingEngine) setGroupInstances(members []*groupMember) error {
+	for i, m := range members {
+		err := s.cfClient.SetAppInstances(m.AppId, m.GetProcessType(), m.newInstances)
+		if err != nil {
+			m.logger.Error("failed-to-set-app-instances", err, lager.Data{"newInstances": m.newInstances})
+			s.handleCfError(m.logger, m.history, err, "failed to set app instances")
+			for _, o := range members[i+1:] {
+				handleGroupCfError(o.history, err, fmt.Sprintf("%s: failed to set app instances", m.AppId))
+				o.history.NewInstances = o.instances
+			}
+			return err
+		}
+		m.scaled = true
+		m.history.Status = models.ScalingStatusSucceeded
+	}
+	return nil
+}
+
+// failGroup records the error met on the member failed in the histories of
+// all the members. Only the member that failed is handled like an app scaled
+// alone, which may mark it orphaned.
+func (s *scalingEngine) failGroup(members []*groupMember, failed *groupMember, err error, message string) {
+	for _, m := range members {
+		if m.skipped {
+			continue
+		}
+		if m == failed {
+			s.handleCfError(m.logger, m.history, err, message)
+			continue
+		}
+		handleGroupCfError(m.history, err, fmt.Sprintf("%s: %s", failed.AppId, message))
+	}
+}
+
+func ignoreGroup(members []*groupMember, message string) {
+	for _, m := range members {
+		m.history.Status = models.ScalingStatusIgnored
+		m.history.NewInstances = m.instances
+		m.history.Message = message
+	}
+}
+
+// skipGroupMember leaves the member out of the scaling of its group and records
+// why in its history.
+func skipGroupMember(m *groupMember, message string) {
+	m.skipped = true
+	m.history.Status = models.ScalingStatusIgnored
+	m.history.Message = message
+}
+
+func isNotFound(err error) bool {
+	switch err.(type) {
+	case *cf.AppNotFoundError, *cf.ProcessNotFoundError:
+		return true
+	}
+	return false
+}
+
+// handleGroupCfError records in the history of a member a cloud controller
+// error met on another member of the group. Unlike handleCfError it does not
+// mark the app orphaned.
+func handleGroupCfError(history *models.AppScalingHistory, err error, message string) {
+	if _, ok := err.(*cf.CircuitOpenError); ok {
+		history.Status = models.ScalingStatusUnavailable
+		history.Error = "cloud controller is unavailable"
+		return
+	}
+	history.Status = models.ScalingStatusFailed
+	history.Error = message
+}
+
+// RestartInstance restarts the instance of the app at instanceIndex unless the
+// instance was restarted within the cooldown of the trigger. The number of
+// instances of the app is left unchanged.
+func (s *scalingEngine) RestartInstance(appId string, instanceIndex int, trigger *models.Trigger) error {
+	logger := s.logger.WithData(lager.Data{"appId": appId, "instanceIndex": instanceIndex})
+
+	err := s.appLock.Lock(appId)
+	if err != nil {
+		logger.Error("failed-to-lock-app", err)
+		return err
+	}
+	defer s.appLock.Unlock(appId)
+
+	now := s.clock.Now()
+	history := &models.AppScalingHistory{
+		AppId:        appId,
+		Timestamp:    now.UnixNano(),
+		ScalingType:  models.ScalingTypeRemediation,
+		OldInstances: -1,
+		NewInstances: -1,
+		Reason:       getRemediationReason(instanceIndex, trigger),
+	}
+
+	defer s.saveScalingHistory(history)
+
+	policy, err := s.policyDB.GetAppPolicy(appId)
+	if err != nil {
+		logger.Error("failed-get-app-policy", err)
+		history.Status = models.ScalingStatusFailed
+		history.Error = "failed to get scaling policy"
+		return err
+	}
+	history.PolicyRevision = policy.Revision
+	processType := policy.GetProcessType()
+
+	instances, err := s.cfClient.GetAppInstances(appId, processType)
+	if err != nil {
+		logger.Error("failed-to-get-app-instances", err)
+		s.handleCfError(logger, history, err, "failed to get app instances")
+		return err
+	}
+	history.OldInstances = instances
+	history.NewInstances = instances
+
+	if instanceIndex < 0 || instanceIndex >= instances {
+		logger.Info("instance-not-found", lager.Data{"instances": instances})
+		history.Status = models.ScalingStatusIgnored
+		history.Message = fmt.Sprintf("instance %d does not exist", instanceIndex)
+		return nil
+	}
+
+	status, err := s.cfClient.GetAppStatus(appId, processType)
+	if err != nil {
+		logger.Error("failed-to-get-app-status", err)
+		s.handleCfError(logger, history, err, "failed to get app status")
+		return err
+	}
+	if status.State == models.AppStateStopped || status.Staging {
+		message := s.checkAppStatus(status)
+		logger.Info("app-not-restartable", lager.Data{"status": status, "message": message})
+		history.Status = models.ScalingStatusIgnored
+		history.Message = message
+		return nil
+	}
+
+	cooldownChecked := false
+	var restartErr error
+	canRestart, err := s.scalingEngineDB.RestartWithCooldown(appId, instanceIndex, now.UnixNano(), func() (int64, error) {
+		cooldownChecked = true
+		restartErr = s.cfClient.RestartAppInstance(appId, processType, instanceIndex)
+		if restartErr != nil {
+			logger.Error("failed-to-restart-app-instance", restartErr)
+			s.handleCfError(logger, history, restartErr, "failed to restart app instance")
+			return 0, restartErr
+		}
+		history.Status = models.ScalingStatusSucceeded
+		return now.Add(trigger.CoolDown()).UnixNano(), nil
+	})
+	if restartErr != nil {
+		return restartErr
+	}
+	if err != nil {
+		if cooldownChecked {
+			logger.Error("failed-to-update-restart-cool-down-expire-time", err)
+			return nil
+		}
+		logger.Error("failed-check-cooldown", err)
+		history.Status = models.ScalingStatusFailed
+		history.Error = "failed to check instance cooldown setting"
+		return err
+	}
+	if !canRestart {
+		history.Status = models.ScalingStatusIgnored
+		history.Message = "instance in cooldown period"
+		return nil
+	}
+
+	return nil
+}
+
+// Resize changes the memory and disk limits of each instance of the app by the
+// adjustments of the trigger, within the bounds of the policy. The cloud
+// controller restarts the instances to apply the new limits, so the app cooldown
+// is extended by the restart time of all the instances.
+func (s *scalingEngine) Resize(appId string, trigger *models.Trigger) error {
+	logger := s.logger.WithData(lager.Data{"appId": appId})
+
+	err := s.appLock.Lock(appId)
+	if err != nil {
+		logger.Error("failed-to-lock-app", err)
+		return err
+	}
+	defer s.appLock.Unlock(appId)
+
+	now := s.clock.Now()
+	history := &models.AppScalingHistory{
+		AppId:        appId,
+		Timestamp:    now.UnixNano(),
+		ScalingType:  models.ScalingTypeVertical,
+		OldInstances: -1,
+		NewInstances: -1,
+		Reason:       getVerticalScalingReason(trigger),
+	}
+
+	defer s.saveScalingHistory(history)
+
+	policy, err := s.policyDB.GetAppPolicy(appId)
+	if err != nil {
+		logger.Error("failed-get-app-policy", err)
+		history.Status = models.ScalingStatusFailed
+		history.Error = "failed to get scaling policy"
+		return err
+	}
+	history.PolicyRevision = policy.Revision
+	processType := policy.GetProcessType()
+
+	instances, err := s.cfClient.GetAppInstances(appId, processType)
+	if err != nil {
+		logger.Error("failed-to-get-app-instances", err)
+		s.handleCfError(logger, history, err, "failed to get app instances")
+		return err
+	}
+	history.OldInstances = instances
+	history.NewInstances = instances
+
+	limits, err := s.cfClient.GetAppLimits(appId, processType)
+	if err != nil {
+		logger.Error("failed-to-get-app-limits", err)
+		s.handleCfError(logger, history, err, "failed to get app limits")
+		return err
+	}
+	history.OldMemoryMb, history.NewMemoryMb = limits.MemoryMb, limits.MemoryMb
+	history.OldDiskMb, history.NewDiskMb = limits.DiskMb, limits.DiskMb
+
+	status, err := s.cfClient.GetAppStatus(appId, processType)
+	if err != nil {
+		logger.Error("failed-to-get-app-status", err)
+		s.handleCfError(logger, history, err, "failed to get app status")
+		return err
+	}
+	if message := s.checkAppStatus(status); message != "" {
+		logger.Info("app-not-resizable", lager.Data{"status": status, "message": message})
+		history.Status = models.ScalingStatusIgnored
+		history.Message = message
+		return nil
+	}
+
+	newLimits, err := s.computeNewLimits(logger, policy, limits, trigger, history)
+	if err != nil {
+		return err
+	}
+	if newLimits == limits {
+		history.Status = models.ScalingStatusIgnored
+		return nil
+	}
+
+	cooldownChecked := false
+	var resizeErr error
+	canResize, err := s.scalingEngineDB.ScaleWithCooldown(appId, now.UnixNano(), func() (int64, error) {
+		cooldownChecked = true
+		resizeErr = s.cfClient.SetAppLimits(appId, processType, newLimits)
+		if resizeErr != nil {
+			logger.Error("failed-to-set-app-limits", resizeErr, lager.Data{"newLimits": newLimits})
+			s.handleCfError(logger, history, resizeErr, "failed to set app limits")
+			return 0, resizeErr
+		}
+		history.Status = models.ScalingStatusSucceeded
+		history.NewMemoryMb = newLimits.MemoryMb
+		history.NewDiskMb = newLimits.DiskMb
+		return now.Add(trigger.CoolDown() + time.Duration(instances)*s.restartTime).UnixNano(), nil
+	})
+	if resizeErr != nil {
+		return resizeErr
+	}
+	if err != nil {
+		if cooldownChecked {
+			logger.Error("failed-to-update-scaling-cool-down-expire-time", err, lager.Data{"newLimits": newLimits})
+			return nil
+		}
+		logger.Error("failed-check-cooldown", err)
+		history.Status = models.ScalingStatusFailed
+		history.Error = "failed to check app cooldown setting"
+		return err
+	}
+	if !canResize {
+		history.Status = models.ScalingStatusIgnored
+		history.NewMemoryMb = limits.MemoryMb
+		history.NewDiskMb = limits.DiskMb
+		history.Message = "app in cooldown period"
+		return nil
+	}
+
+	return nil
+}
+
+// computeNewLimits applies the memory and disk adjustments of the trigger to
+// the limits, keeping them within the bounds of the policy. A limit without an
+// adjustment is left unchanged, a limit without a max bound is not changed.
+func (s *scalingEngine) computeNewLimits(logger lager.Logger, policy *models.ScalingPolicy, limits models.InstanceLimits,
+	trigger *models.Trigger, history *models.AppScalingHistory) (models.InstanceLimits, error) {
+	newLimits := limits
+	messages := []string{}
+
+	for _, l := range []struct {
+		name       string
+		adjustment string
+		min, max   int
+		limit      *int
+	}{
+		{"memory", trigger.MemoryAdjustment, policy.InstanceMemoryMin, policy.InstanceMemoryMax, &newLimits.MemoryMb},
+		{"disk", trigger.DiskAdjustment, policy.InstanceDiskMin, policy.InstanceDiskMax, &newLimits.DiskMb},
+	} {
+		if l.adjustment == "" {
+			continue
+		}
+		if l.max <= 0 {
+			messages = append(messages, fmt.Sprintf("no max %s in policy", l.name))
+			continue
+		}
+
+		newLimit, err := s.ComputeNewInstances(*l.limit, l.adjustment)
+		if err != nil {
+			logger.Error("failed-compute-new-limit", err, lager.Data{"limit": l.name, "adjustment": l.adjustment})
+			history.Status = models.ScalingStatusFailed
+			history.Error = fmt.Sprintf("failed to compute new %s limit", l.name)
+			return limits, err
+		}
+		if newLimit < l.min {
+			newLimit = l.min
+			messages = append(messages, fmt.Sprintf("limited by min %s %d MB", l.name, l.min))
+		} else if newLimit > l.max {
+			newLimit = l.max
+			messages = append(messages, fmt.Sprintf("limited by max %s %d MB", l.name, l.max))
+		}
+		*l.limit = newLimit
+	}
+
+	history.Message = strings.Join(messages, ", ")
+	return newLimits, nil
+}
+
+// checkAppStatus returns why the app should not be scaled, or an empty string
+// when it can be scaled. Scaling an app that is stopped, staging or mostly
+// crashing only wastes quota.
+func (s *scalingEngine) checkAppStatus(status models.AppStatus) string {
+	if status.State == models.AppStateStopped {
+		return "app is stopped"
+	}
+	if status.Staging {
+		return "app is staging"
+	}
+	if status.CrashedInstances > 0 && float64(status.CrashedInstances) > s.maxCrashedRatio*float64(status.Instances) {
+		return fmt.Sprintf("%d of %d instances are crashed", status.CrashedInstances, status.Instances)
+	}
+	return ""
+}
+
+// scaleWithinLimits applies the adjustment to the current instances, limited by
+// the active schedule or the policy of the app, and records the outcome in history.
+func (s *scalingEngine) scaleWithinLimits(logger lager.Logger, appId string, policy *models.ScalingPolicy, instances int,
+	adjustment string, history *models.AppScalingHistory) (int, error) {
+	newInstances, err := s.ComputeNewInstances(instances, adjustment)
+	if err != nil {
+		logger.Error("failed-compute-new-instance", err, lager.Data{"instances": instances, "adjustment": adjustment})
 		history.Status = models.ScalingStatusFailed
 		history.Error = "failed to compute new app instances"
 		return -1, err
 	}
 
+	newInstances, err = s.limitInstances(logger, appId, policy.GetProcessType(), policy, instances, newInstances, history)
+	if err != nil {
+		return -1, err
+	}
+	history.NewInstances = newInstances
+
+	if newInstances == instances {
+		history.Status = models.ScalingStatusIgnored
+		return newInstances, nil
+	}
+
+	err = s.cfClient.SetAppInstances(appId, policy.GetProcessType(), newInstances)
+	if err != nil {
+		logger.Error("failed-to-set-app-instances", err, lager.Data{"newInstances": newInstances})
+		s.handleCfError(logger, history, err, "failed to set app instances")
+		return -1, err
+	}
+
+	history.Status = models.ScalingStatusSucceeded
+	return newInstances, nil
+}
+
+// limitInstances keeps the new instances within the limits of the active
+// schedule of the app, or of its policy when no schedule is active, and limits
+// a scale-out by the quotas.
+func (s *scalingEngine) limitInstances(logger lager.Logger, appId string, processType string, policy *models.ScalingPolicy,
+	instances int, newInstances int, history *models.AppScalingHistory) (int, error) {
 	schedule, err := s.scalingEngineDB.GetActiveSchedule(appId)
 	if err != nil {
 		logger.Error("failed-get-active-schedule", err)
@@ -110,17 +789,8 @@ func (s *scalingEngine) Scale(appId string, trigger *models.Trigger) (int, error
 		instanceMin = schedule.InstanceMin
 		instanceMax = schedule.InstanceMax
 	} else {
-		var policy *models.ScalingPolicy
-		policy, err = s.policyDB.GetAppPolicy(appId)
-		if err != nil {
-			logger.Error("failed-get-app-policy", err)
-			history.Status = models.ScalingStatusFailed
-			history.Error = "failed to get scaling policy"
-			return -1, err
-		} else {
-			instanceMin = policy.InstanceMin
-			instanceMax = policy.InstanceMax
-		}
+		instanceMin = policy.InstanceMin
+		instanceMax = policy.InstanceMax
 	}
 
 	if newInstances < instanceMin {
@@ -130,29 +800,53 @@ func (s *scalingEngine) Scale(appId string, trigger *models.Trigger) (int, error
 		newInstances = instanceMax
 		history.Message = fmt.Sprintf("limited by max instances %d", instanceMax)
 	}
-	history.NewInstances = newInstances
-
-	if newInstances == instances {
-		history.Status = models.ScalingStatusIgnored
-		return newInstances, nil
+	if newInstances > instances {
+		newInstances = s.limitByQuota(logger, appId, processType, instances, newInstances, history)
 	}
+	return newInstances, nil
+}
 
-	err = s.cfClient.SetAppInstances(appId, newInstances)
+// limitByQuota limits a scale-out to the instances that fit into the memory
+// quotas of the org and the space of the app. The scale-out is not limited when
+// the quotas cannot be retrieved; the cloud controller still enforces them.
+func (s *scalingEngine) limitByQuota(logger lager.Logger, appId string, processType string, instances int,
+	newInstances int, history *models.AppScalingHistory) int {
+	quotas, err := s.cfClient.GetAppQuotas(appId, processType)
 	if err != nil {
-		logger.Error("failed-to-set-app-instances", err, lager.Data{"newInstances": newInstances})
-		history.Status = models.ScalingStatusFailed
-		history.Error = "failed to set app instances"
-		return -1, err
+		logger.Error("failed-to-get-app-quotas", err)
+		return newInstances
+	}
+	if quotas.InstanceMemory <= 0 {
+		return newInstances
 	}
 
-	history.Status = models.ScalingStatusSucceeded
-
-	err = s.scalingEngineDB.UpdateScalingCooldownExpireTime(appId, now.Add(trigger.CoolDown()).UnixNano())
-	if err != nil {
-		logger.Error("failed-to-update-scaling-cool-down-expire-time", err, lager.Data{"newInstances": newInstances})
+	limitedBy := ""
+	maxInstances := newInstances
+	for _, q := range []struct {
+		name  string
+		quota models.MemoryQuota
+	}{{"org", quotas.Org}, {"space", quotas.Space}} {
+		if q.quota.Limit == models.UnlimitedMemory {
+			continue
+		}
+		allowed := instances + (q.quota.Limit-q.quota.Used)/quotas.InstanceMemory
+		if allowed < maxInstances {
+			maxInstances = allowed
+			limitedBy = q.name
+		}
+	}
+	if limitedBy == "" {
+		return newInstances
 	}
 
-	return newInstances, nil
+	if maxInstances < instances {
+		maxInstances = instances
+	}
+	logger.Info("limited-by-quota", lager.Data{"quota": limitedBy, "quotas": quotas, "instances": instances,
+		"newInstances": newInstances, "maxInstances": maxInstances})
+	quotaLimitedScalings.Inc(limitedBy)
+	history.Message = fmt.Sprintf("limited by quota of the %s", limitedBy)
+	return maxInstances
 }
 
 func (s *scalingEngine) ComputeNewInstances(currentInstances int, adjustment string) (int, error) {
@@ -179,8 +873,12 @@ func (s *scalingEngine) ComputeNewInstances(currentInstances int, adjustment str
 func (s *scalingEngine) SetActiveSchedule(appId string, schedule *models.ActiveSchedule) error {
 	logger := s.logger.WithData(lager.Data{"appId": appId, "schedule": schedule})
 
-	s.appLock.GetLock(appId).Lock()
-	defer s.appLock.GetLock(appId).Unlock()
+	err := s.appLock.Lock(appId)
+	if err != nil {
+		logger.Error("failed-to-lock-app", err)
+		return err
+	}
+	defer s.appLock.Unlock(appId)
 
 	currentSchedule, err := s.scalingEngineDB.GetActiveSchedule(appId)
 	if err != nil {
@@ -212,13 +910,21 @@ func (s *scalingEngine) SetActiveSchedule(appId string, schedule *models.ActiveS
 		NewInstances: -1,
 		Reason:       getScheduledScalingReason(schedule),
 	}
-	defer s.scalingEngineDB.SaveScalingHistory(history)
+	defer s.saveScalingHistory(history)
 
-	instances, err := s.cfClient.GetAppInstances(appId)
+	policy, err := s.policyDB.GetAppPolicy(appId)
 	if err != nil {
-		logger.Error("failed-to-get-app-instances", err)
+		logger.Error("failed-to-get-app-policy", err)
 		history.Status = models.ScalingStatusFailed
-		history.Error = "failed to get app instances"
+		history.Error = "failed to get app policy"
+		return err
+	}
+	history.PolicyRevision = policy.Revision
+
+	instances, err := s.cfClient.GetAppInstances(appId, policy.GetProcessType())
+	if err != nil {
+		logger.Error("failed-to-get-app-instances", err)
+		s.handleCfError(logger, history, err, "failed to get app instances")
 		return err
 	}
 	history.OldInstances = instances
@@ -236,6 +942,9 @@ func (s *scalingEngine) SetActiveSchedule(appId string, schedule *models.ActiveS
 		newInstances = schedule.InstanceMax
 		history.Message = fmt.Sprintf("limited by max instances %d", instanceMin)
 	}
+	if newInstances > instances {
+		newInstances = s.limitByQuota(logger, appId, policy.GetProcessType(), instances, newInstances, history)
+	}
 
 	history.NewInstances = newInstances
 
@@ -244,11 +953,10 @@ func (s *scalingEngine) SetActiveSchedule(appId string, schedule *models.ActiveS
 		return nil
 	}
 
-	err = s.cfClient.SetAppInstances(appId, newInstances)
+	err = s.cfClient.SetAppInstances(appId, policy.GetProcessType(), newInstances)
 	if err != nil {
 		logger.Error("failed-to-set-app-instances", err)
-		history.Status = models.ScalingStatusFailed
-		history.Error = "failed to set app instances"
+		s.handleCfError(logger, history, err, "failed to set app instances")
 		return err
 	}
 	history.Status = models.ScalingStatusSucceeded
@@ -258,8 +966,12 @@ func (s *scalingEngine) SetActiveSchedule(appId string, schedule *models.ActiveS
 func (s *scalingEngine) RemoveActiveSchedule(appId string, scheduleId string) error {
 	logger := s.logger.WithData(lager.Data{"appId": appId, "scheduleId": scheduleId})
 
-	s.appLock.GetLock(appId).Lock()
-	defer s.appLock.GetLock(appId).Unlock()
+	err := s.appLock.Lock(appId)
+	if err != nil {
+		logger.Error("failed-to-lock-app", err)
+		return err
+	}
+	defer s.appLock.Unlock(appId)
 
 	currentSchedule, err := s.scalingEngineDB.GetActiveSchedule(appId)
 	if err != nil {
@@ -288,24 +1000,24 @@ func (s *scalingEngine) RemoveActiveSchedule(appId string, scheduleId string) er
 		NewInstances: -1,
 		Reason:       "schedule ends",
 	}
-	defer s.scalingEngineDB.SaveScalingHistory(history)
+	defer s.saveScalingHistory(history)
 
-	instances, err := s.cfClient.GetAppInstances(appId)
+	policy, err := s.policyDB.GetAppPolicy(appId)
 	if err != nil {
-		logger.Error("failed-to-get-app-instances", err)
+		logger.Error("failed-to-get-app-policy", err)
 		history.Status = models.ScalingStatusFailed
-		history.Error = "failed to get app instances"
+		history.Error = "failed to get app policy"
 		return err
 	}
-	history.OldInstances = instances
+	history.PolicyRevision = policy.Revision
 
-	policy, err := s.policyDB.GetAppPolicy(appId)
+	instances, err := s.cfClient.GetAppInstances(appId, policy.GetProcessType())
 	if err != nil {
-		logger.Error("failed-to-get-app-policy", err)
-		history.Status = models.ScalingStatusFailed
-		history.Error = "failed to get app policy"
+		logger.Error("failed-to-get-app-instances", err)
+		s.handleCfError(logger, history, err, "failed to get app instances")
 		return err
 	}
+	history.OldInstances = instances
 
 	newInstances := instances
 	if newInstances < policy.InstanceMin {
@@ -315,6 +1027,9 @@ func (s *scalingEngine) RemoveActiveSchedule(appId string, scheduleId string) er
 		newInstances = policy.InstanceMax
 		history.Message = fmt.Sprintf("limited by max instances %d", policy.InstanceMax)
 	}
+	if newInstances > instances {
+		newInstances = s.limitByQuota(logger, appId, policy.GetProcessType(), instances, newInstances, history)
+	}
 
 	history.NewInstances = newInstances
 
@@ -323,17 +1038,64 @@ func (s *scalingEngine) RemoveActiveSchedule(appId string, scheduleId string) er
 		return nil
 	}
 
-	err = s.cfClient.SetAppInstances(appId, newInstances)
+	err = s.cfClient.SetAppInstances(appId, policy.GetProcessType(), newInstances)
 	if err != nil {
 		logger.Error("failed-to-set-app-instances", err)
-		history.Status = models.ScalingStatusFailed
-		history.Error = "failed to set app instances"
+		s.handleCfError(logger, history, err, "failed to set app instances")
 		return err
 	}
 	history.Status = models.ScalingStatusSucceeded
 	return nil
 }
 
+// handleCfError records a failed cloud controller call in history. The history is
+// marked unavailable when the call was short-circuited because the cloud
+// controller is down. When the app or its process is not found, the app is
+// marked orphaned so that its policy is cleaned up, but only once the cloud
+// controller confirms that the app itself no longer exists.
+func (s *scalingEngine) handleCfError(logger lager.Logger, history *models.AppScalingHistory, err error, message string) {
+	switch err.(type) {
+	case *cf.CircuitOpenError:
+		history.Status = models.ScalingStatusUnavailable
+		history.Error = "cloud controller is unavailable"
+	case *cf.AppNotFoundError, *cf.ProcessNotFoundError:
+		history.Status = models.ScalingStatusFailed
+		history.Error = message
+
+		exists, existsErr := s.cfClient.AppExists(history.AppId)
+		if existsErr != nil {
+			logger.Error("failed-to-check-app-exists", existsErr)
+			return
+		}
+		if exists {
+			if _, ok := err.(*cf.ProcessNotFoundError); ok {
+				history.Error = "app process does not exist"
+			}
+			return
+		}
+
+		history.Error = "app does not exist"
+		markErr := s.policyDB.MarkAppOrphaned(history.AppId, history.Timestamp)
+		if markErr != nil {
+			logger.Error("failed-to-mark-app-orphaned", markErr)
+		} else {
+			logger.Info("app-marked-orphaned")
+		}
+	default:
+		history.Status = models.ScalingStatusFailed
+		history.Error = message
+	}
+}
+
+func (s *scalingEngine) saveScalingHistory(history *models.AppScalingHistory) {
+	scalingCalls.Inc(scalingTypeNames[history.ScalingType], scalingStatusNames[history.Status])
+
+	err := s.scalingEngineDB.SaveScalingHistory(history)
+	if err != nil {
+		s.logger.Error("failed-to-save-scaling-history", err, lager.Data{"history": history})
+	}
+}
+
 func getDynamicScalingReason(trigger *models.Trigger) string {
 	return fmt.Sprintf("%s instance(s) because %s %s %d for %d seconds",
 		trigger.Adjustment,
@@ -343,6 +1105,31 @@ func getDynamicScalingReason(trigger *models.Trigger) string {
 		trigger.BreachDurationSeconds)
 }
 
+func getRemediationReason(instanceIndex int, trigger *models.Trigger) string {
+	return fmt.Sprintf("restart instance %d because %s %s %d for %d seconds",
+		instanceIndex,
+		trigger.MetricType,
+		trigger.Operator,
+		trigger.Threshold,
+		trigger.BreachDurationSeconds)
+}
+
+func getVerticalScalingReason(trigger *models.Trigger) string {
+	adjustments := []string{}
+	if trigger.MemoryAdjustment != "" {
+		adjustments = append(adjustments, fmt.Sprintf("%s MB memory", trigger.MemoryAdjustment))
+	}
+	if trigger.DiskAdjustment != "" {
+		adjustments = append(adjustments, fmt.Sprintf("%s MB disk", trigger.DiskAdjustment))
+	}
+	return fmt.Sprintf("%s per instance because %s %s %d for %d seconds",
+		strings.Join(adjustments, " and "),
+		trigger.MetricType,
+		trigger.Operator,
+		trigger.Threshold,
+		trigger.BreachDurationSeconds)
+}
+
 func getScheduledScalingReason(schedule *models.ActiveSchedule) string {
 	return fmt.Sprintf("schedule starts with instance min %d, instance max %d and instance min initial %d",
 		schedule.InstanceMin, schedule.InstanceMax, schedule.InstanceMinInitial)
@@ -1,6 +1,8 @@
 package scalingengine_test
 
 import (
+	"autoscaler/cf"
+	"autoscaler/metrics"
 	"autoscaler/models"
 	"autoscaler/scalingengine/fakes"
 	"strconv"
@@ -11,6 +13,7 @@ import (
 	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
 
+	"database/sql"
 	"errors"
 
 	. "github.com/onsi/ginkgo"
@@ -25,23 +28,30 @@ var _ = Describe("ScalingEngine", func() {
 		cfc             *fakes.FakeCfClient
 		policyDB        *fakes.FakePolicyDB
 		scalingEngineDB *fakes.FakeScalingEngineDB
+		leaseDB         *fakes.FakeLeaseDB
 		clock           *fakeclock.FakeClock
 
-		newInstances int
-		trigger      *models.Trigger
-		buffer       *gbytes.Buffer
-		err          error
+		newInstances     int
+		cooldownExpireAt int64
+		trigger          *models.Trigger
+		buffer           *gbytes.Buffer
+		err              error
 	)
 
 	BeforeEach(func() {
 		cfc = &fakes.FakeCfClient{}
 		policyDB = &fakes.FakePolicyDB{}
 		scalingEngineDB = &fakes.FakeScalingEngineDB{}
+		leaseDB = &fakes.FakeLeaseDB{}
+		leaseDB.AcquireLeaseReturns(true, nil)
+		policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
+		cfc.GetAppStatusReturns(models.AppStatus{State: models.AppStateStarted}, nil)
 
 		logger := lagertest.NewTestLogger("schedule-test")
 		buffer = logger.Buffer()
 		clock = fakeclock.NewFakeClock(time.Now())
-		scalingEngine = NewScalingEngine(logger, cfc, policyDB, scalingEngineDB, clock)
+		appLock := NewAppLock(logger, leaseDB, "an-owner", time.Minute, 0, time.Second, clock)
+		scalingEngine = NewScalingEngine(logger, cfc, policyDB, scalingEngineDB, appLock, 0.5, time.Minute, clock)
 		activeSchedule = &models.ActiveSchedule{
 			ScheduleId:         "a-schedule-id",
 			InstanceMinInitial: 5,
@@ -60,6 +70,15 @@ var _ = Describe("ScalingEngine", func() {
 				Operator:              ">",
 				Adjustment:            "+1",
 			}
+
+			cooldownExpireAt = 0
+			scalingEngineDB.ScaleWithCooldownStub = func(appId string, now int64, scale func() (int64, error)) (bool, error) {
+				expireAt, err := scale()
+				if err == nil {
+					cooldownExpireAt = expireAt
+				}
+				return true, err
+			}
 		})
 
 		JustBeforeEach(func() {
@@ -67,46 +86,133 @@ var _ = Describe("ScalingEngine", func() {
 		})
 
 		Context("when scaling succeeds", func() {
+			var succeededBefore float64
+
 			BeforeEach(func() {
 				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
-				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
+				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6, Revision: 7}, nil)
 
+				succeededBefore = metrics.DefaultRegistry.Value(`autoscaler_scalingengine_scaling_calls_total{type="dynamic",status="succeeded"}`)
+			})
+
+			It("counts the succeeded scaling call", func() {
+				Expect(metrics.DefaultRegistry.Value(`autoscaler_scalingengine_scaling_calls_total{type="dynamic",status="succeeded"}`) - succeededBefore).To(Equal(float64(1)))
 			})
 
 			It("sets the new app instance number and stores the succeeded scaling history", func() {
 				Expect(err).NotTo(HaveOccurred())
-				id, num := cfc.SetAppInstancesArgsForCall(0)
+				id, processType, num := cfc.SetAppInstancesArgsForCall(0)
 				Expect(id).To(Equal("an-app-id"))
+				Expect(processType).To(Equal("web"))
 				Expect(num).To(Equal(3))
 				Expect(newInstances).To(Equal(3))
 
-				id, expiredAt := scalingEngineDB.UpdateScalingCooldownExpireTimeArgsForCall(0)
+				id, now, _ := scalingEngineDB.ScaleWithCooldownArgsForCall(0)
+				Expect(id).To(Equal("an-app-id"))
+				Expect(now).To(Equal(clock.Now().UnixNano()))
+				Expect(cooldownExpireAt).To(Equal(clock.Now().Add(30 * time.Second).UnixNano()))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:          "an-app-id",
+					Timestamp:      clock.Now().UnixNano(),
+					ScalingType:    models.ScalingTypeDynamic,
+					Status:         models.ScalingStatusSucceeded,
+					OldInstances:   2,
+					NewInstances:   3,
+					Reason:         "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					PolicyRevision: 7,
+				}))
+
+			})
+		})
+
+		Context("when scaling", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
+			})
+
+			It("holds the app lock in the database while scaling", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(1))
+				name, owner, _ := leaseDB.AcquireLeaseArgsForCall(0)
+				Expect(name).To(Equal("scalingengine-app-an-app-id"))
+				Expect(owner).To(Equal("an-owner"))
+
+				Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(1))
+				name, owner = leaseDB.ReleaseLeaseArgsForCall(0)
+				Expect(name).To(Equal("scalingengine-app-an-app-id"))
+				Expect(owner).To(Equal("an-owner"))
+			})
+		})
+
+		Context("when the app is locked by another scaling engine", func() {
+			BeforeEach(func() {
+				leaseDB.AcquireLeaseReturns(false, nil)
+			})
+
+			It("should error without scaling the app", func() {
+				Expect(err).To(BeAssignableToTypeOf(&AppLockTimeoutError{}))
+				Eventually(buffer).Should(gbytes.Say("failed-to-lock-app"))
+				Expect(cfc.GetAppInstancesCallCount()).To(BeZero())
+				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+				Expect(leaseDB.ReleaseLeaseCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the policy targets another process type", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6, ProcessType: "worker"}, nil)
+			})
+
+			It("scales the instances of that process type", func() {
+				Expect(err).NotTo(HaveOccurred())
+				id, processType := cfc.GetAppInstancesArgsForCall(0)
+				Expect(id).To(Equal("an-app-id"))
+				Expect(processType).To(Equal("worker"))
+
+				id, processType, num := cfc.SetAppInstancesArgsForCall(0)
 				Expect(id).To(Equal("an-app-id"))
-				Expect(expiredAt).To(Equal(clock.Now().Add(30 * time.Second).UnixNano()))
+				Expect(processType).To(Equal("worker"))
+				Expect(num).To(Equal(3))
+			})
+		})
+
+		Context("when app is in cooldown period", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				scalingEngineDB.ScaleWithCooldownReturns(false, nil)
+			})
+
+			It("ignores the scaling", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
 
 				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
 					AppId:        "an-app-id",
 					Timestamp:    clock.Now().UnixNano(),
 					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusSucceeded,
+					Status:       models.ScalingStatusIgnored,
 					OldInstances: 2,
-					NewInstances: 3,
+					NewInstances: 2,
 					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Message:      "app in cooldown period",
 				}))
 
 			})
 		})
 
-		Context("when app is in cooldown period", func() {
+		Context("when the app is not scalable", func() {
 			BeforeEach(func() {
 				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(false, nil)
 			})
 
-			It("ignores the scaling", func() {
+			expectIgnored := func(message string) {
 				Expect(err).NotTo(HaveOccurred())
+				Expect(newInstances).To(Equal(2))
 				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+				Expect(scalingEngineDB.ScaleWithCooldownCallCount()).To(BeZero())
 
 				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
 					AppId:        "an-app-id",
@@ -116,9 +222,78 @@ var _ = Describe("ScalingEngine", func() {
 					OldInstances: 2,
 					NewInstances: 2,
 					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
-					Message:      "app in cooldown period",
+					Message:      message,
 				}))
+			}
+
+			Context("when the app is stopped", func() {
+				BeforeEach(func() {
+					cfc.GetAppStatusReturns(models.AppStatus{State: models.AppStateStopped}, nil)
+				})
+
+				It("ignores the scaling", func() {
+					Expect(cfc.GetAppStatusCallCount()).To(Equal(1))
+					id, processType := cfc.GetAppStatusArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+					Expect(processType).To(Equal(models.DefaultProcessType))
+					expectIgnored("app is stopped")
+				})
+			})
+
+			Context("when the app is staging", func() {
+				BeforeEach(func() {
+					cfc.GetAppStatusReturns(models.AppStatus{State: models.AppStateStarted, Staging: true}, nil)
+				})
+
+				It("ignores the scaling", func() {
+					expectIgnored("app is staging")
+				})
+			})
+
+			Context("when more instances are crashed than allowed", func() {
+				BeforeEach(func() {
+					cfc.GetAppStatusReturns(models.AppStatus{State: models.AppStateStarted, Instances: 3, CrashedInstances: 2}, nil)
+				})
+
+				It("ignores the scaling", func() {
+					expectIgnored("2 of 3 instances are crashed")
+				})
+			})
+		})
+
+		Context("when some instances are crashed within the allowed ratio", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				cfc.GetAppStatusReturns(models.AppStatus{State: models.AppStateStarted, Instances: 2, CrashedInstances: 1}, nil)
+			})
+
+			It("scales the app", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newInstances).To(Equal(3))
+				Expect(cfc.SetAppInstancesCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when getting the app status fails", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				cfc.GetAppStatusReturns(models.AppStatus{}, errors.New("test error"))
+			})
+
+			It("should error and store the failed scaling history", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
 
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: 2,
+					NewInstances: -1,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "failed to get app status",
+				}))
 			})
 		})
 
@@ -126,7 +301,6 @@ var _ = Describe("ScalingEngine", func() {
 			BeforeEach(func() {
 				trigger.Adjustment = "+20%"
 				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
 				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
 
 			})
@@ -135,6 +309,7 @@ var _ = Describe("ScalingEngine", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
 				Expect(newInstances).To(Equal(2))
+				Expect(cooldownExpireAt).To(BeZero())
 
 				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
 					AppId:        "an-app-id",
@@ -153,7 +328,6 @@ var _ = Describe("ScalingEngine", func() {
 			BeforeEach(func() {
 				trigger.Adjustment = "+2"
 				cfc.GetAppInstancesReturns(5, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
 				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
 
 			})
@@ -161,7 +335,7 @@ var _ = Describe("ScalingEngine", func() {
 			It("updates the app instance with  max instances and stores the succeeded scaling history", func() {
 				Expect(err).NotTo(HaveOccurred())
 
-				id, num := cfc.SetAppInstancesArgsForCall(0)
+				id, _, num := cfc.SetAppInstancesArgsForCall(0)
 				Expect(id).To(Equal("an-app-id"))
 				Expect(num).To(Equal(6))
 				Expect(newInstances).To(Equal(6))
@@ -177,259 +351,1279 @@ var _ = Describe("ScalingEngine", func() {
 					Message:      "limited by max instances 6",
 				}))
 
-			})
+			})
+		})
+
+		Context("when scaling out", func() {
+			BeforeEach(func() {
+				trigger.Adjustment = "+3"
+				cfc.GetAppInstancesReturns(2, nil)
+			})
+
+			Context("when the org quota does not allow all new instances", func() {
+				var limitedBefore float64
+
+				BeforeEach(func() {
+					cfc.GetAppQuotasReturns(models.AppQuotas{
+						InstanceMemory: 256,
+						Org:            models.MemoryQuota{Limit: 4096, Used: 3584},
+						Space:          models.MemoryQuota{Limit: models.UnlimitedMemory},
+					}, nil)
+					limitedBefore = metrics.DefaultRegistry.Value(`autoscaler_scalingengine_quota_limited_scalings_total{quota="org"}`)
+				})
+
+				It("scales to the instances allowed by the quota and stores the succeeded scaling history", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(4))
+					id, processType := cfc.GetAppQuotasArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+					Expect(processType).To(Equal(models.DefaultProcessType))
+					_, _, num := cfc.SetAppInstancesArgsForCall(0)
+					Expect(num).To(Equal(4))
+
+					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+						AppId:        "an-app-id",
+						Timestamp:    clock.Now().UnixNano(),
+						ScalingType:  models.ScalingTypeDynamic,
+						Status:       models.ScalingStatusSucceeded,
+						OldInstances: 2,
+						NewInstances: 4,
+						Reason:       "+3 instance(s) because memorybytes > 222222 for 100 seconds",
+						Message:      "limited by quota of the org",
+					}))
+					Expect(metrics.DefaultRegistry.Value(`autoscaler_scalingengine_quota_limited_scalings_total{quota="org"}`) - limitedBefore).To(Equal(float64(1)))
+				})
+			})
+
+			Context("when the space quota is exhausted", func() {
+				BeforeEach(func() {
+					cfc.GetAppQuotasReturns(models.AppQuotas{
+						InstanceMemory: 256,
+						Org:            models.MemoryQuota{Limit: 4096, Used: 1024},
+						Space:          models.MemoryQuota{Limit: 1024, Used: 1024},
+					}, nil)
+				})
+
+				It("does not scale the app and stores the ignored scaling history", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(2))
+					Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+
+					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+						AppId:        "an-app-id",
+						Timestamp:    clock.Now().UnixNano(),
+						ScalingType:  models.ScalingTypeDynamic,
+						Status:       models.ScalingStatusIgnored,
+						OldInstances: 2,
+						NewInstances: 2,
+						Reason:       "+3 instance(s) because memorybytes > 222222 for 100 seconds",
+						Message:      "limited by quota of the space",
+					}))
+				})
+			})
+
+			Context("when getting the quotas fails", func() {
+				BeforeEach(func() {
+					cfc.GetAppQuotasReturns(models.AppQuotas{}, errors.New("test error"))
+				})
+
+				It("scales the app without limiting it by quota", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(5))
+					Eventually(buffer).Should(gbytes.Say("failed-to-get-app-quotas"))
+				})
+			})
+		})
+
+		Context("when scaling in", func() {
+			BeforeEach(func() {
+				trigger.Adjustment = "-1"
+				cfc.GetAppInstancesReturns(3, nil)
+			})
+
+			It("does not check the quotas", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newInstances).To(Equal(2))
+				Expect(cfc.GetAppQuotasCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when it exceeds min instances limit in scaling policy", func() {
+			BeforeEach(func() {
+				trigger.Adjustment = "-60%"
+				cfc.GetAppInstancesReturns(3, nil)
+				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 2, InstanceMax: 6}, nil)
+
+			})
+
+			It("updates the app instance with  min instances and stores the succeeded scaling history", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				id, _, num := cfc.SetAppInstancesArgsForCall(0)
+				Expect(id).To(Equal("an-app-id"))
+				Expect(num).To(Equal(2))
+				Expect(newInstances).To(Equal(2))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusSucceeded,
+					OldInstances: 3,
+					NewInstances: 2,
+					Reason:       "-60% instance(s) because memorybytes > 222222 for 100 seconds",
+					Message:      "limited by min instances 2",
+				}))
+
+			})
+		})
+
+		Context("when there is active schedule", func() {
+			BeforeEach(func() {
+				scalingEngineDB.GetActiveScheduleReturns(&models.ActiveSchedule{
+					ScheduleId:  "111111",
+					InstanceMin: 3,
+					InstanceMax: 7,
+				}, nil)
+			})
+
+			Context("when it exceeds max instances limit in active schedule", func() {
+				BeforeEach(func() {
+					trigger.Adjustment = "+2"
+					cfc.GetAppInstancesReturns(6, nil)
+				})
+
+				It("updates the app instance with  max instances and stores the succeeded scaling history", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(policyDB.GetAppPolicyCallCount()).To(Equal(1))
+
+					id, _, num := cfc.SetAppInstancesArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+					Expect(num).To(Equal(7))
+					Expect(newInstances).To(Equal(7))
+
+					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+						AppId:        "an-app-id",
+						Timestamp:    clock.Now().UnixNano(),
+						ScalingType:  models.ScalingTypeDynamic,
+						Status:       models.ScalingStatusSucceeded,
+						OldInstances: 6,
+						NewInstances: 7,
+						Reason:       "+2 instance(s) because memorybytes > 222222 for 100 seconds",
+						Message:      "limited by max instances 7",
+					}))
+
+				})
+			})
+
+			Context("when it exceeds min instances limit  in active schedule", func() {
+				BeforeEach(func() {
+					trigger.Adjustment = "-60%"
+					cfc.GetAppInstancesReturns(5, nil)
+				})
+
+				It("updates the app instance with min instances and stores the succeeded scaling history", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(policyDB.GetAppPolicyCallCount()).To(Equal(1))
+
+					id, _, num := cfc.SetAppInstancesArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+					Expect(num).To(Equal(3))
+					Expect(newInstances).To(Equal(3))
+
+					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+						AppId:        "an-app-id",
+						Timestamp:    clock.Now().UnixNano(),
+						ScalingType:  models.ScalingTypeDynamic,
+						Status:       models.ScalingStatusSucceeded,
+						OldInstances: 5,
+						NewInstances: 3,
+						Reason:       "-60% instance(s) because memorybytes > 222222 for 100 seconds",
+						Message:      "limited by min instances 3",
+					}))
+
+				})
+			})
+
+		})
+
+		Context("when getting app instances from cloud controller fails", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(-1, errors.New("test error"))
+			})
+
+			It("should error and store the failed scaling history", func() {
+				Expect(err).To(HaveOccurred())
+				Eventually(buffer).Should(gbytes.Say("failed-to-get-app-instances"))
+				Eventually(buffer).Should(gbytes.Say("test error"))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: -1,
+					NewInstances: -1,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "failed to get app instances",
+				}))
+
+			})
+		})
+
+		Context("when the circuit breaker of the cloud controller is open", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(-1, &cf.CircuitOpenError{RetryAt: clock.Now()})
+			})
+
+			It("should error and store the unavailable scaling history", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cf.CircuitOpenError{}))
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusUnavailable,
+					OldInstances: -1,
+					NewInstances: -1,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "cloud controller is unavailable",
+				}))
+			})
+		})
+
+		Context("when the app does not exist", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(-1, &cf.AppNotFoundError{AppId: "an-app-id"})
+			})
+
+			It("marks the app orphaned and stores the failed scaling history", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cf.AppNotFoundError{}))
+
+				Expect(cfc.AppExistsCallCount()).To(Equal(1))
+				Expect(cfc.AppExistsArgsForCall(0)).To(Equal("an-app-id"))
+				Expect(policyDB.MarkAppOrphanedCallCount()).To(Equal(1))
+				appId, orphanedAt := policyDB.MarkAppOrphanedArgsForCall(0)
+				Expect(appId).To(Equal("an-app-id"))
+				Expect(orphanedAt).To(Equal(clock.Now().UnixNano()))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: -1,
+					NewInstances: -1,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "app does not exist",
+				}))
+			})
+
+			Context("when marking the app orphaned fails", func() {
+				BeforeEach(func() {
+					policyDB.MarkAppOrphanedReturns(errors.New("an error"))
+				})
+
+				It("logs the error", func() {
+					Expect(err).To(BeAssignableToTypeOf(&cf.AppNotFoundError{}))
+					Eventually(buffer).Should(gbytes.Say("failed-to-mark-app-orphaned"))
+				})
+			})
+
+			Context("when the app still exists", func() {
+				BeforeEach(func() {
+					cfc.AppExistsReturns(true, nil)
+				})
+
+				It("stores the failed scaling history without marking the app orphaned", func() {
+					Expect(err).To(BeAssignableToTypeOf(&cf.AppNotFoundError{}))
+					Expect(policyDB.MarkAppOrphanedCallCount()).To(BeZero())
+					history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+					Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+					Expect(history.Error).To(Equal("failed to get app instances"))
+				})
+			})
+
+			Context("when checking whether the app exists fails", func() {
+				BeforeEach(func() {
+					cfc.AppExistsReturns(false, errors.New("an error"))
+				})
+
+				It("stores the failed scaling history without marking the app orphaned", func() {
+					Expect(err).To(BeAssignableToTypeOf(&cf.AppNotFoundError{}))
+					Expect(policyDB.MarkAppOrphanedCallCount()).To(BeZero())
+					Eventually(buffer).Should(gbytes.Say("failed-to-check-app-exists"))
+					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0).Error).To(Equal("failed to get app instances"))
+				})
+			})
+		})
+
+		Context("when the process of the app does not exist", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(-1, &cf.ProcessNotFoundError{AppId: "an-app-id", ProcessType: "web"})
+				cfc.AppExistsReturns(true, nil)
+			})
+
+			It("stores the failed scaling history without marking the app orphaned", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cf.ProcessNotFoundError{}))
+				Expect(policyDB.MarkAppOrphanedCallCount()).To(BeZero())
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+				Expect(history.Error).To(Equal("app process does not exist"))
+			})
+
+			Context("when the app does not exist either", func() {
+				BeforeEach(func() {
+					cfc.AppExistsReturns(false, nil)
+				})
+
+				It("marks the app orphaned", func() {
+					Expect(policyDB.MarkAppOrphanedCallCount()).To(Equal(1))
+					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0).Error).To(Equal("app does not exist"))
+				})
+			})
+		})
+
+		Context("When checking cooldown fails", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				scalingEngineDB.ScaleWithCooldownReturns(false, errors.New("test error"))
+			})
+			It("should error and store the failed scaling history", func() {
+				Expect(err).To(HaveOccurred())
+				Eventually(buffer).Should(gbytes.Say("failed-check-cooldown"))
+				Eventually(buffer).Should(gbytes.Say("test error"))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: 2,
+					NewInstances: -1,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "failed to check app cooldown setting",
+				}))
+
+			})
+		})
+
+		Context("when updating cooldown fails after scaling", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
+				scalingEngineDB.ScaleWithCooldownStub = func(appId string, now int64, scale func() (int64, error)) (bool, error) {
+					scale()
+					return true, errors.New("test error")
+				}
+			})
+
+			It("succeeds and stores the succeeded scaling history", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newInstances).To(Equal(3))
+				Eventually(buffer).Should(gbytes.Say("failed-to-update-scaling-cool-down-expire-time"))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusSucceeded,
+					OldInstances: 2,
+					NewInstances: 3,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+				}))
+			})
+		})
+
+		Context("when computing new app instances fails", func() {
+			BeforeEach(func() {
+				trigger.Adjustment = "+a"
+				cfc.GetAppInstancesReturns(2, nil)
+			})
+
+			It("should error and store failed scaling history", func() {
+				Expect(err).To(HaveOccurred())
+				Eventually(buffer).Should(gbytes.Say("failed-compute-new-instance"))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: 2,
+					NewInstances: -1,
+					Reason:       "+a instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "failed to compute new app instances",
+				}))
+			})
+		})
+
+		Context("when getting active schedule fails", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				scalingEngineDB.GetActiveScheduleReturns(nil, errors.New("test error"))
+			})
+
+			It("should error and store the failed scaling history", func() {
+				Expect(err).To(HaveOccurred())
+				Eventually(buffer).Should(gbytes.Say("failed-get-active-schedule"))
+				Eventually(buffer).Should(gbytes.Say("test error"))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: 2,
+					NewInstances: -1,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "failed to get active schedule",
+				}))
+
+			})
+		})
+
+		Context("when getting policy fails", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				policyDB.GetAppPolicyReturns(nil, errors.New("test error"))
+			})
+
+			It("should error and store the failed scaling history", func() {
+				Expect(err).To(HaveOccurred())
+				Eventually(buffer).Should(gbytes.Say("failed-get-app-policy"))
+				Eventually(buffer).Should(gbytes.Say("test error"))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: -1,
+					NewInstances: -1,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "failed to get scaling policy",
+				}))
+
+			})
+		})
+
+		Context("when set new instances fails", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(2, nil)
+				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
+				cfc.SetAppInstancesReturns(errors.New("test error"))
+			})
+
+			It("should error and store failed scaling history", func() {
+				Expect(err).To(HaveOccurred())
+				Eventually(buffer).Should(gbytes.Say("failed-to-set-app-instances"))
+				Eventually(buffer).Should(gbytes.Say("test error"))
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: 2,
+					NewInstances: 3,
+					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
+					Error:        "failed to set app instances",
+				}))
+
+			})
+		})
+
+		Context("when the app is a member of an app group", func() {
+			var (
+				instances map[string]int
+				policies  map[string]*models.ScalingPolicy
+				cooldowns map[string]int64
+			)
+
+			histories := func() map[string]*models.AppScalingHistory {
+				result := map[string]*models.AppScalingHistory{}
+				for i := 0; i < scalingEngineDB.SaveScalingHistoryCallCount(); i++ {
+					history := scalingEngineDB.SaveScalingHistoryArgsForCall(i)
+					result[history.AppId] = history
+				}
+				return result
+			}
+
+			BeforeEach(func() {
+				trigger.Adjustment = "+2"
+				policyDB.GetAppGroupOfAppReturns(&models.AppGroup{
+					GroupId: "a-group-id",
+					Members: []*models.AppGroupMember{
+						{AppId: "another-app-id", Ratio: 1, InstanceMin: 1, InstanceMax: 4, ProcessType: "worker"},
+						{AppId: "an-app-id", Ratio: 2, InstanceMin: 2, InstanceMax: 10},
+					},
+				}, nil)
+				policies = map[string]*models.ScalingPolicy{
+					"an-app-id":      {InstanceMin: 1, InstanceMax: 12, Revision: 7},
+					"another-app-id": {InstanceMin: 1, InstanceMax: 6, Revision: 8},
+				}
+				policyDB.GetAppPolicyStub = func(appId string) (*models.ScalingPolicy, error) {
+					return policies[appId], nil
+				}
+				instances = map[string]int{"an-app-id": 4, "another-app-id": 2}
+				cfc.GetAppInstancesStub = func(appId string, processType string) (int, error) {
+					return instances[appId], nil
+				}
+				cooldowns = map[string]int64{}
+				scalingEngineDB.ScaleWithCooldownStub = func(appId string, now int64, scale func() (int64, error)) (bool, error) {
+					expireAt, err := scale()
+					cooldowns[appId] = expireAt
+					return true, err
+				}
+			})
+
+			It("scales every member in proportion and stores a history for each member", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newInstances).To(Equal(6))
+
+				Expect(cfc.SetAppInstancesCallCount()).To(Equal(2))
+				id, processType, num := cfc.SetAppInstancesArgsForCall(0)
+				Expect([]interface{}{id, processType, num}).To(Equal([]interface{}{"an-app-id", "web", 6}))
+				id, processType, num = cfc.SetAppInstancesArgsForCall(1)
+				Expect([]interface{}{id, processType, num}).To(Equal([]interface{}{"another-app-id", "worker", 3}))
+
+				Expect(cooldowns).To(Equal(map[string]int64{
+					"an-app-id":      clock.Now().Add(30 * time.Second).UnixNano(),
+					"another-app-id": clock.Now().Add(30 * time.Second).UnixNano(),
+				}))
+
+				Expect(histories()).To(Equal(map[string]*models.AppScalingHistory{
+					"an-app-id": {
+						AppId:          "an-app-id",
+						Timestamp:      clock.Now().UnixNano(),
+						ScalingType:    models.ScalingTypeDynamic,
+						Status:         models.ScalingStatusSucceeded,
+						OldInstances:   4,
+						NewInstances:   6,
+						Reason:         "+2 instance(s) because memorybytes > 222222 for 100 seconds",
+						PolicyRevision: 7,
+						GroupId:        "a-group-id",
+					},
+					"another-app-id": {
+						AppId:          "another-app-id",
+						Timestamp:      clock.Now().UnixNano(),
+						ScalingType:    models.ScalingTypeDynamic,
+						Status:         models.ScalingStatusSucceeded,
+						OldInstances:   2,
+						NewInstances:   3,
+						Reason:         "+2 instance(s) because memorybytes > 222222 for 100 seconds",
+						PolicyRevision: 8,
+						GroupId:        "a-group-id",
+					},
+				}))
+			})
+
+			It("holds the lock of the group, then the locks of the members in the order of their app ids", func() {
+				Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(3))
+				names := []string{}
+				for i := 0; i < leaseDB.AcquireLeaseCallCount(); i++ {
+					name, _, _ := leaseDB.AcquireLeaseArgsForCall(i)
+					names = append(names, name)
+				}
+				Expect(names).To(Equal([]string{
+					"scalingengine-app-group-a-group-id",
+					"scalingengine-app-an-app-id",
+					"scalingengine-app-another-app-id",
+				}))
+				Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(3))
+			})
+
+			Context("when the lock of a member can not be acquired", func() {
+				BeforeEach(func() {
+					leaseDB.AcquireLeaseStub = func(name string, owner string, ttl time.Duration) (bool, error) {
+						if name == "scalingengine-app-another-app-id" {
+							return false, errors.New("an error")
+						}
+						return true, nil
+					}
+				})
+
+				It("releases the locks held and does not scale", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(2))
+					Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+					Expect(scalingEngineDB.SaveScalingHistoryCallCount()).To(BeZero())
+				})
+			})
+
+			Context("when the new instances exceed the max instances of the group", func() {
+				BeforeEach(func() {
+					trigger.Adjustment = "+10"
+				})
+
+				It("keeps each member within its limits in the group", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(10))
+					_, _, num := cfc.SetAppInstancesArgsForCall(1)
+					Expect(num).To(Equal(4))
+				})
+			})
+
+			Context("when the new instances of a member exceed the max instances of its policy", func() {
+				BeforeEach(func() {
+					trigger.Adjustment = "+10"
+					policies["another-app-id"].InstanceMax = 3
+				})
+
+				It("keeps the member within the limits of its policy", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(10))
+					_, _, num := cfc.SetAppInstancesArgsForCall(1)
+					Expect(num).To(Equal(3))
+					Expect(histories()["another-app-id"].Message).To(Equal("limited by max instances 3"))
+				})
+			})
+
+			Context("when a member has an active schedule", func() {
+				BeforeEach(func() {
+					scalingEngineDB.GetActiveScheduleStub = func(appId string) (*models.ActiveSchedule, error) {
+						if appId == "another-app-id" {
+							return &models.ActiveSchedule{ScheduleId: "a-schedule-id", InstanceMin: 4, InstanceMax: 5}, nil
+						}
+						return nil, nil
+					}
+				})
+
+				It("keeps the member within the limits of the schedule", func() {
+					Expect(err).NotTo(HaveOccurred())
+					_, _, num := cfc.SetAppInstancesArgsForCall(1)
+					Expect(num).To(Equal(4))
+					Expect(histories()["another-app-id"].Message).To(Equal("limited by min instances 4"))
+				})
+			})
+
+			Context("when the quota does not allow new instances of a member", func() {
+				BeforeEach(func() {
+					cfc.GetAppQuotasStub = func(appId string, processType string) (models.AppQuotas, error) {
+						if appId == "another-app-id" {
+							return models.AppQuotas{
+								InstanceMemory: 256,
+								Org:            models.MemoryQuota{Limit: models.UnlimitedMemory},
+								Space:          models.MemoryQuota{Limit: 1024, Used: 1024},
+							}, nil
+						}
+						return models.AppQuotas{}, nil
+					}
+				})
+
+				It("scales the other members and ignores the member", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(6))
+					Expect(cfc.SetAppInstancesCallCount()).To(Equal(1))
+					id, _, _ := cfc.SetAppInstancesArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+					Expect(cooldowns).To(HaveKey("an-app-id"))
+					Expect(cooldowns).NotTo(HaveKey("another-app-id"))
+
+					history := histories()["another-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+					Expect(history.NewInstances).To(Equal(2))
+					Expect(history.Message).To(Equal("limited by quota of the space"))
+				})
+			})
+
+			Context("when no member changes", func() {
+				BeforeEach(func() {
+					instances = map[string]int{"an-app-id": 10, "another-app-id": 4}
+				})
+
+				It("ignores the scaling", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(10))
+					Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+					Expect(scalingEngineDB.ScaleWithCooldownCallCount()).To(BeZero())
+					for _, history := range histories() {
+						Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+						Expect(history.NewInstances).To(Equal(history.OldInstances))
+					}
+				})
+			})
+
+			Context("when a member is in cooldown period", func() {
+				BeforeEach(func() {
+					scalingEngineDB.ScaleWithCooldownStub = func(appId string, now int64, scale func() (int64, error)) (bool, error) {
+						if appId == "another-app-id" {
+							return false, nil
+						}
+						expireAt, err := scale()
+						cooldowns[appId] = expireAt
+						return true, err
+					}
+				})
+
+				It("ignores the scaling and restores the cooldown of the other members", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(4))
+					Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+					Expect(cooldowns).To(Equal(map[string]int64{"an-app-id": 0}))
+					for _, history := range histories() {
+						Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+						Expect(history.Message).To(Equal("another-app-id: app in cooldown period"))
+					}
+				})
+			})
+
+			Context("when a member is stopped", func() {
+				BeforeEach(func() {
+					cfc.GetAppStatusStub = func(appId string, processType string) (models.AppStatus, error) {
+						if appId == "another-app-id" {
+							return models.AppStatus{State: models.AppStateStopped}, nil
+						}
+						return models.AppStatus{State: models.AppStateStarted}, nil
+					}
+				})
+
+				It("ignores the scaling", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(4))
+					Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+					for _, history := range histories() {
+						Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+						Expect(history.Message).To(Equal("another-app-id: app is stopped"))
+					}
+				})
+			})
+
+			Context("when a member does not exist", func() {
+				BeforeEach(func() {
+					cfc.GetAppInstancesStub = func(appId string, processType string) (int, error) {
+						if appId == "another-app-id" {
+							return -1, &cf.AppNotFoundError{AppId: appId}
+						}
+						return instances[appId], nil
+					}
+				})
+
+				It("skips the member and scales the others", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(6))
+					Expect(cfc.SetAppInstancesCallCount()).To(Equal(1))
+					id, _, _ := cfc.SetAppInstancesArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+					Expect(cooldowns).NotTo(HaveKey("another-app-id"))
+
+					history := histories()["another-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+					Expect(history.Message).To(Equal("app or process does not exist"))
+					Expect(histories()["an-app-id"].Status).To(Equal(models.ScalingStatusSucceeded))
+				})
+			})
+
+			Context("when the app does not exist", func() {
+				BeforeEach(func() {
+					cfc.GetAppInstancesStub = func(appId string, processType string) (int, error) {
+						if appId == "an-app-id" {
+							return -1, &cf.AppNotFoundError{AppId: appId}
+						}
+						return instances[appId], nil
+					}
+				})
+
+				It("stores the failed histories and marks only the app orphaned", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(policyDB.MarkAppOrphanedCallCount()).To(Equal(1))
+					id, _ := policyDB.MarkAppOrphanedArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+
+					history := histories()["an-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+					Expect(history.Error).To(Equal("app does not exist"))
+					history = histories()["another-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+					Expect(history.Error).To(Equal("an-app-id: failed to get app instances"))
+				})
+			})
+
+			Context("when a member has no scaling policy", func() {
+				BeforeEach(func() {
+					policyDB.GetAppPolicyStub = func(appId string) (*models.ScalingPolicy, error) {
+						if appId == "another-app-id" {
+							return nil, sql.ErrNoRows
+						}
+						return policies[appId], nil
+					}
+				})
+
+				It("skips the member and scales the others", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(newInstances).To(Equal(6))
+					Expect(cfc.SetAppInstancesCallCount()).To(Equal(1))
+					id, _, _ := cfc.SetAppInstancesArgsForCall(0)
+					Expect(id).To(Equal("an-app-id"))
+
+					history := histories()["another-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+					Expect(history.Message).To(Equal("app has no scaling policy"))
+				})
+			})
+
+			Context("when setting the instances of a member fails", func() {
+				BeforeEach(func() {
+					cfc.SetAppInstancesStub = func(appId string, processType string, num int) error {
+						if appId == "another-app-id" {
+							return errors.New("an error")
+						}
+						return nil
+					}
+				})
+
+				It("reports the members scaled and cools down only them", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(cooldowns).To(Equal(map[string]int64{
+						"an-app-id":      clock.Now().Add(30 * time.Second).UnixNano(),
+						"another-app-id": 0,
+					}))
+
+					history := histories()["an-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusSucceeded))
+					Expect(history.NewInstances).To(Equal(6))
+					history = histories()["another-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+					Expect(history.Error).To(Equal("failed to set app instances"))
+				})
+			})
+
+			Context("when setting the instances of the first member fails", func() {
+				BeforeEach(func() {
+					cfc.SetAppInstancesReturns(errors.New("an error"))
+				})
+
+				It("does not scale the other members and reports the failed member", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(cfc.SetAppInstancesCallCount()).To(Equal(1))
+					Expect(cooldowns).To(Equal(map[string]int64{"an-app-id": 0, "another-app-id": 0}))
+
+					history := histories()["another-app-id"]
+					Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+					Expect(history.NewInstances).To(Equal(2))
+					Expect(history.Error).To(Equal("an-app-id: failed to set app instances"))
+				})
+			})
+
+			Context("when getting the group fails", func() {
+				BeforeEach(func() {
+					policyDB.GetAppGroupOfAppReturns(nil, errors.New("an error"))
+				})
+
+				It("should error without scaling", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(leaseDB.AcquireLeaseCallCount()).To(BeZero())
+					Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+					Expect(scalingEngineDB.SaveScalingHistoryCallCount()).To(BeZero())
+				})
+			})
+		})
+	})
+
+	Describe("RestartInstance", func() {
+		var reason string
+
+		BeforeEach(func() {
+			trigger = &models.Trigger{
+				MetricType:            models.MetricNameMemory,
+				BreachDurationSeconds: 100,
+				CoolDownSeconds:       300,
+				Threshold:             222222,
+				Operator:              ">",
+				Action:                models.ActionRestartInstance,
+			}
+			reason = "restart instance 1 because memorybytes > 222222 for 100 seconds"
+
+			cfc.GetAppInstancesReturns(3, nil)
+			policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6, ProcessType: "worker", Revision: 7}, nil)
+
+			cooldownExpireAt = 0
+			scalingEngineDB.RestartWithCooldownStub = func(appId string, instanceIndex int, now int64, restart func() (int64, error)) (bool, error) {
+				expireAt, err := restart()
+				if err == nil {
+					cooldownExpireAt = expireAt
+				}
+				return true, err
+			}
+		})
+
+		JustBeforeEach(func() {
+			err = scalingEngine.RestartInstance("an-app-id", 1, trigger)
+		})
+
+		Context("when restarting succeeds", func() {
+			var succeededBefore float64
+
+			BeforeEach(func() {
+				succeededBefore = metrics.DefaultRegistry.Value(`autoscaler_scalingengine_scaling_calls_total{type="remediation",status="succeeded"}`)
+			})
+
+			It("restarts the instance and stores the succeeded remediation history", func() {
+				Expect(err).NotTo(HaveOccurred())
+				id, processType, index := cfc.RestartAppInstanceArgsForCall(0)
+				Expect(id).To(Equal("an-app-id"))
+				Expect(processType).To(Equal("worker"))
+				Expect(index).To(Equal(1))
+				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+
+				id, index, now, _ := scalingEngineDB.RestartWithCooldownArgsForCall(0)
+				Expect(id).To(Equal("an-app-id"))
+				Expect(index).To(Equal(1))
+				Expect(now).To(Equal(clock.Now().UnixNano()))
+				Expect(cooldownExpireAt).To(Equal(clock.Now().Add(300 * time.Second).UnixNano()))
+				Expect(scalingEngineDB.ScaleWithCooldownCallCount()).To(BeZero())
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:          "an-app-id",
+					Timestamp:      clock.Now().UnixNano(),
+					ScalingType:    models.ScalingTypeRemediation,
+					Status:         models.ScalingStatusSucceeded,
+					OldInstances:   3,
+					NewInstances:   3,
+					Reason:         reason,
+					PolicyRevision: 7,
+				}))
+			})
+
+			It("counts the succeeded remediation call", func() {
+				Expect(metrics.DefaultRegistry.Value(`autoscaler_scalingengine_scaling_calls_total{type="remediation",status="succeeded"}`) - succeededBefore).To(Equal(float64(1)))
+			})
+		})
+
+		Context("when the instance is in cooldown period", func() {
+			BeforeEach(func() {
+				scalingEngineDB.RestartWithCooldownReturns(false, nil)
+			})
+
+			It("ignores the restart", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.RestartAppInstanceCallCount()).To(BeZero())
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:          "an-app-id",
+					Timestamp:      clock.Now().UnixNano(),
+					ScalingType:    models.ScalingTypeRemediation,
+					Status:         models.ScalingStatusIgnored,
+					OldInstances:   3,
+					NewInstances:   3,
+					Reason:         reason,
+					Message:        "instance in cooldown period",
+					PolicyRevision: 7,
+				}))
+			})
+		})
+
+		Context("when the instance does not exist", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(1, nil)
+			})
+
+			It("ignores the restart", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.RestartAppInstanceCallCount()).To(BeZero())
+				Expect(scalingEngineDB.RestartWithCooldownCallCount()).To(BeZero())
+
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+				Expect(history.Message).To(Equal("instance 1 does not exist"))
+			})
+		})
+
+		Context("when the app is stopped", func() {
+			BeforeEach(func() {
+				cfc.GetAppStatusReturns(models.AppStatus{State: models.AppStateStopped}, nil)
+			})
+
+			It("ignores the restart", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.RestartAppInstanceCallCount()).To(BeZero())
+
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+				Expect(history.Message).To(Equal("app is stopped"))
+			})
+		})
+
+		Context("when restarting the instance fails", func() {
+			BeforeEach(func() {
+				cfc.RestartAppInstanceReturns(errors.New("an error"))
+			})
+
+			It("stores the failed remediation history without a cooldown", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(cooldownExpireAt).To(BeZero())
+
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+				Expect(history.Error).To(Equal("failed to restart app instance"))
+			})
+		})
+
+		Context("when getting the policy fails", func() {
+			BeforeEach(func() {
+				policyDB.GetAppPolicyReturns(nil, errors.New("an error"))
+			})
+
+			It("stores the failed remediation history", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(cfc.RestartAppInstanceCallCount()).To(BeZero())
+
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+				Expect(history.Error).To(Equal("failed to get scaling policy"))
+			})
+		})
+
+		Context("when checking the cooldown fails", func() {
+			BeforeEach(func() {
+				scalingEngineDB.RestartWithCooldownReturns(false, errors.New("an error"))
+			})
+
+			It("stores the failed remediation history", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(cfc.RestartAppInstanceCallCount()).To(BeZero())
+
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+				Expect(history.Error).To(Equal("failed to check instance cooldown setting"))
+			})
+		})
+	})
+
+	Describe("Resize", func() {
+		var reason string
+
+		BeforeEach(func() {
+			trigger = &models.Trigger{
+				MetricType:            models.MetricNameMemory,
+				BreachDurationSeconds: 100,
+				CoolDownSeconds:       300,
+				Threshold:             222222,
+				Operator:              ">",
+				Action:                models.ActionResize,
+				MemoryAdjustment:      "+256",
+				DiskAdjustment:        "+512",
+			}
+			reason = "+256 MB memory and +512 MB disk per instance because memorybytes > 222222 for 100 seconds"
+
+			cfc.GetAppInstancesReturns(3, nil)
+			cfc.GetAppLimitsReturns(models.InstanceLimits{MemoryMb: 512, DiskMb: 1024}, nil)
+			policyDB.GetAppPolicyReturns(&models.ScalingPolicy{
+				InstanceMin:       1,
+				InstanceMax:       6,
+				InstanceMemoryMin: 256,
+				InstanceMemoryMax: 1024,
+				InstanceDiskMin:   512,
+				InstanceDiskMax:   2048,
+				Revision:          7,
+			}, nil)
+
+			cooldownExpireAt = 0
+			scalingEngineDB.ScaleWithCooldownStub = func(appId string, now int64, scale func() (int64, error)) (bool, error) {
+				expireAt, err := scale()
+				if err == nil {
+					cooldownExpireAt = expireAt
+				}
+				return true, err
+			}
+		})
+
+		JustBeforeEach(func() {
+			err = scalingEngine.Resize("an-app-id", trigger)
 		})
 
-		Context("when it exceeds min instances limit in scaling policy", func() {
-			BeforeEach(func() {
-				trigger.Adjustment = "-60%"
-				cfc.GetAppInstancesReturns(3, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
-				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 2, InstanceMax: 6}, nil)
+		Context("when resizing succeeds", func() {
+			var succeededBefore float64
 
+			BeforeEach(func() {
+				succeededBefore = metrics.DefaultRegistry.Value(`autoscaler_scalingengine_scaling_calls_total{type="vertical",status="succeeded"}`)
 			})
 
-			It("updates the app instance with  min instances and stores the succeeded scaling history", func() {
+			It("sets the new limits and stores the succeeded vertical scaling history", func() {
 				Expect(err).NotTo(HaveOccurred())
-
-				id, num := cfc.SetAppInstancesArgsForCall(0)
+				id, processType, limits := cfc.SetAppLimitsArgsForCall(0)
 				Expect(id).To(Equal("an-app-id"))
-				Expect(num).To(Equal(2))
-				Expect(newInstances).To(Equal(2))
+				Expect(processType).To(Equal("web"))
+				Expect(limits).To(Equal(models.InstanceLimits{MemoryMb: 768, DiskMb: 1536}))
+				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
 
 				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-					AppId:        "an-app-id",
-					Timestamp:    clock.Now().UnixNano(),
-					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusSucceeded,
-					OldInstances: 3,
-					NewInstances: 2,
-					Reason:       "-60% instance(s) because memorybytes > 222222 for 100 seconds",
-					Message:      "limited by min instances 2",
+					AppId:          "an-app-id",
+					Timestamp:      clock.Now().UnixNano(),
+					ScalingType:    models.ScalingTypeVertical,
+					Status:         models.ScalingStatusSucceeded,
+					OldInstances:   3,
+					NewInstances:   3,
+					Reason:         reason,
+					PolicyRevision: 7,
+					OldMemoryMb:    512,
+					NewMemoryMb:    768,
+					OldDiskMb:      1024,
+					NewDiskMb:      1536,
 				}))
+			})
+
+			It("extends the app cooldown by the restart time of the instances", func() {
+				id, now, _ := scalingEngineDB.ScaleWithCooldownArgsForCall(0)
+				Expect(id).To(Equal("an-app-id"))
+				Expect(now).To(Equal(clock.Now().UnixNano()))
+				Expect(cooldownExpireAt).To(Equal(clock.Now().Add(300*time.Second + 3*time.Minute).UnixNano()))
+			})
 
+			It("counts the succeeded vertical scaling call", func() {
+				Expect(metrics.DefaultRegistry.Value(`autoscaler_scalingengine_scaling_calls_total{type="vertical",status="succeeded"}`) - succeededBefore).To(Equal(float64(1)))
 			})
 		})
 
-		Context("when there is active schedule", func() {
+		Context("when the new limits exceed the bounds of the policy", func() {
 			BeforeEach(func() {
-				scalingEngineDB.GetActiveScheduleReturns(&models.ActiveSchedule{
-					ScheduleId:  "111111",
-					InstanceMin: 3,
-					InstanceMax: 7,
-				}, nil)
+				trigger.MemoryAdjustment = "+1024"
+				trigger.DiskAdjustment = "-1000"
 			})
 
-			Context("when it exceeds max instances limit in active schedule", func() {
-				BeforeEach(func() {
-					trigger.Adjustment = "+2"
-					cfc.GetAppInstancesReturns(6, nil)
-					scalingEngineDB.CanScaleAppReturns(true, nil)
-				})
-
-				It("updates the app instance with  max instances and stores the succeeded scaling history", func() {
-					Expect(err).NotTo(HaveOccurred())
-					Expect(policyDB.GetAppPolicyCallCount()).To(BeZero())
-
-					id, num := cfc.SetAppInstancesArgsForCall(0)
-					Expect(id).To(Equal("an-app-id"))
-					Expect(num).To(Equal(7))
-					Expect(newInstances).To(Equal(7))
-
-					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-						AppId:        "an-app-id",
-						Timestamp:    clock.Now().UnixNano(),
-						ScalingType:  models.ScalingTypeDynamic,
-						Status:       models.ScalingStatusSucceeded,
-						OldInstances: 6,
-						NewInstances: 7,
-						Reason:       "+2 instance(s) because memorybytes > 222222 for 100 seconds",
-						Message:      "limited by max instances 7",
-					}))
+			It("limits them to the bounds", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, limits := cfc.SetAppLimitsArgsForCall(0)
+				Expect(limits).To(Equal(models.InstanceLimits{MemoryMb: 1024, DiskMb: 512}))
 
-				})
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusSucceeded))
+				Expect(history.Message).To(Equal("limited by max memory 1024 MB, limited by min disk 512 MB"))
 			})
+		})
 
-			Context("when it exceeds min instances limit  in active schedule", func() {
-				BeforeEach(func() {
-					trigger.Adjustment = "-60%"
-					cfc.GetAppInstancesReturns(5, nil)
-					scalingEngineDB.CanScaleAppReturns(true, nil)
-				})
+		Context("when the trigger only adjusts the memory", func() {
+			BeforeEach(func() {
+				trigger.DiskAdjustment = ""
+			})
 
-				It("updates the app instance with min instances and stores the succeeded scaling history", func() {
-					Expect(err).NotTo(HaveOccurred())
-					Expect(policyDB.GetAppPolicyCallCount()).To(BeZero())
+			It("leaves the disk limit unchanged", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, limits := cfc.SetAppLimitsArgsForCall(0)
+				Expect(limits).To(Equal(models.InstanceLimits{MemoryMb: 768, DiskMb: 1024}))
+			})
+		})
 
-					id, num := cfc.SetAppInstancesArgsForCall(0)
-					Expect(id).To(Equal("an-app-id"))
-					Expect(num).To(Equal(3))
-					Expect(newInstances).To(Equal(3))
+		Context("when the policy has no max memory", func() {
+			BeforeEach(func() {
+				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
+				trigger.DiskAdjustment = ""
+			})
 
-					Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-						AppId:        "an-app-id",
-						Timestamp:    clock.Now().UnixNano(),
-						ScalingType:  models.ScalingTypeDynamic,
-						Status:       models.ScalingStatusSucceeded,
-						OldInstances: 5,
-						NewInstances: 3,
-						Reason:       "-60% instance(s) because memorybytes > 222222 for 100 seconds",
-						Message:      "limited by min instances 3",
-					}))
+			It("ignores the resize", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.SetAppLimitsCallCount()).To(BeZero())
+				Expect(scalingEngineDB.ScaleWithCooldownCallCount()).To(BeZero())
 
-				})
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+				Expect(history.Message).To(Equal("no max memory in policy"))
+				Expect(history.NewMemoryMb).To(Equal(512))
 			})
-
 		})
 
-		Context("when getting app instances from cloud controller fails", func() {
+		Context("when the limits are already at the bounds", func() {
 			BeforeEach(func() {
-				cfc.GetAppInstancesReturns(-1, errors.New("test error"))
+				cfc.GetAppLimitsReturns(models.InstanceLimits{MemoryMb: 1024, DiskMb: 2048}, nil)
 			})
 
-			It("should error and store the failed scaling history", func() {
-				Expect(err).To(HaveOccurred())
-				Eventually(buffer).Should(gbytes.Say("failed-to-get-app-instances"))
-				Eventually(buffer).Should(gbytes.Say("test error"))
-
-				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-					AppId:        "an-app-id",
-					Timestamp:    clock.Now().UnixNano(),
-					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusFailed,
-					OldInstances: -1,
-					NewInstances: -1,
-					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
-					Error:        "failed to get app instances",
-				}))
+			It("ignores the resize", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.SetAppLimitsCallCount()).To(BeZero())
+				Expect(scalingEngineDB.ScaleWithCooldownCallCount()).To(BeZero())
 
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+				Expect(history.OldMemoryMb).To(Equal(1024))
+				Expect(history.NewMemoryMb).To(Equal(1024))
 			})
 		})
 
-		Context("When checking cooldown fails", func() {
+		Context("when the app is in cooldown period", func() {
 			BeforeEach(func() {
-				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(false, errors.New("test error"))
+				scalingEngineDB.ScaleWithCooldownReturns(false, nil)
 			})
-			It("should error and store the failed scaling history", func() {
-				Expect(err).To(HaveOccurred())
-				Eventually(buffer).Should(gbytes.Say("failed-check-cooldown"))
-				Eventually(buffer).Should(gbytes.Say("test error"))
+
+			It("ignores the resize", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.SetAppLimitsCallCount()).To(BeZero())
 
 				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-					AppId:        "an-app-id",
-					Timestamp:    clock.Now().UnixNano(),
-					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusFailed,
-					OldInstances: 2,
-					NewInstances: -1,
-					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
-					Error:        "failed to check app cooldown setting",
+					AppId:          "an-app-id",
+					Timestamp:      clock.Now().UnixNano(),
+					ScalingType:    models.ScalingTypeVertical,
+					Status:         models.ScalingStatusIgnored,
+					OldInstances:   3,
+					NewInstances:   3,
+					Reason:         reason,
+					Message:        "app in cooldown period",
+					PolicyRevision: 7,
+					OldMemoryMb:    512,
+					NewMemoryMb:    512,
+					OldDiskMb:      1024,
+					NewDiskMb:      1024,
 				}))
-
 			})
 		})
 
-		Context("when computing new app instances fails", func() {
+		Context("when the app is stopped", func() {
 			BeforeEach(func() {
-				trigger.Adjustment = "+a"
-				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
+				cfc.GetAppStatusReturns(models.AppStatus{State: models.AppStateStopped}, nil)
 			})
 
-			It("should error and store failed scaling history", func() {
-				Expect(err).To(HaveOccurred())
-				Eventually(buffer).Should(gbytes.Say("failed-compute-new-instance"))
+			It("ignores the resize", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfc.SetAppLimitsCallCount()).To(BeZero())
 
-				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-					AppId:        "an-app-id",
-					Timestamp:    clock.Now().UnixNano(),
-					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusFailed,
-					OldInstances: 2,
-					NewInstances: -1,
-					Reason:       "+a instance(s) because memorybytes > 222222 for 100 seconds",
-					Error:        "failed to compute new app instances",
-				}))
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusIgnored))
+				Expect(history.Message).To(Equal("app is stopped"))
 			})
 		})
 
-		Context("when getting active schedule fails", func() {
+		Context("when getting the app limits fails", func() {
 			BeforeEach(func() {
-				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
-				scalingEngineDB.GetActiveScheduleReturns(nil, errors.New("test error"))
+				cfc.GetAppLimitsReturns(models.InstanceLimits{}, errors.New("an error"))
 			})
 
-			It("should error and store the failed scaling history", func() {
-				Expect(err).To(HaveOccurred())
-				Eventually(buffer).Should(gbytes.Say("failed-get-active-schedule"))
-				Eventually(buffer).Should(gbytes.Say("test error"))
-
-				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-					AppId:        "an-app-id",
-					Timestamp:    clock.Now().UnixNano(),
-					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusFailed,
-					OldInstances: 2,
-					NewInstances: -1,
-					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
-					Error:        "failed to get active schedule",
-				}))
+			It("stores the failed vertical scaling history", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(cfc.SetAppLimitsCallCount()).To(BeZero())
 
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+				Expect(history.Error).To(Equal("failed to get app limits"))
 			})
 		})
 
-		Context("when getting policy fails", func() {
+		Context("when setting the app limits fails", func() {
 			BeforeEach(func() {
-				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
-				policyDB.GetAppPolicyReturns(nil, errors.New("test error"))
+				cfc.SetAppLimitsReturns(errors.New("an error"))
 			})
 
-			It("should error and store the failed scaling history", func() {
-				Expect(err).To(HaveOccurred())
-				Eventually(buffer).Should(gbytes.Say("failed-get-app-policy"))
-				Eventually(buffer).Should(gbytes.Say("test error"))
-
-				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-					AppId:        "an-app-id",
-					Timestamp:    clock.Now().UnixNano(),
-					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusFailed,
-					OldInstances: 2,
-					NewInstances: -1,
-					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
-					Error:        "failed to get scaling policy",
-				}))
+			It("stores the failed vertical scaling history without a cooldown", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(cooldownExpireAt).To(BeZero())
 
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+				Expect(history.Error).To(Equal("failed to set app limits"))
+				Expect(history.NewMemoryMb).To(Equal(512))
 			})
 		})
 
-		Context("when set new instances fails", func() {
+		Context("when the memory adjustment is invalid", func() {
 			BeforeEach(func() {
-				cfc.GetAppInstancesReturns(2, nil)
-				scalingEngineDB.CanScaleAppReturns(true, nil)
-				policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 1, InstanceMax: 6}, nil)
-				cfc.SetAppInstancesReturns(errors.New("test error"))
+				trigger.MemoryAdjustment = "invalid"
 			})
 
-			It("should error and store failed scaling history", func() {
+			It("stores the failed vertical scaling history", func() {
 				Expect(err).To(HaveOccurred())
-				Eventually(buffer).Should(gbytes.Say("failed-to-set-app-instances"))
-				Eventually(buffer).Should(gbytes.Say("test error"))
-
-				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
-					AppId:        "an-app-id",
-					Timestamp:    clock.Now().UnixNano(),
-					ScalingType:  models.ScalingTypeDynamic,
-					Status:       models.ScalingStatusFailed,
-					OldInstances: 2,
-					NewInstances: 3,
-					Reason:       "+1 instance(s) because memorybytes > 222222 for 100 seconds",
-					Error:        "failed to set app instances",
-				}))
+				Expect(cfc.SetAppLimitsCallCount()).To(BeZero())
 
+				history := scalingEngineDB.SaveScalingHistoryArgsForCall(0)
+				Expect(history.Status).To(Equal(models.ScalingStatusFailed))
+				Expect(history.Error).To(Equal("failed to compute new memory limit"))
 			})
 		})
 	})
@@ -502,6 +1696,23 @@ var _ = Describe("ScalingEngine", func() {
 			Expect(schedule).To(Equal(activeSchedule))
 		})
 
+		It("holds the app lock in the database", func() {
+			Expect(leaseDB.AcquireLeaseCallCount()).To(Equal(1))
+			Expect(leaseDB.ReleaseLeaseCallCount()).To(Equal(1))
+		})
+
+		Context("when locking the app fails", func() {
+			BeforeEach(func() {
+				leaseDB.AcquireLeaseReturns(false, errors.New("an error"))
+			})
+
+			It("should error without setting the active schedule", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(scalingEngineDB.SetActiveScheduleCallCount()).To(BeZero())
+				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+			})
+		})
+
 		Context("when app instance number is greater than InstanceMax in active schedule", func() {
 			BeforeEach(func() {
 				cfc.GetAppInstancesReturns(12, nil)
@@ -510,7 +1721,7 @@ var _ = Describe("ScalingEngine", func() {
 			It("sets the app instances to be InstanceMax", func() {
 				Expect(err).NotTo(HaveOccurred())
 
-				appid, instances := cfc.SetAppInstancesArgsForCall(0)
+				appid, _, instances := cfc.SetAppInstancesArgsForCall(0)
 				Expect(appid).To(Equal("an-app-id"))
 				Expect(instances).To(Equal(10))
 				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
@@ -527,6 +1738,34 @@ var _ = Describe("ScalingEngine", func() {
 			})
 		})
 
+		Context("when the quota does not allow the instance min initial", func() {
+			BeforeEach(func() {
+				cfc.GetAppInstancesReturns(1, nil)
+				cfc.GetAppQuotasReturns(models.AppQuotas{
+					InstanceMemory: 512,
+					Org:            models.MemoryQuota{Limit: 2048, Used: 1024},
+					Space:          models.MemoryQuota{Limit: models.UnlimitedMemory},
+				}, nil)
+			})
+
+			It("sets the app instances allowed by the quota", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, instances := cfc.SetAppInstancesArgsForCall(0)
+				Expect(instances).To(Equal(3))
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeSchedule,
+					Status:       models.ScalingStatusSucceeded,
+					OldInstances: 1,
+					NewInstances: 3,
+					Reason:       "schedule starts with instance min 2, instance max 10 and instance min initial 5",
+					Message:      "limited by quota of the org",
+				}))
+			})
+		})
+
 		Context("when initial min instance is zero (not set)", func() {
 			BeforeEach(func() {
 				activeSchedule.InstanceMinInitial = 0
@@ -540,7 +1779,7 @@ var _ = Describe("ScalingEngine", func() {
 				It("sets the app instances to be InstanceMin", func() {
 					Expect(err).NotTo(HaveOccurred())
 
-					appid, instances := cfc.SetAppInstancesArgsForCall(0)
+					appid, _, instances := cfc.SetAppInstancesArgsForCall(0)
 					Expect(appid).To(Equal("an-app-id"))
 					Expect(instances).To(Equal(2))
 
@@ -588,7 +1827,7 @@ var _ = Describe("ScalingEngine", func() {
 				It("sets the app instances to be InstanceMinInitial", func() {
 					Expect(err).NotTo(HaveOccurred())
 
-					appid, instances := cfc.SetAppInstancesArgsForCall(0)
+					appid, _, instances := cfc.SetAppInstancesArgsForCall(0)
 					Expect(appid).To(Equal("an-app-id"))
 					Expect(instances).To(Equal(5))
 
@@ -681,6 +1920,29 @@ var _ = Describe("ScalingEngine", func() {
 			})
 		})
 
+		Context("when getting app policy fails", func() {
+			BeforeEach(func() {
+				policyDB.GetAppPolicyReturns(nil, errors.New("an error"))
+			})
+
+			It("should error", func() {
+				Expect(err).To(HaveOccurred())
+				Eventually(buffer).Should(gbytes.Say("failed-to-get-app-policy"))
+				Expect(cfc.GetAppInstancesCallCount()).To(BeZero())
+
+				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    clock.Now().UnixNano(),
+					ScalingType:  models.ScalingTypeSchedule,
+					Status:       models.ScalingStatusFailed,
+					OldInstances: -1,
+					NewInstances: -1,
+					Reason:       "schedule starts with instance min 2, instance max 10 and instance min initial 5",
+					Error:        "failed to get app policy",
+				}))
+			})
+		})
+
 		Context("when getting app instances fails", func() {
 			BeforeEach(func() {
 				cfc.GetAppInstancesReturns(0, errors.New("an error"))
@@ -741,6 +2003,19 @@ var _ = Describe("ScalingEngine", func() {
 			policyDB.GetAppPolicyReturns(&models.ScalingPolicy{InstanceMin: 3, InstanceMax: 6}, nil)
 		})
 
+		Context("when locking the app fails", func() {
+			BeforeEach(func() {
+				scalingEngineDB.GetActiveScheduleReturns(&models.ActiveSchedule{ScheduleId: "a-schedule-id"}, nil)
+				leaseDB.AcquireLeaseReturns(false, errors.New("an error"))
+			})
+
+			It("should error without removing the active schedule", func() {
+				Expect(err).To(MatchError("an error"))
+				Expect(scalingEngineDB.RemoveActiveScheduleCallCount()).To(BeZero())
+				Expect(cfc.SetAppInstancesCallCount()).To(BeZero())
+			})
+		})
+
 		Context("when app instance number is in the default range [InstanceMin, InstianceMax] in the policy", func() {
 			BeforeEach(func() {
 				scalingEngineDB.GetActiveScheduleReturns(&models.ActiveSchedule{ScheduleId: "a-schedule-id"}, nil)
@@ -768,7 +2043,7 @@ var _ = Describe("ScalingEngine", func() {
 			})
 
 			It("changes the instance number to InstanceMin", func() {
-				appId, instances := cfc.SetAppInstancesArgsForCall(0)
+				appId, _, instances := cfc.SetAppInstancesArgsForCall(0)
 				Expect(appId).To(Equal("an-app-id"))
 				Expect(instances).To(Equal(3))
 				Expect(scalingEngineDB.SaveScalingHistoryArgsForCall(0)).To(Equal(&models.AppScalingHistory{
@@ -792,7 +2067,7 @@ var _ = Describe("ScalingEngine", func() {
 			})
 
 			It("changes the instance number to instance-max-count", func() {
-				appId, instances := cfc.SetAppInstancesArgsForCall(0)
+				appId, _, instances := cfc.SetAppInstancesArgsForCall(0)
 				Expect(appId).To(Equal("an-app-id"))
 				Expect(instances).To(Equal(6))
 
@@ -895,7 +2170,7 @@ var _ = Describe("ScalingEngine", func() {
 					Timestamp:    clock.Now().UnixNano(),
 					ScalingType:  models.ScalingTypeSchedule,
 					Status:       models.ScalingStatusFailed,
-					OldInstances: 0,
+					OldInstances: -1,
 					NewInstances: -1,
 					Reason:       "schedule ends",
 					Error:        "failed to get app policy",
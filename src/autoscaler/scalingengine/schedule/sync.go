@@ -20,6 +20,8 @@ type ActiveScheduleSychronizer struct {
 	engine      scalingengine.ScalingEngine
 	interval    time.Duration
 	sClock      clock.Clock
+	lock        sync.Mutex
+	lastTick    time.Time
 }
 
 func NewActiveScheduleSychronizer(logger lager.Logger, schedulerDB db.SchedulerDB, engineDB db.ScalingEngineDB, engine scalingengine.ScalingEngine, interval time.Duration, sClock clock.Clock) *ActiveScheduleSychronizer {
@@ -36,6 +38,7 @@ func NewActiveScheduleSychronizer(logger lager.Logger, schedulerDB db.SchedulerD
 func (ss *ActiveScheduleSychronizer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	close(ready)
 	ss.logger.Info("started", lager.Data{"interval": ss.interval})
+	ss.recordTick()
 
 	timer := ss.sClock.NewTimer(ss.interval)
 	for {
@@ -45,11 +48,24 @@ func (ss *ActiveScheduleSychronizer) Run(signals <-chan os.Signal, ready chan<-
 			return nil
 		case <-timer.C():
 			ss.synchronizeActiveSchedules()
+			ss.recordTick()
 			timer.Reset(ss.interval)
 		}
 	}
 }
 
+func (ss *ActiveScheduleSychronizer) recordTick() {
+	ss.lock.Lock()
+	ss.lastTick = ss.sClock.Now()
+	ss.lock.Unlock()
+}
+
+func (ss *ActiveScheduleSychronizer) LastTickTime() time.Time {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	return ss.lastTick
+}
+
 func (ss *ActiveScheduleSychronizer) synchronizeActiveSchedules() {
 	ss.logger.Info("synchronizing-active-schedules")
 
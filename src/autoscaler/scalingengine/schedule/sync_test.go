@@ -71,6 +71,15 @@ var _ = Describe("Sync", func() {
 			signals <- os.Interrupt
 		})
 
+		It("records the time of the start and of the last synchronization", func() {
+			Eventually(synchronizer.LastTickTime).Should(Equal(fclock.Now()))
+
+			fclock.WaitForWatcherAndIncrement(TestSyncInterval)
+			Eventually(synchronizer.LastTickTime).Should(Equal(fclock.Now()))
+
+			signals <- os.Interrupt
+		})
+
 		Context("when data are consistent", func() {
 			BeforeEach(func() {
 				schedulerDB.GetActiveSchedulesReturns(map[string]*models.ActiveSchedule{
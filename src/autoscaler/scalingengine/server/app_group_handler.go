@@ -0,0 +1,173 @@
+package server
+
+import (
+	"autoscaler/db"
+	"autoscaler/models"
+
+	"code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/lager"
+
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// AppGroupHandler manages the app groups whose members the scaling engine
+// scales together.
+type AppGroupHandler struct {
+	logger   lager.Logger
+	policyDB db.PolicyDB
+}
+
+func NewAppGroupHandler(logger lager.Logger, policyDB db.PolicyDB) *AppGroupHandler {
+	return &AppGroupHandler{
+		logger:   logger.Session("app-group-handler"),
+		policyDB: policyDB,
+	}
+}
+
+func (h *AppGroupHandler) GetAppGroup(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	groupId := vars["groupid"]
+	logger := h.logger.Session("get-app-group", lager.Data{"groupid": groupId})
+
+	group, err := h.policyDB.GetAppGroup(groupId)
+	if err != nil {
+		logger.Error("failed-to-get-app-group", err)
+		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    "Internal-server-error",
+			Message: "Error getting app group"})
+		return
+	}
+	if group == nil {
+		handlers.WriteJSONResponse(w, http.StatusNotFound, models.ErrorResponse{
+			Code:    "Not-Found",
+			Message: "App group not found"})
+		return
+	}
+
+	handlers.WriteJSONResponse(w, http.StatusOK, group)
+}
+
+// SetAppGroup creates the group or replaces its members. Every member needs a
+// scaling policy. An app can only be a member of one group; the check before
+// saving gives a helpful message, and the database rejects an app that joined
+// another group meanwhile.
+func (h *AppGroupHandler) SetAppGroup(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	groupId := vars["groupid"]
+	logger := h.logger.Session("set-app-group", lager.Data{"groupid": groupId})
+
+	group := &models.AppGroup{}
+	err := json.NewDecoder(r.Body).Decode(group)
+	if err != nil {
+		logger.Error("failed-to-decode", err)
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: "Incorrect app group in request body"})
+		return
+	}
+	group.GroupId = groupId
+
+	if message := validateAppGroup(group); message != "" {
+		logger.Info("invalid-app-group", lager.Data{"group": group, "message": message})
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: message})
+		return
+	}
+
+	for _, m := range group.Members {
+		_, err := h.policyDB.GetAppPolicy(m.AppId)
+		if err == sql.ErrNoRows {
+			handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+				Code:    "Bad-Request",
+				Message: fmt.Sprintf("App %s has no scaling policy", m.AppId)})
+			return
+		}
+		if err != nil {
+			logger.Error("failed-to-get-app-policy", err, lager.Data{"appid": m.AppId})
+			handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+				Code:    "Internal-server-error",
+				Message: "Error setting app group"})
+			return
+		}
+
+		current, err := h.policyDB.GetAppGroupOfApp(m.AppId)
+		if err != nil {
+			logger.Error("failed-to-get-app-group-of-app", err, lager.Data{"appid": m.AppId})
+			handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+				Code:    "Internal-server-error",
+				Message: "Error setting app group"})
+			return
+		}
+		if current != nil && current.GroupId != groupId {
+			handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+				Code:    "Bad-Request",
+				Message: fmt.Sprintf("App %s is a member of app group %s", m.AppId, current.GroupId)})
+			return
+		}
+	}
+
+	err = h.policyDB.SaveAppGroup(group)
+	if conflictErr, ok := err.(*db.AppGroupConflictError); ok {
+		logger.Info("app-group-conflict", lager.Data{"group": group, "appid": conflictErr.AppId})
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: fmt.Sprintf("App %s is a member of another app group", conflictErr.AppId)})
+		return
+	}
+	if err != nil {
+		logger.Error("failed-to-save-app-group", err, lager.Data{"group": group})
+		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    "Internal-server-error",
+			Message: "Error setting app group"})
+		return
+	}
+
+	logger.Info("app-group-saved", lager.Data{"group": group})
+	handlers.WriteJSONResponse(w, http.StatusOK, group)
+}
+
+func (h *AppGroupHandler) DeleteAppGroup(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	groupId := vars["groupid"]
+	logger := h.logger.Session("delete-app-group", lager.Data{"groupid": groupId})
+
+	err := h.policyDB.DeleteAppGroup(groupId)
+	if err != nil {
+		logger.Error("failed-to-delete-app-group", err)
+		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    "Internal-server-error",
+			Message: "Error deleting app group"})
+		return
+	}
+
+	logger.Info("app-group-deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateAppGroup returns why the group is invalid, or an empty string when
+// it is valid.
+func validateAppGroup(group *models.AppGroup) string {
+	if len(group.Members) < 2 {
+		return "An app group needs at least 2 members"
+	}
+
+	appIds := map[string]bool{}
+	for _, m := range group.Members {
+		if m.AppId == "" {
+			return "The app id of a member is missing"
+		}
+		if appIds[m.AppId] {
+			return fmt.Sprintf("App %s is listed more than once", m.AppId)
+		}
+		appIds[m.AppId] = true
+
+		if m.Ratio < 1 {
+			return fmt.Sprintf("The ratio of app %s must be at least 1", m.AppId)
+		}
+		if m.InstanceMin < 1 || m.InstanceMin > m.InstanceMax {
+			return fmt.Sprintf("The instance limits of app %s must satisfy 1 <= instance_min_count <= instance_max_count", m.AppId)
+		}
+	}
+	return ""
+}
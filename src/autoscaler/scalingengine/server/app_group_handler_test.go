@@ -0,0 +1,336 @@
+package server_test
+
+import (
+	"autoscaler/db"
+	"autoscaler/models"
+	"autoscaler/scalingengine/fakes"
+	. "autoscaler/scalingengine/server"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+)
+
+const testUrlAppGroup = "http://localhost/v1/app_groups/a-group-id"
+
+var _ = Describe("AppGroupHandler", func() {
+	var (
+		policyDB *fakes.FakePolicyDB
+		handler  *AppGroupHandler
+		resp     *httptest.ResponseRecorder
+		req      *http.Request
+		body     []byte
+		err      error
+		group    *models.AppGroup
+	)
+
+	BeforeEach(func() {
+		policyDB = &fakes.FakePolicyDB{}
+		handler = NewAppGroupHandler(lagertest.NewTestLogger("app-group-handler-test"), policyDB)
+		resp = httptest.NewRecorder()
+		group = &models.AppGroup{
+			GroupId: "a-group-id",
+			Members: []*models.AppGroupMember{
+				{AppId: "app-a", Ratio: 2, InstanceMin: 2, InstanceMax: 10},
+				{AppId: "app-b", Ratio: 1, InstanceMin: 1, InstanceMax: 5, ProcessType: "worker"},
+			},
+		}
+	})
+
+	expectErrorResponse := func(code int, errorResponse models.ErrorResponse) {
+		Expect(resp.Code).To(Equal(code))
+		errJson := &models.ErrorResponse{}
+		err = json.Unmarshal(resp.Body.Bytes(), errJson)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*errJson).To(Equal(errorResponse))
+	}
+
+	Describe("GetAppGroup", func() {
+		JustBeforeEach(func() {
+			req, err = http.NewRequest(http.MethodGet, testUrlAppGroup, nil)
+			Expect(err).ToNot(HaveOccurred())
+			handler.GetAppGroup(resp, req, map[string]string{"groupid": "a-group-id"})
+		})
+
+		Context("when the group exists", func() {
+			BeforeEach(func() {
+				policyDB.GetAppGroupReturns(group, nil)
+			})
+
+			It("returns 200 with the group", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(policyDB.GetAppGroupArgsForCall(0)).To(Equal("a-group-id"))
+
+				result := &models.AppGroup{}
+				Expect(json.Unmarshal(resp.Body.Bytes(), result)).To(Succeed())
+				Expect(result).To(Equal(group))
+			})
+		})
+
+		Context("when the group does not exist", func() {
+			It("returns 404", func() {
+				expectErrorResponse(http.StatusNotFound, models.ErrorResponse{
+					Code:    "Not-Found",
+					Message: "App group not found",
+				})
+			})
+		})
+
+		Context("when getting the group fails", func() {
+			BeforeEach(func() {
+				policyDB.GetAppGroupReturns(nil, errors.New("an error"))
+			})
+
+			It("returns 500", func() {
+				expectErrorResponse(http.StatusInternalServerError, models.ErrorResponse{
+					Code:    "Internal-server-error",
+					Message: "Error getting app group",
+				})
+			})
+		})
+	})
+
+	Describe("SetAppGroup", func() {
+		BeforeEach(func() {
+			body, err = json.Marshal(group)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			req, err = http.NewRequest(http.MethodPut, testUrlAppGroup, bytes.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			handler.SetAppGroup(resp, req, map[string]string{"groupid": "a-group-id"})
+		})
+
+		Context("when the group is valid", func() {
+			It("saves the group and returns 200", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(policyDB.SaveAppGroupCallCount()).To(Equal(1))
+				Expect(policyDB.SaveAppGroupArgsForCall(0)).To(Equal(group))
+			})
+		})
+
+		Context("when the group id in the body differs from the path", func() {
+			BeforeEach(func() {
+				group.GroupId = "another-group-id"
+				body, err = json.Marshal(group)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("saves the group with the id in the path", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(policyDB.SaveAppGroupArgsForCall(0).GroupId).To(Equal("a-group-id"))
+			})
+		})
+
+		Context("when the group is already saved", func() {
+			BeforeEach(func() {
+				policyDB.GetAppGroupOfAppReturns(&models.AppGroup{GroupId: "a-group-id"}, nil)
+			})
+
+			It("replaces the group and returns 200", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(policyDB.SaveAppGroupCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when request body is not valid", func() {
+			BeforeEach(func() {
+				body = []byte(`{"members":"a"}`)
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "Incorrect app group in request body",
+				})
+				Expect(policyDB.SaveAppGroupCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the group has a single member", func() {
+			BeforeEach(func() {
+				body = []byte(`{"members":[{"app_id":"app-a","ratio":1,"instance_min_count":1,"instance_max_count":5}]}`)
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "An app group needs at least 2 members",
+				})
+			})
+		})
+
+		Context("when an app is listed twice", func() {
+			BeforeEach(func() {
+				group.Members[1].AppId = "app-a"
+				body, err = json.Marshal(group)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "App app-a is listed more than once",
+				})
+			})
+		})
+
+		Context("when the ratio of a member is not positive", func() {
+			BeforeEach(func() {
+				group.Members[1].Ratio = 0
+				body, err = json.Marshal(group)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "The ratio of app app-b must be at least 1",
+				})
+			})
+		})
+
+		Context("when the instance min of a member is greater than its instance max", func() {
+			BeforeEach(func() {
+				group.Members[0].InstanceMin = 11
+				body, err = json.Marshal(group)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "The instance limits of app app-a must satisfy 1 <= instance_min_count <= instance_max_count",
+				})
+			})
+		})
+
+		Context("when an app is a member of another group", func() {
+			BeforeEach(func() {
+				policyDB.GetAppGroupOfAppStub = func(appId string) (*models.AppGroup, error) {
+					if appId == "app-b" {
+						return &models.AppGroup{GroupId: "another-group-id"}, nil
+					}
+					return nil, nil
+				}
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "App app-b is a member of app group another-group-id",
+				})
+				Expect(policyDB.SaveAppGroupCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when an app has no scaling policy", func() {
+			BeforeEach(func() {
+				policyDB.GetAppPolicyStub = func(appId string) (*models.ScalingPolicy, error) {
+					if appId == "app-b" {
+						return nil, sql.ErrNoRows
+					}
+					return &models.ScalingPolicy{}, nil
+				}
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "App app-b has no scaling policy",
+				})
+				Expect(policyDB.SaveAppGroupCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when getting the policy of an app fails", func() {
+			BeforeEach(func() {
+				policyDB.GetAppPolicyReturns(nil, errors.New("an error"))
+			})
+
+			It("returns 500", func() {
+				expectErrorResponse(http.StatusInternalServerError, models.ErrorResponse{
+					Code:    "Internal-server-error",
+					Message: "Error setting app group",
+				})
+				Expect(policyDB.SaveAppGroupCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when getting the group of an app fails", func() {
+			BeforeEach(func() {
+				policyDB.GetAppGroupOfAppReturns(nil, errors.New("an error"))
+			})
+
+			It("returns 500", func() {
+				expectErrorResponse(http.StatusInternalServerError, models.ErrorResponse{
+					Code:    "Internal-server-error",
+					Message: "Error setting app group",
+				})
+				Expect(policyDB.SaveAppGroupCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when an app joins another group while the group is saved", func() {
+			BeforeEach(func() {
+				policyDB.SaveAppGroupReturns(&db.AppGroupConflictError{AppId: "app-b"})
+			})
+
+			It("returns 400", func() {
+				expectErrorResponse(http.StatusBadRequest, models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "App app-b is a member of another app group",
+				})
+			})
+		})
+
+		Context("when saving the group fails", func() {
+			BeforeEach(func() {
+				policyDB.SaveAppGroupReturns(errors.New("an error"))
+			})
+
+			It("returns 500", func() {
+				expectErrorResponse(http.StatusInternalServerError, models.ErrorResponse{
+					Code:    "Internal-server-error",
+					Message: "Error setting app group",
+				})
+			})
+		})
+	})
+
+	Describe("DeleteAppGroup", func() {
+		JustBeforeEach(func() {
+			req, err = http.NewRequest(http.MethodDelete, testUrlAppGroup, nil)
+			Expect(err).ToNot(HaveOccurred())
+			handler.DeleteAppGroup(resp, req, map[string]string{"groupid": "a-group-id"})
+		})
+
+		Context("when deleting the group succeeds", func() {
+			It("returns 204", func() {
+				Expect(resp.Code).To(Equal(http.StatusNoContent))
+				Expect(policyDB.DeleteAppGroupArgsForCall(0)).To(Equal("a-group-id"))
+			})
+		})
+
+		Context("when deleting the group fails", func() {
+			BeforeEach(func() {
+				policyDB.DeleteAppGroupReturns(errors.New("an error"))
+			})
+
+			It("returns 500", func() {
+				expectErrorResponse(http.StatusInternalServerError, models.ErrorResponse{
+					Code:    "Internal-server-error",
+					Message: "Error deleting app group",
+				})
+			})
+		})
+	})
+})
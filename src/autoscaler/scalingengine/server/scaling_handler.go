@@ -60,10 +60,98 @@ func (h *ScalingHandler) Scale(w http.ResponseWriter, r *http.Request, vars map[
 	handlers.WriteJSONResponse(w, http.StatusOK, models.AppEntity{Instances: newInstances})
 }
 
+func (h *ScalingHandler) RestartInstance(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	appId := vars["appid"]
+	logger := h.logger.Session("restart-instance", lager.Data{"appId": appId, "index": vars["index"]})
+
+	instanceIndex, err := strconv.Atoi(vars["index"])
+	if err != nil || instanceIndex < 0 {
+		logger.Error("failed-to-parse-instance-index", err)
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: "Incorrect instance index in request path"})
+		return
+	}
+
+	trigger := &models.Trigger{}
+	err = json.NewDecoder(r.Body).Decode(trigger)
+	if err != nil {
+		logger.Error("failed-to-decode", err)
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: "Incorrect trigger in request body"})
+		return
+	}
+
+	logger.Debug("handling", lager.Data{"trigger": trigger})
+
+	err = h.scalingEngine.RestartInstance(appId, instanceIndex, trigger)
+	if err != nil {
+		logger.Error("failed-to-restart-instance", err, lager.Data{"trigger": trigger})
+		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    "Internal-server-error",
+			Message: "Error restarting app instance"})
+	}
+}
+
+func (h *ScalingHandler) Resize(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	appId := vars["appid"]
+	logger := h.logger.Session("resize", lager.Data{"appId": appId})
+
+	trigger := &models.Trigger{}
+	err := json.NewDecoder(r.Body).Decode(trigger)
+	if err != nil {
+		logger.Error("failed-to-decode", err)
+		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Code:    "Bad-Request",
+			Message: "Incorrect trigger in request body"})
+		return
+	}
+
+	logger.Debug("handling", lager.Data{"trigger": trigger})
+
+	err = h.scalingEngine.Resize(appId, trigger)
+	if err != nil {
+		logger.Error("failed-to-resize", err, lager.Data{"trigger": trigger})
+		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    "Internal-server-error",
+			Message: "Error resizing app instances"})
+	}
+}
+
 func (h *ScalingHandler) GetScalingHistories(w http.ResponseWriter, r *http.Request, vars map[string]string) {
 	appId := vars["appid"]
 	logger := h.logger.Session("get-scaling-histories", lager.Data{"appId": appId})
 
+	start, end, ok := parseHistoryTimeRange(logger, w, r)
+	if !ok {
+		return
+	}
+
+	histories, err := h.scalingEngineDB.RetrieveScalingHistories(appId, start, end)
+	writeScalingHistories(logger, w, histories, err, start, end)
+}
+
+// GetGroupScalingHistories returns the histories of the members of the app
+// group scaled together. There is no history of the group as such: the members
+// scaled at once share a timestamp, and each history records the trigger, the
+// new instances given by the ratio of the member and the status of its scaling.
+func (h *ScalingHandler) GetGroupScalingHistories(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	groupId := vars["groupid"]
+	logger := h.logger.Session("get-group-scaling-histories", lager.Data{"groupId": groupId})
+
+	start, end, ok := parseHistoryTimeRange(logger, w, r)
+	if !ok {
+		return
+	}
+
+	histories, err := h.scalingEngineDB.RetrieveGroupScalingHistories(groupId, start, end)
+	writeScalingHistories(logger, w, histories, err, start, end)
+}
+
+// parseHistoryTimeRange returns the start and end parameters of the request,
+// or writes a bad request response and returns false when they are invalid.
+func parseHistoryTimeRange(logger lager.Logger, w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
 	startParam := r.URL.Query()["start"]
 	endParam := r.URL.Query()["end"]
 	logger.Debug("handling", lager.Data{"start": startParam, "end": endParam})
@@ -79,14 +167,14 @@ func (h *ScalingHandler) GetScalingHistories(w http.ResponseWriter, r *http.Requ
 			handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
 				Code:    "Bad-Request",
 				Message: "Error parsing start time"})
-			return
+			return 0, 0, false
 		}
 	} else if len(startParam) > 1 {
 		logger.Error("failed-to-get-start-time", err, lager.Data{"start": startParam})
 		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
 			Code:    "Bad-Request",
 			Message: "Incorrect start parameter in query string"})
-		return
+		return 0, 0, false
 	}
 
 	if len(endParam) == 1 {
@@ -96,19 +184,19 @@ func (h *ScalingHandler) GetScalingHistories(w http.ResponseWriter, r *http.Requ
 			handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
 				Code:    "Bad-Request",
 				Message: "Error parsing end time"})
-			return
+			return 0, 0, false
 		}
 	} else if len(endParam) > 1 {
 		logger.Error("failed-to-get-end-time", err, lager.Data{"end": endParam})
 		handlers.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
 			Code:    "Bad-Request",
 			Message: "Incorrect end parameter in query string"})
-		return
+		return 0, 0, false
 	}
+	return start, end, true
+}
 
-	var histories []*models.AppScalingHistory
-
-	histories, err = h.scalingEngineDB.RetrieveScalingHistories(appId, start, end)
+func writeScalingHistories(logger lager.Logger, w http.ResponseWriter, histories []*models.AppScalingHistory, err error, start int64, end int64) {
 	if err != nil {
 		logger.Error("failed-to-retrieve-histories", err, lager.Data{"start": start, "end": end})
 		handlers.WriteJSONResponse(w, http.StatusInternalServerError, models.ErrorResponse{
@@ -19,6 +19,7 @@ import (
 )
 
 const testUrlScalingHistories = "http://localhost/v1/apps/an-app-id/scaling_histories"
+const testUrlGroupScalingHistories = "http://localhost/v1/app_groups/a-group-id/scaling_histories"
 const testUrlActiveSchedules = "http://localhost/v1/apps/an-app-id/active_schedules/a-schedule-id"
 
 var _ = Describe("ScalingHandler", func() {
@@ -127,6 +128,159 @@ var _ = Describe("ScalingHandler", func() {
 		})
 	})
 
+	Describe("RestartInstance", func() {
+		var index string
+
+		BeforeEach(func() {
+			index = "2"
+			trigger = &models.Trigger{
+				MetricType: models.MetricNameMemory,
+				Action:     models.ActionRestartInstance,
+			}
+			body, err = json.Marshal(trigger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			req, err = http.NewRequest("POST", "", bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			handler.RestartInstance(resp, req, map[string]string{"appid": "an-app-id", "index": index})
+		})
+
+		Context("when restarting the instance succeeds", func() {
+			It("returns 200", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+
+				Expect(scalingEngine.RestartInstanceCallCount()).To(Equal(1))
+				appId, instanceIndex, restartTrigger := scalingEngine.RestartInstanceArgsForCall(0)
+				Expect(appId).To(Equal("an-app-id"))
+				Expect(instanceIndex).To(Equal(2))
+				Expect(restartTrigger).To(Equal(trigger))
+			})
+		})
+
+		Context("when the instance index is not valid", func() {
+			BeforeEach(func() {
+				index = "-1"
+			})
+
+			It("returns 400", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(scalingEngine.RestartInstanceCallCount()).To(BeZero())
+
+				errJson := &models.ErrorResponse{}
+				err = json.Unmarshal(resp.Body.Bytes(), errJson)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(errJson).To(Equal(&models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "Incorrect instance index in request path",
+				}))
+			})
+		})
+
+		Context("when request body is not valid", func() {
+			BeforeEach(func() {
+				body = []byte(`bad body`)
+			})
+
+			It("returns 400", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+
+				errJson := &models.ErrorResponse{}
+				err = json.Unmarshal(resp.Body.Bytes(), errJson)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(errJson).To(Equal(&models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "Incorrect trigger in request body",
+				}))
+			})
+		})
+
+		Context("when restarting the instance fails", func() {
+			BeforeEach(func() {
+				scalingEngine.RestartInstanceReturns(errors.New("an error"))
+			})
+
+			It("returns 500", func() {
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+
+				errJson := &models.ErrorResponse{}
+				err = json.Unmarshal(resp.Body.Bytes(), errJson)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(errJson).To(Equal(&models.ErrorResponse{
+					Code:    "Internal-server-error",
+					Message: "Error restarting app instance",
+				}))
+			})
+		})
+	})
+
+	Describe("Resize", func() {
+		BeforeEach(func() {
+			trigger = &models.Trigger{
+				MetricType:       models.MetricNameMemory,
+				Action:           models.ActionResize,
+				MemoryAdjustment: "+256",
+			}
+			body, err = json.Marshal(trigger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			req, err = http.NewRequest("POST", "", bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			handler.Resize(resp, req, map[string]string{"appid": "an-app-id"})
+		})
+
+		Context("when resizing succeeds", func() {
+			It("returns 200", func() {
+				Expect(resp.Code).To(Equal(http.StatusOK))
+
+				Expect(scalingEngine.ResizeCallCount()).To(Equal(1))
+				appId, resizeTrigger := scalingEngine.ResizeArgsForCall(0)
+				Expect(appId).To(Equal("an-app-id"))
+				Expect(resizeTrigger).To(Equal(trigger))
+			})
+		})
+
+		Context("when request body is not valid", func() {
+			BeforeEach(func() {
+				body = []byte(`bad body`)
+			})
+
+			It("returns 400", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(scalingEngine.ResizeCallCount()).To(BeZero())
+
+				errJson := &models.ErrorResponse{}
+				err = json.Unmarshal(resp.Body.Bytes(), errJson)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(errJson).To(Equal(&models.ErrorResponse{
+					Code:    "Bad-Request",
+					Message: "Incorrect trigger in request body",
+				}))
+			})
+		})
+
+		Context("when resizing fails", func() {
+			BeforeEach(func() {
+				scalingEngine.ResizeReturns(errors.New("an error"))
+			})
+
+			It("returns 500", func() {
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+
+				errJson := &models.ErrorResponse{}
+				err = json.Unmarshal(resp.Body.Bytes(), errJson)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(errJson).To(Equal(&models.ErrorResponse{
+					Code:    "Internal-server-error",
+					Message: "Error resizing app instances",
+				}))
+			})
+		})
+	})
+
 	Describe("GetScalingHistories", func() {
 		JustBeforeEach(func() {
 			handler.GetScalingHistories(resp, req, map[string]string{"appid": "an-app-id"})
@@ -321,6 +475,78 @@ var _ = Describe("ScalingHandler", func() {
 		})
 	})
 
+	Describe("GetGroupScalingHistories", func() {
+		JustBeforeEach(func() {
+			handler.GetGroupScalingHistories(resp, req, map[string]string{"groupid": "a-group-id"})
+		})
+
+		Context("when the start time is invalid", func() {
+			BeforeEach(func() {
+				req, err = http.NewRequest(http.MethodGet, testUrlGroupScalingHistories+"?start=abc", nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns 400", func() {
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(scalingEngineDB.RetrieveGroupScalingHistoriesCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when query database succeeds", func() {
+			BeforeEach(func() {
+				req, err = http.NewRequest(http.MethodGet, testUrlGroupScalingHistories+"?start=123&end=567", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				history1 = &models.AppScalingHistory{
+					AppId:        "an-app-id",
+					Timestamp:    222,
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusSucceeded,
+					OldInstances: 2,
+					NewInstances: 4,
+					Reason:       "a reason",
+					GroupId:      "a-group-id",
+				}
+				history2 = &models.AppScalingHistory{
+					AppId:        "another-app-id",
+					Timestamp:    222,
+					ScalingType:  models.ScalingTypeDynamic,
+					Status:       models.ScalingStatusSucceeded,
+					OldInstances: 1,
+					NewInstances: 2,
+					Reason:       "a reason",
+					GroupId:      "a-group-id",
+				}
+				scalingEngineDB.RetrieveGroupScalingHistoriesReturns([]*models.AppScalingHistory{history1, history2}, nil)
+			})
+
+			It("returns 200 with the histories of the members", func() {
+				groupId, start, end := scalingEngineDB.RetrieveGroupScalingHistoriesArgsForCall(0)
+				Expect(groupId).To(Equal("a-group-id"))
+				Expect(start).To(Equal(int64(123)))
+				Expect(end).To(Equal(int64(567)))
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				histories := &[]models.AppScalingHistory{}
+				err = json.Unmarshal(resp.Body.Bytes(), histories)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(*histories).To(Equal([]models.AppScalingHistory{*history1, *history2}))
+			})
+		})
+
+		Context("when query database fails", func() {
+			BeforeEach(func() {
+				req, err = http.NewRequest(http.MethodGet, testUrlGroupScalingHistories, nil)
+				Expect(err).ToNot(HaveOccurred())
+				scalingEngineDB.RetrieveGroupScalingHistoriesReturns(nil, errors.New("database error"))
+			})
+
+			It("returns 500", func() {
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
 	Describe("StartActiveSchedule", func() {
 		JustBeforeEach(func() {
 			req, err = http.NewRequest(http.MethodPut, testUrlActiveSchedules, bytes.NewReader(body))
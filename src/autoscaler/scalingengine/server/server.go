@@ -23,14 +23,22 @@ func (vh VarsFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	vh(w, r, vars)
 }
 
-func NewServer(logger lager.Logger, conf *config.Config, scalingEngineDB db.ScalingEngineDB, scalingEngine scalingengine.ScalingEngine) (ifrit.Runner, error) {
+func NewServer(logger lager.Logger, conf *config.Config, policyDB db.PolicyDB, scalingEngineDB db.ScalingEngineDB,
+	scalingEngine scalingengine.ScalingEngine) (ifrit.Runner, error) {
 	handler := NewScalingHandler(logger, scalingEngineDB, scalingEngine)
+	groupHandler := NewAppGroupHandler(logger, policyDB)
 
 	r := routes.ScalingEngineRoutes()
 	r.Get(routes.ScaleRoute).Methods(http.MethodPost).Handler(VarsFunc(handler.Scale))
+	r.Get(routes.RestartInstanceRoute).Methods(http.MethodPost).Handler(VarsFunc(handler.RestartInstance))
+	r.Get(routes.ResizeRoute).Methods(http.MethodPost).Handler(VarsFunc(handler.Resize))
 	r.Get(routes.HistoreisRoute).Methods(http.MethodGet).Handler(VarsFunc(handler.GetScalingHistories))
 	r.Get(routes.UpdateActiveSchedulesRoute).Methods(http.MethodPut).Handler(VarsFunc(handler.StartActiveSchedule))
 	r.Get(routes.DeleteActiveSchedulesRoute).Methods(http.MethodDelete).Handler(VarsFunc(handler.RemoveActiveSchedule))
+	r.Get(routes.GetAppGroupRoute).Methods(http.MethodGet).Handler(VarsFunc(groupHandler.GetAppGroup))
+	r.Get(routes.SetAppGroupRoute).Methods(http.MethodPut).Handler(VarsFunc(groupHandler.SetAppGroup))
+	r.Get(routes.DeleteAppGroupRoute).Methods(http.MethodDelete).Handler(VarsFunc(groupHandler.DeleteAppGroup))
+	r.Get(routes.GroupHistoriesRoute).Methods(http.MethodGet).Handler(VarsFunc(handler.GetGroupScalingHistories))
 
 	addr := fmt.Sprintf("0.0.0.0:%d", conf.Server.Port)
 	logger.Info("new-http-server", lager.Data{"serverConfig": conf.Server})
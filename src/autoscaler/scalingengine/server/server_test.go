@@ -33,9 +33,10 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 			Port: port,
 		},
 	}
+	policyDB := &fakes.FakePolicyDB{}
 	scalingEngineDB := &fakes.FakeScalingEngineDB{}
 	scalingEngine := &fakes.FakeScalingEngine{}
-	httpServer, err := NewServer(lager.NewLogger("test"), conf, scalingEngineDB, scalingEngine)
+	httpServer, err := NewServer(lager.NewLogger("test"), conf, policyDB, scalingEngineDB, scalingEngine)
 	Expect(err).NotTo(HaveOccurred())
 	server = ginkgomon.Invoke(httpServer)
 	serverUrl = fmt.Sprintf("http://127.0.0.1:%d", conf.Server.Port)
@@ -105,6 +106,76 @@ var _ = Describe("Server", func() {
 		})
 	})
 
+	Context("when restarting an instance", func() {
+		BeforeEach(func() {
+			body, err = json.Marshal(models.Trigger{Action: models.ActionRestartInstance})
+			Expect(err).NotTo(HaveOccurred())
+
+			uPath, err := route.Get(routes.RestartInstanceRoute).URLPath("appid", "test-app-id", "index", "1")
+			Expect(err).NotTo(HaveOccurred())
+			urlPath = uPath.Path
+		})
+
+		Context("when requesting correctly", func() {
+			JustBeforeEach(func() {
+				rsp, err = http.Post(serverUrl+urlPath, "application/json", bytes.NewReader(body))
+			})
+
+			It("should return 200", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+				rsp.Body.Close()
+			})
+		})
+
+		Context("when using the wrong method", func() {
+			JustBeforeEach(func() {
+				rsp, err = http.Get(serverUrl + urlPath)
+			})
+
+			It("should return 404", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+				rsp.Body.Close()
+			})
+		})
+	})
+
+	Context("when resizing an app", func() {
+		BeforeEach(func() {
+			body, err = json.Marshal(models.Trigger{Action: models.ActionResize, MemoryAdjustment: "+256"})
+			Expect(err).NotTo(HaveOccurred())
+
+			uPath, err := route.Get(routes.ResizeRoute).URLPath("appid", "test-app-id")
+			Expect(err).NotTo(HaveOccurred())
+			urlPath = uPath.Path
+		})
+
+		Context("when requesting correctly", func() {
+			JustBeforeEach(func() {
+				rsp, err = http.Post(serverUrl+urlPath, "application/json", bytes.NewReader(body))
+			})
+
+			It("should return 200", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+				rsp.Body.Close()
+			})
+		})
+
+		Context("when using the wrong method", func() {
+			JustBeforeEach(func() {
+				rsp, err = http.Get(serverUrl + urlPath)
+			})
+
+			It("should return 404", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+				rsp.Body.Close()
+			})
+		})
+	})
+
 	Context("when getting scaling histories", func() {
 		BeforeEach(func() {
 			uPath, err := route.Get(routes.HistoreisRoute).URLPath("appid", "test-app-id")
@@ -232,4 +303,81 @@ var _ = Describe("Server", func() {
 			})
 		})
 	})
+
+	Context("when getting the scaling histories of an app group", func() {
+		BeforeEach(func() {
+			uPath, err := route.Get(routes.GroupHistoriesRoute).URLPath("groupid", "test-group-id")
+			Expect(err).NotTo(HaveOccurred())
+			urlPath = uPath.Path
+		})
+
+		JustBeforeEach(func() {
+			rsp, err = http.Get(serverUrl + urlPath)
+		})
+
+		It("should return 200", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+			rsp.Body.Close()
+		})
+	})
+
+	Context("when requesting app groups", func() {
+		JustBeforeEach(func() {
+			req, err = http.NewRequest(method, serverUrl+urlPath, bodyReader)
+			Expect(err).NotTo(HaveOccurred())
+			rsp, err = http.DefaultClient.Do(req)
+		})
+
+		Context("when setting an app group", func() {
+			BeforeEach(func() {
+				uPath, err := route.Get(routes.SetAppGroupRoute).URLPath("groupid", "test-group-id")
+				Expect(err).NotTo(HaveOccurred())
+				urlPath = uPath.Path
+				bodyReader = bytes.NewReader([]byte(`{"members":[` +
+					`{"app_id":"app-a","ratio":2,"instance_min_count":2,"instance_max_count":10},` +
+					`{"app_id":"app-b","ratio":1,"instance_min_count":1,"instance_max_count":5}]}`))
+			})
+
+			Context("when requesting correctly", func() {
+				BeforeEach(func() {
+					method = http.MethodPut
+				})
+
+				It("should return 200", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+					rsp.Body.Close()
+				})
+			})
+
+			Context("when using the wrong method", func() {
+				BeforeEach(func() {
+					method = http.MethodPost
+				})
+
+				It("should return 404", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(http.StatusNotFound))
+					rsp.Body.Close()
+				})
+			})
+		})
+
+		Context("when deleting an app group", func() {
+			BeforeEach(func() {
+				uPath, err := route.Get(routes.DeleteAppGroupRoute).URLPath("groupid", "test-group-id")
+				Expect(err).NotTo(HaveOccurred())
+				urlPath = uPath.Path
+				bodyReader = nil
+				method = http.MethodDelete
+			})
+
+			It("should return 204", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(http.StatusNoContent))
+				rsp.Body.Close()
+			})
+		})
+	})
 })
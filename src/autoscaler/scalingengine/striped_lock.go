@@ -2,6 +2,7 @@ package scalingengine
 
 import (
 	"hash/fnv"
+	"sort"
 	"sync"
 )
 
@@ -25,10 +26,32 @@ func NewStripedLock(capacity int) *StripedLock {
 }
 
 func (sl *StripedLock) GetLock(key string) *sync.Mutex {
-	h := fnv.New32a()
-	_, err := h.Write([]byte(key))
-	if err != nil {
-		return nil
+	return sl.locks[sl.index(key)]
+}
+
+// GetLocks returns the distinct locks of the keys in the order of the stripes,
+// which is the order to take them in to hold several keys at once.
+func (sl *StripedLock) GetLocks(keys []string) []*sync.Mutex {
+	indexes := []int{}
+	seen := map[int]bool{}
+	for _, key := range keys {
+		i := sl.index(key)
+		if !seen[i] {
+			seen[i] = true
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+
+	locks := make([]*sync.Mutex, 0, len(indexes))
+	for _, i := range indexes {
+		locks = append(locks, sl.locks[i])
 	}
-	return sl.locks[h.Sum32()%uint32(len(sl.locks))]
+	return locks
+}
+
+func (sl *StripedLock) index(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(sl.locks)))
 }
@@ -52,4 +52,22 @@ var _ = Describe("StripedLock", func() {
 			})
 		})
 	})
+
+	Describe("GetLocks", func() {
+		BeforeEach(func() {
+			stripedLock = NewStripedLock(4)
+		})
+
+		It("returns the distinct locks of the keys in the same order whatever the order of the keys", func() {
+			keys := []string{"key-1", "key-2", "key-3", "key-4", "key-5", "key-6"}
+			locks := stripedLock.GetLocks(keys)
+			Expect(len(locks)).To(BeNumerically("<=", 4))
+			for _, key := range keys {
+				Expect(locks).To(ContainElement(BeIdenticalTo(stripedLock.GetLock(key))))
+			}
+
+			reversed := []string{"key-6", "key-5", "key-4", "key-3", "key-2", "key-1"}
+			Expect(stripedLock.GetLocks(reversed)).To(Equal(locks))
+		})
+	})
 })
@@ -0,0 +1,47 @@
+package sharding
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultHeartbeatInterval time.Duration = 10 * time.Second
+	DefaultMemberTTL         time.Duration = 30 * time.Second
+)
+
+type ShardingConfig struct {
+	Index             int           `yaml:"index"`
+	Count             int           `yaml:"count"`
+	Discovery         bool          `yaml:"discovery"`
+	MemberId          string        `yaml:"member_id"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	MemberTTL         time.Duration `yaml:"member_ttl"`
+}
+
+var DefaultShardingConfig = ShardingConfig{
+	Index:             0,
+	Count:             1,
+	HeartbeatInterval: DefaultHeartbeatInterval,
+	MemberTTL:         DefaultMemberTTL,
+}
+
+func (conf *ShardingConfig) Validate() error {
+	if conf.Discovery {
+		if conf.HeartbeatInterval <= 0 {
+			return fmt.Errorf("Configuration error: sharding heartbeat interval is less than or equal to 0")
+		}
+		if conf.MemberTTL <= conf.HeartbeatInterval {
+			return fmt.Errorf("Configuration error: sharding member ttl is not greater than heartbeat interval")
+		}
+		return nil
+	}
+
+	if conf.Count < 1 {
+		return fmt.Errorf("Configuration error: sharding count is less than 1")
+	}
+	if conf.Index < 0 || conf.Index >= conf.Count {
+		return fmt.Errorf("Configuration error: sharding index is less than 0 or not less than sharding count")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sharding_test
+
+import (
+	. "autoscaler/sharding"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"time"
+)
+
+var _ = Describe("ShardingConfig", func() {
+	var (
+		conf ShardingConfig
+		err  error
+	)
+
+	BeforeEach(func() {
+		conf = DefaultShardingConfig
+	})
+
+	JustBeforeEach(func() {
+		err = conf.Validate()
+	})
+
+	Context("when using the default config", func() {
+		It("should not error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when count is less than 1", func() {
+		BeforeEach(func() {
+			conf.Count = 0
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("Configuration error: sharding count is less than 1"))
+		})
+	})
+
+	Context("when index is not less than count", func() {
+		BeforeEach(func() {
+			conf.Index = 2
+			conf.Count = 2
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("Configuration error: sharding index is less than 0 or not less than sharding count"))
+		})
+	})
+
+	Context("when index is negative", func() {
+		BeforeEach(func() {
+			conf.Index = -1
+		})
+		It("should error", func() {
+			Expect(err).To(MatchError("Configuration error: sharding index is less than 0 or not less than sharding count"))
+		})
+	})
+
+	Context("when discovery is enabled", func() {
+		BeforeEach(func() {
+			conf.Discovery = true
+			conf.Count = 0
+		})
+
+		It("should ignore index and count", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when heartbeat interval is 0", func() {
+			BeforeEach(func() {
+				conf.HeartbeatInterval = 0
+			})
+			It("should error", func() {
+				Expect(err).To(MatchError("Configuration error: sharding heartbeat interval is less than or equal to 0"))
+			})
+		})
+
+		Context("when member ttl is not greater than heartbeat interval", func() {
+			BeforeEach(func() {
+				conf.HeartbeatInterval = 10 * time.Second
+				conf.MemberTTL = 10 * time.Second
+			})
+			It("should error", func() {
+				Expect(err).To(MatchError("Configuration error: sharding member ttl is not greater than heartbeat interval"))
+			})
+		})
+	})
+})
@@ -0,0 +1,236 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"autoscaler/db"
+	"sync"
+	"time"
+)
+
+type FakeShardDB struct {
+	RegisterShardMemberStub        func(group string, memberId string, ttl time.Duration) error
+	registerShardMemberMutex       sync.RWMutex
+	registerShardMemberArgsForCall []struct {
+		group    string
+		memberId string
+		ttl      time.Duration
+	}
+	registerShardMemberReturns struct {
+		result1 error
+	}
+	RetrieveShardMembersStub        func(group string) ([]string, error)
+	retrieveShardMembersMutex       sync.RWMutex
+	retrieveShardMembersArgsForCall []struct {
+		group string
+	}
+	retrieveShardMembersReturns struct {
+		result1 []string
+		result2 error
+	}
+	RemoveShardMemberStub        func(group string, memberId string) error
+	removeShardMemberMutex       sync.RWMutex
+	removeShardMemberArgsForCall []struct {
+		group    string
+		memberId string
+	}
+	removeShardMemberReturns struct {
+		result1 error
+	}
+	PingStub        func() error
+	pingMutex       sync.RWMutex
+	pingArgsForCall []struct{}
+	pingReturns     struct {
+		result1 error
+	}
+	CloseStub        func() error
+	closeMutex       sync.RWMutex
+	closeArgsForCall []struct{}
+	closeReturns     struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeShardDB) RegisterShardMember(group string, memberId string, ttl time.Duration) error {
+	fake.registerShardMemberMutex.Lock()
+	fake.registerShardMemberArgsForCall = append(fake.registerShardMemberArgsForCall, struct {
+		group    string
+		memberId string
+		ttl      time.Duration
+	}{group, memberId, ttl})
+	fake.recordInvocation("RegisterShardMember", []interface{}{group, memberId, ttl})
+	fake.registerShardMemberMutex.Unlock()
+	if fake.RegisterShardMemberStub != nil {
+		return fake.RegisterShardMemberStub(group, memberId, ttl)
+	} else {
+		return fake.registerShardMemberReturns.result1
+	}
+}
+
+func (fake *FakeShardDB) RegisterShardMemberCallCount() int {
+	fake.registerShardMemberMutex.RLock()
+	defer fake.registerShardMemberMutex.RUnlock()
+	return len(fake.registerShardMemberArgsForCall)
+}
+
+func (fake *FakeShardDB) RegisterShardMemberArgsForCall(i int) (string, string, time.Duration) {
+	fake.registerShardMemberMutex.RLock()
+	defer fake.registerShardMemberMutex.RUnlock()
+	return fake.registerShardMemberArgsForCall[i].group, fake.registerShardMemberArgsForCall[i].memberId, fake.registerShardMemberArgsForCall[i].ttl
+}
+
+func (fake *FakeShardDB) RegisterShardMemberReturns(result1 error) {
+	fake.RegisterShardMemberStub = nil
+	fake.registerShardMemberReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeShardDB) RetrieveShardMembers(group string) ([]string, error) {
+	fake.retrieveShardMembersMutex.Lock()
+	fake.retrieveShardMembersArgsForCall = append(fake.retrieveShardMembersArgsForCall, struct {
+		group string
+	}{group})
+	fake.recordInvocation("RetrieveShardMembers", []interface{}{group})
+	fake.retrieveShardMembersMutex.Unlock()
+	if fake.RetrieveShardMembersStub != nil {
+		return fake.RetrieveShardMembersStub(group)
+	} else {
+		return fake.retrieveShardMembersReturns.result1, fake.retrieveShardMembersReturns.result2
+	}
+}
+
+func (fake *FakeShardDB) RetrieveShardMembersCallCount() int {
+	fake.retrieveShardMembersMutex.RLock()
+	defer fake.retrieveShardMembersMutex.RUnlock()
+	return len(fake.retrieveShardMembersArgsForCall)
+}
+
+func (fake *FakeShardDB) RetrieveShardMembersArgsForCall(i int) string {
+	fake.retrieveShardMembersMutex.RLock()
+	defer fake.retrieveShardMembersMutex.RUnlock()
+	return fake.retrieveShardMembersArgsForCall[i].group
+}
+
+func (fake *FakeShardDB) RetrieveShardMembersReturns(result1 []string, result2 error) {
+	fake.RetrieveShardMembersStub = nil
+	fake.retrieveShardMembersReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeShardDB) RemoveShardMember(group string, memberId string) error {
+	fake.removeShardMemberMutex.Lock()
+	fake.removeShardMemberArgsForCall = append(fake.removeShardMemberArgsForCall, struct {
+		group    string
+		memberId string
+	}{group, memberId})
+	fake.recordInvocation("RemoveShardMember", []interface{}{group, memberId})
+	fake.removeShardMemberMutex.Unlock()
+	if fake.RemoveShardMemberStub != nil {
+		return fake.RemoveShardMemberStub(group, memberId)
+	} else {
+		return fake.removeShardMemberReturns.result1
+	}
+}
+
+func (fake *FakeShardDB) RemoveShardMemberCallCount() int {
+	fake.removeShardMemberMutex.RLock()
+	defer fake.removeShardMemberMutex.RUnlock()
+	return len(fake.removeShardMemberArgsForCall)
+}
+
+func (fake *FakeShardDB) RemoveShardMemberArgsForCall(i int) (string, string) {
+	fake.removeShardMemberMutex.RLock()
+	defer fake.removeShardMemberMutex.RUnlock()
+	return fake.removeShardMemberArgsForCall[i].group, fake.removeShardMemberArgsForCall[i].memberId
+}
+
+func (fake *FakeShardDB) RemoveShardMemberReturns(result1 error) {
+	fake.RemoveShardMemberStub = nil
+	fake.removeShardMemberReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeShardDB) Ping() error {
+	fake.pingMutex.Lock()
+	fake.pingArgsForCall = append(fake.pingArgsForCall, struct{}{})
+	fake.recordInvocation("Ping", []interface{}{})
+	fake.pingMutex.Unlock()
+	if fake.PingStub != nil {
+		return fake.PingStub()
+	} else {
+		return fake.pingReturns.result1
+	}
+}
+
+func (fake *FakeShardDB) PingCallCount() int {
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	return len(fake.pingArgsForCall)
+}
+
+func (fake *FakeShardDB) PingReturns(result1 error) {
+	fake.PingStub = nil
+	fake.pingReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeShardDB) Close() error {
+	fake.closeMutex.Lock()
+	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
+	fake.recordInvocation("Close", []interface{}{})
+	fake.closeMutex.Unlock()
+	if fake.CloseStub != nil {
+		return fake.CloseStub()
+	} else {
+		return fake.closeReturns.result1
+	}
+}
+
+func (fake *FakeShardDB) CloseCallCount() int {
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return len(fake.closeArgsForCall)
+}
+
+func (fake *FakeShardDB) CloseReturns(result1 error) {
+	fake.CloseStub = nil
+	fake.closeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeShardDB) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.registerShardMemberMutex.RLock()
+	defer fake.registerShardMemberMutex.RUnlock()
+	fake.retrieveShardMembersMutex.RLock()
+	defer fake.retrieveShardMembersMutex.RUnlock()
+	fake.removeShardMemberMutex.RLock()
+	defer fake.removeShardMemberMutex.RUnlock()
+	fake.pingMutex.RLock()
+	defer fake.pingMutex.RUnlock()
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeShardDB) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ db.ShardDB = new(FakeShardDB)
@@ -0,0 +1,123 @@
+package sharding
+
+import (
+	"autoscaler/db"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Membership discovers the shard of an instance from the live members of its
+// group. Every member heartbeats into the shard db; the shard index is the
+// position of the member in the ordered list of unexpired members and the
+// count is the length of that list, so shards are rebalanced whenever a member
+// joins, leaves or expires. Expirations are computed by the shard db from its
+// own clock, so that members agree on them regardless of clock skew.
+type Membership struct {
+	logger            lager.Logger
+	database          db.ShardDB
+	group             string
+	memberId          string
+	heartbeatInterval time.Duration
+	ttl               time.Duration
+	mclock            clock.Clock
+	ticker            clock.Ticker
+	doneChan          chan bool
+	shard             Shard
+	lock              sync.Mutex
+}
+
+func NewMembership(logger lager.Logger, database db.ShardDB, group string, memberId string, heartbeatInterval time.Duration, ttl time.Duration, mclock clock.Clock) *Membership {
+	return &Membership{
+		logger:            logger,
+		database:          database,
+		group:             group,
+		memberId:          memberId,
+		heartbeatInterval: heartbeatInterval,
+		ttl:               ttl,
+		mclock:            mclock,
+		doneChan:          make(chan bool),
+	}
+}
+
+// Start registers the member before returning, so that Shard reflects the
+// membership as soon as the db is reachable.
+func (m *Membership) Start() {
+	m.heartbeat()
+	m.ticker = m.mclock.NewTicker(m.heartbeatInterval)
+	go m.startHeartbeat()
+	m.logger.Info("membership-started", lager.Data{"group": m.group, "memberid": m.memberId})
+}
+
+func (m *Membership) startHeartbeat() {
+	for {
+		select {
+		case <-m.doneChan:
+			return
+		case <-m.ticker.C():
+			m.heartbeat()
+		}
+	}
+}
+
+func (m *Membership) heartbeat() {
+	err := m.database.RegisterShardMember(m.group, m.memberId, m.ttl)
+	if err != nil {
+		m.logger.Error("heartbeat-register-member", err)
+		return
+	}
+
+	members, err := m.database.RetrieveShardMembers(m.group)
+	if err != nil {
+		m.logger.Error("heartbeat-retrieve-members", err)
+		return
+	}
+
+	shard := Shard{}
+	for i, id := range members {
+		if id == m.memberId {
+			shard = Shard{Index: i, Count: len(members), Members: members}
+			break
+		}
+	}
+
+	m.lock.Lock()
+	if !shard.Equal(m.shard) {
+		m.logger.Info("shard-changed", lager.Data{"old": m.shard, "new": shard, "members": members})
+		m.shard = shard
+	}
+	m.lock.Unlock()
+}
+
+func (m *Membership) Shard() Shard {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.shard
+}
+
+// Check fails while the member is not among the live members of its group, as
+// it then owns no app, e.g. when its first heartbeat failed.
+func (m *Membership) Check() error {
+	if m.Shard().Count == 0 {
+		return fmt.Errorf("member %s is not a live member of group %s", m.memberId, m.group)
+	}
+	return nil
+}
+
+// Stop removes the member from the group so the remaining members take over
+// its apps without waiting for it to expire.
+func (m *Membership) Stop() {
+	if m.ticker != nil {
+		m.ticker.Stop()
+		close(m.doneChan)
+		err := m.database.RemoveShardMember(m.group, m.memberId)
+		if err != nil {
+			m.logger.Error("remove-member", err)
+		}
+	}
+	m.logger.Info("membership-stopped")
+}
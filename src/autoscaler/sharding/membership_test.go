@@ -0,0 +1,127 @@
+package sharding_test
+
+import (
+	. "autoscaler/sharding"
+	"autoscaler/sharding/fakes"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"errors"
+	"time"
+)
+
+const testHeartbeatInterval = 10 * time.Second
+const testMemberTTL = 30 * time.Second
+
+var _ = Describe("Membership", func() {
+	var (
+		database   *fakes.FakeShardDB
+		fclock     *fakeclock.FakeClock
+		membership *Membership
+		buffer     *gbytes.Buffer
+		members    []string
+	)
+
+	BeforeEach(func() {
+		database = &fakes.FakeShardDB{}
+		fclock = fakeclock.NewFakeClock(time.Now())
+		logger := lagertest.NewTestLogger("membership-test")
+		buffer = logger.Buffer()
+		members = []string{"member-a", "member-b", "member-c"}
+		database.RetrieveShardMembersStub = func(group string) ([]string, error) {
+			return members, nil
+		}
+		membership = NewMembership(logger, database, "test-group", "member-b", testHeartbeatInterval, testMemberTTL, fclock)
+	})
+
+	Describe("Start", func() {
+		JustBeforeEach(func() {
+			membership.Start()
+		})
+
+		AfterEach(func() {
+			membership.Stop()
+		})
+
+		It("registers the member with the ttl before returning", func() {
+			Expect(database.RegisterShardMemberCallCount()).To(Equal(1))
+			group, memberId, ttl := database.RegisterShardMemberArgsForCall(0)
+			Expect(group).To(Equal("test-group"))
+			Expect(memberId).To(Equal("member-b"))
+			Expect(ttl).To(Equal(testMemberTTL))
+
+			Expect(database.RetrieveShardMembersArgsForCall(0)).To(Equal("test-group"))
+		})
+
+		It("takes the position among the live members as the shard", func() {
+			Expect(membership.Shard()).To(Equal(Shard{Index: 1, Count: 3, Members: []string{"member-a", "member-b", "member-c"}}))
+			Expect(membership.Check()).To(Succeed())
+		})
+
+		It("heartbeats with the given interval", func() {
+			fclock.Increment(testHeartbeatInterval)
+			Eventually(database.RegisterShardMemberCallCount).Should(Equal(2))
+			fclock.Increment(testHeartbeatInterval)
+			Eventually(database.RegisterShardMemberCallCount).Should(Equal(3))
+		})
+
+		Context("when the membership changes", func() {
+			JustBeforeEach(func() {
+				members = []string{"member-b", "member-c"}
+				fclock.Increment(testHeartbeatInterval)
+			})
+
+			It("rebalances the shard", func() {
+				Eventually(membership.Shard).Should(Equal(Shard{Index: 0, Count: 2, Members: []string{"member-b", "member-c"}}))
+				Eventually(buffer).Should(gbytes.Say("shard-changed"))
+			})
+		})
+
+		Context("when registering the member fails", func() {
+			BeforeEach(func() {
+				database.RegisterShardMemberReturns(errors.New("an error"))
+			})
+
+			It("owns no app and fails the check", func() {
+				Expect(membership.Shard()).To(Equal(Shard{}))
+				Expect(membership.Check()).To(MatchError("member member-b is not a live member of group test-group"))
+				Expect(database.RetrieveShardMembersCallCount()).To(BeZero())
+				Eventually(buffer).Should(gbytes.Say("heartbeat-register-member"))
+			})
+		})
+
+		Context("when retrieving the members fails after a successful heartbeat", func() {
+			JustBeforeEach(func() {
+				database.RetrieveShardMembersStub = nil
+				database.RetrieveShardMembersReturns(nil, errors.New("an error"))
+				fclock.Increment(testHeartbeatInterval)
+			})
+
+			It("keeps the last known shard", func() {
+				Eventually(buffer).Should(gbytes.Say("heartbeat-retrieve-members"))
+				Expect(membership.Shard()).To(Equal(Shard{Index: 1, Count: 3, Members: []string{"member-a", "member-b", "member-c"}}))
+			})
+		})
+	})
+
+	Describe("Stop", func() {
+		BeforeEach(func() {
+			membership.Start()
+		})
+
+		It("stops heartbeating and removes the member", func() {
+			membership.Stop()
+			Expect(database.RemoveShardMemberCallCount()).To(Equal(1))
+			group, memberId := database.RemoveShardMemberArgsForCall(0)
+			Expect(group).To(Equal("test-group"))
+			Expect(memberId).To(Equal("member-b"))
+
+			fclock.Increment(testHeartbeatInterval)
+			Consistently(database.RegisterShardMemberCallCount).Should(Equal(1))
+		})
+	})
+})
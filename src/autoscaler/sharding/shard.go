@@ -0,0 +1,71 @@
+package sharding
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+// Shard is the slice of apps an instance is responsible for. Apps are assigned
+// to the members of the group by rendezvous hashing: every app goes to the
+// member with the highest weight for it, so a member joining or leaving only
+// moves the apps it gains or loses. Members are identified by Members when they
+// are discovered, and by their index otherwise; Index is the position of this
+// instance among them. A zero Shard owns no app.
+type Shard struct {
+	Index   int      `json:"index"`
+	Count   int      `json:"count"`
+	Members []string `json:"members,omitempty"`
+}
+
+var AllApps = Shard{Index: 0, Count: 1}
+
+func (s Shard) Owns(appId string) bool {
+	if s.Count <= 0 {
+		return false
+	}
+	owner := 0
+	var max uint64
+	for i := 0; i < s.Count; i++ {
+		w := weight(s.member(i), appId)
+		if i == 0 || w > max {
+			owner, max = i, w
+		}
+	}
+	return owner == s.Index
+}
+
+// Equal tells whether both shards assign the same apps to the same member.
+func (s Shard) Equal(other Shard) bool {
+	if s.Index != other.Index || s.Count != other.Count || len(s.Members) != len(other.Members) {
+		return false
+	}
+	for i := range s.Members {
+		if s.Members[i] != other.Members[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Shard) member(i int) string {
+	if len(s.Members) == s.Count {
+		return s.Members[i]
+	}
+	return strconv.Itoa(i)
+}
+
+func weight(member string, appId string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(member))
+	h.Write([]byte{0})
+	h.Write([]byte(appId))
+	// fnv leaves similar inputs with similar high bits, mix them to compare
+	// the weights of the members fairly
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
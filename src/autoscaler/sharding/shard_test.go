@@ -0,0 +1,95 @@
+package sharding_test
+
+import (
+	. "autoscaler/sharding"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"fmt"
+)
+
+var _ = Describe("Shard", func() {
+	var appIds []string
+
+	BeforeEach(func() {
+		appIds = []string{}
+		for i := 0; i < 100; i++ {
+			appIds = append(appIds, fmt.Sprintf("app-id-%d", i))
+		}
+	})
+
+	It("should assign every app to exactly one shard", func() {
+		for _, appId := range appIds {
+			owners := 0
+			for i := 0; i < 3; i++ {
+				if (Shard{Index: i, Count: 3}).Owns(appId) {
+					owners++
+				}
+			}
+			Expect(owners).To(Equal(1))
+		}
+	})
+
+	It("should assign the apps deterministically", func() {
+		Expect(Shard{Index: 1, Count: 3}.Owns("app-id-1")).To(Equal(Shard{Index: 1, Count: 3}.Owns("app-id-1")))
+	})
+
+	It("should spread the apps over the members", func() {
+		for i := 0; i < 3; i++ {
+			owned := 0
+			for _, appId := range appIds {
+				if (Shard{Index: i, Count: 3}).Owns(appId) {
+					owned++
+				}
+			}
+			Expect(owned).To(BeNumerically(">", 20))
+		}
+	})
+
+	Context("when a member joins", func() {
+		owner := func(shard Shard, appId string) string {
+			for i, member := range shard.Members {
+				if (Shard{Index: i, Count: shard.Count, Members: shard.Members}).Owns(appId) {
+					return member
+				}
+			}
+			return ""
+		}
+
+		It("should only move apps to the new member", func() {
+			before := Shard{Count: 3, Members: []string{"member-a", "member-c", "member-d"}}
+			after := Shard{Count: 4, Members: []string{"member-a", "member-b", "member-c", "member-d"}}
+			moved := 0
+			for _, appId := range appIds {
+				if owner(before, appId) != owner(after, appId) {
+					Expect(owner(after, appId)).To(Equal("member-b"))
+					moved++
+				}
+			}
+			Expect(moved).To(BeNumerically("<", 50))
+		})
+	})
+
+	Context("Equal", func() {
+		It("should compare the index, the count and the members", func() {
+			shard := Shard{Index: 1, Count: 2, Members: []string{"member-a", "member-b"}}
+			Expect(shard.Equal(Shard{Index: 1, Count: 2, Members: []string{"member-a", "member-b"}})).To(BeTrue())
+			Expect(shard.Equal(Shard{Index: 0, Count: 2, Members: []string{"member-a", "member-b"}})).To(BeFalse())
+			Expect(shard.Equal(Shard{Index: 1, Count: 2, Members: []string{"member-a", "member-c"}})).To(BeFalse())
+			Expect(shard.Equal(Shard{Index: 1, Count: 2})).To(BeFalse())
+		})
+	})
+
+	It("should assign all apps to a single shard", func() {
+		for _, appId := range appIds {
+			Expect(AllApps.Owns(appId)).To(BeTrue())
+		}
+	})
+
+	It("should assign no app to the zero shard", func() {
+		for _, appId := range appIds {
+			Expect(Shard{}.Owns(appId)).To(BeFalse())
+		}
+	})
+})